@@ -251,7 +251,7 @@ if (loginForm) {
     const ephemeralInput = document.getElementById("login-ephemeral-checkbox");
     const username = usernameInput.value.trim();
     const password = passwordInput.value;
-    const ephemeral = ephemeralInput.checked;
+    const ephemeral = !ephemeralInput.checked;
 
     if (!username || !password) {
       showInlineNotification(
@@ -159,11 +159,21 @@ document.addEventListener("DOMContentLoaded", () => {
       return response.json();
     })
     .then((data) => {
-      users = data.users.map((user) => {
+      if (data.unavailable && data.unavailable.length > 0) {
+        console.warn(
+          `Search results are incomplete, unavailable: ${data.unavailable.join(", ")}`,
+        );
+      }
+      if (data.stale && data.stale.length > 0) {
+        console.warn(
+          `Search results may be out of date, cached: ${data.stale.join(", ")}`,
+        );
+      }
+
+      users = (data.users || []).map((user) => {
         const card = userCardTemplate.content.cloneNode(true).children[0];
         const avatar = card.querySelector("[data-result-user-avatar]");
         const name = card.querySelector("[data-result-user-name]");
-        const imageAttr = "db/userdata/images/user-images/";
         const id = user.ID;
 
         name.textContent = user.Username;
@@ -174,18 +184,17 @@ document.addEventListener("DOMContentLoaded", () => {
           avatar.setAttribute("data-name-user", user.Name);
           // console.log("added a placeholder image for channel: ", channel.name);
         } else {
-          avatar.setAttribute("data-image-user", `${imageAttr + user.Avatar}`);
+          avatar.setAttribute("data-image-user", user.AvatarURL);
         }
 
         userCardContainer.append(card);
         return { username: user.Username, avatar: user.Avatar, element: card };
       });
 
-      channels = data.channels.map((channel) => {
+      channels = (data.channels || []).map((channel) => {
         const card = channelCardTemplate.content.cloneNode(true).children[0];
         const avatar = card.querySelector("[data-result-channel-avatar]");
         const name = card.querySelector("[data-result-channel-name]");
-        const imageAttr = "db/userdata/images/channel-images/";
         const id = channel.ID;
 
         name.textContent = "/" + channel.Name;
@@ -196,10 +205,7 @@ document.addEventListener("DOMContentLoaded", () => {
           avatar.setAttribute("data-name-channel", channel.Name);
           // console.log("added a placeholder image for channel: ", channel.name);
         } else {
-          avatar.setAttribute(
-            "data-image-channel",
-            `${imageAttr + channel.Avatar}`,
-          );
+          avatar.setAttribute("data-image-channel", channel.AvatarURL);
         }
 
         channelCardContainer.append(card);
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// runCheck prints every integrity problem in the database and reports whether
+// any were found
+func runCheck(db *sql.DB) (bool, error) {
+	fmt.Printf("%s> Checking database integrity...%s\n", seedColors.CodexPink, seedColors.Reset)
+
+	checker := sqlite.IntegrityModel{DB: db}
+	issues, err := checker.Check(context.Background())
+	if err != nil {
+		return false, err
+	}
+	if len(issues) == 0 {
+		fmt.Printf("%s✓ No problems found%s\n", seedColors.Green, seedColors.Reset)
+		return true, nil
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s✗ %s%s\n", seedColors.Red, issue, seedColors.Reset)
+	}
+	fmt.Printf("%s%d problem(s) found%s\n", seedColors.Yellow, len(issues), seedColors.Reset)
+	return false, nil
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/gary-norman/forum/internal/app"
 	"github.com/gary-norman/forum/internal/colors"
+	"github.com/gary-norman/forum/internal/events"
 	"github.com/gary-norman/forum/internal/http/routes"
 	"github.com/gary-norman/forum/internal/models"
 	"github.com/gary-norman/forum/internal/view"
@@ -37,7 +38,7 @@ func main() {
 		log.Fatalf("Failed to initialize app: %v", err)
 	}
 
-	// CLI commands: migrate + seed
+	// CLI commands: migrate + seed + check
 	if len(os.Args) > 1 {
 		defer cleanup()
 		switch os.Args[1] {
@@ -55,6 +56,16 @@ func main() {
 				log.Fatalf("Seeding failed: %v", err)
 			}
 			return
+		case "check":
+			ok, err := runCheck(appInstance.DB)
+			if err != nil {
+				log.Fatalf("Integrity check failed: %v", err)
+			}
+			if !ok {
+				cleanup()
+				os.Exit(1)
+			}
+			return
 		}
 	}
 
@@ -87,11 +98,32 @@ func startServer(appInstance *app.App, dbCleanup func()) {
 	// Create and start logger pool
 	loggerPool := workers.NewLoggerPool(3, 1000, appInstance.DB)
 	loggerPool.Start()
+	// Warnings and errors logged with a context are also stored, with the request ID when
+	// there is one; if the queue is full they stay on the console and LoggerPool counts the drop
+	models.SetErrorLogSink(func(entry models.ErrorLog) { _ = loggerPool.LogError(entry) })
+
+	// Background jobs register here and share the server's shutdown
+	backgroundWorkers := workers.NewRegistry()
+	if queue, ok := appInstance.Events.(*events.Queue); ok {
+		if err := backgroundWorkers.Register("event-queue", queue.Run); err != nil {
+			log.Fatalf("Failed to register event queue: %v", err)
+		}
+	}
+	pruner := workers.NewImagePruner(appInstance.Images, workers.ImageDir,
+		appInstance.Config.ImagePruneGrace, appInstance.Config.ImagePruneInterval)
+	if err := backgroundWorkers.Register("image-pruner", pruner.Run); err != nil {
+		log.Fatalf("Failed to register image pruner: %v", err)
+	}
+	collector := workers.NewMetricsCollector(appInstance.DB, loggerPool, appInstance.Config.MetricsInterval)
+	if err := backgroundWorkers.Register("metrics-collector", collector.Run); err != nil {
+		log.Fatalf("Failed to register metrics collector: %v", err)
+	}
+	backgroundWorkers.Start(context.Background())
 
 	// Router
 	router := routes.NewRouter(appInstance, loggerPool)
 
-	port := 8888
+	port := appInstance.Config.Port
 	portStr := fmt.Sprintf(Colors.CodexPink+"%d"+Colors.Reset, port)
 	addr := fmt.Sprintf(":%d", port)
 
@@ -122,11 +154,17 @@ func startServer(appInstance *app.App, dbCleanup func()) {
 	defer shutdownRelease()
 
 	log.Println("Shutting down gracefully...")
+	appInstance.StartDraining()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf(ErrorMsgs.Shutdown, err)
 	}
 
+	log.Println("Stopping background workers...")
+	if err := backgroundWorkers.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: Background worker shutdown: %v", err)
+	}
+
 	log.Println("Draining log queue...")
 	if err := loggerPool.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Warning: Logger pool shutdown timeout: %v", err)
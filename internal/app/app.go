@@ -3,34 +3,35 @@ package app
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
 	"strings"
-	"time"
+	"sync/atomic"
 
+	"github.com/gary-norman/forum/internal/auth/oauth"
 	"github.com/gary-norman/forum/internal/colors"
+	"github.com/gary-norman/forum/internal/config"
+	"github.com/gary-norman/forum/internal/cursor"
 	"github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/events"
 	"github.com/gary-norman/forum/internal/models"
 	"github.com/gary-norman/forum/internal/patterns"
+	"github.com/gary-norman/forum/internal/service"
+	"github.com/gary-norman/forum/internal/signedurl"
 	"github.com/gary-norman/forum/internal/sqlite"
 )
 
-type Config struct {
-	DBType     string
-	DBDriver   string
-	DBEnv      string
-	DBPath     string
-	SchemaPath string
-	ImagePath  string
-}
-
 var (
 	Colors, _ = colors.UseFlavor("Mocha")
 	ErrorMsgs = models.CreateErrorMessages()
 )
 
+// eventQueueSize is how many undelivered webhook events are held before new ones are dropped
+const eventQueueSize = 256
+
 // LoadEnv reads .env and sets os.Environ()
 func loadEnv(filename string) error {
 	file, err := os.Open(filename)
@@ -63,18 +64,14 @@ func loadEnv(filename string) error {
 }
 
 // InitConfig loads .env and builds the Config
-func initConfig() *Config {
+func initConfig() *config.Config {
 	if err := loadEnv("./.env"); err != nil {
 		log.Fatalf("❌ failed to load .env: %v", err)
 	}
 
-	cfg := &Config{
-		DBType:     "SQLite",
-		DBDriver:   "sqlite3",
-		DBEnv:      os.Getenv("DB_ENV"),
-		DBPath:     os.Getenv("DB_PATH"),
-		SchemaPath: "./migrations/001_schema.sql",
-		ImagePath:  "/db/userdata/images/",
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("❌ invalid configuration: %v", err)
 	}
 
 	if cfg.DBEnv == "" || cfg.DBPath == "" {
@@ -99,18 +96,54 @@ type App struct {
 	Flags          *sqlite.FlagModel
 	Loyalty        *sqlite.LoyaltyModel
 	Memberships    *sqlite.MembershipModel
+	Visits         *sqlite.ChannelVisitModel
 	Muted          *sqlite.MutedChannelModel
 	Cookies        *sqlite.CookieModel
+	Sessions       *sqlite.SessionModel
+	Notifications  *sqlite.NotificationModel
 	Rules          *sqlite.RuleModel
 	Chats          *sqlite.ChatModel
+	Filters        *sqlite.ContentFilterModel
+	Audit          *sqlite.AuditLogModel
+	Search         *sqlite.SearchModel
+	PasswordResets *sqlite.PasswordResetModel
+	Logs           *sqlite.LoggingModel
+	PostService    *service.PostService
+	ChannelService *service.ChannelService
+	PasswordReset  *service.PasswordResetService
+	OAuth          *service.OAuthService
+	// OAuth providers users can log in with, by name; only configured ones are present
+	OAuthProviders map[string]*oauth.Provider
+	SearchCache    SearchCache
+	Events         events.EventEmitter
+	URLSigner      *signedurl.Signer
+	Cursors        *cursor.Codec
+	Config         *config.Config
 	Paths          models.ImagePaths
+
+	draining atomic.Bool
+}
+
+// StartDraining marks the server as shutting down. Requests already running are
+// unaffected; new writes and readiness checks are refused from then on.
+func (a *App) StartDraining() { a.draining.Store(true) }
+
+// Draining reports whether StartDraining has been called
+func (a *App) Draining() bool { return a.draining.Load() }
+
+// SearchCache holds the last successful All() results for each search source, which
+// search serves (marked stale) while the database is failing or its circuit is open
+type SearchCache struct {
+	Users    *patterns.LastGood[[]*models.User]
+	Posts    *patterns.LastGood[[]*models.Post]
+	Channels *patterns.LastGood[[]*models.Channel]
 }
 
-func NewApp(db *sql.DB, imagePath string) *App {
-	// Initialize circuit breaker: 5 failures, 5 second timeout
-	dbCircuit := patterns.NewCircuitBreaker(5, 5*time.Second)
+func NewApp(db *sql.DB, cfg *config.Config) *App {
+	dbCircuit := patterns.NewCircuitBreaker(cfg.DBCircuitMaxFailures, cfg.DBCircuitTimeout)
+	imagePath := cfg.ImagePath
 
-	return &App{
+	a := &App{
 		DB:          db,
 		DBCircuit:   dbCircuit,
 		Users:       &sqlite.UserModel{DB: db},
@@ -124,10 +157,26 @@ func NewApp(db *sql.DB, imagePath string) *App {
 		Flags:       &sqlite.FlagModel{DB: db},
 		Loyalty:     &sqlite.LoyaltyModel{DB: db},
 		Memberships: &sqlite.MembershipModel{DB: db},
+		Visits:      &sqlite.ChannelVisitModel{DB: db},
 		Muted:       &sqlite.MutedChannelModel{DB: db},
-		Cookies:     &sqlite.CookieModel{DB: db},
-		Rules:       &sqlite.RuleModel{DB: db},
-		Chats:       &sqlite.ChatModel{DB: db},
+		Cookies: &sqlite.CookieModel{
+			DB:                 db,
+			RememberedLifetime: cfg.RememberedSessionLifetime,
+			EphemeralLifetime:  cfg.EphemeralSessionLifetime,
+		},
+		Sessions:       &sqlite.SessionModel{DB: db},
+		Notifications:  &sqlite.NotificationModel{DB: db},
+		Rules:          &sqlite.RuleModel{DB: db},
+		Chats:          &sqlite.ChatModel{DB: db},
+		Filters:        &sqlite.ContentFilterModel{DB: db},
+		Audit:          &sqlite.AuditLogModel{DB: db},
+		Search:         &sqlite.SearchModel{DB: db},
+		PasswordResets: &sqlite.PasswordResetModel{DB: db},
+		Logs:           &sqlite.LoggingModel{DB: db},
+		Events:         events.NoopEmitter{},
+		URLSigner:      signedurl.NewSigner(cfg.ImageURLSecret),
+		Cursors:        cursor.NewCodec(cfg.CursorSecret),
+		Config:         cfg,
 
 		Paths: models.ImagePaths{
 			Channel: imagePath + "channel-images/",
@@ -135,6 +184,51 @@ func NewApp(db *sql.DB, imagePath string) *App {
 			User:    imagePath + "user-images/",
 		},
 	}
+	a.SearchCache = SearchCache{
+		Users:    patterns.NewLastGood[[]*models.User](cfg.SearchCacheTTL),
+		Posts:    patterns.NewLastGood[[]*models.Post](cfg.SearchCacheTTL),
+		Channels: patterns.NewLastGood[[]*models.Channel](cfg.SearchCacheTTL),
+	}
+	a.PostService = &service.PostService{
+		Posts:     a.Posts,
+		Reactions: a.Reactions,
+		Comments:  a.Comments,
+		Channels:  a.Channels,
+	}
+	a.ChannelService = &service.ChannelService{Channels: a.Channels}
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("http://localhost:%d", cfg.Port)
+	}
+	var mailer service.EmailSender = service.LogSender{}
+	if cfg.SMTPAddr != "" {
+		mailer = &service.SMTPSender{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		}
+	}
+	a.PasswordReset = &service.PasswordResetService{
+		Users:   a.Users,
+		Resets:  a.PasswordResets,
+		Mailer:  mailer,
+		Signer:  signedurl.NewSigner(cfg.PasswordResetSecret),
+		TTL:     cfg.PasswordResetTTL,
+		BaseURL: publicURL,
+	}
+	a.OAuth = &service.OAuthService{Users: a.Users}
+	a.OAuthProviders = make(map[string]*oauth.Provider)
+	redirectURL := func(provider string) string { return publicURL + "/auth/" + provider + "/callback" }
+	if cfg.GoogleClientID != "" {
+		a.OAuthProviders[oauth.ProviderGoogle] = oauth.Google(cfg.GoogleClientID, cfg.GoogleClientSecret,
+			redirectURL(oauth.ProviderGoogle))
+	}
+	if cfg.GitHubClientID != "" {
+		a.OAuthProviders[oauth.ProviderGitHub] = oauth.GitHub(cfg.GitHubClientID, cfg.GitHubClientSecret,
+			redirectURL(oauth.ProviderGitHub))
+	}
+	return a
 }
 
 func InitializeApp() (*App, func(), error) {
@@ -160,7 +254,30 @@ func InitializeApp() (*App, func(), error) {
 	log.Printf(ErrorMsgs.DBSuccess, cfg.DBType, dbVersion)
 
 	// App instance with DB reference
-	appInstance := NewApp(initDB, cfg.ImagePath)
+	appInstance := NewApp(initDB, cfg)
+	if cfg.WebhookURL != "" {
+		// delivered by a background job that main registers with its worker registry
+		appInstance.Events = events.NewQueue(events.NewWebhookEmitter(cfg.WebhookURL, cfg.WebhookSecret), eventQueueSize)
+	}
+	fts, err := appInstance.Search.EnsureIndex(context.Background())
+	switch {
+	case err != nil:
+		models.LogError("Failed to set up the search index, search will use LIKE matching", err)
+	case !fts:
+		models.LogWarn("SQLite was built without FTS5 (-tags sqlite_fts5), search will use LIKE matching")
+	}
+	if cfg.ImageURLSecret == "" {
+		models.LogWarn("IMAGE_URL_SECRET not set, signed image links will not survive a restart")
+	}
+	if cfg.CursorSecret == "" {
+		models.LogWarn("CURSOR_SECRET not set, pagination cursors will not survive a restart")
+	}
+	if cfg.PasswordResetSecret == "" {
+		models.LogWarn("PASSWORD_RESET_SECRET not set, password reset links will not survive a restart")
+	}
+	if cfg.SMTPAddr == "" {
+		models.LogWarn("SMTP_ADDR not set, password reset emails will only be logged")
+	}
 
 	// Cleanup function to close DB connection
 	cleanup := func() {
@@ -0,0 +1,238 @@
+// Package oauth signs users in with their Google or GitHub accounts using the OAuth2
+// authorization code flow.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrExchange = errors.New("oauth: code exchange failed")
+	ErrProfile  = errors.New("oauth: failed to fetch profile")
+)
+
+// Provider names, used in the /auth/{provider}/ routes and stored with each identity
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// Profile is what a provider tells us about the account that signed in
+type Profile struct {
+	// Subject is the provider's stable ID for the account
+	Subject string
+	Email   string
+	// EmailVerified is set when the provider vouches that the account owns Email
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is an OAuth2 authorization server and the API that describes its users
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	// EmailsURL lists the account's email addresses, for providers whose user info
+	// may leave the email out
+	EmailsURL   string
+	Scopes      []string
+	RedirectURL string
+	Client      *http.Client
+
+	profile func(ctx context.Context, p *Provider, token string) (*Profile, error)
+}
+
+// Google returns the Google provider for the given client credentials
+func Google(clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Name:         ProviderGoogle,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+		Scopes:       []string{"openid", "email", "profile"},
+		RedirectURL:  redirectURL,
+		profile:      googleProfile,
+	}
+}
+
+// GitHub returns the GitHub provider for the given client credentials
+func GitHub(clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Name:         ProviderGitHub,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		UserInfoURL:  "https://api.github.com/user",
+		EmailsURL:    "https://api.github.com/user/emails",
+		Scopes:       []string{"read:user", "user:email"},
+		RedirectURL:  redirectURL,
+		profile:      githubProfile,
+	}
+}
+
+func (p *Provider) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// AuthCodeURL is where to send the user to sign in. state comes back unchanged on
+// the callback, which must check it matches before exchanging the code.
+func (p *Provider) AuthCodeURL(state string) string {
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.ClientID},
+		"redirect_uri":  {p.RedirectURL},
+		"scope":         {strings.Join(p.Scopes, " ")},
+		"state":         {state},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.AuthURL + sep + q.Encode()
+}
+
+// Exchange trades the code from the callback for an access token
+func (p *Provider) Exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	// GitHub reports a bad code with 200 and an error field, Google with 400
+	status, err := p.getJSON(req, &token)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("%w: %s: %s", ErrExchange, token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("%w: %s returned %d without a token", ErrExchange, p.Name, status)
+	}
+	return token.AccessToken, nil
+}
+
+// Profile fetches the account the access token belongs to
+func (p *Provider) Profile(ctx context.Context, token string) (*Profile, error) {
+	profile, err := p.profile(ctx, p, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w from %s: %w", ErrProfile, p.Name, err)
+	}
+	if profile.Subject == "" {
+		return nil, fmt.Errorf("%w from %s: no account ID", ErrProfile, p.Name)
+	}
+	return profile, nil
+}
+
+// get fetches an API URL with the access token into v, failing on any status but 200
+func (p *Provider) get(ctx context.Context, apiURL, token string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	status, err := p.getJSON(req, v)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%s returned %d", apiURL, status)
+	}
+	return nil
+}
+
+// getJSON sends req asking for JSON and decodes the response into v, whatever the status
+func (p *Provider) getJSON(req *http.Request, v any) (int, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("failed to decode %s response: %w", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
+
+func googleProfile(ctx context.Context, p *Provider, token string) (*Profile, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := p.get(ctx, p.UserInfoURL, token, &info); err != nil {
+		return nil, err
+	}
+	return &Profile{Subject: info.Sub, Email: info.Email, EmailVerified: info.EmailVerified, Name: info.Name}, nil
+}
+
+// githubProfile reads the account from /user, taking the email from /user/emails
+// since /user only shows one the user has made public, and says nothing of whether
+// it is verified
+func githubProfile(ctx context.Context, p *Provider, token string) (*Profile, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(ctx, p.UserInfoURL, token, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return &Profile{}, nil
+	}
+	profile := &Profile{Subject: strconv.FormatInt(user.ID, 10), Name: user.Login}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, p.EmailsURL, token, &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Primary {
+			profile.Email, profile.EmailVerified = e.Email, e.Verified
+			break
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,131 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// fakeGitHub serves GitHub's token, user and emails endpoints, accepting only the
+// code "good-code"
+func fakeGitHub(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Expected the token request to ask for JSON, got %q", r.Header.Get("Accept"))
+		}
+		if r.FormValue("client_secret") != "secret" || r.FormValue("redirect_uri") != "https://codex.example/cb" {
+			t.Errorf("Unexpected token request form %v", r.Form)
+		}
+		if r.FormValue("code") != "good-code" {
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "tok", "token_type": "bearer"})
+	})
+	authorized := func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer tok" }
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 4242, "login": "octocat", "name": "The Octocat"})
+	})
+	mux.HandleFunc("GET /user/emails", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{"email": "old@example.com", "primary": false, "verified": true},
+			{"email": "octo@example.com", "primary": true, "verified": true},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testGitHub(srv *httptest.Server) *Provider {
+	p := GitHub("client", "secret", "https://codex.example/cb")
+	p.TokenURL = srv.URL + "/login/oauth/access_token"
+	p.UserInfoURL = srv.URL + "/user"
+	p.EmailsURL = srv.URL + "/user/emails"
+	p.Client = srv.Client()
+	return p
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	p := Google("client", "secret", "https://codex.example/cb")
+	u, err := url.Parse(p.AuthCodeURL("xyz"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL does not parse: %v", err)
+	}
+	if u.Host != "accounts.google.com" {
+		t.Errorf("Expected Google's authorization host, got %s", u.Host)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type": "code",
+		"client_id":     "client",
+		"redirect_uri":  "https://codex.example/cb",
+		"scope":         "openid email profile",
+		"state":         "xyz",
+	}
+	for key, value := range want {
+		if got := q.Get(key); got != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, got)
+		}
+	}
+}
+
+func TestGitHubSignIn(t *testing.T) {
+	p := testGitHub(fakeGitHub(t))
+	ctx := context.Background()
+
+	token, err := p.Exchange(ctx, "good-code")
+	if err != nil || token != "tok" {
+		t.Fatalf("Expected token tok, got %q, %v", token, err)
+	}
+	profile, err := p.Profile(ctx, token)
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	want := Profile{Subject: "4242", Email: "octo@example.com", EmailVerified: true, Name: "octocat"}
+	if *profile != want {
+		t.Errorf("Expected %+v, got %+v", want, *profile)
+	}
+
+	if _, err := p.Exchange(ctx, "stale-code"); !errors.Is(err, ErrExchange) {
+		t.Errorf("Expected ErrExchange for a refused code, got %v", err)
+	}
+	if _, err := p.Profile(ctx, "forged"); !errors.Is(err, ErrProfile) {
+		t.Errorf("Expected ErrProfile for a refused token, got %v", err)
+	}
+}
+
+func TestGoogleProfile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"sub": "1098", "email": "ada@example.com", "email_verified": false, "name": "Ada Lovelace",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	p := Google("client", "secret", "https://codex.example/cb")
+	p.UserInfoURL = srv.URL
+	p.Client = srv.Client()
+
+	profile, err := p.Profile(context.Background(), "tok")
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	want := Profile{Subject: "1098", Email: "ada@example.com", EmailVerified: false, Name: "Ada Lovelace"}
+	if *profile != want {
+		t.Errorf("Expected %+v, got %+v", want, *profile)
+	}
+}
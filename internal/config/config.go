@@ -0,0 +1,361 @@
+// Package config holds the server's tunable settings. Every field has a default
+// matching the value that used to be hardcoded; environment variables (usually
+// loaded from .env) override them.
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// Defaults for values that other packages fall back to when left unset
+const (
+	DefaultPort                      = 8888
+	DefaultImagePath                 = "/db/userdata/images/"
+	DefaultRequestTimeout            = 10 * time.Second
+	DefaultRememberedSessionLifetime = 90 * 24 * time.Hour
+	DefaultEphemeralSessionLifetime  = 24 * time.Hour
+	DefaultDBCircuitMaxFailures      = 5
+	DefaultDBCircuitTimeout          = 5 * time.Second
+	DefaultSearchCacheTTL            = time.Minute
+	DefaultMaxUploadSize             = 10 << 20
+	DefaultImagePruneInterval        = 6 * time.Hour
+	DefaultImagePruneGrace           = 24 * time.Hour
+	DefaultPasswordResetTTL          = time.Hour
+	DefaultMetricsInterval           = time.Minute
+)
+
+// Default rate limits: every client IP across the whole site, each IP on the
+// login, register and password reset endpoints, and each logged-in user on the
+// endpoints that create content
+var (
+	DefaultRateLimit      = models.RateLimit{Requests: 300, Per: time.Minute}
+	DefaultAuthRateLimit  = models.RateLimit{Requests: 10, Per: time.Minute}
+	DefaultWriteRateLimit = models.RateLimit{Requests: 30, Per: time.Minute}
+)
+
+// Config is the full set of settings the server starts with
+type Config struct {
+	DBType     string
+	DBDriver   string
+	DBEnv      string
+	DBPath     string
+	SchemaPath string
+	ImagePath  string
+
+	// HTTP listener port and the per-request handler deadline
+	Port           int
+	RequestTimeout time.Duration
+
+	// Moderation webhook; events are dropped when WebhookURL is empty
+	WebhookURL    string
+	WebhookSecret string
+	// HMAC key for signed private image URLs; random per process when empty
+	ImageURLSecret string
+	// HMAC key for pagination cursors; random per process when empty
+	CursorSecret string
+	// HMAC key for password reset links; random per process when empty
+	PasswordResetSecret string
+	// How long a password reset link works
+	PasswordResetTTL time.Duration
+	// Scheme and host that links in emails point at, e.g. https://codex.example;
+	// http://localhost on Port when empty
+	PublicURL string
+	// SMTP server (host:port) emails are sent through; emails are only logged when
+	// SMTPAddr is empty
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+	// OAuth client credentials; each provider's login is offered only when its
+	// client ID is set
+	GoogleClientID     string
+	GoogleClientSecret string
+	GitHubClientID     string
+	GitHubClientSecret string
+	// Origins allowed to call the JSON API cross-origin
+	AllowedOrigins []string
+	// Reverse proxies whose X-Forwarded-For and X-Real-IP headers name the client;
+	// with none, the client is always the connection's address
+	TrustedProxies []netip.Prefix
+
+	// Paths (and everything beneath them) the request logger skips
+	LogExcludePaths []string
+
+	// Requests allowed per client IP site-wide, per client IP on the login,
+	// register and password reset endpoints, and per user on content writes
+	RateLimit      models.RateLimit
+	AuthRateLimit  models.RateLimit
+	WriteRateLimit models.RateLimit
+
+	// Which parts of the login fingerprint a session is bound to
+	SessionBinding models.SessionBinding
+	// How long "keep me logged in" sessions and browser-session logins last
+	RememberedSessionLifetime time.Duration
+	EphemeralSessionLifetime  time.Duration
+
+	// Consecutive DB failures before the circuit opens, and how long it stays open
+	DBCircuitMaxFailures uint32
+	DBCircuitTimeout     time.Duration
+	// How long search may keep serving the last successful results while the DB fails
+	SearchCacheTTL time.Duration
+
+	// Largest multipart request, in bytes, accepted with a user or channel avatar
+	// and with a post image
+	MaxAvatarUploadSize    int64
+	MaxPostImageUploadSize int64
+
+	// How often unreferenced uploads are deleted, and how old they must be first
+	ImagePruneInterval time.Duration
+	ImagePruneGrace    time.Duration
+
+	// How often memory, goroutine and DB pool metrics are recorded in SystemMetrics
+	MetricsInterval time.Duration
+
+	// Count database queries per request and report them in X-DB-Query-Count
+	DebugQueryCount bool
+
+	// Hide flagged posts and comments from everyone but the channel's owner and
+	// moderators until they are reviewed. Flags stay in the moderation queue either way.
+	HideFlaggedContent bool
+}
+
+// DefaultLogExcludePaths are the monitoring endpoints kept out of RequestLogs
+func DefaultLogExcludePaths() []string {
+	return []string{"/health", "/ready", "/metrics"}
+}
+
+// Default returns a Config with every tunable at its default and no database selected
+func Default() *Config {
+	return &Config{
+		DBType:     "SQLite",
+		DBDriver:   "sqlite3",
+		SchemaPath: "./migrations/001_schema.sql",
+		ImagePath:  DefaultImagePath,
+
+		Port:            DefaultPort,
+		RequestTimeout:  DefaultRequestTimeout,
+		LogExcludePaths: DefaultLogExcludePaths(),
+
+		RateLimit:      DefaultRateLimit,
+		AuthRateLimit:  DefaultAuthRateLimit,
+		WriteRateLimit: DefaultWriteRateLimit,
+
+		RememberedSessionLifetime: DefaultRememberedSessionLifetime,
+		EphemeralSessionLifetime:  DefaultEphemeralSessionLifetime,
+
+		DBCircuitMaxFailures: DefaultDBCircuitMaxFailures,
+		DBCircuitTimeout:     DefaultDBCircuitTimeout,
+		SearchCacheTTL:       DefaultSearchCacheTTL,
+
+		MaxAvatarUploadSize:    DefaultMaxUploadSize,
+		MaxPostImageUploadSize: DefaultMaxUploadSize,
+
+		ImagePruneInterval: DefaultImagePruneInterval,
+		ImagePruneGrace:    DefaultImagePruneGrace,
+
+		PasswordResetTTL: DefaultPasswordResetTTL,
+		MetricsInterval:  DefaultMetricsInterval,
+	}
+}
+
+// Load builds a Config from the process environment
+func Load() (*Config, error) {
+	return FromEnv(os.Getenv)
+}
+
+// FromEnv builds a Config from the defaults, overridden by any variable getenv
+// returns a non-empty value for. Malformed values are reported, not ignored.
+func FromEnv(getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	cfg.DBEnv = getenv("DB_ENV")
+	cfg.DBPath = getenv("DB_PATH")
+	cfg.WebhookURL = getenv("MODERATION_WEBHOOK_URL")
+	cfg.WebhookSecret = getenv("MODERATION_WEBHOOK_SECRET")
+	cfg.ImageURLSecret = getenv("IMAGE_URL_SECRET")
+	cfg.CursorSecret = getenv("CURSOR_SECRET")
+	cfg.PasswordResetSecret = getenv("PASSWORD_RESET_SECRET")
+	cfg.PublicURL = strings.TrimSuffix(strings.TrimSpace(getenv("PUBLIC_URL")), "/")
+	cfg.SMTPAddr = getenv("SMTP_ADDR")
+	cfg.SMTPFrom = getenv("SMTP_FROM")
+	cfg.SMTPUsername = getenv("SMTP_USERNAME")
+	cfg.SMTPPassword = getenv("SMTP_PASSWORD")
+	cfg.GoogleClientID = getenv("GOOGLE_CLIENT_ID")
+	cfg.GoogleClientSecret = getenv("GOOGLE_CLIENT_SECRET")
+	cfg.GitHubClientID = getenv("GITHUB_CLIENT_ID")
+	cfg.GitHubClientSecret = getenv("GITHUB_CLIENT_SECRET")
+	cfg.AllowedOrigins = splitList(getenv("ALLOWED_ORIGINS"))
+	if paths := splitList(getenv("LOG_EXCLUDE_PATHS")); paths != nil {
+		cfg.LogExcludePaths = paths
+	}
+
+	binding, err := models.ParseSessionBinding(splitList(getenv("SESSION_BINDING")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SESSION_BINDING: %w", err)
+	}
+	cfg.SessionBinding = binding
+
+	p := parser{getenv: getenv}
+	p.int("PORT", &cfg.Port)
+	p.duration("REQUEST_TIMEOUT", &cfg.RequestTimeout)
+	p.duration("SESSION_LIFETIME", &cfg.RememberedSessionLifetime)
+	p.duration("EPHEMERAL_SESSION_LIFETIME", &cfg.EphemeralSessionLifetime)
+	p.uint32("DB_CIRCUIT_MAX_FAILURES", &cfg.DBCircuitMaxFailures)
+	p.duration("DB_CIRCUIT_TIMEOUT", &cfg.DBCircuitTimeout)
+	p.duration("SEARCH_CACHE_TTL", &cfg.SearchCacheTTL)
+	p.size("MAX_AVATAR_UPLOAD_SIZE", &cfg.MaxAvatarUploadSize)
+	p.size("MAX_POST_IMAGE_UPLOAD_SIZE", &cfg.MaxPostImageUploadSize)
+	p.duration("IMAGE_PRUNE_INTERVAL", &cfg.ImagePruneInterval)
+	p.duration("IMAGE_PRUNE_GRACE", &cfg.ImagePruneGrace)
+	p.duration("METRICS_INTERVAL", &cfg.MetricsInterval)
+	p.bool("DEBUG_QUERY_COUNT", &cfg.DebugQueryCount)
+	p.bool("HIDE_FLAGGED_CONTENT", &cfg.HideFlaggedContent)
+	p.duration("PASSWORD_RESET_TTL", &cfg.PasswordResetTTL)
+	p.rateLimit("RATE_LIMIT", &cfg.RateLimit)
+	p.rateLimit("AUTH_RATE_LIMIT", &cfg.AuthRateLimit)
+	p.rateLimit("WRITE_RATE_LIMIT", &cfg.WriteRateLimit)
+	p.prefixes("TRUSTED_PROXIES", &cfg.TrustedProxies)
+	if p.err != nil {
+		return nil, p.err
+	}
+	if cfg.SMTPAddr != "" && cfg.SMTPFrom == "" {
+		return nil, fmt.Errorf("SMTP_FROM must be set when SMTP_ADDR is")
+	}
+	if (cfg.GoogleClientID == "") != (cfg.GoogleClientSecret == "") {
+		return nil, fmt.Errorf("GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET must be set together")
+	}
+	if (cfg.GitHubClientID == "") != (cfg.GitHubClientSecret == "") {
+		return nil, fmt.Errorf("GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET must be set together")
+	}
+	return cfg, nil
+}
+
+// splitList parses a comma-separated env value, dropping empty entries
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// parser overrides fields from the environment, keeping the first error it hits
+type parser struct {
+	getenv func(string) string
+	err    error
+}
+
+func (p *parser) lookup(key string) (string, bool) {
+	if p.err != nil {
+		return "", false
+	}
+	value := strings.TrimSpace(p.getenv(key))
+	return value, value != ""
+}
+
+func (p *parser) int(key string, dst *int) {
+	if value, ok := p.lookup(key); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			p.err = fmt.Errorf("invalid %s %q: must be a positive integer", key, value)
+			return
+		}
+		*dst = n
+	}
+}
+
+func (p *parser) uint32(key string, dst *uint32) {
+	if value, ok := p.lookup(key); ok {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n == 0 {
+			p.err = fmt.Errorf("invalid %s %q: must be a positive integer", key, value)
+			return
+		}
+		*dst = uint32(n)
+	}
+}
+
+func (p *parser) duration(key string, dst *time.Duration) {
+	if value, ok := p.lookup(key); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			p.err = fmt.Errorf("invalid %s %q: must be a positive duration such as 30s or 24h", key, value)
+			return
+		}
+		*dst = d
+	}
+}
+
+func (p *parser) bool(key string, dst *bool) {
+	if value, ok := p.lookup(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			p.err = fmt.Errorf("invalid %s %q: must be true or false", key, value)
+			return
+		}
+		*dst = b
+	}
+}
+
+func (p *parser) rateLimit(key string, dst *models.RateLimit) {
+	if value, ok := p.lookup(key); ok {
+		limit, err := models.ParseRateLimit(value)
+		if err != nil {
+			p.err = fmt.Errorf("invalid %s: %w", key, err)
+			return
+		}
+		*dst = limit
+	}
+}
+
+// prefixes reads a comma-separated list of IP addresses and CIDR ranges
+func (p *parser) prefixes(key string, dst *[]netip.Prefix) {
+	if value, ok := p.lookup(key); ok {
+		var out []netip.Prefix
+		for _, item := range splitList(value) {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				addr, addrErr := netip.ParseAddr(item)
+				if addrErr != nil {
+					p.err = fmt.Errorf("invalid %s %q: must be IP addresses or CIDR ranges such as 10.0.0.0/8", key, item)
+					return
+				}
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			out = append(out, prefix.Masked())
+		}
+		*dst = out
+	}
+}
+
+// sizeUnits are the suffixes size accepts, largest first so "MB" is not read as "B"
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+func (p *parser) size(key string, dst *int64) {
+	if value, ok := p.lookup(key); ok {
+		number, unit := strings.ToUpper(value), int64(1)
+		for _, u := range sizeUnits {
+			if trimmed, found := strings.CutSuffix(number, u.suffix); found {
+				number, unit = strings.TrimSpace(trimmed), u.bytes
+				break
+			}
+		}
+		n, err := strconv.ParseInt(number, 10, 64)
+		if err != nil || n <= 0 || n > (1<<62)/unit {
+			p.err = fmt.Errorf("invalid %s %q: must be a positive size such as 512KB or 10MB", key, value)
+			return
+		}
+		*dst = n * unit
+	}
+}
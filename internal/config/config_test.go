@@ -0,0 +1,160 @@
+package config
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// envMap returns a getenv backed by vars
+func envMap(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestFromEnv_Defaults(t *testing.T) {
+	cfg, err := FromEnv(envMap(nil))
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Expected an empty environment to give the defaults, got %+v", cfg)
+	}
+
+	if !reflect.DeepEqual(cfg.LogExcludePaths, []string{"/health", "/ready", "/metrics"}) {
+		t.Errorf("Unexpected default log exclusions %v", cfg.LogExcludePaths)
+	}
+
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"port", cfg.Port, 8888},
+		{"request timeout", cfg.RequestTimeout, 10 * time.Second},
+		{"remembered session", cfg.RememberedSessionLifetime, 90 * 24 * time.Hour},
+		{"ephemeral session", cfg.EphemeralSessionLifetime, 24 * time.Hour},
+		{"circuit failures", cfg.DBCircuitMaxFailures, uint32(5)},
+		{"circuit timeout", cfg.DBCircuitTimeout, 5 * time.Second},
+		{"search cache ttl", cfg.SearchCacheTTL, time.Minute},
+		{"avatar upload size", cfg.MaxAvatarUploadSize, int64(10 << 20)},
+		{"post image upload size", cfg.MaxPostImageUploadSize, int64(10 << 20)},
+		{"image prune interval", cfg.ImagePruneInterval, 6 * time.Hour},
+		{"image prune grace", cfg.ImagePruneGrace, 24 * time.Hour},
+		{"password reset ttl", cfg.PasswordResetTTL, time.Hour},
+		{"metrics interval", cfg.MetricsInterval, time.Minute},
+		{"image path", cfg.ImagePath, "/db/userdata/images/"},
+		{"session binding", cfg.SessionBinding, models.SessionBinding{}},
+		{"rate limit", cfg.RateLimit, models.RateLimit{Requests: 300, Per: time.Minute}},
+		{"auth rate limit", cfg.AuthRateLimit, models.RateLimit{Requests: 10, Per: time.Minute}},
+		{"write rate limit", cfg.WriteRateLimit, models.RateLimit{Requests: 30, Per: time.Minute}},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, c.got)
+		}
+	}
+}
+
+func TestFromEnv_Overrides(t *testing.T) {
+	cfg, err := FromEnv(envMap(map[string]string{
+		"DB_ENV":                     "prod",
+		"DB_PATH":                    "/var/lib/codex.db",
+		"PORT":                       "9090",
+		"REQUEST_TIMEOUT":            "30s",
+		"SESSION_LIFETIME":           "720h",
+		"EPHEMERAL_SESSION_LIFETIME": "2h",
+		"DB_CIRCUIT_MAX_FAILURES":    "10",
+		"DB_CIRCUIT_TIMEOUT":         "1m",
+		"SEARCH_CACHE_TTL":           "5m",
+		"MAX_AVATAR_UPLOAD_SIZE":     "512kb",
+		"MAX_POST_IMAGE_UPLOAD_SIZE": "20971520",
+		"IMAGE_PRUNE_INTERVAL":       "1h",
+		"IMAGE_PRUNE_GRACE":          "48h",
+		"METRICS_INTERVAL":           "15s",
+		"DEBUG_QUERY_COUNT":          "true",
+		"HIDE_FLAGGED_CONTENT":       "true",
+		"ALLOWED_ORIGINS":            "https://a.example.com, ,https://b.example.com",
+		"SESSION_BINDING":            "ip-subnet,user-agent",
+		"LOG_EXCLUDE_PATHS":          "/status",
+		"PASSWORD_RESET_TTL":         "15m",
+		"PUBLIC_URL":                 "https://codex.example/",
+		"SMTP_ADDR":                  "mail.example:587",
+		"SMTP_FROM":                  "codex@codex.example",
+		"GITHUB_CLIENT_ID":           "gh-client",
+		"GITHUB_CLIENT_SECRET":       "gh-secret",
+		"RATE_LIMIT":                 "1000/1h",
+		"AUTH_RATE_LIMIT":            "off",
+		"WRITE_RATE_LIMIT":           " 5 / 10s ",
+		"TRUSTED_PROXIES":            "10.0.0.0/8, 192.0.2.1",
+	}))
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+
+	want := Default()
+	want.DBEnv, want.DBPath = "prod", "/var/lib/codex.db"
+	want.Port = 9090
+	want.RequestTimeout = 30 * time.Second
+	want.RememberedSessionLifetime = 720 * time.Hour
+	want.EphemeralSessionLifetime = 2 * time.Hour
+	want.DBCircuitMaxFailures = 10
+	want.DBCircuitTimeout = time.Minute
+	want.SearchCacheTTL = 5 * time.Minute
+	want.MaxAvatarUploadSize = 512 << 10
+	want.MaxPostImageUploadSize = 20 << 20
+	want.ImagePruneInterval = time.Hour
+	want.ImagePruneGrace = 48 * time.Hour
+	want.MetricsInterval = 15 * time.Second
+	want.DebugQueryCount = true
+	want.HideFlaggedContent = true
+	want.AllowedOrigins = []string{"https://a.example.com", "https://b.example.com"}
+	want.SessionBinding = models.SessionBinding{IP: true, IPSubnet: true, UserAgent: true}
+	want.LogExcludePaths = []string{"/status"}
+	want.PasswordResetTTL = 15 * time.Minute
+	want.PublicURL = "https://codex.example"
+	want.SMTPAddr, want.SMTPFrom = "mail.example:587", "codex@codex.example"
+	want.GitHubClientID, want.GitHubClientSecret = "gh-client", "gh-secret"
+	want.RateLimit = models.RateLimit{Requests: 1000, Per: time.Hour}
+	want.AuthRateLimit = models.RateLimit{}
+	want.WriteRateLimit = models.RateLimit{Requests: 5, Per: 10 * time.Second}
+	want.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.1/32")}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Expected %+v, got %+v", want, cfg)
+	}
+}
+
+func TestFromEnv_InvalidValues(t *testing.T) {
+	tests := []struct {
+		key, value string
+	}{
+		{"PORT", "http"},
+		{"PORT", "-1"},
+		{"REQUEST_TIMEOUT", "10"},
+		{"SESSION_LIFETIME", "0s"},
+		{"DB_CIRCUIT_MAX_FAILURES", "0"},
+		{"MAX_AVATAR_UPLOAD_SIZE", "MB"},
+		{"MAX_POST_IMAGE_UPLOAD_SIZE", "-5MB"},
+		{"MAX_POST_IMAGE_UPLOAD_SIZE", "1.5MB"},
+		{"SESSION_BINDING", "cookie"},
+		{"DEBUG_QUERY_COUNT", "sometimes"},
+		{"PASSWORD_RESET_TTL", "-1h"},
+		{"SMTP_ADDR", "mail.example:587"},
+		{"GOOGLE_CLIENT_ID", "google-client"},
+		{"GITHUB_CLIENT_SECRET", "gh-secret"},
+		{"RATE_LIMIT", "300"},
+		{"AUTH_RATE_LIMIT", "0/1m"},
+		{"WRITE_RATE_LIMIT", "10/soon"},
+		{"TRUSTED_PROXIES", "10.0.0.0/8,proxy.internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			_, err := FromEnv(envMap(map[string]string{tt.key: tt.value}))
+			if err == nil || !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("Expected an error naming %s, got %v", tt.key, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := NewCodec("test-secret")
+	want := Cursor{Created: time.Date(2025, 3, 14, 15, 9, 26, 535000000, time.UTC), ID: 42}
+
+	token := c.Encode(want)
+	if strings.Contains(token, "2025") || strings.Contains(token, "42") {
+		t.Errorf("Expected an opaque token, got %q", token)
+	}
+	got, err := c.Decode(token)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !got.Created.Equal(want.Created) || got.ID != want.ID {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	c := NewCodec("test-secret")
+	token := c.Encode(Cursor{Created: time.Now(), ID: 7})
+
+	raw, _ := base64.RawURLEncoding.DecodeString(token)
+	raw[15]++ // bump the ID without re-signing
+	forged := base64.RawURLEncoding.EncodeToString(raw)
+
+	tests := map[string]string{
+		"modified payload": forged,
+		"other secret":     NewCodec("other-secret").Encode(Cursor{Created: time.Now(), ID: 7}),
+		"truncated":        token[:len(token)-4],
+		"not base64":       "!!!",
+		"empty":            "",
+	}
+	for name, tok := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := c.Decode(tok); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
@@ -6,15 +6,20 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
-
-	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 func InitDB(filepath string, schemaFile string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", filepath)
+	db, err := sql.Open(CountingDriver, filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	if filepath == ":memory:" {
+		// every pooled connection to :memory: is a separate database
+		db.SetMaxOpenConns(1)
+	}
 
 	// Enforce foreign keys
 	if _, err := db.Exec(`PRAGMA foreign_keys = ON;`); err != nil {
@@ -50,3 +55,44 @@ func InitDB(filepath string, schemaFile string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// OpenMemory opens a private in-memory database with foreign keys on and every
+// *.sql file in migrationsDir applied in name order. It is meant for tests.
+func OpenMemory(migrationsDir string) (*sql.DB, error) {
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find migrations: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no migrations found in %s", migrationsDir)
+	}
+	sort.Strings(files)
+
+	db, err := sql.Open(CountingDriver, ":memory:?_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// every pooled connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+
+	for _, file := range files {
+		schema, err := os.ReadFile(file)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if _, err := db.Exec(string(schema)); err != nil {
+			// 007 only exists for databases created before Users.Updated
+			if strings.Contains(err.Error(), "duplicate column name") {
+				if _, rbErr := db.Exec("ROLLBACK"); rbErr != nil {
+					db.Close()
+					return nil, fmt.Errorf("failed to roll back %s: %w", file, rbErr)
+				}
+				continue
+			}
+			db.Close()
+			return nil, fmt.Errorf("failed to apply %s: %w", file, err)
+		}
+	}
+	return db, nil
+}
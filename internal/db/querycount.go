@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// CountingDriver is the sqlite3 driver wrapped so that statements run with a
+// context from WithQueryCounter are counted. InitDB and OpenMemory open with it.
+const CountingDriver = "sqlite3_counting"
+
+func init() {
+	sql.Register(CountingDriver, &countingDriver{})
+}
+
+// QueryCounter counts the statements executed with a context it was attached to
+type QueryCounter struct {
+	n atomic.Int64
+}
+
+// Count returns the number of statements executed so far
+func (c *QueryCounter) Count() int64 { return c.n.Load() }
+
+type queryCounterKey struct{}
+
+// WithQueryCounter returns a context that counts every query and exec made with it,
+// or with a context derived from it, and the counter doing the counting
+func WithQueryCounter(ctx context.Context) (context.Context, *QueryCounter) {
+	c := &QueryCounter{}
+	return context.WithValue(ctx, queryCounterKey{}, c), c
+}
+
+func countQuery(ctx context.Context) {
+	if c, ok := ctx.Value(queryCounterKey{}).(*QueryCounter); ok {
+		c.n.Add(1)
+	}
+}
+
+type countingDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// countingConn counts statements by the context database/sql passes down
+type countingConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	countQuery(ctx)
+	return c.SQLiteConn.QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	countQuery(ctx)
+	return c.SQLiteConn.ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &countingStmt{stmt.(*sqlite3.SQLiteStmt)}, nil
+}
+
+// countingStmt counts each execution of a prepared statement
+type countingStmt struct {
+	*sqlite3.SQLiteStmt
+}
+
+func (s *countingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	countQuery(ctx)
+	return s.SQLiteStmt.QueryContext(ctx, args)
+}
+
+func (s *countingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	countQuery(ctx)
+	return s.SQLiteStmt.ExecContext(ctx, args)
+}
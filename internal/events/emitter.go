@@ -0,0 +1,50 @@
+// Package events publishes application events (moderation actions etc.) to external systems.
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// Moderation event types. FlagApproved, UserBanned and ContentDeleted are part of the
+// webhook contract but nothing emits them yet: the tree has no handlers for those actions.
+const (
+	ModeratorAdded      = "moderator.added"
+	ModerationRequested = "moderation.requested"
+	FlagApproved        = "flag.approved"
+	UserBanned          = "user.banned"
+	ContentDeleted      = "content.deleted"
+	ContentAutoFlagged  = "content.auto_flagged"
+)
+
+// Event is a single occurrence sent to subscribers
+type Event struct {
+	Type      string           `json:"type"`
+	Timestamp time.Time        `json:"timestamp"`
+	ActorID   models.UUIDField `json:"actorId"`             // user who performed the action
+	ChannelID int64            `json:"channelId,omitempty"` // channel the action applies to, if any
+	TargetID  string           `json:"targetId,omitempty"`  // affected user/post/comment/flag
+	Data      map[string]any   `json:"data,omitempty"`      // event-specific details
+}
+
+// NewEvent creates an event of the given type stamped with the current UTC time
+func NewEvent(eventType string, actorID models.UUIDField) Event {
+	return Event{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		ActorID:   actorID,
+	}
+}
+
+// EventEmitter delivers events to an external consumer
+type EventEmitter interface {
+	Emit(ctx context.Context, event Event) error
+}
+
+// NoopEmitter discards every event; used when no webhook is configured
+type NoopEmitter struct{}
+
+// Emit implements EventEmitter
+func (NoopEmitter) Emit(context.Context, Event) error { return nil }
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// ErrQueueFull is returned by Queue.Emit when the backlog is at capacity
+var ErrQueueFull = errors.New("event queue is full")
+
+// Queue buffers events and hands them to an EventEmitter from one background job,
+// so handlers never wait on delivery and shutdown can wait for what is in flight
+type Queue struct {
+	emitter EventEmitter
+	events  chan Event
+}
+
+// NewQueue creates a queue holding up to size undelivered events for emitter
+func NewQueue(emitter EventEmitter, size int) *Queue {
+	return &Queue{emitter: emitter, events: make(chan Event, size)}
+}
+
+// Emit implements EventEmitter by queueing event. It never blocks; when the
+// backlog is full the event is dropped and ErrQueueFull returned.
+func (q *Queue) Emit(_ context.Context, event Event) error {
+	select {
+	case q.events <- event:
+		return nil
+	default:
+		return fmt.Errorf("dropped %s event: %w", event.Type, ErrQueueFull)
+	}
+}
+
+// Run delivers queued events until ctx is cancelled, then delivers whatever is
+// still queued before returning. Cancelling ctx does not cut a delivery short,
+// so the caller bounds shutdown with its own deadline (see workers.Registry).
+func (q *Queue) Run(ctx context.Context) error {
+	deliveryCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case event := <-q.events:
+			q.deliver(deliveryCtx, event)
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-q.events:
+					q.deliver(deliveryCtx, event)
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) deliver(ctx context.Context, event Event) {
+	if err := q.emitter.Emit(ctx, event); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to emit event", err)
+	}
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/workers"
+)
+
+// blockingEmitter records events, holding each delivery until release is closed
+type blockingEmitter struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	got     []string
+}
+
+func (b *blockingEmitter) Emit(ctx context.Context, event Event) error {
+	b.started <- struct{}{}
+	<-b.release
+	b.mu.Lock()
+	b.got = append(b.got, event.Type)
+	b.mu.Unlock()
+	return nil
+}
+
+func TestQueue_ShutdownWaitsForInFlightDeliveries(t *testing.T) {
+	emitter := &blockingEmitter{started: make(chan struct{}, 3), release: make(chan struct{})}
+	queue := NewQueue(emitter, 10)
+	for _, eventType := range []string{ModeratorAdded, ModerationRequested, ContentAutoFlagged} {
+		if err := queue.Emit(context.Background(), NewEvent(eventType, models.NewUUIDField())); err != nil {
+			t.Fatalf("Emit failed: %v", err)
+		}
+	}
+
+	registry := workers.NewRegistry()
+	if err := registry.Register("event-queue", queue.Run); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	registry.Start(context.Background())
+	select {
+	case <-emitter.started:
+	case <-time.After(time.Second):
+		t.Fatal("Delivery never started")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- registry.Shutdown(ctx)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown returned while a delivery was in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(emitter.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected clean shutdown, got %v", err)
+	}
+	emitter.mu.Lock()
+	defer emitter.mu.Unlock()
+	if len(emitter.got) != 3 {
+		t.Errorf("Expected every queued event to be delivered before shutdown, got %v", emitter.got)
+	}
+}
+
+func TestQueue_EmitDoesNotBlockWhenFull(t *testing.T) {
+	queue := NewQueue(NoopEmitter{}, 1)
+	if err := queue.Emit(context.Background(), NewEvent(ModeratorAdded, models.NewUUIDField())); err != nil {
+		t.Fatalf("Emit failed: %v", err)
+	}
+	err := queue.Emit(context.Background(), NewEvent(ModeratorAdded, models.NewUUIDField()))
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+}
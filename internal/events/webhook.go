@@ -0,0 +1,98 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gary-norman/forum/internal/patterns"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the request body
+const SignatureHeader = "X-Codex-Signature"
+
+// WebhookEmitter POSTs events as signed JSON to a configured URL
+type WebhookEmitter struct {
+	URL        string
+	Secret     []byte
+	Client     *http.Client
+	Circuit    *patterns.CircuitBreaker
+	MaxRetries int           // attempts after the first failure
+	Backoff    time.Duration // base delay, doubled after each failed attempt
+}
+
+// NewWebhookEmitter creates an emitter with 3 retries and its own circuit breaker
+func NewWebhookEmitter(url, secret string) *WebhookEmitter {
+	return &WebhookEmitter{
+		URL:        url,
+		Secret:     []byte(secret),
+		Client:     &http.Client{Timeout: 5 * time.Second},
+		Circuit:    patterns.NewCircuitBreaker(5, 30*time.Second),
+		MaxRetries: 3,
+		Backoff:    200 * time.Millisecond,
+	}
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Emit implements EventEmitter. Each attempt runs through the circuit breaker, so
+// a webhook that keeps failing is skipped quickly instead of being retried on every event.
+func (e *WebhookEmitter) Emit(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+
+	delay := e.Backoff
+	for attempt := 0; ; attempt++ {
+		err = e.Circuit.Execute(func() error {
+			return e.post(ctx, body)
+		})
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, patterns.ErrCircuitOpen) || attempt >= e.MaxRetries {
+			return fmt.Errorf("failed to deliver %s event after %d attempts: %w", event.Type, attempt+1, err)
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// post sends one signed delivery; any non-2xx response counts as a failure
+func (e *WebhookEmitter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(e.Secret, body))
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
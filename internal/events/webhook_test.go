@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/patterns"
+)
+
+func newTestEmitter(url string) *WebhookEmitter {
+	e := NewWebhookEmitter(url, "test-secret")
+	e.Backoff = time.Millisecond
+	return e
+}
+
+func TestWebhookEmitter_SendsSignedPayload(t *testing.T) {
+	var got Event
+	var sigOK bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sigOK = r.Header.Get(SignatureHeader) == Sign([]byte("test-secret"), body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("Webhook received invalid JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	actor := models.NewUUIDField()
+	event := NewEvent(ModeratorAdded, actor)
+	event.ChannelID = 7
+
+	if err := newTestEmitter(srv.URL).Emit(context.Background(), event); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if !sigOK {
+		t.Error("Expected a valid signature header")
+	}
+	if got.Type != ModeratorAdded || got.ChannelID != 7 || got.ActorID != actor {
+		t.Errorf("Unexpected payload: %+v", got)
+	}
+}
+
+func TestWebhookEmitter_RetriesOnFailure(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	err := newTestEmitter(srv.URL).Emit(context.Background(), NewEvent(FlagApproved, models.NewUUIDField()))
+	if err != nil {
+		t.Fatalf("Expected delivery to succeed after retries, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+}
+
+func TestWebhookEmitter_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	e := newTestEmitter(srv.URL)
+	e.MaxRetries = 2
+	if err := e.Emit(context.Background(), NewEvent(UserBanned, models.NewUUIDField())); err == nil {
+		t.Fatal("Expected an error when every attempt fails")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+}
+
+func TestWebhookEmitter_StopsWhenCircuitOpen(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	e := newTestEmitter(srv.URL)
+	e.Circuit = patterns.NewCircuitBreaker(2, time.Minute)
+
+	err := e.Emit(context.Background(), NewEvent(ContentDeleted, models.NewUUIDField()))
+	if !errors.Is(err, patterns.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected the breaker to stop after 2 attempts, got %d", calls.Load())
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strings"
 
 	"github.com/gary-norman/forum/internal/app"
 	"github.com/gary-norman/forum/internal/colors"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
 	"github.com/gary-norman/forum/internal/service"
-	"github.com/gary-norman/forum/internal/view"
+	"github.com/gary-norman/forum/internal/sqlite"
 )
 
 var (
@@ -25,45 +27,24 @@ type AuthHandler struct {
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	username := r.FormValue("register_user")
+	// usernames are matched case-insensitively, so only surrounding space is dropped
+	username := strings.TrimSpace(r.FormValue("register_user"))
 	email := r.FormValue("register_email")
 	validEmail, _ := regexp.MatchString(`^[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}$`, email)
 	password := r.FormValue("register_password")
-	if len(username) < 5 || len(username) > 16 {
-		w.WriteHeader(http.StatusNotAcceptable)
-		err := json.NewEncoder(w).Encode(map[string]any{
-			"code":    http.StatusNotAcceptable,
-			"message": "username must be between 5 and 16 characters",
-		})
-		if err != nil {
-			models.LogErrorWithContext(ctx, "Failed to encode register response (username validation)", err)
-			return
-		}
-		return
+	var invalid ValidationError
+	if !validUsernameLength(username) {
+		invalid.Add("register_user", usernameLengthMessage)
 	}
 	if !IsValidPassword(password) {
-		w.WriteHeader(http.StatusNotAcceptable)
-		err := json.NewEncoder(w).Encode(map[string]any{
-			"code": http.StatusNotAcceptable,
-			"message": "password must contain at least one number and one uppercase and lowercase letter," +
-				"and at least 8 or more characters",
-		})
-		if err != nil {
-			models.LogErrorWithContext(ctx, "Failed to encode register response (password validation)", err)
-			return
-		}
-		return
+		invalid.Add("register_password", "password must contain at least one number and one uppercase and lowercase letter, "+
+			"and at least 8 or more characters")
 	}
 	if !validEmail {
-		w.WriteHeader(http.StatusNotAcceptable)
-		err := json.NewEncoder(w).Encode(map[string]any{
-			"code":    http.StatusNotAcceptable,
-			"message": "please enter a valid email address",
-		})
-		if err != nil {
-			models.LogErrorWithContext(ctx, "Failed to encode register response (email validation)", err)
-			return
-		}
+		invalid.Add("register_email", "please enter a valid email address")
+	}
+	if err := invalid.Err(); err != nil {
+		writeError(w, err)
 		return
 	}
 	_, ok, emailErr := h.App.Users.QueryUserEmailExists(ctx, email)
@@ -114,15 +95,8 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		user.HashedPassword,
 	); err != nil {
 		models.LogErrorWithContext(ctx, "Failed to insert user into database", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		encErr := json.NewEncoder(w).Encode(map[string]any{
-			"code":    http.StatusInternalServerError,
-			"message": "registration failed!",
-		})
-		if encErr != nil {
-			models.LogErrorWithContext(ctx, "Failed to encode register error response", encErr)
-			return
-		}
+		writeError(w, withMessage(err, "registration failed!"))
+		return
 	}
 
 	type FormFields struct {
@@ -156,9 +130,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		Password  string `json:"password"`
 		Ephemeral bool   `json:"ephemeral"`
 	}
-	err := json.NewDecoder(r.Body).Decode(&credentials)
-	if err != nil {
-		view.RenderErrorPage(w, models.NotProcess("login"), 500, models.ParseError("request body", "Login", err))
+	if err := decodeJSON(r, &credentials); err != nil {
+		models.LogWarnWithContext(ctx, "Rejected login payload: %v", err)
+		writeError(w, err)
 		return
 	}
 
@@ -170,18 +144,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	user, getUserErr := h.App.Users.GetUserFromLogin(ctx, login, "login")
 	if getUserErr != nil {
-		// Respond with an unsuccessful login message
-		w.Header().Set("Content-Type", "application/json")
 		models.LogWarnWithContext(ctx, "User not found: %s", getUserErr, login)
-		w.WriteHeader(http.StatusOK)
-		encErr := json.NewEncoder(w).Encode(map[string]any{
-			"code":    http.StatusUnauthorized,
-			"message": "user not found",
-		})
-		if encErr != nil {
-			models.LogErrorWithContext(ctx, "Failed to encode login response (user not found)", encErr)
-			return
-		}
+		writeError(w, withMessage(getUserErr, "user not found"))
 		return
 	}
 
@@ -189,16 +153,13 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		// Set Session Token and CSRF Token cookies
 		createCookiErr, expires := h.App.Cookies.CreateCookies(ctx, w, user, ephemeral)
 		if createCookiErr != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			encErr := json.NewEncoder(w).Encode(map[string]any{
-				"code":    http.StatusInternalServerError,
-				"message": "failed to create cookies",
-				"body":    fmt.Errorf(ErrorMsgs.Cookies, "create", createCookiErr),
-			})
-			if encErr != nil {
-				models.LogErrorWithContext(ctx, "Failed to encode login response (cookie creation)", encErr)
-				return
-			}
+			models.LogErrorWithContext(ctx, "Failed to create cookies during login", fmt.Errorf(ErrorMsgs.Cookies, "create", createCookiErr))
+			writeError(w, withMessage(createCookiErr, "failed to create cookies"))
+			return
+		}
+		if err := h.App.Cookies.BindSession(ctx, user, mw.ClientFingerprint(r)); err != nil {
+			models.LogErrorWithContext(ctx, "Failed to bind session during login", err)
+			writeError(w, withMessage(err, "failed to create session"))
 			return
 		}
 		// Respond with a successful login message
@@ -214,45 +175,28 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		// Respond with an unsuccessful login message
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		encErr := json.NewEncoder(w).Encode(map[string]any{
-			"code":    http.StatusUnauthorized,
-			"message": "incorrect password",
-		})
-		if encErr != nil {
-			models.LogErrorWithContext(ctx, "Failed to encode login failure response", encErr)
-			return
-		}
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "incorrect password"))
 	}
 }
 
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	// Retrieve the cookie
-	cookie, cookiErr := r.Cookie("username")
-	if cookiErr != nil {
-		http.Error(w, "User not logged in", http.StatusUnauthorized)
-		return
-	}
-	username := cookie.Value
-	if username == "" {
-		models.LogWarnWithContext(ctx, "Logout aborted: no user is logged in")
+	// Only the session's own token may end it; a bare username cookie is not enough
+	user, err := mw.ResolveUser(r, h.App)
+	if err != nil {
+		models.LogWarnWithContext(ctx, "Logout aborted: %v", err)
+		writeError(w, withMessage(err, "User not logged in"))
 		return
 	}
-	fmt.Printf(Colors.Peach+"Attempting logout for "+Colors.Text+"%v\n"+Colors.Reset, username)
+	fmt.Printf(Colors.Peach+"Attempting logout for "+Colors.Text+"%v\n"+Colors.Reset, user.Username)
 	fmt.Println(ErrorMsgs.Divider)
-	var user *models.User
-	user, getUserErr := h.App.Users.GetUserByUsername(ctx, username, "logout")
-	if getUserErr != nil {
-		models.LogErrorWithContext(ctx, "Failed to get user %s for logout", getUserErr, username)
-	}
 
 	// Delete the Session Token and CSRF Token cookies
 	delCookiErr := h.App.Cookies.DeleteCookies(ctx, w, user)
 	if delCookiErr != nil {
 		models.LogErrorWithContext(ctx, "Failed to delete cookies during logout", delCookiErr)
+		writeError(w, withMessage(delCookiErr, "logout failed"))
+		return
 	}
 	// send user confirmation
 	models.LogInfoWithContext(ctx, "User %s logged out successfully", user.Username)
@@ -266,6 +210,77 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// RequestPasswordReset emails a reset link to the account registered to the posted
+// address. The response is the same whether or not there is one, so the endpoint
+// cannot be used to find out which addresses are registered.
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := decodeJSON(r, &body); err != nil {
+		models.LogWarnWithContext(ctx, "Rejected password reset request payload: %v", err)
+		writeError(w, err)
+		return
+	}
+	email := strings.TrimSpace(body.Email)
+	if email == "" {
+		writeError(w, &RequestError{Field: "email", Msg: "please enter your email address"})
+		return
+	}
+
+	if err := h.App.PasswordReset.Request(ctx, email); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to send password reset for %s", err, email)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encErr := json.NewEncoder(w).Encode(map[string]any{
+		"code":    http.StatusOK,
+		"message": "if an account uses that address, a reset link is on its way",
+	})
+	if encErr != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode password reset request response", encErr)
+	}
+}
+
+// ResetPassword sets a new password from a reset link. The link's expires and sig
+// query parameters are passed through unchanged; the new password is posted as JSON.
+// Any session the user had is ended, so they log in again with the new password.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body struct {
+		Password string `json:"password"`
+	}
+	if err := decodeJSON(r, &body); err != nil {
+		models.LogWarnWithContext(ctx, "Rejected password reset payload: %v", err)
+		writeError(w, err)
+		return
+	}
+	if !IsValidPassword(body.Password) {
+		var invalid ValidationError
+		invalid.Add("password", "password must contain at least one number and one uppercase and lowercase letter, "+
+			"and at least 8 or more characters")
+		writeError(w, invalid.Err())
+		return
+	}
+
+	q := r.URL.Query()
+	userID, err := h.App.PasswordReset.Reset(ctx, r.PathValue("token"), q.Get("expires"), q.Get("sig"), body.Password)
+	if err != nil {
+		models.LogWarnWithContext(ctx, "Password reset failed: %v", err)
+		writeError(w, withMessage(err, "this reset link is invalid or has expired"))
+		return
+	}
+	models.LogInfoWithContext(ctx, "Password reset for user %s", userID)
+	w.Header().Set("Content-Type", "application/json")
+	encErr := json.NewEncoder(w).Encode(map[string]any{
+		"code":    http.StatusOK,
+		"message": "password updated, please log in",
+	})
+	if encErr != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode password reset response", encErr)
+	}
+}
+
 // SECTION ------- routing handlers ----------
 
 func (h *AuthHandler) Protected(w http.ResponseWriter, r *http.Request) {
@@ -275,9 +290,11 @@ func (h *AuthHandler) Protected(w http.ResponseWriter, r *http.Request) {
 	user, getUserErr := h.App.Users.GetUserFromLogin(ctx, login, "protected")
 	if getUserErr != nil {
 		models.LogErrorWithContext(ctx, "Failed to get user %s for protected route", getUserErr, login)
+		writeError(w, getUserErr)
+		return
 	}
 	if authErr := h.Session.IsAuthenticated(r, user.Username); authErr != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "Unauthorized"))
 		return
 	}
 	_, err := fmt.Fprintf(w, "CSRF Valildation successful! Welcome, %s", user.Username)
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/service"
+)
+
+func TestUsernamesAreCaseInsensitive(t *testing.T) {
+	a := newTestApp(t)
+	h := &AuthHandler{App: a}
+
+	register := func(username, email string) *httptest.ResponseRecorder {
+		form := url.Values{
+			"register_user":     {username},
+			"register_email":    {email},
+			"register_password": {"Secret-pass1"},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.Register(rec, req)
+		return rec
+	}
+	login := func(username string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"username":%q,"password":"Secret-pass1"}`, username)
+		rec := httptest.NewRecorder()
+		h.Login(rec, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := register("  Alice ", "alice@example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected registration to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stored string
+	if err := a.DB.QueryRow("SELECT Username FROM Users").Scan(&stored); err != nil || stored != "Alice" {
+		t.Errorf("Expected the username stored as \"Alice\", got %q (%v)", stored, err)
+	}
+
+	t.Run("duplicate differing only in case", func(t *testing.T) {
+		rec := register("alice", "other@example.com")
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected 409, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var count int
+		if err := a.DB.QueryRow("SELECT COUNT(*) FROM Users").Scan(&count); err != nil || count != 1 {
+			t.Errorf("Expected a single user, got %d (%v)", count, err)
+		}
+	})
+
+	for _, username := range []string{"Alice", "alice", "ALICE"} {
+		t.Run("login as "+username, func(t *testing.T) {
+			rec := login(username)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), "Welcome, Alice!") {
+				t.Errorf("Expected to be logged in as Alice, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+// recordingSender keeps the emails it is asked to send
+type recordingSender struct {
+	sent []service.Email
+}
+
+func (s *recordingSender) Send(_ context.Context, email service.Email) error {
+	s.sent = append(s.sent, email)
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	a := newTestApp(t)
+	mailer := &recordingSender{}
+	a.PasswordReset.Mailer = mailer
+	h := &AuthHandler{App: a}
+	alice := insertTestUser(t, a, "alice")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /password-reset", h.RequestPasswordReset)
+	mux.HandleFunc("POST "+service.PasswordResetPath+"{token}", h.ResetPassword)
+	post := func(target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec
+	}
+
+	unknown := post("/password-reset", `{"email":"nobody@example.com"}`)
+	if unknown.Code != http.StatusOK || len(mailer.sent) != 0 {
+		t.Fatalf("Expected 200 and no email for an unknown address, got %d and %d emails", unknown.Code, len(mailer.sent))
+	}
+	known := post("/password-reset", `{"email":"Alice@example.com"}`)
+	if known.Code != http.StatusOK || known.Body.String() != unknown.Body.String() {
+		t.Errorf("Expected the same response for known and unknown addresses, got %q and %q", known.Body, unknown.Body)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "alice@example.com" {
+		t.Fatalf("Expected one email to alice@example.com, got %+v", mailer.sent)
+	}
+	link, err := url.Parse(regexp.MustCompile(`http\S+`).FindString(mailer.sent[0].Body))
+	if err != nil || !strings.HasPrefix(link.Path, service.PasswordResetPath) {
+		t.Fatalf("Expected a reset link in the email, got %q (%v)", mailer.sent[0].Body, err)
+	}
+
+	tampered := link.Path + "x?" + link.RawQuery
+	if rec := post(tampered, `{"password":"New-secret1"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a tampered link, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(link.RequestURI(), `{"password":"weak"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a weak password, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(link.RequestURI(), `{"password":"New-secret1"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the reset to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(link.RequestURI(), `{"password":"Other-secret1"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a used link, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodPost, "/login",
+		strings.NewReader(fmt.Sprintf(`{"username":%q,"password":"New-secret1"}`, alice.Username))))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected to log in with the new password, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
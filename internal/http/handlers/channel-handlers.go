@@ -11,6 +11,7 @@ import (
 	"github.com/gary-norman/forum/internal/app"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
 	"github.com/gary-norman/forum/internal/view"
 )
 
@@ -24,14 +25,7 @@ func (c *ChannelHandler) GetChannelPage(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	models.LogInfoWithContext(ctx, "GetChannelPage called")
 	w.Header().Set("Content-Type", "application/json")
-	userLoggedIn := true
-	currentUser, ok := mw.GetUserFromContext(ctx)
-
-	if !ok {
-		models.LogWarnWithContext(ctx, "User not authenticated in GetChannelPage")
-		userLoggedIn = false
-	}
-
+	currentUser, userLoggedIn := mw.CurrentUser(ctx)
 	allChannels, err := c.App.Channels.All(ctx)
 	if err != nil {
 		http.Error(w, `{"error": "Error getting all channels"}`, http.StatusInternalServerError)
@@ -70,7 +64,7 @@ func (c *ChannelHandler) GetChannelPage(w http.ResponseWriter, r *http.Request)
 
 	// TODO make a better struct for all
 	data := models.ChannelPage{
-		UserID:                 models.NewUUIDField(), // Default value of 0 for logged out users
+		UserID:                 mw.CurrentUserID(ctx),
 		CurrentUser:            currentUser,
 		Instance:               "all-channels-page",
 		OwnedChannels:          ownedChannels,
@@ -85,12 +79,7 @@ func (c *ChannelHandler) GetThisChannel(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 	models.LogInfoWithContext(ctx, "GetThisChannel called")
-	userLoggedIn := true
-	currentUser, ok := mw.GetUserFromContext(ctx)
-	if !ok {
-		models.LogWarnWithContext(ctx, "User not authenticated in GetThisChannel")
-		userLoggedIn = false
-	}
+	currentUser, userLoggedIn := mw.CurrentUser(ctx)
 
 	// Parse channelID from the request
 	channelID, err := models.GetIntFromPathValue(r.PathValue("channelId"))
@@ -102,14 +91,13 @@ func (c *ChannelHandler) GetThisChannel(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Fetch the channel
-	foundChannels, err := c.App.Channels.GetChannelsByID(ctx, channelID)
-	if err != nil || len(foundChannels) == 0 {
+	thisChannel, err := c.App.Channels.GetChannelByID(ctx, channelID)
+	if err != nil {
 		models.LogErrorWithContext(ctx, "Channel not found by ID", err)
 		error := fmt.Errorf("channel %d not found: %w", channelID, err)
 		view.RenderErrorPage(w, models.NotFoundLocation("channel"), 400, error)
 		return
 	}
-	thisChannel := foundChannels[0]
 	models.LogInfoWithContext(ctx, "Fetching channel: %s", thisChannel.Name)
 	models.UpdateTimeSince(thisChannel)
 
@@ -127,18 +115,12 @@ func (c *ChannelHandler) GetThisChannel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Fetch channel posts
-	var thisChannelPosts []*models.Post
-	thisChannelPostIDs, err := c.App.Channels.GetPostIDsFromChannel(ctx, thisChannel.ID)
+	// Fetch channel posts with reactions and comments
+	thisChannelPosts, err := c.App.PostService.ForChannel(ctx, thisChannel.ID, sqlite.NoLimit, 0)
 	if err != nil {
-		http.Error(w, `{"error": "Error getting Post IDs"}`, http.StatusInternalServerError)
-	}
-	for p := range thisChannelPostIDs {
-		post, err := c.App.Posts.GetPostByID(ctx, thisChannelPostIDs[p])
-		if err != nil {
-			http.Error(w, `{"error": "Error getting post ID:" + thisChannelPostIDs[p]}`, http.StatusInternalServerError)
-		}
-		thisChannelPosts = append(thisChannelPosts, &post)
+		models.LogErrorWithContext(ctx, "Failed to fetch channel posts", err)
+		http.Error(w, `{"error": "Error getting channel posts"}`, http.StatusInternalServerError)
+		return
 	}
 
 	allChannels, err := c.App.Channels.All(ctx)
@@ -148,32 +130,13 @@ func (c *ChannelHandler) GetThisChannel(w http.ResponseWriter, r *http.Request)
 	for c := range allChannels {
 		models.UpdateTimeSince(allChannels[c])
 	}
-	channelName, err := c.App.Channels.GetChannelNameFromID(ctx, thisChannel.ID)
-	if err != nil {
-		http.Error(w, `{"error": "error fetching channel name"}`, http.StatusInternalServerError)
-	}
 
-	// Add channel ID & name and fetch timesince for posts
+	// A post shared to several channels is shown under this one here
 	for p := range thisChannelPosts {
-		thisChannelPosts[p].ChannelID, thisChannelPosts[p].ChannelName = thisChannel.ID, channelName
-		// TODO no need for this
-		models.UpdateTimeSince(thisChannelPosts[p])
-	}
-
-	// Retrieve total likes and dislikes for each Channel post
-	thisChannelPosts = c.Reaction.GetPostsLikesAndDislikes(thisChannelPosts)
-
-	// Retrieve last reaction time for posts
-	thisChannelPosts, err = c.Reaction.getLastReactionTimeForPosts(thisChannelPosts)
-	if err != nil {
-		http.Error(w, `{"error": "Error getting channel posts" }`, http.StatusInternalServerError)
-	}
-
-	// Retrieve comments for posts
-	thisChannelPosts, err = c.Comment.GetPostsComments(thisChannelPosts)
-	if err != nil {
-		http.Error(w, `{"error": "Error getting comments" }`, http.StatusInternalServerError)
+		thisChannelPosts[p].ChannelID, thisChannelPosts[p].ChannelName = thisChannel.ID, thisChannel.Name
 	}
+	// judged as posts of this channel, so its moderators see flagged cross-posts
+	thisChannelPosts = hideFlaggedPosts(ctx, c.App, currentUser, thisChannelPosts)
 
 	ownedChannels := make([]*models.Channel, 0)
 	joinedChannels := make([]*models.Channel, 0)
@@ -214,10 +177,14 @@ func (c *ChannelHandler) GetThisChannel(w http.ResponseWriter, r *http.Request)
 				break
 			}
 		}
+
+		if err := c.App.Visits.RecordVisit(ctx, currentUser.ID, thisChannel.ID); err != nil {
+			models.LogErrorWithContext(ctx, "Failed to record channel visit", err)
+		}
 	}
 
 	data := models.ChannelPage{
-		UserID:                 models.NewUUIDField(), // Default value of 0 for logged out users
+		UserID:                 mw.CurrentUserID(ctx),
 		CurrentUser:            currentUser,
 		Instance:               "channel-page",
 		ThisChannel:            thisChannel,
@@ -234,20 +201,6 @@ func (c *ChannelHandler) GetThisChannel(w http.ResponseWriter, r *http.Request)
 	view.RenderPageData(w, data)
 }
 
-func (c *ChannelHandler) GetChannelInfoFromPostID(postID int64) (int64, string, error) {
-	ctx := context.Background()
-	channelIDs, err := c.App.Channels.GetChannelIDFromPost(ctx, postID)
-	if err != nil {
-		return 0, "", fmt.Errorf("failed to get channel ID from post %d: %w", postID, err)
-	}
-	channelID := channelIDs[0]
-	channelName, err := c.App.Channels.GetChannelNameFromID(ctx, channelID)
-	if err != nil {
-		return 0, "", fmt.Errorf("failed to get channel name from ID %d: %w", channelID, err)
-	}
-	return channelID, channelName, nil
-}
-
 func (c *ChannelHandler) StoreChannel(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	user, ok := mw.GetUserFromContext(ctx)
@@ -255,10 +208,9 @@ func (c *ChannelHandler) StoreChannel(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
-	parseErr := r.ParseMultipartForm(10 << 20)
-	if parseErr != nil {
-		models.LogErrorWithContext(ctx, "Failed to parse multipart form in StoreChannel", parseErr)
-		http.Error(w, parseErr.Error(), 400)
+	if err := parseUpload(w, r, c.App.Config.MaxAvatarUploadSize); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to parse multipart form in StoreChannel", err)
+		writeError(w, err)
 		return
 	}
 
@@ -275,23 +227,11 @@ func (c *ChannelHandler) StoreChannel(w http.ResponseWriter, r *http.Request) {
 	if r.PostForm.Get("privacy") == "on" {
 		createChannelData.Privacy = true
 	}
-	createChannelData.Avatar = GetFileName(r, "file-drop", "storeChannel", "channel")
-
-	insertErr := c.App.Channels.Insert(
-		ctx,
-		createChannelData.OwnerID,
-		createChannelData.Name,
-		createChannelData.Description,
-		createChannelData.Avatar,
-		createChannelData.Banner,
-		createChannelData.Privacy,
-		createChannelData.IsFlagged,
-		createChannelData.IsMuted,
-	)
-
-	if insertErr != nil {
-		models.LogErrorWithContext(ctx, "Failed to insert channel", insertErr)
-		http.Error(w, insertErr.Error(), 500)
+	createChannelData.Avatar = GetFileName(r, "file-drop", "storeChannel", "channel", c.App.Config.MaxAvatarUploadSize)
+
+	if err := c.App.ChannelService.Create(ctx, &createChannelData); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to insert channel", err)
+		writeError(w, err)
 		return
 	}
 	// TODO fix this redirect
@@ -316,7 +256,8 @@ func (c *ChannelHandler) StoreMembership(w http.ResponseWriter, r *http.Request)
 	if convErr != nil {
 		models.LogErrorWithContext(ctx, "Failed to convert channelId to int", convErr)
 	}
-	if err := c.App.Memberships.Insert(ctx, user.ID, joinedChannelID); err != nil {
+	created, err := c.App.Memberships.Insert(ctx, user.ID, joinedChannelID)
+	if err != nil {
 		models.LogErrorWithContext(ctx, "Failed to insert membership", err)
 		http.Error(w, err.Error(), 500)
 		return
@@ -326,12 +267,16 @@ func (c *ChannelHandler) StoreMembership(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		models.LogErrorWithContext(ctx, "Failed to get channel name", err)
 	}
+	message := fmt.Sprintf("Welcome to %v!", channelName)
+	if !created {
+		message = fmt.Sprintf("You are already a member of %v", channelName)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	encErr := json.NewEncoder(w).Encode(map[string]any{
 		"code":    http.StatusOK,
-		"message": fmt.Sprintf("Welcome to %v!", channelName),
+		"message": message,
 	})
 	if encErr != nil {
 		models.LogErrorWithContext(ctx, "Failed to encode response in StoreMembership", encErr)
@@ -345,13 +290,13 @@ func (c *ChannelHandler) StoreMembership(w http.ResponseWriter, r *http.Request)
 func (c *ChannelHandler) JoinedByCurrentUser(memberships []models.Membership) ([]*models.Channel, error) {
 	ctx := context.Background()
 	models.LogInfo("Checking user memberships")
-	var channels []*models.Channel
-	for _, membership := range memberships {
-		channel, err := c.App.Channels.GetChannelsByID(ctx, membership.ChannelID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get channel %d for membership: %w", membership.ChannelID, err)
-		}
-		channels = append(channels, channel[0])
+	channelIDs := make([]int64, len(memberships))
+	for i, membership := range memberships {
+		channelIDs[i] = membership.ChannelID
+	}
+	channels, err := c.App.Channels.GetChannelsByIDs(ctx, channelIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channels for memberships: %w", err)
 	}
 	// TODO add logic that checks if the user is an owner of this channel
 	if len(channels) > 0 {
@@ -406,3 +351,48 @@ func (c *ChannelHandler) CreateAndInsertRule(w http.ResponseWriter, r *http.Requ
 	}
 	http.Redirect(w, r, "/channels/"+r.PathValue("channelId"), http.StatusFound)
 }
+
+// SetChannelPrivacy lets the owner switch a channel between public and private.
+// The form value "private" must be "true" or "false".
+func (c *ChannelHandler) SetChannelPrivacy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeJSONResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	channelID, err := strconv.ParseInt(r.PathValue("channelId"), 10, 64)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	private, err := strconv.ParseBool(r.FormValue("private"))
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, "private must be true or false")
+		return
+	}
+
+	if err := c.App.Channels.SetPrivacy(ctx, channelID, user.ID, private); err != nil {
+		if statusForError(err) == http.StatusInternalServerError {
+			models.LogErrorWithContext(ctx, "Failed to set channel privacy", err)
+		}
+		writeError(w, err)
+		return
+	}
+
+	recordAudit(ctx, c.App, models.AuditEntry{
+		ActorID:    user.ID,
+		Action:     models.AuditChannelPrivacy,
+		TargetType: models.AuditTargetChannel,
+		TargetID:   strconv.FormatInt(channelID, 10),
+		ChannelID:  channelID,
+		Metadata:   map[string]any{"private": private},
+	})
+
+	visibility := "public"
+	if private {
+		visibility = "private"
+	}
+	writeJSONResponse(w, http.StatusOK, fmt.Sprintf("Channel is now %s", visibility))
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+)
+
+func TestSetChannelPrivacy(t *testing.T) {
+	a := newTestApp(t)
+	owner := insertTestUser(t, a, "owner")
+	other := insertTestUser(t, a, "other")
+	channelID := insertTestChannel(t, a, owner.ID, "general")
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /channels/privacy/{channelId}", mw.WithUser(http.HandlerFunc((&ChannelHandler{App: a}).SetChannelPrivacy), a))
+	send := func(username, channel, private string) *httptest.ResponseRecorder {
+		form := url.Values{"private": {private}}
+		req := httptest.NewRequest(http.MethodPost, "/channels/privacy/"+channel, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, withUsername(req, username))
+		return rec
+	}
+	id := strconv.FormatInt(channelID, 10)
+
+	tests := []struct {
+		name, username, channel, private string
+		wantStatus                       int
+		wantPrivate                      bool
+	}{
+		{"non-owner is forbidden", other.Username, id, "true", http.StatusForbidden, false},
+		{"owner makes it private", owner.Username, id, "true", http.StatusOK, true},
+		{"invalid value", owner.Username, id, "maybe", http.StatusBadRequest, true},
+		{"missing channel", owner.Username, "9999", "false", http.StatusNotFound, true},
+		{"owner makes it public", owner.Username, id, "false", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := send(tt.username, tt.channel, tt.private)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			channel, err := a.Channels.GetChannelByID(context.Background(), channelID)
+			if err != nil {
+				t.Fatalf("GetChannelByID failed: %v", err)
+			}
+			if channel.Privacy != tt.wantPrivate {
+				t.Errorf("Expected privacy %v, got %v", tt.wantPrivate, channel.Privacy)
+			}
+		})
+	}
+}
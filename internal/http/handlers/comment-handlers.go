@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -12,6 +11,7 @@ import (
 	"github.com/gary-norman/forum/internal/app"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
 )
 
 type CommentHandler struct {
@@ -41,16 +41,19 @@ func (h *CommentHandler) StoreComment(w http.ResponseWriter, r *http.Request) {
 
 	// SECTION setting channel data
 	// Get channel data
-	selectionJSON := r.PostForm.Get("channel")
-	if selectionJSON == "" {
-		http.Error(w, "No selection provided for channel", http.StatusBadRequest)
-		return
-	}
-
+	var invalid ValidationError
 	var channelData models.ChannelData
-	if err := json.Unmarshal([]byte(selectionJSON), &channelData); err != nil {
+	if selectionJSON := r.PostForm.Get("channel"); selectionJSON == "" {
+		invalid.Add("channel", "no channel selected")
+	} else if err := json.Unmarshal([]byte(selectionJSON), &channelData); err != nil {
 		models.LogErrorWithContext(ctx, "Failed to unmarshal channel data", err)
-		http.Error(w, "Invalid selection format", http.StatusBadRequest)
+		invalid.Add("channel", "invalid channel selection")
+	}
+	if strings.TrimSpace(r.PostForm.Get("content")) == "" {
+		invalid.Add("content", "comment cannot be empty")
+	}
+	if err := invalid.Err(); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -103,59 +106,154 @@ func (h *CommentHandler) StoreComment(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("commentData.CommentedPostID: %v\n", commentData.CommentedPostID)
 	fmt.Printf("commentData.CommentedCommentID: %v\n", commentData.CommentedCommentID)
 
-	// Insert the comment
-	insertErr := h.App.Comments.Upsert(ctx, commentData)
-
-	if insertErr != nil {
-		models.LogErrorWithContext(ctx, "Failed to upsert comment", insertErr)
-		http.Error(w, insertErr.Error(), 500)
+	filtered, filterErr := checkContent(ctx, h.App, []int64{commentData.ChannelID}, commentData.Content)
+	if filterErr != nil {
+		models.LogErrorWithContext(ctx, "Failed to run content filter in StoreComment", filterErr)
+		writeError(w, filterErr)
+		return
+	}
+	if !filtered.Allowed {
+		writeError(w, &ValidationError{Fields: map[string]string{"content": filtered.Reason}})
 		return
 	}
 
+	if filtered.Flag {
+		// flagged comments are always new rows, so insert directly to get the ID for the flag
+		commentData.IsFlagged = true
+		newID, insertErr := h.App.Comments.Insert(ctx, commentData)
+		if insertErr != nil {
+			models.LogErrorWithContext(ctx, "Failed to insert comment", insertErr)
+			http.Error(w, insertErr.Error(), 500)
+			return
+		}
+		autoFlag(ctx, h.App, filtered, user.ID, nil, &newID)
+	} else {
+		// Insert the comment
+		newID, insertErr := h.App.Comments.Upsert(ctx, commentData)
+
+		if insertErr != nil {
+			models.LogErrorWithContext(ctx, "Failed to upsert comment", insertErr)
+			http.Error(w, insertErr.Error(), 500)
+			return
+		}
+		// resubmitting a comment updates it rather than adding one, and is not news
+		if newID != 0 {
+			notifyComment(ctx, h.App, commentData, newID)
+		}
+	}
+
 	path := strings.TrimSuffix(r.URL.Path, "/store-comment")
 
 	http.Redirect(w, r, path, http.StatusFound)
 }
 
-func (h *CommentHandler) GetPostsComments(posts []*models.Post) ([]*models.Post, error) {
-	ctx := context.Background()
-	for p, post := range posts {
-		comments, err := h.App.Comments.GetCommentByPostID(ctx, post.ID)
+// EditComment replaces the text of a comment with the JSON body {"content": "..."}.
+// Only the author may edit; the text being replaced is kept in the comment's edit
+// history. The new text goes through the channel's content filter like a new comment.
+func (h *CommentHandler) EditComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to edit a comment"))
+		return
+	}
+	commentID, err := models.GetIntFromPathValue(r.PathValue("commentId"))
+	if err != nil {
+		writeError(w, &RequestError{Field: "commentId", Msg: "comment ID must be a number", err: err})
+		return
+	}
+	var input struct {
+		Content string `json:"content"`
+	}
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, err)
+		return
+	}
+	if strings.TrimSpace(input.Content) == "" {
+		var invalid ValidationError
+		invalid.Add("content", "comment cannot be empty")
+		writeError(w, invalid.Err())
+		return
+	}
+
+	comment, err := h.App.Comments.GetByID(ctx, commentID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if comment.AuthorID != user.ID {
+		writeError(w, withMessage(sqlite.ErrForbidden, "you can only edit your own comments"))
+		return
+	}
+
+	filtered, err := checkContent(ctx, h.App, []int64{comment.ChannelID}, input.Content)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to run content filter in EditComment", err)
+		writeError(w, err)
+		return
+	}
+	if !filtered.Allowed {
+		writeError(w, &ValidationError{Fields: map[string]string{"content": filtered.Reason}})
+		return
+	}
+
+	edited, err := h.App.Comments.Edit(ctx, commentID, user.ID, input.Content, filtered.Flag)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to edit comment", err, "CommentID:", commentID)
+		writeError(w, err)
+		return
+	}
+	if filtered.Flag && edited.Content != comment.Content {
+		autoFlag(ctx, h.App, filtered, user.ID, nil, &commentID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"comment": edited}); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode edited comment", err)
+	}
+}
+
+// GetCommentRevisions returns the earlier versions of a comment, oldest first. The
+// history is visible to the comment's author and to whoever owns or moderates its channel.
+func (h *CommentHandler) GetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to see edit history"))
+		return
+	}
+	commentID, err := models.GetIntFromPathValue(r.PathValue("commentId"))
+	if err != nil {
+		writeError(w, &RequestError{Field: "commentId", Msg: "comment ID must be a number", err: err})
+		return
+	}
+
+	comment, err := h.App.Comments.GetByID(ctx, commentID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if comment.AuthorID != user.ID {
+		moderated, err := h.App.Mods.ModeratedChannelIDs(ctx, user.ID)
 		if err != nil {
-			return nil, err
+			models.LogErrorWithContext(ctx, "Failed to fetch moderated channels", err)
+			writeError(w, err)
+			return
 		}
-		comments = h.Reaction.GetCommentsLikesAndDislikes(comments)
-		/// Filter comments that belong to the current post based on the postID and CommentedPostID
-		var postComments []models.Comment
-		var commentsCount int
-		for _, comment := range comments {
-			models.UpdateTimeSince(&comment)
-			// For each comment, recursively assign its replies
-			commentWithReplies := h.GetRepliesForComment(comment)
-			postComments = append(postComments, commentWithReplies)
+		if !moderated[comment.ChannelID] {
+			writeError(w, withMessage(sqlite.ErrForbidden, "only moderators can see the edit history of this comment"))
+			return
 		}
-		commentsCount = len(postComments)
-		posts[p].Comments = postComments
-		posts[p].CommentsCount = commentsCount
 	}
-	return posts, nil
-}
 
-// GetRepliesForComment Recursively fetches replies for each comment
-func (h *CommentHandler) GetRepliesForComment(comment models.Comment) models.Comment {
-	ctx := context.Background()
-	// Find replies to the current comment
-	var replies []models.Comment
-	comments, _ := h.App.Comments.GetCommentByCommentID(ctx, comment.ID)
-	comments = h.Reaction.GetCommentsLikesAndDislikes(comments)
-	for r, possibleReply := range comments {
-		models.UpdateTimeSince(&comments[r])
-		replyWithReplies := h.GetRepliesForComment(possibleReply) // Recursively get replies for this reply
-		replies = append(replies, replyWithReplies)
-	}
-	if len(replies) > 0 {
-		comment.Replies = replies
-	}
-	// Return the comment with its replies
-	return comment
+	revisions, err := h.App.Comments.Revisions(ctx, commentID)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to list comment revisions", err, "CommentID:", commentID)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"revisions": revisions}); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode comment revisions", err)
+	}
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestEditComment(t *testing.T) {
+	a := newTestApp(t)
+	owner := insertTestUser(t, a, "owner")
+	author := insertTestUser(t, a, "author")
+	insertTestUser(t, a, "stranger")
+	channelID := insertTestChannel(t, a, owner.ID, "general")
+	postID := insertTestChannelPost(t, a, author, channelID, "thread")
+	res, err := a.DB.Exec(`INSERT INTO Comments (Content, CommentedPostID, IsCommentable, IsFlagged, IsReply,
+		Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+		VALUES ('first draft', ?, 1, 0, 0, 'author', ?, '', 'general', ?)`, postID, author.ID, channelID)
+	if err != nil {
+		t.Fatalf("Failed to insert comment: %v", err)
+	}
+	commentID, _ := res.LastInsertId()
+
+	comments := &CommentHandler{App: a}
+	mux := http.NewServeMux()
+	mux.Handle("PATCH /api/comments/{commentId}", mw.WithUser(http.HandlerFunc(comments.EditComment), a))
+	mux.Handle("GET /api/comments/{commentId}/revisions", mw.WithUser(http.HandlerFunc(comments.GetCommentRevisions), a))
+
+	serve := func(username, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, withUsername(req, username))
+		return rec
+	}
+	path := "/api/comments/" + strconv.FormatInt(commentID, 10)
+
+	if rec := serve("stranger", http.MethodPatch, path, `{"content": "hijacked"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for another user's comment, got %d", rec.Code)
+	}
+	if rec := serve("author", http.MethodPatch, path, `{"content": "  "}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for empty content, got %d", rec.Code)
+	}
+	rec := serve("author", http.MethodPatch, path, `{"content": "second draft"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the author's edit to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var edited struct {
+		Comment models.Comment `json:"comment"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&edited); err != nil || edited.Comment.Content != "second draft" {
+		t.Errorf("Expected the edited comment in the response, got %+v (%v)", edited.Comment, err)
+	}
+
+	if rec := serve("stranger", http.MethodGet, path+"/revisions", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the edit history to be hidden from other users, got %d", rec.Code)
+	}
+	for _, viewer := range []string{"owner", "author"} {
+		rec := serve(viewer, http.MethodGet, path+"/revisions", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", viewer, rec.Code, rec.Body.String())
+		}
+		var history struct {
+			Revisions []models.CommentRevision `json:"revisions"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+			t.Fatalf("Failed to decode revisions: %v", err)
+		}
+		if len(history.Revisions) != 1 || history.Revisions[0].Content != "first draft" {
+			t.Errorf("%s: expected the first draft in the history, got %+v", viewer, history.Revisions)
+		}
+	}
+}
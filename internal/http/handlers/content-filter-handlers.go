@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/events"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/moderation"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// contentFilterFor builds the ContentFilter configured for a channel
+func contentFilterFor(ctx context.Context, a *app.App, channelID int64) (moderation.ContentFilter, error) {
+	terms, err := a.Filters.TermsForChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return moderation.NewWordListFilter(terms), nil
+}
+
+// filterResult is the outcome of running content through its channels' filters
+type filterResult struct {
+	Allowed   bool   // false when a channel in reject mode matched
+	Flag      bool   // a channel in flag mode matched; store the content flagged
+	Reason    string // why the content matched
+	ChannelID int64  // channel whose filter matched
+}
+
+// checkContent runs texts through the filter of every channel the content is posted to.
+// A match in a reject-mode channel wins over matches in flag-mode channels.
+func checkContent(ctx context.Context, a *app.App, channelIDs []int64, texts ...string) (filterResult, error) {
+	result := filterResult{Allowed: true}
+	for _, channelID := range channelIDs {
+		filter, err := contentFilterFor(ctx, a, channelID)
+		if err != nil {
+			return filterResult{}, err
+		}
+		for _, text := range texts {
+			allowed, reason := filter.Check(text)
+			if allowed {
+				continue
+			}
+			mode, err := a.Filters.ModeForChannel(ctx, channelID)
+			if err != nil {
+				return filterResult{}, err
+			}
+			if mode != moderation.ModeFlag {
+				return filterResult{Reason: reason, ChannelID: channelID}, nil
+			}
+			if !result.Flag {
+				result = filterResult{Allowed: true, Flag: true, Reason: reason, ChannelID: channelID}
+			}
+			break
+		}
+	}
+	return result, nil
+}
+
+// autoFlag opens an unapproved flag for content that tripped a flag-mode filter, so it
+// shows up in the channel's moderation queue. The content author is recorded as the
+// flag's author since no user reported it.
+func autoFlag(ctx context.Context, a *app.App, res filterResult, authorID models.UUIDField, postID, commentID *int64) {
+	flag := models.Flag{
+		FlagType:         moderation.FlagTypeContentFilter,
+		Content:          res.Reason,
+		Approved:         false,
+		AuthorID:         authorID,
+		ChannelID:        res.ChannelID,
+		FlaggedPostID:    postID,
+		FlaggedCommentID: commentID,
+	}
+	flagID, err := a.Flags.Insert(ctx, flag)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to create content filter flag", err)
+		return
+	}
+
+	event := events.NewEvent(events.ContentAutoFlagged, authorID)
+	event.ChannelID = res.ChannelID
+	event.TargetID = strconv.FormatInt(flagID, 10)
+	event.Data = map[string]any{"reason": res.Reason}
+	emitEvent(ctx, a, event)
+}
+
+// StoreFilterTerm lets a channel owner add ("add") or remove ("remove") a banned term,
+// or switch the channel's filter between moderation.ModeReject and ModeFlag ("mode").
+// Any other action is rejected rather than treated as "add".
+func (c *ChannelHandler) StoreFilterTerm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeJSONResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	channelID, err := strconv.ParseInt(r.PathValue("channelId"), 10, 64)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+
+	channel, err := c.App.Channels.GetChannelByID(ctx, channelID)
+	if err != nil {
+		if !errors.Is(err, sqlite.ErrChannelNotFound) {
+			models.LogErrorWithContext(ctx, "Failed to fetch channel for filter term", err)
+		}
+		writeError(w, err)
+		return
+	}
+	if channel.OwnerID != user.ID {
+		writeError(w, withMessage(sqlite.ErrForbidden, "only the channel owner can edit the content filter"))
+		return
+	}
+
+	action := r.FormValue("action")
+	term := strings.TrimSpace(r.FormValue("term"))
+	mode := r.FormValue("mode")
+	var invalid ValidationError
+	switch action {
+	case "add", "remove":
+		if term == "" {
+			invalid.Add("term", "term is required")
+		}
+	case "mode":
+		if mode != moderation.ModeReject && mode != moderation.ModeFlag {
+			invalid.Add("mode", fmt.Sprintf("mode must be %q or %q", moderation.ModeReject, moderation.ModeFlag))
+		}
+	default:
+		invalid.Add("action", `action must be "add", "remove" or "mode"`)
+	}
+	if err := invalid.Err(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	entry := models.AuditEntry{
+		ActorID:    user.ID,
+		TargetType: models.AuditTargetChannel,
+		TargetID:   strconv.FormatInt(channelID, 10),
+		ChannelID:  channelID,
+	}
+	switch action {
+	case "add":
+		err = c.App.Filters.AddTerm(ctx, channelID, term)
+		entry.Action, entry.Metadata = models.AuditFilterTermAdded, map[string]any{"term": term}
+	case "remove":
+		err = c.App.Filters.DeleteTerm(ctx, channelID, term)
+		entry.Action, entry.Metadata = models.AuditFilterTermRemoved, map[string]any{"term": term}
+	case "mode":
+		err = c.App.Filters.SetMode(ctx, channelID, mode)
+		entry.Action, entry.Metadata = models.AuditFilterModeChanged, map[string]any{"mode": mode}
+	}
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to update content filter", err)
+		writeError(w, err)
+		return
+	}
+	recordAudit(ctx, c.App, entry)
+
+	writeJSONResponse(w, http.StatusOK, fmt.Sprintf("Content filter updated for %s", channel.Name))
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/moderation"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// newPostRequest builds the multipart form StorePost expects
+func newPostRequest(t *testing.T, channelID int64, title, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mp := multipart.NewWriter(&body)
+	mp.WriteField("post_channel_list", strconv.FormatInt(channelID, 10))
+	mp.WriteField("title", title)
+	mp.WriteField("content", content)
+	mp.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/posts/create", &body)
+	req.Header.Set("Content-Type", mp.FormDataContentType())
+	return req
+}
+
+func TestStorePost_ContentFilter(t *testing.T) {
+	a := newTestApp(t)
+	ctx := context.Background()
+	user := insertTestUser(t, a, "poster")
+	channelID := insertTestChannel(t, a, user.ID, "filtered")
+	if err := a.Filters.AddTerm(ctx, channelID, "forbidden"); err != nil {
+		t.Fatalf("AddTerm failed: %v", err)
+	}
+	h := mw.WithUser(http.HandlerFunc((&PostHandler{App: a}).StorePost), a)
+
+	t.Run("reject mode refuses matching content", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, withUsername(newPostRequest(t, channelID, "Hello", "a forbidden word"), user.Username))
+		expectFields(t, decodeValidation(t, rec), "content")
+		var count int
+		a.DB.QueryRow("SELECT COUNT(*) FROM Posts").Scan(&count)
+		if count != 0 {
+			t.Errorf("Expected no post to be stored, found %d", count)
+		}
+	})
+
+	t.Run("flag mode stores the post flagged with an open flag", func(t *testing.T) {
+		if err := a.Filters.SetMode(ctx, channelID, moderation.ModeFlag); err != nil {
+			t.Fatalf("SetMode failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, withUsername(newPostRequest(t, channelID, "Hello", "a forbidden word"), user.Username))
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("Expected 303, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		posts, err := a.Posts.All(ctx, sqlite.NoLimit, 0)
+		if err != nil {
+			t.Fatalf("Posts.All failed: %v", err)
+		}
+		if len(posts) != 1 || !posts[0].IsFlagged {
+			t.Fatalf("Expected one visible, flagged post, got %+v", posts)
+		}
+
+		var flagType string
+		var approved bool
+		var flaggedPostID int64
+		err = a.DB.QueryRow("SELECT FlagType, Approved, FlaggedPostID FROM Flags WHERE ChannelID = ?", channelID).
+			Scan(&flagType, &approved, &flaggedPostID)
+		if err != nil {
+			t.Fatalf("Expected a flag row: %v", err)
+		}
+		if flagType != moderation.FlagTypeContentFilter || approved || flaggedPostID != posts[0].ID {
+			t.Errorf("Unexpected flag: type=%s approved=%v post=%d", flagType, approved, flaggedPostID)
+		}
+	})
+
+	t.Run("clean content is stored unflagged", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, withUsername(newPostRequest(t, channelID, "Hi", "perfectly fine"), user.Username))
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("Expected 303, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var flagged bool
+		a.DB.QueryRow("SELECT IsFlagged FROM Posts WHERE Title = 'Hi'").Scan(&flagged)
+		if flagged {
+			t.Error("Expected clean post not to be flagged")
+		}
+	})
+}
+
+func TestStoreFilterTerm(t *testing.T) {
+	a := newTestApp(t)
+	owner := insertTestUser(t, a, "owner")
+	other := insertTestUser(t, a, "other")
+	channelID := insertTestChannel(t, a, owner.ID, "filtered")
+	id := strconv.FormatInt(channelID, 10)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /channels/filter-terms/{channelId}", mw.WithUser(http.HandlerFunc((&ChannelHandler{App: a}).StoreFilterTerm), a))
+	send := func(username, channel string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/channels/filter-terms/"+channel, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, withUsername(req, username))
+		return rec
+	}
+	terms := func() []string {
+		terms, err := a.Filters.TermsForChannel(context.Background(), channelID)
+		if err != nil {
+			t.Fatalf("TermsForChannel failed: %v", err)
+		}
+		return terms
+	}
+
+	t.Run("unknown or missing action adds nothing", func(t *testing.T) {
+		for _, action := range []string{"remvoe", ""} {
+			rec := send(owner.Username, id, url.Values{"action": {action}, "term": {"spam"}})
+			expectFields(t, decodeValidation(t, rec), "action")
+		}
+		if got := terms(); len(got) != 0 {
+			t.Errorf("Expected no terms, got %v", got)
+		}
+	})
+
+	t.Run("invalid mode is a field error", func(t *testing.T) {
+		rec := send(owner.Username, id, url.Values{"action": {"mode"}, "mode": {"loud"}})
+		expectFields(t, decodeValidation(t, rec), "mode")
+	})
+
+	t.Run("add and remove", func(t *testing.T) {
+		if rec := send(owner.Username, id, url.Values{"action": {"add"}, "term": {"Spam"}}); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := terms(); len(got) != 1 || got[0] != "spam" {
+			t.Fatalf("Expected [spam], got %v", got)
+		}
+		if rec := send(owner.Username, id, url.Values{"action": {"remove"}, "term": {"spam"}}); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := terms(); len(got) != 0 {
+			t.Errorf("Expected no terms, got %v", got)
+		}
+	})
+
+	t.Run("non-owner and missing channel", func(t *testing.T) {
+		if rec := send(other.Username, id, url.Values{"action": {"add"}, "term": {"x"}}); rec.Code != http.StatusForbidden {
+			t.Errorf("Expected 403, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec := send(owner.Username, "9999", url.Values{"action": {"add"}, "term": {"x"}}); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("database errors are not echoed", func(t *testing.T) {
+		if _, err := a.DB.Exec("DROP TABLE ChannelFilterTerms"); err != nil {
+			t.Fatalf("Failed to drop table: %v", err)
+		}
+		rec := send(owner.Username, id, url.Values{"action": {"add"}, "term": {"spam"}})
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected 500, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "ChannelFilterTerms") {
+			t.Errorf("Response leaks the database error: %s", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// clientError attaches the message shown to the client to an underlying error,
+// without hiding the sentinel it wraps from errors.Is
+type clientError struct {
+	msg string
+	err error
+}
+
+func (e *clientError) Error() string { return e.msg }
+func (e *clientError) Unwrap() error { return e.err }
+
+// withMessage wraps err so that writeError reports msg instead of the error text
+func withMessage(err error, msg string) error {
+	return &clientError{msg: msg, err: err}
+}
+
+// ValidationError reports every invalid field of a submitted form at once, keyed by
+// the form field name, so the UI can highlight each field rather than one message
+type ValidationError struct {
+	Fields map[string]string `json:"fields"`
+}
+
+// Add records msg against field. The first problem found for a field is kept.
+func (v *ValidationError) Add(field, msg string) {
+	if v.Fields == nil {
+		v.Fields = make(map[string]string)
+	}
+	if _, exists := v.Fields[field]; !exists {
+		v.Fields[field] = msg
+	}
+}
+
+// Err returns v if any field was invalid, otherwise nil
+func (v *ValidationError) Err() error {
+	if len(v.Fields) == 0 {
+		return nil
+	}
+	return v
+}
+
+// Error joins the field messages in field order
+func (v *ValidationError) Error() string {
+	names := make([]string, 0, len(v.Fields))
+	for name := range v.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = v.Fields[name]
+	}
+	return strings.Join(messages, "; ")
+}
+
+// statusForError maps the model layer's sentinel errors to an HTTP status code,
+// falling back to 500 for anything it does not recognise
+func statusForError(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, new(*ValidationError)):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, new(*RequestError)):
+		return http.StatusBadRequest
+	case errors.Is(err, sqlite.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, sqlite.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, sqlite.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, sqlite.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes err as a JSON {code, message} body with the status from
+// statusForError, adding a fields object for a ValidationError or a RequestError that
+// names its field. Unrecognised errors are reported as a generic 500 so that database
+// details never reach the client.
+func writeError(w http.ResponseWriter, err error) {
+	status := statusForError(err)
+
+	message := "internal server error"
+	var ce *clientError
+	switch {
+	case err == nil:
+		message = http.StatusText(status)
+	case errors.As(err, &ce):
+		message = ce.msg
+	case status != http.StatusInternalServerError:
+		message = err.Error()
+	}
+
+	body := map[string]any{
+		"code":    status,
+		"message": message,
+	}
+	var ve *ValidationError
+	var re *RequestError
+	if errors.As(err, &ve) {
+		body["fields"] = ve.Fields
+	} else if errors.As(err, &re) && re.Field != "" {
+		body["fields"] = map[string]string{re.Field: re.Msg}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encErr := json.NewEncoder(w).Encode(body); encErr != nil {
+		models.LogError("Failed to encode error response", encErr)
+	}
+}
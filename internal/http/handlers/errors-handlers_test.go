@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, http.StatusOK},
+		{"not found", fmt.Errorf("user bob: %w", sqlite.ErrNotFound), http.StatusNotFound},
+		{"channel not found", fmt.Errorf("no channel found for ID 3: %w", sqlite.ErrChannelNotFound), http.StatusNotFound},
+		{"conflict", fmt.Errorf("user bob already exists: %w", sqlite.ErrConflict), http.StatusConflict},
+		{"unauthorized", fmt.Errorf("wrong password: %w", sqlite.ErrUnauthorized), http.StatusUnauthorized},
+		{"forbidden", fmt.Errorf("not the owner: %w", sqlite.ErrForbidden), http.StatusForbidden},
+		{"unknown", errors.New("disk on fire"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusForError(tt.err); got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+	}{
+		{"not found", fmt.Errorf("user bob: %w", sqlite.ErrNotFound), http.StatusNotFound, "user bob: not found"},
+		{"conflict", fmt.Errorf("channel general already exists: %w", sqlite.ErrConflict), http.StatusConflict, "channel general already exists: conflict"},
+		{"unauthorized", withMessage(sqlite.ErrUnauthorized, "incorrect password"), http.StatusUnauthorized, "incorrect password"},
+		{"unknown hides details", errors.New("no such table: Users"), http.StatusInternalServerError, "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected application/json, got %q", ct)
+			}
+			var body struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Response is not valid JSON: %v", err)
+			}
+			if body.Code != tt.wantStatus || body.Message != tt.wantMessage {
+				t.Errorf("Unexpected body: %+v", body)
+			}
+		})
+	}
+}
+
+func TestLogin_ErrorStatuses(t *testing.T) {
+	a := newTestApp(t)
+	insertTestUser(t, a, "alice")
+	h := &AuthHandler{App: a}
+
+	tests := []struct {
+		name, username string
+		wantStatus     int
+		wantMessage    string
+	}{
+		{"unknown user", "nobody", http.StatusNotFound, "user not found"},
+		{"wrong password", "alice", http.StatusUnauthorized, "incorrect password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"username":%q,"password":"Wrong-pass1"}`, tt.username)
+			rec := httptest.NewRecorder()
+			h.Login(rec, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantMessage) {
+				t.Errorf("Expected message %q, got %s", tt.wantMessage, rec.Body.String())
+			}
+		})
+	}
+}
+
+// decodeValidation reads a 422 response body written by writeError
+func decodeValidation(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Code    int               `json:"code"`
+		Message string            `json:"message"`
+		Fields  map[string]string `json:"fields"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Response is not valid JSON: %v", err)
+	}
+	if body.Code != http.StatusUnprocessableEntity || body.Message == "" {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+	return body.Fields
+}
+
+// expectFields checks every wanted field, and only those, was reported
+func expectFields(t *testing.T, fields map[string]string, want ...string) {
+	t.Helper()
+	if len(fields) != len(want) {
+		t.Errorf("Expected errors for %v, got %v", want, fields)
+	}
+	for _, name := range want {
+		if fields[name] == "" {
+			t.Errorf("Expected an error for %s, got %v", name, fields)
+		}
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	var v ValidationError
+	if v.Err() != nil {
+		t.Fatal("Expected a nil error with no fields")
+	}
+	v.Add("title", "title is required")
+	v.Add("content", "content is required")
+	v.Add("title", "second problem")
+
+	err := v.Err()
+	if statusForError(err) != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422, got %d", statusForError(err))
+	}
+	if got := err.Error(); got != "content is required; title is required" {
+		t.Errorf("Unexpected message %q", got)
+	}
+	wrapped := fmt.Errorf("store post: %w", err)
+	if statusForError(wrapped) != http.StatusUnprocessableEntity {
+		t.Error("Expected a wrapped ValidationError to stay 422")
+	}
+}
+
+func TestRegister_ReportsEveryInvalidField(t *testing.T) {
+	a := newTestApp(t)
+	h := &AuthHandler{App: a}
+
+	form := url.Values{
+		"register_user":     {"abc"},
+		"register_email":    {"not-an-email"},
+		"register_password": {"short"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.Register(rec, req)
+
+	expectFields(t, decodeValidation(t, rec), "register_user", "register_email", "register_password")
+}
+
+func TestStorePost_ReportsEveryInvalidField(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "poster")
+	h := mw.WithUser(http.HandlerFunc((&PostHandler{App: a}).StorePost), a)
+
+	var body bytes.Buffer
+	mp := multipart.NewWriter(&body)
+	mp.WriteField("title", "   ")
+	mp.Close()
+	req := httptest.NewRequest(http.MethodPost, "/posts/create", &body)
+	req.Header.Set("Content-Type", mp.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, withUsername(req, user.Username))
+
+	expectFields(t, decodeValidation(t, rec), "post_channel_list", "title", "content")
+	var count int
+	a.DB.QueryRow("SELECT COUNT(*) FROM Posts").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected no post to be stored, found %d", count)
+	}
+}
+
+func TestStoreComment_ReportsEveryInvalidField(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "commenter")
+	h := mw.WithUser(http.HandlerFunc((&CommentHandler{App: a}).StoreComment), a)
+
+	var body bytes.Buffer
+	mp := multipart.NewWriter(&body)
+	mp.WriteField("postID", "1")
+	mp.Close()
+	req := httptest.NewRequest(http.MethodPost, "/cdx/post/1/store-comment", &body)
+	req.Header.Set("Content-Type", mp.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, withUsername(req, user.Username))
+
+	expectFields(t, decodeValidation(t, rec), "channel", "content")
+}
+
+func TestEditUserDetails_RejectsInvalidName(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "editor")
+	h := mw.WithUser(http.HandlerFunc((&UserHandler{App: a}).EditUserDetails), a)
+
+	var body bytes.Buffer
+	mp := multipart.NewWriter(&body)
+	mp.WriteField("name", "this-name-is-far-too-long")
+	mp.Close()
+	req := httptest.NewRequest(http.MethodPost, "/edituser", &body)
+	req.Header.Set("Content-Type", mp.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, withUsername(req, user.Username))
+
+	expectFields(t, decodeValidation(t, rec), "name")
+	stored, err := a.Users.GetUserByUsername(context.Background(), user.Username, "test")
+	if err != nil || stored == nil {
+		t.Errorf("Expected the username to be unchanged, got %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand/v2"
@@ -9,9 +11,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/gary-norman/forum/internal/app"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/service"
+	"github.com/gary-norman/forum/internal/workers"
 )
 
 func IsValidPassword(password string) bool {
@@ -34,6 +40,26 @@ func IsValidPassword(password string) bool {
 	return hasUpper
 }
 
+const usernameLengthMessage = "username must be between 5 and 16 characters"
+
+// validUsernameLength applies the length rule shared by registration and profile edits
+func validUsernameLength(username string) bool {
+	return len(username) >= 5 && len(username) <= 16
+}
+
+// parsePagination reads the limit and offset query parameters, falling back to
+// defaultLimit and 0 when they are missing or invalid and capping limit at maxLimit
+func parsePagination(r *http.Request, defaultLimit, maxLimit int) (limit, offset int) {
+	limit = defaultLimit
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = min(v, maxLimit)
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && v > 0 {
+		offset = v
+	}
+	return limit, offset
+}
+
 func GetTimeSince(created time.Time) string {
 	now := time.Now()
 	hours := now.Sub(created).Hours()
@@ -68,9 +94,25 @@ func GetRandomUser(userSlice []*models.User) *models.User {
 	return user
 }
 
-func GetFileName(r *http.Request, fileFieldName, calledBy, imageType string) string {
-	// Limit the size of the incoming file to prevent memory issues
-	parseErr := r.ParseMultipartForm(10 << 20) // Limit upload size to 10MB
+// parseUpload parses a multipart form whose whole body may be at most limit bytes.
+// A larger body is reported as a 413 naming the limit, any other parse failure as a 400.
+func parseUpload(w http.ResponseWriter, r *http.Request, limit int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return &RequestError{Msg: fmt.Sprintf("upload must be at most %d KB", limit>>10), err: err}
+		}
+		return &RequestError{Msg: "request body is not a valid multipart form", err: err}
+	}
+	return nil
+}
+
+// GetFileName saves the file in fileFieldName under imageType and returns its new
+// name, or "noimage" when there is none or it is larger than limit bytes. A JPEG or
+// PNG whose metadata cannot be stripped is not saved and returns "". Callers parse
+// the form with parseUpload first so an oversized request is rejected outright.
+func GetFileName(r *http.Request, fileFieldName, calledBy, imageType string, limit int64) string {
+	parseErr := r.ParseMultipartForm(limit)
 	if parseErr != nil {
 		models.LogError("Failed to parse multipart form in %s", parseErr, calledBy)
 		return "noimage"
@@ -81,6 +123,11 @@ func GetFileName(r *http.Request, fileFieldName, calledBy, imageType string) str
 		models.LogError("Failed to retrieve file in %s", retrieveErr, calledBy)
 		return "noimage"
 	}
+	if handler.Size > limit {
+		models.LogWarn("Rejected %d byte file in %s: limit is %d", handler.Size, calledBy, limit)
+		_ = file.Close()
+		return "noimage"
+	}
 	defer func(file multipart.File) {
 		closeErr := file.Close()
 		if closeErr != nil {
@@ -101,12 +148,35 @@ func GetFileName(r *http.Request, fileFieldName, calledBy, imageType string) str
 			models.LogError("Failed to close destination file in %s", closeErr, calledBy)
 		}
 	}(dst)
-	// Copy the uploaded file data to the server's file
-	_, copyErr := io.Copy(dst, file)
-	if copyErr != nil {
-		models.LogError("Failed to save file in %s", copyErr, calledBy)
+	data, readErr := io.ReadAll(file)
+	if readErr != nil {
+		models.LogError("Failed to read uploaded file in %s", readErr, calledBy)
 		return ""
 	}
+	// Re-encode JPEGs and PNGs to drop EXIF/GPS metadata; anything else is copied as-is
+	if _, stripErr := workers.StripMetadata(data, dst); stripErr != nil {
+		contentType := http.DetectContentType(data)
+		if !errors.Is(stripErr, workers.ErrMetadataUnsupported) && (contentType == "image/jpeg" || contentType == "image/png") {
+			// saving the original would publish the metadata stripping exists to remove
+			models.LogWarn("Rejected %s upload in %s, could not strip metadata: %v", contentType, calledBy, stripErr)
+			if removeErr := os.Remove(dst.Name()); removeErr != nil {
+				models.LogError("Failed to remove rejected file in %s", removeErr, calledBy)
+			}
+			return ""
+		}
+		if _, seekErr := dst.Seek(0, io.SeekStart); seekErr != nil {
+			models.LogError("Failed to rewind file in %s", seekErr, calledBy)
+			return ""
+		}
+		if truncErr := dst.Truncate(0); truncErr != nil {
+			models.LogError("Failed to truncate file in %s", truncErr, calledBy)
+			return ""
+		}
+		if _, copyErr := dst.Write(data); copyErr != nil {
+			models.LogError("Failed to save file in %s", copyErr, calledBy)
+			return ""
+		}
+	}
 	return renamedFile
 }
 
@@ -124,3 +194,49 @@ func renameFileWithUUID(oldFilePath string) string {
 
 	return newFilePath
 }
+
+// publicChannels keeps only the channels an anonymous visitor may see
+func publicChannels(channels []*models.Channel) []*models.Channel {
+	public := make([]*models.Channel, 0, len(channels))
+	for _, channel := range channels {
+		if !channel.Privacy {
+			public = append(public, channel)
+		}
+	}
+	return public
+}
+
+// publicPosts drops posts that are not in any public channel. If the lookup fails
+// no posts are returned, so a database error cannot leak private content.
+func publicPosts(ctx context.Context, a *app.App, posts []*models.Post) ([]*models.Post, error) {
+	private, err := a.Posts.PrivatePostIDs(ctx)
+	if err != nil {
+		return make([]*models.Post, 0), err
+	}
+	public := make([]*models.Post, 0, len(posts))
+	for _, post := range posts {
+		if !private[post.ID] {
+			public = append(public, post)
+		}
+	}
+	return public, nil
+}
+
+// hideFlaggedPosts applies the HideFlaggedContent policy to posts: flagged posts and
+// comments are dropped unless user owns or moderates their channel. user is nil for
+// anonymous visitors. If the moderator lookup fails, all flagged content is hidden.
+func hideFlaggedPosts(ctx context.Context, a *app.App, user *models.User, posts []*models.Post) []*models.Post {
+	if !a.Config.HideFlaggedContent {
+		return posts
+	}
+	moderated := map[int64]bool{}
+	if user != nil {
+		ids, err := a.Mods.ModeratedChannelIDs(ctx, user.ID)
+		if err != nil {
+			models.LogErrorWithContext(ctx, "Failed to fetch moderated channels", err)
+		} else {
+			moderated = ids
+		}
+	}
+	return service.HideFlagged(posts, moderated)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+func TestHideFlaggedPosts(t *testing.T) {
+	a := newTestApp(t)
+	ctx := context.Background()
+	owner := insertTestUser(t, a, "owner")
+	mod := insertTestUser(t, a, "mod")
+	reader := insertTestUser(t, a, "reader")
+	channelID := insertTestChannel(t, a, owner.ID, "general")
+	if _, err := a.DB.Exec("INSERT INTO Mods (UserID, ChannelID) VALUES (?, ?)", mod.ID, channelID); err != nil {
+		t.Fatalf("Failed to add moderator: %v", err)
+	}
+
+	clean := insertTestChannelPost(t, a, owner, channelID, "clean")
+	flagged := insertTestChannelPost(t, a, owner, channelID, "flagged")
+	if _, err := a.DB.Exec("UPDATE Posts SET IsFlagged = 1 WHERE ID = ?", flagged); err != nil {
+		t.Fatalf("Failed to flag post: %v", err)
+	}
+	for _, comment := range []struct {
+		content string
+		flagged bool
+	}{{"fine", false}, {"rude", true}} {
+		if _, err := a.DB.Exec(`INSERT INTO Comments (Content, CommentedPostID, IsCommentable, IsFlagged, IsReply,
+			Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+			VALUES (?, ?, 1, ?, 0, 'owner', ?, '', 'general', ?)`, comment.content, clean, comment.flagged, owner.ID, channelID); err != nil {
+			t.Fatalf("Failed to insert comment: %v", err)
+		}
+	}
+
+	// visible lists the titles of the posts user sees and the comments on the clean post
+	visible := func(user *models.User) (titles, comments []string) {
+		t.Helper()
+		posts, err := a.PostService.ForChannel(ctx, channelID, sqlite.NoLimit, 0)
+		if err != nil {
+			t.Fatalf("ForChannel failed: %v", err)
+		}
+		for _, post := range hideFlaggedPosts(ctx, a, user, posts) {
+			titles = append(titles, post.Title)
+			if post.ID == clean {
+				for _, c := range post.Comments {
+					comments = append(comments, c.Content)
+				}
+				if post.CommentsCount != len(post.Comments) {
+					t.Errorf("CommentsCount %d does not match %d comments", post.CommentsCount, len(post.Comments))
+				}
+			}
+		}
+		slices.Sort(titles)
+		slices.Sort(comments)
+		return titles, comments
+	}
+
+	all, allComments := []string{"clean", "flagged"}, []string{"fine", "rude"}
+	tests := []struct {
+		name         string
+		hide         bool
+		user         *models.User
+		wantTitles   []string
+		wantComments []string
+	}{
+		{"policy off shows everything", false, reader, all, allComments},
+		{"regular user", true, reader, []string{"clean"}, []string{"fine"}},
+		{"anonymous visitor", true, nil, []string{"clean"}, []string{"fine"}},
+		{"channel owner", true, owner, all, allComments},
+		{"channel moderator", true, mod, all, allComments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.Config.HideFlaggedContent = tt.hide
+			titles, comments := visible(tt.user)
+			if !slices.Equal(titles, tt.wantTitles) || !slices.Equal(comments, tt.wantComments) {
+				t.Errorf("Expected posts %v and comments %v, got %v and %v", tt.wantTitles, tt.wantComments, titles, comments)
+			}
+		})
+	}
+}
+
+func TestGetFileName_RejectsUnstrippableImages(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir := "db/userdata/images/post-images/"
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create image directory: %v", err)
+	}
+
+	// a JPEG whose EXIF segment carries GPS coordinates, cut off before any image data
+	exif := append([]byte("Exif\x00\x00"), []byte("GPSLatitude 51.5007 N GPSLongitude 0.1246 W")...)
+	corrupt := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, byte((len(exif) + 2) >> 8), byte(len(exif) + 2)}, exif...)
+	corrupt = append(corrupt, 0xFF, 0xDB, 0x00)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file-drop", "holiday.jpg")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	_, _ = part.Write(corrupt)
+	_ = form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/posts/create", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	if name := GetFileName(req, "file-drop", "test", "post", 1<<20); name != "" {
+		t.Errorf("Expected the upload to be rejected, got %q", name)
+	}
+	saved, err := os.ReadDir(dir)
+	if err != nil || len(saved) != 0 {
+		t.Errorf("Expected nothing saved, got %v, %v", saved, err)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/config"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/testutil"
+)
+
+// newTestApp returns an App backed by an in-memory database with every migration applied
+func newTestApp(t *testing.T) *app.App {
+	t.Helper()
+	return app.NewApp(testutil.NewTestDB(t), config.Default())
+}
+
+// insertTestUser creates a user with a live session and returns it
+func insertTestUser(t *testing.T, a *app.App, username string) *models.User {
+	t.Helper()
+	id := models.NewUUIDField()
+	_, err := a.DB.Exec(`INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype,
+		IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, ?, ?, ?, '', '', 'user', 0, '', '', 'hash')`, id, username, username+"@example.com", username+".png")
+	if err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	_, err = a.DB.Exec("INSERT INTO Sessions (Token, UserID, Expires) VALUES (?, ?, ?)",
+		testSessionToken(username), id, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+	return &models.User{ID: id, Username: username, Login: models.Login{SessionToken: testSessionToken(username)}}
+}
+
+// insertTestChannel creates a public channel owned by ownerID and returns its ID
+func insertTestChannel(t *testing.T, a *app.App, ownerID models.UUIDField, name string) int64 {
+	t.Helper()
+	res, err := a.DB.Exec(`INSERT INTO Channels (OwnerID, Name, Avatar, Banner, Description, Privacy, IsMuted, IsFlagged)
+		VALUES (?, ?, '', '', 'test channel', 0, 0, 0)`, ownerID, name)
+	if err != nil {
+		t.Fatalf("Failed to insert channel: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+// testSessionToken is the session token insertTestUser stores for username
+func testSessionToken(username string) string {
+	return "session-" + username
+}
+
+// withUsername attaches the session cookies WithUser reads to resolve the current user
+func withUsername(r *http.Request, username string) *http.Request {
+	r.AddCookie(&http.Cookie{Name: "username", Value: username})
+	r.AddCookie(&http.Cookie{Name: "session_token", Value: testSessionToken(username)})
+	return r
+}
@@ -9,6 +9,7 @@ import (
 	// "github.com/gary-norman/forum/internal/dao"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
 	"github.com/gary-norman/forum/internal/view"
 )
 
@@ -51,8 +52,6 @@ func (h *HomeHandler) RenderIndex(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	userLoggedIn := true
-	userID := models.ZeroUUIDField()
 	// SECTION --- posts and comments ---
 	// postDAO := dao.NewDAO[*models.Post](h.App.DB)
 	// ctx := context.Background()
@@ -83,10 +82,7 @@ func (h *HomeHandler) RenderIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	randomUser := GetRandomUser(allUsers)
-	currentUser, ok := mw.GetUserFromContext(ctx)
-	if !ok {
-		userLoggedIn = false
-	}
+	currentUser, userLoggedIn := mw.CurrentUser(ctx)
 
 	var currentUserErr error
 	// attach following/follower numbers to the random user
@@ -95,40 +91,6 @@ func (h *HomeHandler) RenderIndex(w http.ResponseWriter, r *http.Request) {
 		models.LogErrorWithContext(ctx, "Failed to count random user followers/following", currentUserErr)
 	}
 
-	// SECTION --- posts and comments ---
-	allPosts, err := h.App.Posts.All(ctx)
-	if err != nil {
-		models.LogErrorWithContext(ctx, "Failed to fetch all posts", err)
-	}
-	// Retrieve total likes and dislikes for each post
-	allPosts = h.Reaction.GetPostsLikesAndDislikes(allPosts)
-
-	// Retrieve last reaction time for posts
-	allPosts, err = h.Reaction.getLastReactionTimeForPosts(allPosts)
-	if err != nil {
-		models.LogErrorWithContext(ctx, "Failed to get last reaction time for posts", err)
-	}
-
-	for p := range allPosts {
-		models.UpdateTimeSince(allPosts[p])
-	}
-	allPosts, err = h.Comment.GetPostsComments(allPosts)
-	if err != nil {
-		models.LogErrorWithContext(ctx, "Failed to fetch post comments", err)
-	}
-
-	for p := range allPosts {
-		channelIDs, err := h.App.Channels.GetChannelIDFromPost(ctx, allPosts[p].ID)
-		if err != nil {
-			models.LogErrorWithContext(ctx, "Failed to get channel ID from post", err)
-		}
-		if len(allPosts) > 0 && len(channelIDs) > 0 {
-			allPosts[p].ChannelID = channelIDs[0]
-		} else {
-			models.LogWarnWithContext(ctx, "Post %d does not belong to any channel", allPosts[p].ID)
-		}
-	}
-
 	// SECTION --- channels --
 	allChannels, err := h.App.Channels.All(ctx)
 	if err != nil {
@@ -138,21 +100,12 @@ func (h *HomeHandler) RenderIndex(w http.ResponseWriter, r *http.Request) {
 		models.UpdateTimeSince(allChannels[c])
 	}
 
-	for p := range allPosts {
-		for _, channel := range allChannels {
-			if channel.ID == allPosts[p].ChannelID {
-				allPosts[p].ChannelName = channel.Name
-			}
-		}
-	}
-
 	ownedChannels := make([]*models.Channel, 0)
 	joinedChannels := make([]*models.Channel, 0)
 	ownedAndJoinedChannels := make([]*models.Channel, 0)
 	channelMap := make(map[int64]bool)
 
 	if userLoggedIn {
-		userID = currentUser.ID
 		// attach following/follower numbers to currently logged-in user
 		currentUser.Followers, currentUser.Following, err = h.App.Loyalty.CountUsers(ctx, currentUser.ID)
 		if err != nil {
@@ -221,7 +174,7 @@ func (h *HomeHandler) RenderIndex(w http.ResponseWriter, r *http.Request) {
 
 	chats = append(chats, models.Chat{
 		ID:         fakeChatID_1,
-		ChatType:   "buddy",
+		ChatType:   models.ChatTypeBuddy,
 		Name:       "chat logic",
 		LastActive: time.Now(),
 		Buddy:      fakeUser_1,
@@ -245,7 +198,7 @@ func (h *HomeHandler) RenderIndex(w http.ResponseWriter, r *http.Request) {
 
 	chats = append(chats, models.Chat{
 		ID:         fakeChatID_2,
-		ChatType:   "group",
+		ChatType:   models.ChatTypeGroup,
 		Name:       "languages",
 		LastActive: time.Now(),
 		Group:      models.Group{ID: models.NewUUIDField(), Name: "Language Lovers"},
@@ -284,7 +237,7 @@ func (h *HomeHandler) RenderIndex(w http.ResponseWriter, r *http.Request) {
 	// SECTION -- template ---
 	data := models.TemplateData{
 		// ---------- users ----------
-		UserID:      userID,
+		UserID:      mw.CurrentUserID(ctx),
 		AllUsers:    allUsers,
 		RandomUser:  randomUser,
 		CurrentUser: currentUser,
@@ -328,8 +281,6 @@ func (h *HomeHandler) GetHome(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	start := time.Now()
 	var userPosts []*models.Post
-	userLoggedIn := true
-	userID := models.ZeroUUIDField()
 
 	// SECTION --- user ---
 	allUsers, allUsersErr := h.App.Users.All(ctx)
@@ -349,10 +300,7 @@ func (h *HomeHandler) GetHome(w http.ResponseWriter, r *http.Request) {
 	}
 
 	randomUser := GetRandomUser(allUsers)
-	currentUser, ok := mw.GetUserFromContext(ctx)
-	if !ok {
-		userLoggedIn = false
-	}
+	currentUser, userLoggedIn := mw.CurrentUser(ctx)
 
 	var currentUserErr error
 	// attach following/follower numbers to the random user
@@ -362,38 +310,11 @@ func (h *HomeHandler) GetHome(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// SECTION --- posts and comments ---
-	allPosts, err := h.App.Posts.All(ctx)
+	allPosts, err := h.App.PostService.Feed(ctx, sqlite.NoLimit, 0)
 	if err != nil {
 		models.LogErrorWithContext(ctx, "Failed to fetch all posts", err)
 	}
-	// Retrieve total likes and dislikes for each post
-	allPosts = h.Reaction.GetPostsLikesAndDislikes(allPosts)
-
-	// Retrieve last reaction time for posts
-	allPosts, err = h.Reaction.getLastReactionTimeForPosts(allPosts)
-	if err != nil {
-		models.LogErrorWithContext(ctx, "Failed to get last reaction time for posts", err)
-	}
-
-	for p := range allPosts {
-		models.UpdateTimeSince(allPosts[p])
-	}
-	allPosts, err = h.Comment.GetPostsComments(allPosts)
-	if err != nil {
-		models.LogErrorWithContext(ctx, "Failed to fetch post comments", err)
-	}
-
-	for p := range allPosts {
-		channelIDs, err := h.App.Channels.GetChannelIDFromPost(ctx, allPosts[p].ID)
-		if err != nil {
-			models.LogErrorWithContext(ctx, "Failed to get channel ID from post", err)
-		}
-		if len(allPosts) > 0 && len(channelIDs) > 0 {
-			allPosts[p].ChannelID = channelIDs[0]
-		} else {
-			models.LogWarnWithContext(ctx, "Post %d does not belong to any channel", allPosts[p].ID)
-		}
-	}
+	allPosts = hideFlaggedPosts(ctx, h.App, currentUser, allPosts)
 
 	// SECTION --- channels --
 	allChannels, err := h.App.Channels.All(ctx)
@@ -404,21 +325,12 @@ func (h *HomeHandler) GetHome(w http.ResponseWriter, r *http.Request) {
 		models.UpdateTimeSince(allChannels[c])
 	}
 
-	for p := range allPosts {
-		for _, channel := range allChannels {
-			if channel.ID == allPosts[p].ChannelID {
-				allPosts[p].ChannelName = channel.Name
-			}
-		}
-	}
-
 	ownedChannels := make([]*models.Channel, 0)
 	joinedChannels := make([]*models.Channel, 0)
 	ownedAndJoinedChannels := make([]*models.Channel, 0)
 	channelMap := make(map[int64]bool)
 
 	if userLoggedIn {
-		userID = currentUser.ID
 		userPosts = h.Post.GetUserPosts(currentUser, allPosts)
 		// attach following/follower numbers to currently logged-in user
 		currentUser.Followers, currentUser.Following, err = h.App.Loyalty.CountUsers(ctx, currentUser.ID)
@@ -464,7 +376,7 @@ func (h *HomeHandler) GetHome(w http.ResponseWriter, r *http.Request) {
 	// SECTION -- template ---
 	data := models.HomePage{
 		// ---------- users ----------
-		UserID:      userID,
+		UserID:      mw.CurrentUserID(ctx),
 		CurrentUser: currentUser,
 		// ---------- posts ----------
 		AllPosts:  allPosts,
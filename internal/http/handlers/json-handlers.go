@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// RequestError reports a request body that could not be decoded. Field names the
+// offending JSON field when the problem is tied to one.
+type RequestError struct {
+	Field string
+	Msg   string
+	err   error
+}
+
+func (e *RequestError) Error() string { return e.Msg }
+func (e *RequestError) Unwrap() error { return e.err }
+
+// sentKinds describes the JSON kinds encoding/json names in UnmarshalTypeError.Value
+var sentKinds = map[string]string{
+	"string": "a string",
+	"number": "a number",
+	"bool":   "a boolean",
+	"object": "an object",
+	"array":  "an array",
+}
+
+// jsonKind describes the JSON kind a Go value of kind k is decoded from
+func jsonKind(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "a number"
+	}
+}
+
+// decodeJSON decodes the request body into dst. Unknown fields, type mismatches,
+// malformed JSON and trailing data are returned as a *RequestError, which
+// writeError reports as a 400 naming the field.
+func decodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return requestErrorFor(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return &RequestError{Msg: "Invalid JSON: request body must contain a single JSON object"}
+	}
+	return nil
+}
+
+// requestErrorFor turns a decoding error into a *RequestError with a client-facing message
+func requestErrorFor(err error) *RequestError {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		// Value is the JSON kind that was sent, e.g. "string" or "number -1"
+		sent, _, _ := strings.Cut(typeErr.Value, " ")
+		if name, ok := sentKinds[sent]; ok {
+			sent = name
+		}
+		return &RequestError{
+			Field: typeErr.Field,
+			Msg:   fmt.Sprintf("field %q must be %s, got %s", typeErr.Field, jsonKind(typeErr.Type.Kind()), sent),
+			err:   err,
+		}
+	case errors.As(err, &typeErr):
+		return &RequestError{Msg: "Invalid JSON: request body must be " + jsonKind(typeErr.Type.Kind()), err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &RequestError{Field: field, Msg: fmt.Sprintf("unknown field %q", field), err: err}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return &RequestError{Msg: "Invalid JSON: request body is not valid JSON", err: err}
+	default:
+		return &RequestError{Msg: err.Error(), err: err}
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+)
+
+func TestDecodeJSON_RejectsBadBodies(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "alice").ID
+
+	login := (&AuthHandler{App: a}).Login
+	storeReaction := mw.WithUser(http.HandlerFunc((&ReactionHandler{App: a}).StoreReaction), a)
+	react := func(w http.ResponseWriter, r *http.Request) { storeReaction.ServeHTTP(w, withUsername(r, "alice")) }
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		body        string
+		wantField   string
+		wantMessage string
+	}{
+		{"login unknown field", login, `{"username":"alice","password":"x","remember":true}`, "remember", `unknown field "remember"`},
+		{"login wrong type", login, `{"username":"alice","password":"x","ephemeral":"yes"}`, "ephemeral", `field "ephemeral" must be a boolean, got a string`},
+		{"login number for string", login, `{"username":42,"password":"x"}`, "username", `field "username" must be a string, got a number`},
+		{"login trailing data", login, `{"username":"alice","password":"x"} {}`, "", "single JSON object"},
+		{"login not an object", login, `["alice"]`, "", "request body must be an object"},
+		{"reaction unknown field", react, fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":1,"postId":1}`, author), "postId", `unknown field "postId"`},
+		{"reaction wrong type", react, fmt.Sprintf(`{"liked":"true","authorId":%q,"reactedPostId":1}`, author), "liked", `field "liked" must be a boolean, got a string`},
+		{"reaction string target", react, fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":"1"}`, author), "reactedPostId", `field "reactedPostId" must be a number, got a string`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var body struct {
+				Code    int               `json:"code"`
+				Message string            `json:"message"`
+				Fields  map[string]string `json:"fields"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Response is not valid JSON: %v", err)
+			}
+			if !strings.Contains(body.Message, tt.wantMessage) {
+				t.Errorf("Expected message to mention %q, got %q", tt.wantMessage, body.Message)
+			}
+			if tt.wantField == "" {
+				if len(body.Fields) != 0 {
+					t.Errorf("Expected no fields, got %v", body.Fields)
+				}
+			} else if _, ok := body.Fields[tt.wantField]; !ok || len(body.Fields) != 1 {
+				t.Errorf("Expected only field %q, got %v", tt.wantField, body.Fields)
+			}
+		})
+	}
+}
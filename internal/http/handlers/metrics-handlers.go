@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gary-norman/forum/internal/app"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+type MetricsHandler struct {
+	App *app.App
+}
+
+// adminMetricsWindow is how far back GetAdminMetrics looks without ?since=, and
+// adminMetricsLimit caps how many SystemMetrics rows it returns
+const (
+	adminMetricsWindow = 24 * time.Hour
+	adminMetricsLimit  = 1000
+)
+
+// GetAdminMetrics serves site administrators the request statistics and system
+// metrics recorded over the last ?since= (a duration such as 1h, default 24h),
+// newest metrics first, along with the database circuit breaker's state
+func (h *MetricsHandler) GetAdminMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to see metrics"))
+		return
+	}
+	if !user.IsAdmin() {
+		writeError(w, withMessage(sqlite.ErrForbidden, "only administrators can see metrics"))
+		return
+	}
+
+	window := adminMetricsWindow
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			writeError(w, &RequestError{Field: "since", Msg: "since must be a positive duration such as 1h", err: err})
+			return
+		}
+		window = d
+	}
+	since := time.Now().Add(-window)
+
+	stats, err := h.App.Logs.GetRequestStats(ctx, since)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to get request stats", err)
+		writeError(w, err)
+		return
+	}
+	metrics, err := h.App.Logs.GetSystemMetricsSince(ctx, since, adminMetricsLimit)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to get system metrics", err)
+		writeError(w, err)
+		return
+	}
+	if metrics == nil {
+		metrics = []models.SystemMetric{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"since":    since.UTC(),
+		"requests": stats,
+		"metrics":  metrics,
+		"dbCircuit": map[string]any{
+			"state":    h.App.DBCircuit.State().String(),
+			"failures": h.App.DBCircuit.Failures(),
+		},
+	}); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode metrics", err)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestGetAdminMetrics(t *testing.T) {
+	a := newTestApp(t)
+	h := &MetricsHandler{App: a}
+	handler := mw.WithUser(http.HandlerFunc(h.GetAdminMetrics), a)
+
+	insertTestUser(t, a, "regular")
+	admin := insertTestUser(t, a, "siteadmin")
+	if _, err := a.DB.Exec("UPDATE Users SET Usertype = ? WHERE ID = ?", models.UsertypeAdmin, admin.ID); err != nil {
+		t.Fatalf("Failed to promote admin: %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	for _, status := range []int{200, 404} {
+		if err := a.Logs.InsertRequestLog(ctx, models.RequestLog{Timestamp: now, Method: "GET", Path: "/home", StatusCode: status, Duration: 10}); err != nil {
+			t.Fatalf("InsertRequestLog failed: %v", err)
+		}
+	}
+	if err := a.Logs.InsertRequestLog(ctx, models.RequestLog{Timestamp: now.Add(-48 * time.Hour), Method: "GET", Path: "/old", StatusCode: 200}); err != nil {
+		t.Fatalf("InsertRequestLog failed: %v", err)
+	}
+	if err := a.Logs.InsertSystemMetric(ctx, models.SystemMetric{Timestamp: now, MetricType: models.MetricTypeMemory, MetricName: "heap_alloc", MetricValue: 1024, Unit: "bytes"}); err != nil {
+		t.Fatalf("InsertSystemMetric failed: %v", err)
+	}
+
+	serve := func(username, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/metrics"+query, nil)
+		if username != "" {
+			req = withUsername(req, username)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("requires login", func(t *testing.T) {
+		if rec := serve("", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", rec.Code)
+		}
+	})
+
+	t.Run("requires admin", func(t *testing.T) {
+		if rec := serve("regular", ""); rec.Code != http.StatusForbidden {
+			t.Errorf("Expected 403, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects a bad window", func(t *testing.T) {
+		if rec := serve("siteadmin", "?since=yesterday"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("admin sees the last day", func(t *testing.T) {
+		rec := serve("siteadmin", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Requests struct {
+				TotalRequests   int64
+				ErrorRate       float64
+				RequestsPerPath map[string]int64
+			} `json:"requests"`
+			Metrics   []models.SystemMetric `json:"metrics"`
+			DBCircuit struct {
+				State    string `json:"state"`
+				Failures uint32 `json:"failures"`
+			} `json:"dbCircuit"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if body.Requests.TotalRequests != 2 || body.Requests.ErrorRate != 50 || body.Requests.RequestsPerPath["/old"] != 0 {
+			t.Errorf("Expected the two requests of the last day, got %+v", body.Requests)
+		}
+		if len(body.Metrics) != 1 || body.Metrics[0].MetricName != "heap_alloc" {
+			t.Errorf("Expected the recorded metric, got %+v", body.Metrics)
+		}
+		if body.DBCircuit.State != "closed" {
+			t.Errorf("Expected a closed circuit, got %+v", body.DBCircuit)
+		}
+	})
+
+	t.Run("narrower window", func(t *testing.T) {
+		if _, err := a.DB.Exec("UPDATE RequestLogs SET Timestamp = ? WHERE Path = '/home' AND StatusCode = 404",
+			models.FormatTimestamp(now.Add(-2*time.Hour))); err != nil {
+			t.Fatalf("Failed to age request log: %v", err)
+		}
+		rec := serve("siteadmin", "?since=1h")
+		var body struct {
+			Requests struct{ TotalRequests int64 } `json:"requests"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Requests.TotalRequests != 1 {
+			t.Errorf("Expected one request in the last hour, got %+v, %v", body.Requests, err)
+		}
+	})
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/events"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
 )
 
 type ModHandler struct {
@@ -35,6 +39,22 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, message string) {
 	}
 }
 
+// emitEvent hands event to App.Events. With a webhook configured that is an
+// events.Queue, so a slow or failing webhook never holds up the response.
+func emitEvent(ctx context.Context, a *app.App, event events.Event) {
+	if err := a.Events.Emit(ctx, event); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to emit moderation event", err)
+	}
+}
+
+// recordAudit writes entry to the audit log. A failure is logged rather than
+// returned: the action itself has already happened and the response should say so.
+func recordAudit(ctx context.Context, a *app.App, entry models.AuditEntry) {
+	if _, err := a.Audit.Insert(ctx, entry); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to record audit entry", err)
+	}
+}
+
 func (m *ModHandler) RequestModeration(w http.ResponseWriter, r *http.Request, channelID int64) {
 	ctx := r.Context()
 	currentUser, ok := mw.GetUserFromContext(ctx)
@@ -48,9 +68,13 @@ func (m *ModHandler) RequestModeration(w http.ResponseWriter, r *http.Request, c
 	}
 
 	channel, err := m.App.Channels.GetChannelByID(ctx, channelID)
+	if errors.Is(err, sqlite.ErrChannelNotFound) {
+		writeJSONResponse(w, http.StatusNotFound, "channel not found")
+		return
+	}
 	if err != nil {
 		models.LogErrorWithContext(ctx, "Failed to fetch channel", err)
-		http.Error(w, `{"error": "channel not found"}`, http.StatusNotFound)
+		writeJSONResponse(w, http.StatusInternalServerError, "failed to fetch channel")
 		return
 	}
 
@@ -58,11 +82,34 @@ func (m *ModHandler) RequestModeration(w http.ResponseWriter, r *http.Request, c
 	case true:
 		// construct the request, set the status to pending, notify the user
 		// send a message to the channel owner
+		event := events.NewEvent(events.ModerationRequested, currentUser.ID)
+		event.ChannelID = channelID
+		event.TargetID = channelOwner
+		emitEvent(ctx, m.App, event)
+		recordAudit(ctx, m.App, models.AuditEntry{
+			ActorID:    currentUser.ID,
+			Action:     models.AuditModerationRequested,
+			TargetType: models.AuditTargetUser,
+			TargetID:   channelOwner,
+			ChannelID:  channelID,
+		})
 		writeJSONResponse(w, http.StatusOK, fmt.Sprintf("Moderation request sent to %s", channelOwner))
 	case false:
 		// call the  AddModeration function
-		if m.App.Mods.AddModeration(currentUser.ID, channelID) != nil {
+		if err := m.App.Mods.AddModeration(currentUser.ID, channelID); err != nil {
 			models.LogErrorWithContext(ctx, "Failed to add moderation", err)
+		} else {
+			event := events.NewEvent(events.ModeratorAdded, currentUser.ID)
+			event.ChannelID = channelID
+			event.TargetID = currentUser.ID.String()
+			emitEvent(ctx, m.App, event)
+			recordAudit(ctx, m.App, models.AuditEntry{
+				ActorID:    currentUser.ID,
+				Action:     models.AuditModeratorAdded,
+				TargetType: models.AuditTargetUser,
+				TargetID:   currentUser.ID.String(),
+				ChannelID:  channelID,
+			})
 		}
 		writeJSONResponse(w, http.StatusOK, fmt.Sprintf("Welcome to %s!", channel.Name))
 	default:
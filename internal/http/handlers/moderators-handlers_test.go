@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestModerationActionsAreAudited(t *testing.T) {
+	a := newTestApp(t)
+	owner := insertTestUser(t, a, "owner")
+	mod := insertTestUser(t, a, "mod")
+	publicID := insertTestChannel(t, a, owner.ID, "open")
+	privateID := insertTestChannel(t, a, owner.ID, "closed")
+	otherID := insertTestChannel(t, a, owner.ID, "other")
+	if _, err := a.DB.Exec("UPDATE Channels SET Privacy = 1 WHERE ID = ?", privateID); err != nil {
+		t.Fatalf("make channel private: %v", err)
+	}
+
+	channels := &ChannelHandler{App: a}
+	mux := http.NewServeMux()
+	mux.Handle("POST /channels/privacy/{channelId}", mw.WithUser(http.HandlerFunc(channels.SetChannelPrivacy), a))
+	mux.Handle("POST /channels/filter-terms/{channelId}", mw.WithUser(http.HandlerFunc(channels.StoreFilterTerm), a))
+	mux.Handle("POST /moderate/{channelId}", mw.WithUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		channelID, _ := strconv.ParseInt(r.PathValue("channelId"), 10, 64)
+		(&ModHandler{App: a}).RequestModeration(w, r, channelID)
+	}), a))
+
+	post := func(username, path string, form url.Values) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, withUsername(req, username))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+	latest := func(actor *models.User) models.AuditEntry {
+		t.Helper()
+		entries, err := a.Audit.ForActor(context.Background(), actor.ID, 1)
+		if err != nil {
+			t.Fatalf("ForActor: %v", err)
+		}
+		if len(entries) == 0 {
+			t.Fatal("no audit entry recorded")
+		}
+		e := entries[0]
+		if e.Created.IsZero() {
+			t.Error("audit entry has no Created time")
+		}
+		e.ID, e.Created = 0, time.Time{}
+		return e
+	}
+	public := strconv.FormatInt(publicID, 10)
+	private := strconv.FormatInt(privateID, 10)
+	other := strconv.FormatInt(otherID, 10)
+
+	tests := []struct {
+		name  string
+		actor *models.User
+		path  string
+		form  url.Values
+		want  models.AuditEntry
+	}{
+		{"privacy change", owner, "/channels/privacy/" + other, url.Values{"private": {"true"}},
+			models.AuditEntry{Action: models.AuditChannelPrivacy, TargetType: models.AuditTargetChannel, TargetID: other,
+				ChannelID: otherID, Metadata: map[string]any{"private": true}}},
+		{"filter term added", owner, "/channels/filter-terms/" + public, url.Values{"action": {"add"}, "term": {"spam"}},
+			models.AuditEntry{Action: models.AuditFilterTermAdded, TargetType: models.AuditTargetChannel, TargetID: public,
+				ChannelID: publicID, Metadata: map[string]any{"term": "spam"}}},
+		{"filter term removed", owner, "/channels/filter-terms/" + public, url.Values{"action": {"remove"}, "term": {"spam"}},
+			models.AuditEntry{Action: models.AuditFilterTermRemoved, TargetType: models.AuditTargetChannel, TargetID: public,
+				ChannelID: publicID, Metadata: map[string]any{"term": "spam"}}},
+		{"filter mode changed", owner, "/channels/filter-terms/" + public, url.Values{"action": {"mode"}, "mode": {"flag"}},
+			models.AuditEntry{Action: models.AuditFilterModeChanged, TargetType: models.AuditTargetChannel, TargetID: public,
+				ChannelID: publicID, Metadata: map[string]any{"mode": "flag"}}},
+		{"moderator added", mod, "/moderate/" + public, nil,
+			models.AuditEntry{Action: models.AuditModeratorAdded, TargetType: models.AuditTargetUser, TargetID: mod.ID.String(),
+				ChannelID: publicID}},
+		{"moderation requested", mod, "/moderate/" + private, nil,
+			models.AuditEntry{Action: models.AuditModerationRequested, TargetType: models.AuditTargetUser, TargetID: owner.Username,
+				ChannelID: privateID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post(tt.actor.Username, tt.path, tt.form)
+			got := latest(tt.actor)
+			want := tt.want
+			want.ActorID = tt.actor.ID
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("audit entry = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gary-norman/forum/internal/app"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+type NotificationHandler struct {
+	App *app.App
+}
+
+// notificationsPageSize and notificationsMaxPageSize bound the page size of ListNotifications
+const (
+	notificationsPageSize    = 20
+	notificationsMaxPageSize = 100
+)
+
+// mentionPattern matches an @username in comment text. The @ must start a word, so
+// email addresses are not read as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])@([\p{L}\p{N}_.-]+)`)
+
+// mentionedUsernames returns the usernames mentioned in text, each once, leaving out
+// any in skip. Names are compared case-insensitively, as usernames are.
+func mentionedUsernames(text string, skip ...string) []string {
+	seen := make(map[string]bool)
+	for _, name := range skip {
+		seen[strings.ToLower(name)] = true
+	}
+	var names []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// a mention at the end of a sentence keeps its full stop out of the name
+		name := strings.TrimRight(match[1], ".")
+		if !validUsernameLength(name) || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// notify stores n. The action it reports has already succeeded, so a failure is
+// logged rather than returned.
+func notify(ctx context.Context, a *app.App, n models.Notification) {
+	if err := a.Notifications.Insert(ctx, n); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to store notification", err)
+	}
+}
+
+// notifyComment tells the author of whatever comment replied to, and anyone it
+// mentions, about the new comment commentID
+func notifyComment(ctx context.Context, a *app.App, comment models.Comment, commentID int64) {
+	n := models.Notification{ActorID: comment.AuthorID, CommentID: &commentID}
+	if comment.CommentedPostID.Valid {
+		n.PostID = &comment.CommentedPostID.Int64
+	}
+
+	var repliedTo string
+	switch {
+	case comment.CommentedCommentID.Valid:
+		parent, err := a.Comments.GetByID(ctx, comment.CommentedCommentID.Int64)
+		if err != nil {
+			models.LogWarnWithContext(ctx, "Failed to look up replied-to comment: %v", err)
+			break
+		}
+		repliedTo = parent.Author
+		reply := n
+		reply.UserID, reply.Kind = parent.AuthorID, models.NotificationReply
+		notify(ctx, a, reply)
+	case comment.CommentedPostID.Valid:
+		post, err := a.Posts.GetPostByID(ctx, comment.CommentedPostID.Int64)
+		if err != nil {
+			models.LogWarnWithContext(ctx, "Failed to look up commented post: %v", err)
+			break
+		}
+		repliedTo = post.Author
+		reply := n
+		reply.UserID, reply.Kind = post.AuthorID, models.NotificationReply
+		notify(ctx, a, reply)
+	}
+
+	// whoever was replied to already has a notification for this comment
+	n.Kind = models.NotificationMention
+	if err := a.Notifications.InsertMentions(ctx, n, mentionedUsernames(comment.Content, repliedTo)); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to store mention notifications", err)
+	}
+}
+
+// notifyReaction tells the author of target that actorID likes it. Dislikes, and
+// presses that cleared a like, are not reported.
+func notifyReaction(ctx context.Context, a *app.App, actorID models.UUIDField, target models.ReactionTarget) {
+	status, err := a.Reactions.GetReactionStatus(ctx, actorID, target)
+	if err != nil {
+		models.LogWarnWithContext(ctx, "Failed to read reaction status: %v", err)
+		return
+	}
+	if !status.Liked {
+		return
+	}
+
+	n := models.Notification{ActorID: actorID, Kind: models.NotificationReaction}
+	switch target.Type {
+	case models.ReactionTargetPost:
+		post, err := a.Posts.GetPostByID(ctx, target.ID)
+		if err != nil {
+			models.LogWarnWithContext(ctx, "Failed to look up liked post: %v", err)
+			return
+		}
+		n.UserID, n.PostID = post.AuthorID, &post.ID
+	case models.ReactionTargetComment:
+		comment, err := a.Comments.GetByID(ctx, target.ID)
+		if err != nil {
+			models.LogWarnWithContext(ctx, "Failed to look up liked comment: %v", err)
+			return
+		}
+		n.UserID, n.CommentID = comment.AuthorID, &comment.ID
+		if comment.CommentedPostID.Valid {
+			n.PostID = &comment.CommentedPostID.Int64
+		}
+	default:
+		return
+	}
+	notify(ctx, a, n)
+}
+
+// ListNotifications returns a page of the current user's notifications, newest
+// first, for ?limit= and ?offset=. UnreadNotifications has the unread count.
+func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to see your notifications"))
+		return
+	}
+
+	limit, offset := parsePagination(r, notificationsPageSize, notificationsMaxPageSize)
+	notifications, err := h.App.Notifications.ForUser(ctx, user.ID, limit, offset)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to list notifications", err, "UserID:", user.ID)
+		writeError(w, err)
+		return
+	}
+	total, err := h.App.Notifications.Count(ctx, user.ID)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count notifications", err, "UserID:", user.ID)
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(NewPage(notifications, total, limit, offset)); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode notifications", err)
+	}
+}
+
+// UnreadNotifications returns how many of the current user's notifications are unread
+func (h *NotificationHandler) UnreadNotifications(w http.ResponseWriter, r *http.Request) {
+	user, ok := mw.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to see your notifications"))
+		return
+	}
+	h.writeUnread(w, r, user.ID)
+}
+
+// MarkNotificationRead marks one of the current user's notifications as read
+func (h *NotificationHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to update your notifications"))
+		return
+	}
+	id, err := models.GetIntFromPathValue(r.PathValue("notificationId"))
+	if err != nil {
+		writeError(w, &RequestError{Field: "notificationId", Msg: "notification ID must be a number", err: err})
+		return
+	}
+
+	if err := h.App.Notifications.MarkRead(ctx, user.ID, id); err != nil {
+		models.LogWarnWithContext(ctx, "Failed to mark notification %d read: %v", id, err)
+		writeError(w, err)
+		return
+	}
+	h.writeUnread(w, r, user.ID)
+}
+
+// MarkAllNotificationsRead marks every notification of the current user as read
+func (h *NotificationHandler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to update your notifications"))
+		return
+	}
+
+	if err := h.App.Notifications.MarkAllRead(ctx, user.ID); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to mark notifications read", err, "UserID:", user.ID)
+		writeError(w, err)
+		return
+	}
+	h.writeUnread(w, r, user.ID)
+}
+
+// writeUnread responds with userID's remaining unread count
+func (h *NotificationHandler) writeUnread(w http.ResponseWriter, r *http.Request, userID models.UUIDField) {
+	ctx := r.Context()
+	unread, err := h.App.Notifications.UnreadCount(ctx, userID)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count unread notifications", err, "UserID:", userID)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"unread": unread}); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode unread count", err)
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestMentionedUsernames(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		skip []string
+		want []string
+	}{
+		{"none", "no mentions here", nil, nil},
+		{"trailing full stop", "thanks @carol.", nil, []string{"carol"}},
+		{"repeats in any case", "@Carol and @carol and @CAROL", nil, []string{"Carol"}},
+		{"too short to be a username", "@bob and @carol", nil, []string{"carol"}},
+		{"skipped names", "@alice, @carol", []string{"ALICE"}, []string{"carol"}},
+		{"email address", "mail carol@example.com", nil, nil},
+		{"start of text", "@carol, @dave1 look", nil, []string{"carol", "dave1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mentionedUsernames(tt.text, tt.skip...); !slices.Equal(got, tt.want) {
+				t.Errorf("mentionedUsernames(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	a := newTestApp(t)
+	ctx := context.Background()
+	alice := insertTestUser(t, a, "alice")
+	bobby := insertTestUser(t, a, "bobby")
+	insertTestUser(t, a, "carol")
+	channelID := insertTestChannel(t, a, alice.ID, "general")
+	postID := insertTestChannelPost(t, a, alice, channelID, "thread")
+
+	comment := models.Comment{
+		Content:         "agreed, and @carol @alice should see this",
+		Author:          bobby.Username,
+		AuthorID:        bobby.ID,
+		ChannelID:       channelID,
+		ChannelName:     "general",
+		CommentedPostID: sql.NullInt64{Int64: postID, Valid: true},
+		IsCommentable:   true,
+	}
+	commentID, err := a.Comments.Insert(ctx, comment)
+	if err != nil {
+		t.Fatalf("Failed to insert comment: %v", err)
+	}
+	notifyComment(ctx, a, comment, commentID)
+	if err := a.Reactions.Upsert(ctx, true, false, bobby.ID, models.PostTarget(postID)); err != nil {
+		t.Fatalf("Failed to like post: %v", err)
+	}
+	notifyReaction(ctx, a, bobby.ID, models.PostTarget(postID))
+
+	notifications := &NotificationHandler{App: a}
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/notifications", mw.WithUser(http.HandlerFunc(notifications.ListNotifications), a))
+	mux.Handle("GET /api/notifications/unread", mw.WithUser(http.HandlerFunc(notifications.UnreadNotifications), a))
+	mux.Handle("POST /api/notifications/read", mw.WithUser(http.HandlerFunc(notifications.MarkAllNotificationsRead), a))
+	mux.Handle("POST /api/notifications/{notificationId}/read", mw.WithUser(http.HandlerFunc(notifications.MarkNotificationRead), a))
+
+	serve := func(username, method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, withUsername(httptest.NewRequest(method, path, nil), username))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s as %s: expected 200, got %d: %s", method, path, username, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+	list := func(username string) Page[models.Notification] {
+		t.Helper()
+		var got Page[models.Notification]
+		if err := json.NewDecoder(serve(username, http.MethodGet, "/api/notifications").Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode notifications: %v", err)
+		}
+		return got
+	}
+	type unreadCount struct {
+		Unread int `json:"unread"`
+	}
+	unread := func(username, method, path string) int {
+		t.Helper()
+		var got unreadCount
+		if err := json.NewDecoder(serve(username, method, path).Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode unread count: %v", err)
+		}
+		return got.Unread
+	}
+
+	// alice is told of the reply and the like; the mention in the reply is not repeated
+	got := list("alice")
+	var kinds []string
+	for _, n := range got.Items {
+		kinds = append(kinds, n.Kind)
+	}
+	if !slices.Equal(kinds, []string{models.NotificationReaction, models.NotificationReply}) && !slices.Equal(kinds, []string{models.NotificationReply, models.NotificationReaction}) {
+		t.Fatalf("Expected a reply and a like for alice, got %+v", got.Items)
+	}
+	if got.Total != 2 || got.HasMore {
+		t.Errorf("Expected 2 in total and no more, got %d, has_more %v", got.Total, got.HasMore)
+	}
+	if n := unread("alice", http.MethodGet, "/api/notifications/unread"); n != 2 {
+		t.Errorf("Expected 2 unread, got %d", n)
+	}
+	var first Page[models.Notification]
+	if err := json.NewDecoder(serve("alice", http.MethodGet, "/api/notifications?limit=1").Body).Decode(&first); err != nil {
+		t.Fatalf("Failed to decode notifications: %v", err)
+	}
+	if len(first.Items) != 1 || first.Total != 2 || !first.HasMore {
+		t.Errorf("Expected 1 of 2 with more to come, got %+v", first)
+	}
+	for _, n := range got.Items {
+		if n.Actor != "bobby" || n.PostID == nil || *n.PostID != postID {
+			t.Errorf("Expected bobby's notification about post %d, got %+v", postID, n)
+		}
+		if n.Kind == models.NotificationReply && (n.CommentID == nil || *n.CommentID != commentID) {
+			t.Errorf("Expected the reply to name comment %d, got %+v", commentID, n)
+		}
+	}
+	if carol := list("carol"); len(carol.Items) != 1 || carol.Items[0].Kind != models.NotificationMention {
+		t.Errorf("Expected carol to be told of the mention, got %+v", carol.Items)
+	}
+	if bob := list("bobby"); len(bob.Items) != 0 {
+		t.Errorf("Expected bobby to have no notifications, got %+v", bob.Items)
+	}
+
+	rec := httptest.NewRecorder()
+	path := "/api/notifications/" + strconv.FormatInt(got.Items[0].ID, 10) + "/read"
+	mux.ServeHTTP(rec, withUsername(httptest.NewRequest(http.MethodPost, path, nil), "carol"))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 marking another user's notification, got %d", rec.Code)
+	}
+	if n := unread("alice", http.MethodPost, path); n != 1 {
+		t.Errorf("Expected 1 unread after marking one read, got %d", n)
+	}
+	serve("alice", http.MethodPost, "/api/notifications/read")
+	if n := unread("alice", http.MethodGet, "/api/notifications/unread"); n != 0 || !list("alice").Items[0].Read {
+		t.Errorf("Expected everything read, got %d unread", n)
+	}
+}
+
+func TestReactionNotificationActor(t *testing.T) {
+	a := newTestApp(t)
+	ctx := context.Background()
+	alice := insertTestUser(t, a, "alice")
+	bobby := insertTestUser(t, a, "bobby")
+	carol := insertTestUser(t, a, "carol")
+	postID := insertTestChannelPost(t, a, alice, insertTestChannel(t, a, alice.ID, "general"), "thread")
+	handler := mw.WithUser(http.HandlerFunc((&ReactionHandler{App: a}).StoreReaction), a)
+
+	like := func(authorID models.UUIDField) int {
+		body := fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":%d}`, authorID, postID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, withUsername(httptest.NewRequest(http.MethodPost, "/store-reaction", strings.NewReader(body)), "carol"))
+		return rec.Code
+	}
+
+	// carol cannot have alice told that bobby liked her post
+	if code := like(bobby.ID); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for a spoofed authorId, got %d", code)
+	}
+	got, err := a.Notifications.ForUser(ctx, alice.ID, 10, 0)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Expected no notification from a spoofed like, got %+v, %v", got, err)
+	}
+
+	if code := like(carol.ID); code != http.StatusOK {
+		t.Fatalf("Expected 200 for carol's own like, got %d", code)
+	}
+	got, err = a.Notifications.ForUser(ctx, alice.ID, 10, 0)
+	if err != nil || len(got) != 1 || got[0].Actor != "carol" {
+		t.Errorf("Expected one like from carol, got %+v, %v", got, err)
+	}
+}
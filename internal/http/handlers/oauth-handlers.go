@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gary-norman/forum/internal/auth/oauth"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/service"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// oauthStateCookie carries the state sent to the provider, so the callback can check
+// it is finishing a sign-in this browser started
+const oauthStateCookie = "oauth_state"
+
+// oauthStateMaxAge is how long a user has to sign in at the provider, in seconds
+const oauthStateMaxAge = 10 * 60
+
+// oauthProvider returns the configured provider named in the path, writing a 404
+// when there is none
+func (h *AuthHandler) oauthProvider(w http.ResponseWriter, r *http.Request) (*oauth.Provider, bool) {
+	provider, ok := h.App.OAuthProviders[r.PathValue("provider")]
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrNotFound, "unknown login provider"))
+	}
+	return provider, ok
+}
+
+// oauthStateCookiePath scopes the state cookie to one provider's routes
+func oauthStateCookiePath(provider *oauth.Provider) string {
+	return "/auth/" + provider.Name + "/"
+}
+
+// OAuthLogin sends the user to the provider to sign in, remembering the state it
+// will come back with
+func (h *AuthHandler) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.oauthProvider(w, r)
+	if !ok {
+		return
+	}
+	state := models.GenerateToken(32)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     oauthStateCookiePath(provider),
+		MaxAge:   oauthStateMaxAge,
+		HttpOnly: true,
+		// Lax, not Strict, so the cookie comes with the provider's redirect back
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(state), http.StatusFound)
+}
+
+// OAuthCallback finishes a sign-in at the provider by exchanging the code for the
+// account's profile. A logged-in user has the account linked to them. Anyone else is
+// logged in as the user the account is linked to, or a new user created for it, with
+// a session started as Login does. Either way it then redirects home.
+func (h *AuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	provider, ok := h.oauthProvider(w, r)
+	if !ok {
+		return
+	}
+
+	// the state is single use whatever happens next
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     oauthStateCookiePath(provider),
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	query := r.URL.Query()
+	stored, _ := r.Cookie(oauthStateCookie)
+	if stored == nil || !tokensMatch(query.Get("state"), stored.Value) {
+		models.LogWarnWithContext(ctx, "Rejected %s callback with a mismatched state", provider.Name)
+		writeError(w, withMessage(sqlite.ErrForbidden, "login link expired or invalid, please try again"))
+		return
+	}
+	if reason := query.Get("error"); reason != "" {
+		models.LogInfoWithContext(ctx, "%s sign-in not completed: %s", provider.Name, reason)
+		writeError(w, withMessage(sqlite.ErrUnauthorized, fmt.Sprintf("%s sign-in was not completed", provider.Name)))
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		writeError(w, &RequestError{Field: "code", Msg: "missing authorization code"})
+		return
+	}
+
+	token, err := provider.Exchange(ctx, code)
+	if err != nil {
+		models.LogWarnWithContext(ctx, "Failed to exchange %s code: %v", provider.Name, err)
+		writeError(w, withMessage(sqlite.ErrUnauthorized, fmt.Sprintf("%s sign-in failed", provider.Name)))
+		return
+	}
+	profile, err := provider.Profile(ctx, token)
+	if err != nil {
+		models.LogWarnWithContext(ctx, "Failed to fetch %s profile: %v", provider.Name, err)
+		writeError(w, withMessage(sqlite.ErrUnauthorized, fmt.Sprintf("%s sign-in failed", provider.Name)))
+		return
+	}
+	if current, ok := mw.GetUserFromContext(ctx); ok {
+		if err := h.App.OAuth.Link(ctx, current, provider.Name, profile); err != nil {
+			models.LogWarnWithContext(ctx, "Failed to link %s account to %s: %v", provider.Name, current.Username, err)
+			writeError(w, withMessage(err, fmt.Sprintf("could not link your %s account", provider.Name)))
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	user, err := h.App.OAuth.Login(ctx, provider.Name, profile)
+	switch {
+	case errors.Is(err, service.ErrUnverifiedEmail):
+		writeError(w, withMessage(sqlite.ErrForbidden,
+			fmt.Sprintf("your %s account needs a verified email address to log in", provider.Name)))
+		return
+	case errors.Is(err, service.ErrEmailRegistered):
+		writeError(w, withMessage(sqlite.ErrConflict, fmt.Sprintf("an account already uses your %[1]s email address; "+
+			"log in with your password, then sign in with %[1]s to link it", provider.Name)))
+		return
+	case err != nil:
+		models.LogErrorWithContext(ctx, "Failed to log in with "+provider.Name, err)
+		writeError(w, withMessage(err, "login failed"))
+		return
+	}
+
+	createCookiErr, expires := h.App.Cookies.CreateCookies(ctx, w, user, false)
+	if createCookiErr != nil {
+		models.LogErrorWithContext(ctx, "Failed to create cookies during OAuth login", createCookiErr)
+		writeError(w, withMessage(createCookiErr, "failed to create cookies"))
+		return
+	}
+	if err := h.App.Cookies.BindSession(ctx, user, mw.ClientFingerprint(r)); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to bind session during OAuth login", err)
+		writeError(w, withMessage(err, "failed to create session"))
+		return
+	}
+	models.LogInfoWithContext(ctx, ErrorMsgs.LoginSuccess, user.Username, expires)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/auth/oauth"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+)
+
+// withFakeGoogle configures a Google provider whose token and user info endpoints
+// are a test server that signs in the account for the given email with any code
+func withFakeGoogle(t *testing.T, a *app.App, subject, email string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "tok"})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"sub": subject, "email": email, "email_verified": true})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	p := oauth.Google("client", "secret", "http://localhost/auth/google/callback")
+	p.TokenURL, p.UserInfoURL, p.Client = srv.URL+"/token", srv.URL+"/userinfo", srv.Client()
+	a.OAuthProviders[oauth.ProviderGoogle] = p
+}
+
+func TestOAuthLogin(t *testing.T) {
+	a := newTestApp(t)
+	withFakeGoogle(t, a, "g-1", "oauthuser@example.com")
+	h := &AuthHandler{App: a}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/login", nil)
+	req.SetPathValue("provider", "google")
+	rec := httptest.NewRecorder()
+	h.OAuthLogin(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect to the provider, got %d", rec.Code)
+	}
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || cookies[0].Value != location.Query().Get("state") {
+		t.Fatalf("Expected a state cookie matching the redirect's state, got %v and %s", cookies, location)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/gitlab/login", nil)
+	req.SetPathValue("provider", "gitlab")
+	rec = httptest.NewRecorder()
+	h.OAuthLogin(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unconfigured provider, got %d", rec.Code)
+	}
+}
+
+func TestOAuthCallback(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "oauthuser")
+	withFakeGoogle(t, a, "g-1", "oauthuser@example.com")
+	h := &AuthHandler{App: a}
+
+	handler := mw.WithUser(http.HandlerFunc(h.OAuthCallback), a)
+	callback := func(state, cookie, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?code=c&state="+state, nil)
+		req.SetPathValue("provider", "google")
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: cookie})
+		}
+		if username != "" {
+			req = withUsername(req, username)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"state mismatch":  callback("forged", "real", ""),
+		"no state cookie": callback("real", "", ""),
+	} {
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", name, rec.Code)
+		}
+	}
+	if _, err := a.Users.GetUserByIdentity(t.Context(), oauth.ProviderGoogle, "g-1"); err == nil {
+		t.Fatal("Expected no identity to be linked by a refused callback")
+	}
+
+	// an account registered to the email locally proves nothing about who owns it
+	rec := callback("real", "real", "")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 for an email registered locally, got %d: %s", rec.Code, rec.Body)
+	}
+	if sessionCookie(rec) != "" {
+		t.Error("Expected no session for an email registered locally")
+	}
+	if _, err := a.Users.GetUserByIdentity(t.Context(), oauth.ProviderGoogle, "g-1"); err == nil {
+		t.Fatal("Expected the Google account not to be linked by email")
+	}
+
+	rec = callback("real", "real", user.Username)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected the logged-in user to link the account, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = callback("real", "real", "")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Fatalf("Expected a redirect home, got %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+	session := sessionCookie(rec)
+	if session == "" {
+		t.Fatal("Expected the callback to set a session cookie")
+	}
+	var sessionUser string
+	if err := a.DB.QueryRow("SELECT u.Username FROM Sessions s JOIN Users u ON u.ID = s.UserID WHERE s.Token = ?",
+		session).Scan(&sessionUser); err != nil || sessionUser != user.Username {
+		t.Errorf("Expected a session for %s, got %q, %v", user.Username, sessionUser, err)
+	}
+}
+
+func sessionCookie(rec *httptest.ResponseRecorder) string {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session_token" {
+			return c.Value
+		}
+	}
+	return ""
+}
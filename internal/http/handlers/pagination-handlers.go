@@ -0,0 +1,26 @@
+package handlers
+
+// Page is the envelope list endpoints return: one page of items plus what a
+// client needs to ask for the next one
+type Page[T any] struct {
+	Items   []T  `json:"items"`
+	Total   int  `json:"total"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"`
+}
+
+// NewPage wraps items fetched with limit and offset out of total matching rows.
+// A nil items becomes an empty list so the JSON is [] rather than null.
+func NewPage[T any](items []T, total, limit, offset int) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{
+		Items:   items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: offset+len(items) < total,
+	}
+}
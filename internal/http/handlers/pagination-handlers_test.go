@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewPage_HasMore(t *testing.T) {
+	tests := []struct {
+		name                 string
+		items, total, offset int
+		want                 bool
+	}{
+		{"empty result", 0, 0, 0, false},
+		{"single partial page", 3, 3, 0, false},
+		{"full page with more behind it", 10, 11, 0, true},
+		{"full page ending exactly at total", 10, 10, 0, false},
+		{"last page", 1, 11, 10, false},
+		{"middle page", 10, 30, 10, true},
+		{"offset past the end", 0, 5, 20, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := NewPage(make([]int, tt.items), tt.total, 10, tt.offset)
+			if page.HasMore != tt.want {
+				t.Errorf("Expected HasMore %v, got %v", tt.want, page.HasMore)
+			}
+		})
+	}
+}
+
+func TestNewPage_NilItemsEncodeAsEmptyList(t *testing.T) {
+	data, err := json.Marshal(NewPage[string](nil, 0, 20, 0))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `{"items":[],"total":0,"limit":20,"offset":0,"has_more":false}`
+	if string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,6 +12,7 @@ import (
 	"github.com/gary-norman/forum/internal/app"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
 	"github.com/gary-norman/forum/internal/view"
 )
 
@@ -75,17 +78,11 @@ func (p *PostHandler) GetThisPost(w http.ResponseWriter, r *http.Request) {
 	var ctx = r.Context()
 	w.Header().Set("Content-Type", "application/json")
 	var thisPost *models.Post
-	var posts []*models.Post
 	isMember := false
 	var isMemberErr error
 	isOwner := false
 
-	userLoggedIn := true
-	currentUser, ok := mw.GetUserFromContext(ctx)
-	if !ok {
-		models.LogInfoWithContext(ctx, "User not found in context for GetThisPost")
-		userLoggedIn = false
-	}
+	currentUser, userLoggedIn := mw.CurrentUser(ctx)
 
 	// Parse post ID from URL
 	postID, err := models.GetIntFromPathValue(r.PathValue("postId"))
@@ -96,29 +93,35 @@ func (p *PostHandler) GetThisPost(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch the post
 	post, err := p.App.Posts.GetPostByID(ctx, postID)
-	if err != nil {
-		view.RenderErrorPage(w, models.NotFoundLocation("post"), 400, models.NotFoundError(postID, "GetThisPost", err))
+	if errors.Is(err, sqlite.ErrPostNotFound) {
+		view.RenderErrorPage(w, models.NotFoundLocation("post"), 404, models.NotFoundError(postID, "GetThisPost", err))
 		return
 	}
-	posts = append(posts, &post)
-	foundPosts, err := p.Comment.GetPostsComments(posts)
 	if err != nil {
+		view.RenderErrorPage(w, models.NotFoundLocation("post"), 500, models.QueryError("posts", "GetThisPost", err))
+		return
+	}
+	thisPost = &post
+	if _, err := p.App.PostService.Enrich(ctx, []*models.Post{thisPost}); err != nil {
 		view.RenderErrorPage(w, models.NotFoundLocation("post"), 500, models.FetchError("post comments", "GetThisPost", err))
+		return
 	}
-	foundPosts = p.Reaction.GetPostsLikesAndDislikes(foundPosts)
-	thisPost = foundPosts[0]
-
-	thisPost.ChannelID, thisPost.ChannelName, err = p.Channel.GetChannelInfoFromPostID(thisPost.ID)
-	if err != nil {
-		view.RenderErrorPage(w, models.NotFoundLocation("post"), 500, models.FetchError("channel info", "GetThisPost", err))
+	// a hidden flagged post is answered as if it did not exist
+	if len(hideFlaggedPosts(ctx, p.App, currentUser, []*models.Post{thisPost})) == 0 {
+		view.RenderErrorPage(w, models.NotFoundLocation("post"), 404,
+			models.NotFoundError(postID, "GetThisPost", sqlite.ErrPostNotFound))
+		return
 	}
 
-	models.UpdateTimeSince(thisPost)
-
 	// Fetch the channel
 	channel, err := p.App.Channels.GetChannelByID(ctx, thisPost.ChannelID)
+	if errors.Is(err, sqlite.ErrChannelNotFound) {
+		view.RenderErrorPage(w, models.NotFoundLocation("post"), 404, models.NotFoundError(thisPost.ChannelID, "GetThisPost", err))
+		return
+	}
 	if err != nil {
 		view.RenderErrorPage(w, models.NotFoundLocation("post"), 500, models.QueryError("channels", "GetThisPost", err))
+		return
 	}
 
 	// Fetch the author
@@ -142,7 +145,7 @@ func (p *PostHandler) GetThisPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := models.PostPage{
-		UserID:      models.NewUUIDField(), // Default value of 0 for logged out users
+		UserID:      mw.CurrentUserID(ctx),
 		CurrentUser: currentUser,
 		Instance:    "post-page",
 		ThisPost:    thisPost,
@@ -156,6 +159,175 @@ func (p *PostHandler) GetThisPost(w http.ResponseWriter, r *http.Request) {
 	view.RenderPageData(w, data)
 }
 
+// postsBatchMax caps how many posts one GetPostsBatch request may ask for
+const postsBatchMax = 100
+
+// GetPostsBatch returns the posts named in the JSON body {"ids": [...]}, in the order
+// asked for. IDs with no post are left out, as are private posts for visitors who
+// are not logged in.
+func (p *PostHandler) GetPostsBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var input struct {
+		IDs []int64 `json:"ids"`
+	}
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(input.IDs) > postsBatchMax {
+		var invalid ValidationError
+		invalid.Add("ids", fmt.Sprintf("at most %d posts can be fetched at once", postsBatchMax))
+		writeError(w, invalid.Err())
+		return
+	}
+
+	byID, err := p.App.Posts.GetPostsByIDs(ctx, input.IDs)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to fetch posts by ID", err)
+		writeError(w, err)
+		return
+	}
+	if _, loggedIn := mw.GetUserFromContext(ctx); !loggedIn {
+		private, err := p.App.Posts.PrivatePostIDs(ctx)
+		if err != nil {
+			models.LogErrorWithContext(ctx, "Failed to check post privacy", err)
+			writeError(w, err)
+			return
+		}
+		for id := range byID {
+			if private[id] {
+				delete(byID, id)
+			}
+		}
+	}
+
+	posts := sqlite.PostsInOrder(input.IDs, byID)
+	if _, err := p.App.PostService.Enrich(ctx, posts); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to enrich posts", err)
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"posts": posts}); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode posts", err)
+	}
+}
+
+// postsPageSize and postsMaxPageSize bound the page size of the paged post lists
+const (
+	postsPageSize    = 20
+	postsMaxPageSize = 100
+)
+
+// GetFeedPage returns a Page of every post, newest first, for ?limit= and ?offset=.
+// Private posts are only left out of a page after it is fetched, which would break
+// offsets, so the feed is for logged-in users only.
+func (p *PostHandler) GetFeedPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	currentUser, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to page through the feed"))
+		return
+	}
+
+	limit, offset := parsePagination(r, postsPageSize, postsMaxPageSize)
+	posts, err := p.App.PostService.Feed(ctx, limit, offset)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to fetch feed posts", err)
+		writeError(w, err)
+		return
+	}
+	total, err := p.App.Posts.Count(ctx)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count posts", err)
+		writeError(w, err)
+		return
+	}
+	p.writePostsPage(w, r, currentUser, posts, total, limit, offset)
+}
+
+// GetUserPostsPage returns a Page of the posts written by the user in the path,
+// newest first. Like GetFeedPage it is for logged-in users only.
+func (p *PostHandler) GetUserPostsPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	currentUser, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to page through a user's posts"))
+		return
+	}
+	userID, err := models.UUIDFieldFromString(r.PathValue("userId"))
+	if err != nil {
+		writeError(w, &RequestError{Field: "userId", Msg: "user ID must be a UUID", err: err})
+		return
+	}
+
+	limit, offset := parsePagination(r, postsPageSize, postsMaxPageSize)
+	posts, err := p.App.PostService.ForUser(ctx, userID, limit, offset)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to fetch user posts", err, "UserID:", userID)
+		writeError(w, err)
+		return
+	}
+	total, err := p.App.Posts.CountByUserID(ctx, userID)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count user posts", err, "UserID:", userID)
+		writeError(w, err)
+		return
+	}
+	p.writePostsPage(w, r, currentUser, posts, total, limit, offset)
+}
+
+// GetChannelPostsPage returns a Page of the posts in the channel in the path, newest
+// first. A private channel is not found for visitors who are not logged in.
+func (p *PostHandler) GetChannelPostsPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	channelID, err := models.GetIntFromPathValue(r.PathValue("channelId"))
+	if err != nil {
+		writeError(w, &RequestError{Field: "channelId", Msg: "channel ID must be a number", err: err})
+		return
+	}
+	channel, err := p.App.Channels.GetChannelByID(ctx, channelID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	currentUser, loggedIn := mw.CurrentUser(ctx)
+	if channel.Privacy && !loggedIn {
+		writeError(w, fmt.Errorf("channel %d is private: %w", channelID, sqlite.ErrChannelNotFound))
+		return
+	}
+
+	limit, offset := parsePagination(r, postsPageSize, postsMaxPageSize)
+	posts, err := p.App.PostService.ForChannel(ctx, channelID, limit, offset)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to fetch channel posts", err, "ChannelID:", channelID)
+		writeError(w, err)
+		return
+	}
+	total, err := p.App.Posts.CountByChannel(ctx, channelID)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count channel posts", err, "ChannelID:", channelID)
+		writeError(w, err)
+		return
+	}
+	// A post shared to several channels is shown under this one here
+	for _, post := range posts {
+		post.ChannelID, post.ChannelName = channel.ID, channel.Name
+	}
+	p.writePostsPage(w, r, currentUser, posts, total, limit, offset)
+}
+
+// writePostsPage applies the HideFlaggedContent policy to posts and writes them as a
+// Page. Hidden posts are dropped after paging, so such a page can be short.
+func (p *PostHandler) writePostsPage(w http.ResponseWriter, r *http.Request, user *models.User, posts []*models.Post, total, limit, offset int) {
+	posts = hideFlaggedPosts(r.Context(), p.App, user, posts)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(NewPage(posts, total, limit, offset)); err != nil {
+		models.LogErrorWithContext(r.Context(), "Failed to encode posts page", err)
+	}
+}
+
 // StorePost handles the creation of a new post.
 func (p *PostHandler) StorePost(w http.ResponseWriter, r *http.Request) {
 	var ctx = r.Context()
@@ -165,22 +337,50 @@ func (p *PostHandler) StorePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	if err := parseUpload(w, r, p.App.Config.MaxPostImageUploadSize); err != nil {
 		models.LogErrorWithContext(ctx, "Failed to parse multipart form in StorePost", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
+	var invalid ValidationError
 	channels := r.MultipartForm.Value["post_channel_list"]
 	if len(channels) < 1 {
-		models.LogErrorWithContext(ctx, "At least one channel required", fmt.Errorf("at least one channel required"))
-		return
+		invalid.Add("post_channel_list", "choose at least one channel")
+	}
+	channelIDs := make([]int64, 0, len(channels))
+	for _, c := range channels {
+		channelID, convErr := strconv.ParseInt(c, 10, 64)
+		if convErr != nil {
+			invalid.Add("post_channel_list", "invalid channel id")
+			continue
+		}
+		channelIDs = append(channelIDs, channelID)
 	}
 
 	title := strings.TrimSpace(r.FormValue("title"))
+	if title == "" {
+		invalid.Add("title", "title is required")
+	}
 	content := strings.TrimSpace(r.FormValue("content"))
-	if title == "" || content == "" {
-		models.LogErrorWithContext(ctx, "Title and content are required", fmt.Errorf("title and content are required"))
+	if content == "" {
+		invalid.Add("content", "content is required")
+	}
+	if err := invalid.Err(); err != nil {
+		models.LogWarnWithContext(ctx, "Rejected post: %v", err)
+		writeError(w, err)
+		return
+	}
+
+	filtered, err := checkContent(ctx, p.App, channelIDs, title, content)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to run content filter in StorePost", err)
+		writeError(w, err)
+		return
+	}
+	if !filtered.Allowed {
+		invalid.Add("content", filtered.Reason)
+		writeError(w, &invalid)
 		return
 	}
 
@@ -191,9 +391,10 @@ func (p *PostHandler) StorePost(w http.ResponseWriter, r *http.Request) {
 		AuthorID:      user.ID,
 		AuthorAvatar:  user.Avatar,
 		IsCommentable: r.FormValue("commentable") == "on",
+		IsFlagged:     filtered.Flag,
 	}
 
-	if img := GetFileName(r, "file-drop", "storePost", "post"); img != "" {
+	if img := GetFileName(r, "file-drop", "storePost", "post", p.App.Config.MaxPostImageUploadSize); img != "" {
 		createPostData.Images = img
 	}
 
@@ -215,18 +416,16 @@ func (p *PostHandler) StorePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for _, c := range channels {
-		channelID, convErr := strconv.ParseInt(c, 10, 64)
-		if convErr != nil {
-			http.Error(w, "invalid channel id", http.StatusBadRequest)
-			return
-		}
+	for _, channelID := range channelIDs {
 		if err := p.App.Channels.AddPostToChannel(ctx, channelID, postID); err != nil {
 			models.LogErrorWithContext(ctx, "Failed to add post to channel", err, "postID", postID, "channelID", channelID)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
+	if filtered.Flag {
+		autoFlag(ctx, p.App, filtered, user.ID, &postID, nil)
+	}
 	// ✅ Redirect only — no JSON write
 	postURL := fmt.Sprintf("/cdx/post/%d", postID)
 	http.Redirect(w, r, postURL, http.StatusSeeOther)
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestGetPostsBatch(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	public := insertTestChannel(t, a, author.ID, "public")
+	hidden := insertTestChannel(t, a, author.ID, "hidden")
+	if _, err := a.DB.Exec("UPDATE Channels SET Privacy = 1 WHERE ID = ?", hidden); err != nil {
+		t.Fatalf("Failed to make channel private: %v", err)
+	}
+	first := insertTestChannelPost(t, a, author, public, "first")
+	second := insertTestChannelPost(t, a, author, public, "second")
+	private := insertTestChannelPost(t, a, author, hidden, "private")
+
+	handler := mw.WithUser(http.HandlerFunc((&PostHandler{App: a}).GetPostsBatch), a)
+	post := func(username, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/batch", strings.NewReader(body))
+		if username != "" {
+			req = withUsername(req, username)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	fetch := func(t *testing.T, username string, ids ...int64) []models.Post {
+		t.Helper()
+		body, _ := json.Marshal(map[string][]int64{"ids": ids})
+		rec := post(username, string(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct{ Posts []models.Post }
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Response is not valid JSON: %v", err)
+		}
+		return resp.Posts
+	}
+	ids := func(posts []models.Post) []int64 {
+		out := make([]int64, len(posts))
+		for i, p := range posts {
+			out[i] = p.ID
+		}
+		return out
+	}
+
+	t.Run("existing and missing IDs", func(t *testing.T) {
+		posts := fetch(t, "author", second, 9999, private, first)
+		if want := []int64{second, private, first}; !slices.Equal(ids(posts), want) {
+			t.Errorf("Expected posts %v, got %v", want, ids(posts))
+		}
+		if posts[0].Title != "second" || posts[0].ChannelName != "public" {
+			t.Errorf("Expected an enriched post, got %+v", posts[0])
+		}
+	})
+
+	t.Run("anonymous visitors do not see private posts", func(t *testing.T) {
+		if got := ids(fetch(t, "", private, first)); !slices.Equal(got, []int64{first}) {
+			t.Errorf("Expected only the public post, got %v", got)
+		}
+	})
+
+	t.Run("no IDs", func(t *testing.T) {
+		if posts := fetch(t, ""); len(posts) != 0 {
+			t.Errorf("Expected no posts, got %v", ids(posts))
+		}
+	})
+
+	t.Run("too many IDs", func(t *testing.T) {
+		many := make([]string, postsBatchMax+1)
+		for i := range many {
+			many[i] = fmt.Sprint(i + 1)
+		}
+		rec := post("author", `{"ids":[`+strings.Join(many, ",")+`]}`)
+		expectFields(t, decodeValidation(t, rec), "ids")
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		if rec := post("author", `{"ids":["one"]}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestPostsPages(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	public := insertTestChannel(t, a, author.ID, "public")
+	hidden := insertTestChannel(t, a, author.ID, "hidden")
+	if _, err := a.DB.Exec("UPDATE Channels SET Privacy = 1 WHERE ID = ?", hidden); err != nil {
+		t.Fatalf("Failed to make channel private: %v", err)
+	}
+	var publicPosts []int64
+	for i := range 3 {
+		publicPosts = append(publicPosts, insertTestChannelPost(t, a, author, public, fmt.Sprintf("post %d", i)))
+	}
+	insertTestChannelPost(t, a, author, hidden, "private")
+
+	h := &PostHandler{App: a}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/posts", h.GetFeedPage)
+	mux.HandleFunc("GET /api/users/{userId}/posts", h.GetUserPostsPage)
+	mux.HandleFunc("GET /api/channels/{channelId}/posts", h.GetChannelPostsPage)
+	handler := mw.WithUser(mux, a)
+	get := func(username, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if username != "" {
+			req = withUsername(req, username)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name      string
+		username  string
+		target    string
+		wantItems int
+		wantTotal int
+		wantMore  bool
+	}{
+		{"feed", "author", "/api/posts?limit=3", 3, 4, true},
+		{"user posts", "author", fmt.Sprintf("/api/users/%s/posts?limit=3&offset=3", author.ID), 1, 4, false},
+		{"public channel, anonymous", "", fmt.Sprintf("/api/channels/%d/posts?limit=2", public), 2, 3, true},
+		{"private channel, logged in", "author", fmt.Sprintf("/api/channels/%d/posts", hidden), 1, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.username, tt.target)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var page Page[models.Post]
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("Response is not valid JSON: %v", err)
+			}
+			if len(page.Items) != tt.wantItems || page.Total != tt.wantTotal || page.HasMore != tt.wantMore {
+				t.Errorf("Expected %d of %d items (more: %v), got %d of %d (more: %v)",
+					tt.wantItems, tt.wantTotal, tt.wantMore, len(page.Items), page.Total, page.HasMore)
+			}
+		})
+	}
+
+	t.Run("channel pages are newest first", func(t *testing.T) {
+		var page Page[models.Post]
+		if err := json.NewDecoder(get("", fmt.Sprintf("/api/channels/%d/posts?limit=2&offset=1", public)).Body).Decode(&page); err != nil {
+			t.Fatalf("Response is not valid JSON: %v", err)
+		}
+		got := []int64{}
+		for _, p := range page.Items {
+			got = append(got, p.ID)
+		}
+		if want := []int64{publicPosts[1], publicPosts[0]}; !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	refused := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"anonymous feed", "/api/posts", http.StatusUnauthorized},
+		{"anonymous user posts", fmt.Sprintf("/api/users/%s/posts", author.ID), http.StatusUnauthorized},
+		{"anonymous private channel", fmt.Sprintf("/api/channels/%d/posts", hidden), http.StatusNotFound},
+		{"missing channel", "/api/channels/9999/posts", http.StatusNotFound},
+	}
+	for _, tt := range refused {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := get("", tt.target); rec.Code != tt.status {
+				t.Errorf("Expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
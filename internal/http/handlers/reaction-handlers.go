@@ -7,7 +7,10 @@ import (
 	"net/http"
 
 	"github.com/gary-norman/forum/internal/app"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+	"github.com/gary-norman/forum/internal/view"
 )
 
 type ReactionHandler struct {
@@ -18,8 +21,7 @@ type ReactionHandler struct {
 func (h *ReactionHandler) GetPostsLikesAndDislikes(posts []*models.Post) []*models.Post {
 	ctx := context.Background()
 	for p, post := range posts {
-		likes, dislikes, err := h.App.Reactions.CountReactions(ctx, post.ID, 0) // Pass 0 for CommentID if it's a post
-		// fmt.Printf("PostID: %v, Likes: %v, Dislikes: %v\n", posts[i].ID, likes, dislikes)
+		likes, dislikes, err := h.App.Reactions.CountReactions(ctx, models.PostTarget(post.ID))
 		if err != nil {
 			models.LogError("Failed to count reactions for post", err, "PostID:", post.ID)
 			likes, dislikes = 0, 0 // Default values if there is an error
@@ -29,131 +31,163 @@ func (h *ReactionHandler) GetPostsLikesAndDislikes(posts []*models.Post) []*mode
 	return posts
 }
 
-func (h *ReactionHandler) getLastReactionTimeForPosts(posts []*models.Post) ([]*models.Post, error) {
-	ctx := context.Background()
-	for i := range posts {
-		p := posts[i]
+// likedPostsPageSize and likedPostsMaxPageSize bound the liked posts page size
+const (
+	likedPostsPageSize    = 20
+	likedPostsMaxPageSize = 100
+)
 
-		if p.Likes < 0 || p.Dislikes < 0 {
-			continue
-		}
+// GetLikedPosts returns a Page of the current user's liked posts, newest like first
+func (h *ReactionHandler) GetLikedPosts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	currentUser, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to see liked posts"))
+		return
+	}
+
+	limit, offset := parsePagination(r, likedPostsPageSize, likedPostsMaxPageSize)
+	liked, err := h.App.Reactions.GetLikedPostsByUser(ctx, currentUser.ID, limit, offset)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to fetch liked posts", err)
+		writeError(w, err)
+		return
+	}
+
+	total, err := h.App.Reactions.CountLikedPostsByUser(ctx, currentUser.ID)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count liked posts", err)
+		writeError(w, err)
+		return
+	}
+
+	posts := make([]*models.Post, len(liked))
+	for i := range liked {
+		posts[i] = &liked[i]
+	}
+	posts = h.GetPostsLikesAndDislikes(posts)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(NewPage(posts, total, limit, offset)); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode liked posts", err)
+	}
+}
+
+// GetPostReactions returns the like and dislike counts for a post and, for logged-in
+// users, who reacted and how. Anonymous visitors get only the counts, and a post
+// that is only in private channels is not found for them.
+func (h *ReactionHandler) GetPostReactions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID, err := models.GetIntFromPathValue(r.PathValue("postId"))
+	if err != nil {
+		writeError(w, &RequestError{Field: "postId", Msg: "post ID must be a number", err: err})
+		return
+	}
+	if _, err := h.App.Posts.GetPostByID(ctx, postID); err != nil {
+		writeError(w, err)
+		return
+	}
 
-		lastReactionTime, err := h.App.Reactions.GetLastReaction(ctx, p.ID, 0)
+	_, loggedIn := mw.GetUserFromContext(ctx)
+	if !loggedIn {
+		private, err := h.App.Posts.PrivatePostIDs(ctx)
 		if err != nil {
-			models.LogError("Failed to get last reaction time for post", err, "PostID:", p.ID)
+			models.LogErrorWithContext(ctx, "Failed to check post privacy", err, "PostID:", postID)
+			writeError(w, err)
+			return
 		}
-		if lastReactionTime.Created.IsZero() {
-			// fmt.Printf("No reaction time found for PostID: %v\n", p.ID)
-
-			p.LastReaction = nil
-		} else {
-			p.LastReaction = &lastReactionTime.Created
-			// fmt.Printf("\nPostID: %v\nLastReaction: %v\n", p.ID, p.LastReaction)
+		if private[postID] {
+			writeError(w, fmt.Errorf("post %d is private: %w", postID, sqlite.ErrPostNotFound))
+			return
 		}
 	}
-	return posts, nil
-}
 
-// GetCommentsLikesAndDislikes updates the reactions of each comment in the given slice
-func (h *ReactionHandler) GetCommentsLikesAndDislikes(comments []models.Comment) []models.Comment {
-	ctx := context.Background()
-	for c, comment := range comments {
-		likes, dislikes, err := h.App.Reactions.CountReactions(ctx, 0, comment.ID) // Pass 0 for PostID if it's a comment
-		// fmt.Printf("PostID: %v, Likes: %v, Dislikes: %v\n", posts[i].ID, likes, dislikes)
+	likes, dislikes, err := h.App.Reactions.CountReactions(ctx, models.PostTarget(postID))
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count reactions for post", err, "PostID:", postID)
+		writeError(w, err)
+		return
+	}
+	response := map[string]any{
+		"likes":    likes,
+		"dislikes": dislikes,
+	}
+	if loggedIn {
+		details, err := h.App.Reactions.GetReactionsForPost(ctx, postID)
 		if err != nil {
-			models.LogError("Failed to count reactions for comment", err, "CommentID:", comment.ID)
-			likes, dislikes = 0, 0 // Default values if there is an error
+			models.LogErrorWithContext(ctx, "Failed to fetch reactions for post", err, "PostID:", postID)
+			writeError(w, err)
+			return
+		}
+		for i := range details {
+			details[i].AvatarURL = view.SignedImageURL(h.App, "user-images", details[i].Avatar)
 		}
-		models.React(&comments[c], likes, dislikes)
+		response["reactions"] = details
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode post reactions", err)
 	}
-	return comments
 }
 
+// StoreReaction toggles the current user's reaction on a post or comment. The
+// payload's authorId must be the current user's. When the payload sets withCounts,
+// the response also carries the target's updated likes and dislikes, counted in the
+// same transaction as the toggle.
 func (h *ReactionHandler) StoreReaction(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	models.LogInfoWithContext(r.Context(), "Processing reaction storage request")
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to react"))
+		return
+	}
 
 	// Variable to hold the decoded data
 	var input models.ReactionInput
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if err := decodeJSON(r, &input); err != nil {
+		models.LogWarnWithContext(r.Context(), "Rejected reaction payload: %v", err)
+		writeError(w, err)
 		return
 	}
-
-	// Convert AuthorID string to UUIDField
-	authorID, err := models.UUIDFieldFromString(input.AuthorID)
-	if err != nil {
-		http.Error(w, "Invalid authorId", http.StatusBadRequest)
+	if input.Author() != user.ID {
+		models.LogWarnWithContext(ctx, "User %s tried to react as %s", user.ID, input.Author())
+		writeError(w, withMessage(sqlite.ErrForbidden, "you can only react as yourself"))
 		return
 	}
+	target, _ := input.Target()
 
-	reactionData := models.Reaction{
-		Liked:            input.Liked,
-		Disliked:         input.Disliked,
-		AuthorID:         authorID,
-		ReactedPostID:    input.ReactedPostID,
-		ReactedCommentID: input.ReactedCommentID,
-	}
+	models.LogInfoWithContext(r.Context(), "Updating reaction for %s", fmt.Sprintf("%s: %d", target.Type, target.ID))
 
-	//// Validate that at least one of reactedPostID or reactedCommentID is non-zero
-	if (reactionData.ReactedPostID == nil || *reactionData.ReactedPostID == 0) && (reactionData.ReactedCommentID == nil || *reactionData.ReactedCommentID == 0) {
-		models.LogWarnWithContext(r.Context(), "Invalid reaction data: both reactedPostID and reactedCommentID are nil or zero")
-		w.WriteHeader(http.StatusBadRequest)
+	response := map[string]any{"message": "Reaction added to database"}
+	if input.WithCounts {
+		counts, err := h.App.Reactions.UpsertWithCounts(ctx, input.Liked, input.Disliked, user.ID, target)
+		if err != nil {
+			models.LogErrorWithContext(r.Context(), "Failed to upsert reaction", err, fmt.Sprintf("%s: %d", target.Type, target.ID))
+			writeError(w, err)
+			return
+		}
+		response["likes"] = counts.Likes
+		response["dislikes"] = counts.Dislikes
+	} else if err := h.App.Reactions.Upsert(ctx, input.Liked, input.Disliked, user.ID, target); err != nil {
+		models.LogErrorWithContext(r.Context(), "Failed to upsert reaction", err, fmt.Sprintf("%s: %d", target.Type, target.ID))
+		writeError(w, err)
 		return
 	}
-
-	var updatedID int64
-	var updatedStr string
-
-	if reactionData.ReactedPostID != nil {
-		reactionData.PostID = *reactionData.ReactedPostID
-		// log.Println("ReactedPostID:", *reactionData.ReactedPostID)
-		updatedID = *reactionData.ReactedPostID
-		updatedStr = "post"
-	} else {
-		reactionData.CommentID = *reactionData.ReactedCommentID
-		// log.Printf("ReactedCommentID: %d", *reactionData.ReactedPostID)
-		updatedID = *reactionData.ReactedCommentID
-		updatedStr = "comment"
-	}
-
-	models.LogInfoWithContext(r.Context(), "Updating reaction for %s", fmt.Sprintf("%s: %d", updatedStr, updatedID))
-
-	if err := h.App.Reactions.Upsert(ctx, reactionData.Liked, reactionData.Disliked, reactionData.AuthorID, reactionData.PostID, reactionData.CommentID); err != nil {
-		models.LogErrorWithContext(r.Context(), "Failed to upsert reaction", err, fmt.Sprintf("%s: %d", updatedStr, updatedID))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	if input.Liked {
+		notifyReaction(ctx, h.App, user.ID, target)
 	}
 
 	// Respond with a JSON response
 	w.Header().Set("Content-Type", "application/json")
 	// Send a response indicating success
 	// w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(map[string]string{"message": "Reaction added to database"})
+	err := json.NewEncoder(w).Encode(response)
 	if err != nil {
 		models.LogErrorWithContext(r.Context(), "Failed to encode JSON response", err)
 		http.Error(w, err.Error(), 500)
 		return
 	}
-
-	// updatedReactions, err := h.App.Reactions.All()
-	// if err != nil {
-	// 	log.Printf(ErrorMsgs.Read, updatedReactions, "storeReaction > h.App.reactions.All", err)
-	// 	return
-	// }
-	//
-	// for _, reaction := range updatedReactions {
-	// 	if reaction.ReactedPostID != nil {
-	// 		reaction.PostID = *reaction.ReactedPostID
-	// 		reaction.CommentID = 0
-	// 	} else {
-	// 		reaction.PostID = 0
-	// 		reaction.CommentID = *reaction.ReactedCommentID
-	// 	}
-	// 	reaction.ReactedCommentID = nil
-	// 	reaction.ReactedPostID = nil
-	// 	models.JsonPost(reaction)
-	// 	fmt.Println(ErrorMsgs.Divider)
-	// }
 }
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestGetLikedPosts(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	fan := insertTestUser(t, a, "fanuser")
+	ctx := context.Background()
+
+	res, err := a.DB.Exec(`INSERT INTO Posts (Title, Content, Images, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged)
+		VALUES ('liked', 'body', '', 1, 'author', ?, '', 0)`, author.ID)
+	if err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+	postID, _ := res.LastInsertId()
+	if err := a.Reactions.Upsert(ctx, true, false, fan.ID, models.PostTarget(postID)); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	h := &ReactionHandler{App: a}
+	handler := mw.WithUser(http.HandlerFunc(h.GetLikedPosts), a)
+
+	t.Run("anonymous is unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/liked-posts", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns the user's liked posts with counts", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, withUsername(httptest.NewRequest(http.MethodGet, "/user/liked-posts?limit=5", nil), "fanuser"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Posts []struct {
+				ID    int64
+				Title string
+				Likes int
+			} `json:"items"`
+			Limit   int  `json:"limit"`
+			Total   int  `json:"total"`
+			HasMore bool `json:"has_more"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Response is not valid JSON: %v", err)
+		}
+		if len(body.Posts) != 1 || body.Posts[0].ID != postID || body.Posts[0].Likes != 1 ||
+			body.Limit != 5 || body.Total != 1 || body.HasMore {
+			t.Errorf("Unexpected response: %+v", body)
+		}
+	})
+}
+
+func TestStoreReaction_LandsOnTarget(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	fan := insertTestUser(t, a, "fan")
+	channelID := insertTestChannel(t, a, author.ID, "general")
+	res, err := a.DB.Exec(`INSERT INTO Posts (Title, Content, Images, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged)
+		VALUES ('hello', 'content', '', 1, 'author', ?, '', 0)`, author.ID)
+	if err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+	postID, _ := res.LastInsertId()
+	res, err = a.DB.Exec(`INSERT INTO Comments (Content, CommentedPostID, IsCommentable, IsFlagged, IsReply,
+		Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+		VALUES ('reply', ?, 1, 0, 0, 'author', ?, '', 'general', ?)`, postID, author.ID, channelID)
+	if err != nil {
+		t.Fatalf("Failed to insert comment: %v", err)
+	}
+	commentID, _ := res.LastInsertId()
+
+	handler := mw.WithUser(http.HandlerFunc((&ReactionHandler{App: a}).StoreReaction), a)
+	store := func(body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, withUsername(httptest.NewRequest(http.MethodPost, "/store-reaction", strings.NewReader(body)), fan.Username))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	store(fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":%d}`, fan.ID, postID))
+	store(fmt.Sprintf(`{"disliked":true,"authorId":%q,"reactedCommentId":%d}`, fan.ID, commentID))
+
+	ctx := context.Background()
+	likes, dislikes, err := a.Reactions.CountReactions(ctx, models.PostTarget(postID))
+	if err != nil || likes != 1 || dislikes != 0 {
+		t.Errorf("Post: expected 1 like, got %d/%d, %v", likes, dislikes, err)
+	}
+	likes, dislikes, err = a.Reactions.CountReactions(ctx, models.CommentTarget(commentID))
+	if err != nil || likes != 0 || dislikes != 1 {
+		t.Errorf("Comment: expected 1 dislike, got %d/%d, %v", likes, dislikes, err)
+	}
+}
+
+func TestStoreReaction_WithCounts(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	fan := insertTestUser(t, a, "fan")
+	critic := insertTestUser(t, a, "critic")
+	channelID := insertTestChannel(t, a, author.ID, "general")
+	postID := insertTestChannelPost(t, a, author, channelID, "hello")
+	handler := mw.WithUser(http.HandlerFunc((&ReactionHandler{App: a}).StoreReaction), a)
+
+	store := func(user *models.User, body string) map[string]any {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, withUsername(httptest.NewRequest(http.MethodPost, "/store-reaction", strings.NewReader(body)), user.Username))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+		var resp map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Response is not valid JSON: %v", err)
+		}
+		return resp
+	}
+
+	if resp := store(fan, fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":%d}`, fan.ID, postID)); resp["likes"] != nil {
+		t.Errorf("Expected no counts without withCounts, got %v", resp)
+	}
+
+	tests := []struct {
+		name            string
+		user            *models.User
+		body            string
+		likes, dislikes float64
+	}{
+		{"dislike", critic, fmt.Sprintf(`{"disliked":true,"authorId":%q,"reactedPostId":%d,"withCounts":true}`, critic.ID, postID), 1, 1},
+		{"switch to like", critic, fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":%d,"withCounts":true}`, critic.ID, postID), 2, 0},
+		{"clear like", fan, fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":%d,"withCounts":true}`, fan.ID, postID), 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := store(tt.user, tt.body)
+			if resp["likes"] != tt.likes || resp["dislikes"] != tt.dislikes {
+				t.Errorf("Expected %v likes and %v dislikes, got %v", tt.likes, tt.dislikes, resp)
+			}
+		})
+	}
+}
+
+func TestStoreReaction_RejectsMalformedPayloads(t *testing.T) {
+	a := newTestApp(t)
+	handler := mw.WithUser(http.HandlerFunc((&ReactionHandler{App: a}).StoreReaction), a)
+	author := insertTestUser(t, a, "reactor").ID
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"both targets", fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":1,"reactedCommentId":2}`, author), "send only one of reactedPostId or reactedCommentId"},
+		{"neither target", fmt.Sprintf(`{"liked":true,"authorId":%q}`, author), "one of reactedPostId or reactedCommentId is required"},
+		{"zero target ID", fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":0}`, author), "target ID must be a positive integer"},
+		{"invalid author", `{"liked":true,"authorId":"not-a-uuid","reactedPostId":1}`, `authorId "not-a-uuid" is not a valid UUID`},
+		{"missing author", `{"liked":true,"reactedCommentId":1}`, `authorId "" is not a valid UUID`},
+		{"not JSON", `{"liked":`, "Invalid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, withUsername(httptest.NewRequest(http.MethodPost, "/store-reaction", strings.NewReader(tt.body)), "reactor"))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var body struct{ Message string }
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode error response: %v", err)
+			}
+			if !strings.Contains(body.Message, tt.message) {
+				t.Errorf("Expected message to mention %q, got %q", tt.message, body.Message)
+			}
+		})
+	}
+
+	var count int
+	if err := a.DB.QueryRow(`SELECT COUNT(*) FROM Reactions`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count reactions: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no reactions stored, got %d", count)
+	}
+}
+
+func TestStoreReaction_RequiresCurrentUser(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	fan := insertTestUser(t, a, "fan")
+	channelID := insertTestChannel(t, a, author.ID, "general")
+	postID := insertTestChannelPost(t, a, author, channelID, "hello")
+	handler := mw.WithUser(http.HandlerFunc((&ReactionHandler{App: a}).StoreReaction), a)
+	body := fmt.Sprintf(`{"liked":true,"authorId":%q,"reactedPostId":%d}`, fan.ID, postID)
+
+	tests := []struct {
+		name     string
+		username string
+		want     int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"as someone else", author.Username, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/store-reaction", strings.NewReader(body))
+			if tt.username != "" {
+				req = withUsername(req, tt.username)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	likes, _, err := a.Reactions.CountReactions(context.Background(), models.PostTarget(postID))
+	if err != nil || likes != 0 {
+		t.Errorf("Expected no reaction stored, got %d likes, %v", likes, err)
+	}
+}
+
+func TestGetPostReactions(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	fan := insertTestUser(t, a, "fanuser")
+	critic := insertTestUser(t, a, "critic")
+	public := insertTestChannel(t, a, author.ID, "public")
+	hidden := insertTestChannel(t, a, author.ID, "hidden")
+	if _, err := a.DB.Exec("UPDATE Channels SET Privacy = 1 WHERE ID = ?", hidden); err != nil {
+		t.Fatalf("Failed to make channel private: %v", err)
+	}
+	publicPost := insertTestChannelPost(t, a, author, public, "public post")
+	privatePost := insertTestChannelPost(t, a, author, hidden, "private post")
+	ctx := context.Background()
+	for _, postID := range []int64{publicPost, privatePost} {
+		if err := a.Reactions.Upsert(ctx, true, false, fan.ID, models.PostTarget(postID)); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if err := a.Reactions.Upsert(ctx, false, true, critic.ID, models.PostTarget(postID)); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/posts/{postId}/reactions", mw.WithUser(http.HandlerFunc((&ReactionHandler{App: a}).GetPostReactions), a))
+	get := func(username, post string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/posts/"+post+"/reactions", nil)
+		if username != "" {
+			req = withUsername(req, username)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) map[string]json.RawMessage {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Response is not valid JSON: %v", err)
+		}
+		if string(body["likes"]) != "1" || string(body["dislikes"]) != "1" {
+			t.Errorf("Expected 1 like and 1 dislike, got %s and %s", body["likes"], body["dislikes"])
+		}
+		return body
+	}
+
+	t.Run("logged-in users see who reacted", func(t *testing.T) {
+		body := decode(t, get(fan.Username, strconv.FormatInt(publicPost, 10)))
+		var reactions []map[string]any
+		if err := json.Unmarshal(body["reactions"], &reactions); err != nil {
+			t.Fatalf("Expected a reactions list, got %s", body["reactions"])
+		}
+		kinds := make(map[any]any)
+		for _, reaction := range reactions {
+			kinds[reaction["username"]] = reaction["kind"]
+			for _, field := range []string{"Email", "HashedPassword", "SessionToken", "Avatar"} {
+				if _, ok := reaction[field]; ok {
+					t.Errorf("Reaction exposes %s: %v", field, reaction)
+				}
+			}
+			if url, _ := reaction["avatarUrl"].(string); !strings.Contains(url, "sig=") {
+				t.Errorf("Expected a signed avatar URL, got %q", url)
+			}
+		}
+		if len(reactions) != 2 || kinds["fanuser"] != "like" || kinds["critic"] != "dislike" {
+			t.Errorf("Unexpected reactions: %v", reactions)
+		}
+	})
+
+	t.Run("anonymous visitors get counts only", func(t *testing.T) {
+		body := decode(t, get("", strconv.FormatInt(publicPost, 10)))
+		if _, ok := body["reactions"]; ok {
+			t.Errorf("Expected no reactions list for anonymous visitors, got %s", body["reactions"])
+		}
+	})
+
+	t.Run("private posts", func(t *testing.T) {
+		if rec := get("", strconv.FormatInt(privatePost, 10)); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an anonymous visitor, got %d", rec.Code)
+		}
+		decode(t, get(fan.Username, strconv.FormatInt(privatePost, 10)))
+	})
+
+	t.Run("bad post IDs", func(t *testing.T) {
+		if rec := get(fan.Username, strconv.FormatInt(privatePost+100, 10)); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing post, got %d", rec.Code)
+		}
+		if rec := get(fan.Username, "abc"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a non-numeric ID, got %d", rec.Code)
+		}
+	})
+}
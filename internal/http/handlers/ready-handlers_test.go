@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReady(t *testing.T) {
+	a := newTestApp(t)
+	ready := Ready(a)
+	check := func() int {
+		rec := httptest.NewRecorder()
+		ready.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec.Code
+	}
+
+	if code := check(); code != http.StatusOK {
+		t.Errorf("Expected 200 before draining, got %d", code)
+	}
+	a.StartDraining()
+	if code := check(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while draining, got %d", code)
+	}
+}
@@ -3,41 +3,85 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gary-norman/forum/internal/app"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/view"
 )
 
+// StaleSourcesHeader lists the search sources served from cache, comma-separated
+const StaleSourcesHeader = "X-Search-Stale"
+
+// snippetContextRunes is how much post content search snippets show either side of a match
+const snippetContextRunes = 60
+
 type SearchHandler struct {
 	App *app.App
 }
 
 func (s *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
-	// Use concurrent search with request context
-	result, err := ConcurrentSearch(r.Context(), s.App)
+	// Use concurrent search with request context. With ?q= the server returns ranked
+	// matches; without it, everything. Sources that fail are served from the search
+	// cache and listed under "stale", or left empty and listed under "unavailable",
+	// so the page can say results are old or incomplete.
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	result, err := ConcurrentSearch(r.Context(), s.App, query)
 	if err != nil {
-		models.LogWarnWithContext(r.Context(), "Search completed with errors: %v", err)
+		models.LogWarnWithContext(r.Context(), "Search failed: %v", err)
+	} else if len(result.Errors) > 0 {
+		models.LogWarnWithContext(r.Context(), "Search degraded, stale %v, unavailable %v: %v", result.Stale, result.Unavailable, result.Errors)
 	}
 
-	// Enrich posts with channel information
-	enrichedPosts := enrichPostsWithChannels(s.App, result.Posts, result.Channels)
-
-	currentUser, ok := mw.GetUserFromContext(r.Context())
-
-	if !ok {
-		models.LogInfoWithContext(r.Context(), "Anonymous user accessing search")
+	currentUser, userLoggedIn := mw.CurrentUser(r.Context())
+	if !userLoggedIn {
+		// anonymous visitors only see public channels and the posts in them
+		result.Channels = publicChannels(result.Channels)
+		result.Posts, err = publicPosts(r.Context(), s.App, result.Posts)
+		if err != nil {
+			models.LogErrorWithContext(r.Context(), "Failed to filter private posts from search", err)
+		}
 	} else {
 		models.LogInfoWithContext(r.Context(), "User %s accessing search", currentUser.ID)
 	}
 
+	for i := range result.Users {
+		result.Users[i].AvatarURL = view.SignedImageURL(s.App, "user-images", result.Users[i].Avatar)
+	}
+	for i := range result.Channels {
+		result.Channels[i].AvatarURL = view.SignedImageURL(s.App, "channel-images", result.Channels[i].Avatar)
+	}
+
+	// Enrich posts with channel information
+	enrichedPosts := enrichPostsWithChannels(s.App, result.Posts, result.Channels)
+
 	searchResults := map[string]any{
-		"users":    result.Users,
-		"channels": result.Channels,
-		"posts":    enrichedPosts,
+		"users":       result.Users,
+		"channels":    result.Channels,
+		"posts":       enrichedPosts,
+		"unavailable": result.Unavailable,
+		"stale":       result.Stale,
+	}
+	// With ?q=, each post whose content matches gets an HTML snippet, keyed by post ID
+	if query != "" {
+		snippets := make(map[int64]string)
+		for _, post := range enrichedPosts {
+			if snippet := view.Highlight(post.Content, query, snippetContextRunes); snippet != "" {
+				snippets[post.ID] = snippet
+			}
+		}
+		searchResults["snippets"] = snippets
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if len(result.Stale) > 0 {
+		// Age is how old the oldest cached source is, in whole seconds
+		w.Header().Set("Age", strconv.Itoa(int(time.Since(result.StaleSince).Seconds())))
+		w.Header().Set(StaleSourcesHeader, strings.Join(result.Stale, ","))
+	}
 
 	if err := json.NewEncoder(w).Encode(searchResults); err != nil {
 		models.LogErrorWithContext(r.Context(), "Failed to encode search results", err)
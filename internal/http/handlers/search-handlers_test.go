@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/app"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// insertTestChannelPost creates a post by author and links it to channelID
+func insertTestChannelPost(t *testing.T, a *app.App, author *models.User, channelID int64, title string) int64 {
+	t.Helper()
+	res, err := a.DB.Exec(`INSERT INTO Posts (Title, Content, Images, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged)
+		VALUES (?, 'test content', '', 1, ?, ?, '', 0)`, title, author.Username, author.ID)
+	if err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+	postID, _ := res.LastInsertId()
+	if _, err := a.DB.Exec(`INSERT INTO PostChannels (ChannelID, PostID) VALUES (?, ?)`, channelID, postID); err != nil {
+		t.Fatalf("Failed to link post to channel: %v", err)
+	}
+	return postID
+}
+
+func TestSearch_AnonymousSeesOnlyPublicData(t *testing.T) {
+	a := newTestApp(t)
+	owner := insertTestUser(t, a, "owner")
+	publicID := insertTestChannel(t, a, owner.ID, "public")
+	privateID := insertTestChannel(t, a, owner.ID, "private")
+	if _, err := a.DB.Exec(`UPDATE Channels SET Privacy = 1 WHERE ID = ?`, privateID); err != nil {
+		t.Fatalf("Failed to make channel private: %v", err)
+	}
+	insertTestChannelPost(t, a, owner, publicID, "public post")
+	insertTestChannelPost(t, a, owner, privateID, "private post")
+
+	handler := mw.WithUser(http.HandlerFunc((&SearchHandler{App: a}).Search), a)
+	search := func(username string) (channels, posts []string) {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		if username != "" {
+			req = withUsername(req, username)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Channels []struct{ Name string }
+			Posts    []struct{ Title string }
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode search results: %v", err)
+		}
+		for _, c := range body.Channels {
+			channels = append(channels, c.Name)
+		}
+		for _, p := range body.Posts {
+			posts = append(posts, p.Title)
+		}
+		return channels, posts
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		channels, posts := search("")
+		if len(channels) != 1 || channels[0] != "public" {
+			t.Errorf("Expected only the public channel, got %v", channels)
+		}
+		if len(posts) != 1 || posts[0] != "public post" {
+			t.Errorf("Expected only the public post, got %v", posts)
+		}
+	})
+
+	t.Run("logged in", func(t *testing.T) {
+		channels, posts := search(owner.Username)
+		if len(channels) != 2 || len(posts) != 2 {
+			t.Errorf("Expected both channels and posts, got %v and %v", channels, posts)
+		}
+	})
+}
+
+func TestSearch_Snippets(t *testing.T) {
+	a := newTestApp(t)
+	owner := insertTestUser(t, a, "owner")
+	channelID := insertTestChannel(t, a, owner.ID, "general")
+	matching := insertTestChannelPost(t, a, owner, channelID, "matching")
+	other := insertTestChannelPost(t, a, owner, channelID, "other")
+	if _, err := a.DB.Exec(`UPDATE Posts SET Content = 'Tuning <SQLite> for a busy forum' WHERE ID = ?`, matching); err != nil {
+		t.Fatalf("Failed to set post content: %v", err)
+	}
+
+	search := func(target string) map[string]json.RawMessage {
+		rec := httptest.NewRecorder()
+		(&SearchHandler{App: a}).Search(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode search results: %v", err)
+		}
+		return body
+	}
+
+	var snippets map[int64]string
+	if err := json.Unmarshal(search("/search?q=sqlite")["snippets"], &snippets); err != nil {
+		t.Fatalf("Failed to decode snippets: %v", err)
+	}
+	if want := "Tuning &lt;<mark>SQLite</mark>&gt; for a busy forum"; snippets[matching] != want {
+		t.Errorf("Expected snippet %q, got %q", want, snippets[matching])
+	}
+	if _, ok := snippets[other]; ok {
+		t.Errorf("Expected no snippet for a post that does not match, got %q", snippets[other])
+	}
+
+	if _, ok := search("/search")["snippets"]; ok {
+		t.Error("Expected no snippets without a query")
+	}
+}
+
+func TestSearch_UsersExposeNoCredentials(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "searchable")
+	if _, err := a.DB.Exec(`UPDATE Users SET SessionToken = 'live-session', CsrfToken = 'live-csrf' WHERE ID = ?`,
+		user.ID); err != nil {
+		t.Fatalf("Failed to set user tokens: %v", err)
+	}
+
+	for _, target := range []string{"/search", "/search?q=searchable"} {
+		rec := httptest.NewRecorder()
+		(&SearchHandler{App: a}).Search(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `"searchable"`) {
+			t.Fatalf("%s: expected the user in the results, got %s", target, body)
+		}
+		for _, secret := range []string{"HashedPassword", "SessionToken", "CSRFToken", "Email",
+			"searchable@example.com", "live-session", "live-csrf"} {
+			if strings.Contains(body, secret) {
+				t.Errorf("%s: expected no %s in the results, got %s", target, secret, body)
+			}
+		}
+	}
+}
@@ -2,21 +2,37 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"slices"
 	"time"
 
 	"github.com/gary-norman/forum/internal/app"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/patterns"
+	"github.com/gary-norman/forum/internal/sqlite"
 )
 
+// searchSources is the number of sources ConcurrentSearch queries
+const searchSources = 3
+
+// searchResultLimit caps how many matches each source returns for a query
+const searchResultLimit = 50
+
 // SearchResult holds aggregated search results from multiple sources
 type SearchResult struct {
 	Users    []*models.User
 	Posts    []*models.Post
 	Channels []*models.Channel
 	Errors   []error // Collect errors from goroutines
-	Duration time.Duration
+	// Unavailable names the sources ("users", "posts", "channels") whose results are
+	// missing because their query failed or the circuit breaker was open
+	Unavailable []string
+	// Stale names the sources whose query failed but whose last successful results
+	// were served from app.SearchCache instead; StaleSince is when the oldest was fetched
+	Stale      []string
+	StaleSince time.Time
+	Duration   time.Duration
 }
 
 // searchError wraps errors with source information
@@ -29,24 +45,67 @@ func (e searchError) Error() string {
 	return fmt.Sprintf("%s: %v", e.Source, e.Err)
 }
 
+func (e searchError) Unwrap() error { return e.Err }
+
+// staleSource records a failed source that was answered from the cache
+type staleSource struct {
+	searchError
+	CachedAt time.Time
+}
+
+// fetchSource runs fetch through the circuit breaker and caches what it returns.
+// When fetch fails but the cache still holds results, those are returned along
+// with fetch's error and the time they were cached; cachedAt is zero otherwise.
+// Results are copied into and out of the cache, so callers may modify them. A nil
+// cache is skipped, for results that must not be served for another query.
+func fetchSource[T any](app *app.App, cache *patterns.LastGood[[]*T], fetch func() ([]*T, error)) (items []*T, cachedAt time.Time, err error) {
+	err = app.DBCircuit.Execute(func() error {
+		var execErr error
+		items, execErr = fetch()
+		return execErr
+	})
+	if cache == nil {
+		return items, time.Time{}, err
+	}
+	if err == nil {
+		cache.Store(cloneAll(items))
+		return items, time.Time{}, nil
+	}
+	if cached, storedAt, ok := cache.Load(); ok {
+		return cloneAll(cached), storedAt, err
+	}
+	return nil, time.Time{}, err
+}
+
+// cloneAll returns a slice of pointers to shallow copies of items
+func cloneAll[T any](items []*T) []*T {
+	out := make([]*T, len(items))
+	for i, item := range items {
+		c := *item
+		out[i] = &c
+	}
+	return out
+}
+
 // ConcurrentSearch performs parallel search across users, posts, and channels
-// Uses fan-out pattern to execute queries concurrently, then fan-in results
-func ConcurrentSearch(ctx context.Context, app *app.App) (*SearchResult, error) {
+// Uses fan-out pattern to execute queries concurrently, then fan-in results.
+// With a query, each source returns up to searchResultLimit ranked matches from
+// app.Search; without one, every row is returned. A source that fails is answered
+// from app.SearchCache (listed in Stale) while the cache is fresh, and otherwise
+// left empty (listed in Unavailable); an error is only returned when every source
+// is unavailable. Query results are never cached.
+func ConcurrentSearch(ctx context.Context, app *app.App, query string) (*SearchResult, error) {
 	start := time.Now()
 
 	// Create result channels for each search type
 	usersCh := make(chan []*models.User, 1)
 	postsCh := make(chan []*models.Post, 1)
 	channelsCh := make(chan []*models.Channel, 1)
-	errorsCh := make(chan searchError, 3) // Buffer for 3 possible errors
-
-	// WaitGroup to track goroutine completion
-	var wg sync.WaitGroup
+	errorsCh := make(chan searchError, searchSources)
+	staleCh := make(chan staleSource, searchSources)
 
 	// Launch goroutine to search users with circuit breaker protection
-	wg.Add(1)
 	go func() {
-		defer wg.Done()
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
@@ -54,23 +113,23 @@ func ConcurrentSearch(ctx context.Context, app *app.App) (*SearchResult, error)
 			return
 		default:
 		}
-		var users []*models.User
-		err := app.DBCircuit.Execute(func() error {
-			var execErr error
-			users, execErr = app.Users.All(ctx)
-			return execErr
-		})
+		cache, fetch := app.SearchCache.Users, func() ([]*models.User, error) { return app.Users.All(ctx) }
+		if query != "" {
+			cache, fetch = nil, func() ([]*models.User, error) { return app.Search.Users(ctx, query, searchResultLimit) }
+		}
+		users, cachedAt, err := fetchSource(app, cache, fetch)
 		if err != nil {
-			errorsCh <- searchError{Source: "users", Err: err}
-			return
+			if cachedAt.IsZero() {
+				errorsCh <- searchError{Source: "users", Err: err}
+				return
+			}
+			staleCh <- staleSource{searchError: searchError{Source: "users", Err: err}, CachedAt: cachedAt}
 		}
 		usersCh <- users
 	}()
 
 	// Launch goroutine to search posts with circuit breaker protection
-	wg.Add(1)
 	go func() {
-		defer wg.Done()
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
@@ -78,23 +137,23 @@ func ConcurrentSearch(ctx context.Context, app *app.App) (*SearchResult, error)
 			return
 		default:
 		}
-		var posts []*models.Post
-		err := app.DBCircuit.Execute(func() error {
-			var execErr error
-			posts, execErr = app.Posts.All(ctx)
-			return execErr
-		})
+		cache, fetch := app.SearchCache.Posts, func() ([]*models.Post, error) { return app.Posts.All(ctx, sqlite.NoLimit, 0) }
+		if query != "" {
+			cache, fetch = nil, func() ([]*models.Post, error) { return app.Search.Posts(ctx, query, searchResultLimit) }
+		}
+		posts, cachedAt, err := fetchSource(app, cache, fetch)
 		if err != nil {
-			errorsCh <- searchError{Source: "posts", Err: err}
-			return
+			if cachedAt.IsZero() {
+				errorsCh <- searchError{Source: "posts", Err: err}
+				return
+			}
+			staleCh <- staleSource{searchError: searchError{Source: "posts", Err: err}, CachedAt: cachedAt}
 		}
 		postsCh <- posts
 	}()
 
 	// Launch goroutine to search channels with circuit breaker protection
-	wg.Add(1)
 	go func() {
-		defer wg.Done()
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
@@ -102,35 +161,34 @@ func ConcurrentSearch(ctx context.Context, app *app.App) (*SearchResult, error)
 			return
 		default:
 		}
-		var channels []*models.Channel
-		err := app.DBCircuit.Execute(func() error {
-			var execErr error
-			channels, execErr = app.Channels.All(ctx)
-			return execErr
-		})
+		cache, fetch := app.SearchCache.Channels, func() ([]*models.Channel, error) { return app.Channels.All(ctx) }
+		if query != "" {
+			cache, fetch = nil, func() ([]*models.Channel, error) { return app.Search.Channels(ctx, query, searchResultLimit) }
+		}
+		channels, cachedAt, err := fetchSource(app, cache, fetch)
 		if err != nil {
-			errorsCh <- searchError{Source: "channels", Err: err}
-			return
+			if cachedAt.IsZero() {
+				errorsCh <- searchError{Source: "channels", Err: err}
+				return
+			}
+			staleCh <- staleSource{searchError: searchError{Source: "channels", Err: err}, CachedAt: cachedAt}
 		}
 		channelsCh <- channels
 	}()
 
-	// Close error channel when all workers are done
-	go func() {
-		wg.Wait()
-		close(errorsCh)
-	}()
-
 	// Collect results
 	result := &SearchResult{
-		Users:    make([]*models.User, 0),
-		Posts:    make([]*models.Post, 0),
-		Channels: make([]*models.Channel, 0),
-		Errors:   make([]error, 0),
+		Users:       make([]*models.User, 0),
+		Posts:       make([]*models.Post, 0),
+		Channels:    make([]*models.Channel, 0),
+		Errors:      make([]error, 0),
+		Unavailable: make([]string, 0),
+		Stale:       make([]string, 0),
 	}
 
-	// Receive from each result channel exactly once
-	for range 3 {
+	// Each source sends exactly once, either its results or its error. A source
+	// answered from the cache reports that on staleCh before sending its results.
+	for range searchSources {
 		select {
 		case users := <-usersCh:
 			result.Users = users
@@ -138,19 +196,26 @@ func ConcurrentSearch(ctx context.Context, app *app.App) (*SearchResult, error)
 			result.Posts = posts
 		case channels := <-channelsCh:
 			result.Channels = channels
+		case err := <-errorsCh:
+			result.Errors = append(result.Errors, err)
+			result.Unavailable = append(result.Unavailable, err.Source)
 		}
 	}
-
-	// Collect errors
-	for err := range errorsCh {
-		result.Errors = append(result.Errors, err)
+	for len(staleCh) > 0 {
+		stale := <-staleCh
+		result.Errors = append(result.Errors, stale.searchError)
+		result.Stale = append(result.Stale, stale.Source)
+		if result.StaleSince.IsZero() || stale.CachedAt.Before(result.StaleSince) {
+			result.StaleSince = stale.CachedAt
+		}
 	}
+	slices.Sort(result.Unavailable)
+	slices.Sort(result.Stale)
 
 	result.Duration = time.Since(start)
 
-	// Return error if any search failed
-	if len(result.Errors) > 0 {
-		return result, fmt.Errorf("search completed with %d errors", len(result.Errors))
+	if len(result.Unavailable) == searchSources {
+		return result, fmt.Errorf("search failed for every source: %w", errors.Join(result.Errors...))
 	}
 
 	return result, nil
@@ -2,10 +2,18 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/patterns"
+	"github.com/gary-norman/forum/internal/sqlite"
 )
 
 func TestConcurrentSearch(t *testing.T) {
@@ -19,7 +27,7 @@ func TestConcurrentSearch(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("returns results from all sources", func(t *testing.T) {
-		result, err := ConcurrentSearch(ctx, appInstance)
+		result, err := ConcurrentSearch(ctx, appInstance, "")
 		if err != nil {
 			t.Fatalf("ConcurrentSearch failed: %v", err)
 		}
@@ -47,7 +55,7 @@ func TestConcurrentSearch(t *testing.T) {
 
 		time.Sleep(10 * time.Millisecond) // Ensure context is cancelled
 
-		result, err := ConcurrentSearch(ctx, appInstance)
+		result, err := ConcurrentSearch(ctx, appInstance, "")
 
 		// Should handle cancellation gracefully
 		if err == nil && len(result.Errors) == 0 {
@@ -62,7 +70,7 @@ func TestConcurrentSearch(t *testing.T) {
 	t.Run("is faster than sequential search", func(t *testing.T) {
 		// Run concurrent search
 		concurrentStart := time.Now()
-		_, err := ConcurrentSearch(ctx, appInstance)
+		_, err := ConcurrentSearch(ctx, appInstance, "")
 		concurrentDuration := time.Since(concurrentStart)
 		if err != nil {
 			t.Fatalf("Concurrent search failed: %v", err)
@@ -71,7 +79,7 @@ func TestConcurrentSearch(t *testing.T) {
 		// Run sequential search
 		sequentialStart := time.Now()
 		appInstance.Users.All(ctx)
-		appInstance.Posts.All(ctx)
+		appInstance.Posts.All(ctx, sqlite.NoLimit, 0)
 		appInstance.Channels.All(ctx)
 		sequentialDuration := time.Since(sequentialStart)
 
@@ -87,7 +95,7 @@ func TestConcurrentSearch(t *testing.T) {
 
 	t.Run("handles partial failures", func(t *testing.T) {
 		// Even if one search fails, others should succeed
-		result, _ := ConcurrentSearch(ctx, appInstance)
+		result, _ := ConcurrentSearch(ctx, appInstance, "")
 
 		// At least some data should be returned
 		totalResults := len(result.Users) + len(result.Posts) + len(result.Channels)
@@ -105,7 +113,7 @@ func TestEnrichPostsWithChannels(t *testing.T) {
 	}
 	defer cleanup()
 
-	posts, err := appInstance.Posts.All(ctx)
+	posts, err := appInstance.Posts.All(ctx, sqlite.NoLimit, 0)
 	if err != nil {
 		t.Fatalf("Failed to get posts: %v", err)
 	}
@@ -139,7 +147,7 @@ func BenchmarkConcurrentSearch(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := ConcurrentSearch(ctx, appInstance)
+		_, err := ConcurrentSearch(ctx, appInstance, "")
 		if err != nil {
 			b.Fatalf("Search failed: %v", err)
 		}
@@ -157,7 +165,134 @@ func BenchmarkSequentialSearch(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		appInstance.Users.All(ctx)
-		appInstance.Posts.All(ctx)
+		appInstance.Posts.All(ctx, sqlite.NoLimit, 0)
 		appInstance.Channels.All(ctx)
 	}
 }
+
+func TestSearchReturnsPartialResults(t *testing.T) {
+	a := newTestApp(t)
+	owner := insertTestUser(t, a, "owner")
+	insertTestChannel(t, a, owner.ID, "cooking")
+	// break only the posts query
+	if _, err := a.DB.Exec(`ALTER TABLE Posts RENAME TO PostsUnavailable`); err != nil {
+		t.Fatalf("Failed to rename Posts: %v", err)
+	}
+
+	result, err := ConcurrentSearch(context.Background(), a, "")
+	if err != nil {
+		t.Fatalf("Expected partial results without an error, got %v", err)
+	}
+	if len(result.Users) != 1 || len(result.Channels) != 1 {
+		t.Errorf("Expected the user and channel, got %d users and %d channels", len(result.Users), len(result.Channels))
+	}
+	if !slices.Equal(result.Unavailable, []string{"posts"}) {
+		t.Errorf("Expected posts to be unavailable, got %v", result.Unavailable)
+	}
+}
+
+func TestSearchWithCircuitOpen(t *testing.T) {
+	a := newTestApp(t)
+	insertTestUser(t, a, "owner")
+	a.DBCircuit = patterns.NewCircuitBreaker(1, time.Hour)
+	_ = a.DBCircuit.Execute(func() error { return errors.New("database is down") })
+	if a.DBCircuit.State() != patterns.StateOpen {
+		t.Fatal("Expected the circuit to be open")
+	}
+
+	result, err := ConcurrentSearch(context.Background(), a, "")
+	if !errors.Is(err, patterns.ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if result == nil || len(result.Unavailable) != searchSources {
+		t.Fatalf("Expected every source to be unavailable, got %+v", result)
+	}
+
+	// the handler still answers, naming what is missing
+	rec := httptest.NewRecorder()
+	(&SearchHandler{App: a}).Search(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Users       []any    `json:"users"`
+		Unavailable []string `json:"unavailable"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode search results: %v", err)
+	}
+	if len(body.Users) != 0 {
+		t.Errorf("Expected no users, got %d", len(body.Users))
+	}
+	if !slices.Equal(body.Unavailable, []string{"channels", "posts", "users"}) {
+		t.Errorf("Expected every source to be listed as unavailable, got %v", body.Unavailable)
+	}
+}
+
+func TestSearchServesCachedResultsWhenDatabaseFails(t *testing.T) {
+	search := func(a *app.App) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		(&SearchHandler{App: a}).Search(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+	type searchBody struct {
+		Users       []struct{ Username string } `json:"users"`
+		Unavailable []string                    `json:"unavailable"`
+		Stale       []string                    `json:"stale"`
+	}
+
+	t.Run("fresh cache", func(t *testing.T) {
+		a := newTestApp(t)
+		insertTestUser(t, a, "owner")
+		if rec := search(a); rec.Header().Get(StaleSourcesHeader) != "" {
+			t.Fatalf("Expected fresh results, got stale sources %q", rec.Header().Get(StaleSourcesHeader))
+		}
+
+		a.DB.Close()
+		rec := search(a)
+		if got := rec.Header().Get(StaleSourcesHeader); got != "channels,posts,users" {
+			t.Errorf("Expected every source to be stale, got %q", got)
+		}
+		if rec.Header().Get("Age") == "" {
+			t.Error("Expected an Age header on cached results")
+		}
+		var body searchBody
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode search results: %v", err)
+		}
+		if len(body.Users) != 1 || body.Users[0].Username != "owner" {
+			t.Errorf("Expected the cached user, got %+v", body.Users)
+		}
+		if len(body.Unavailable) != 0 || len(body.Stale) != searchSources {
+			t.Errorf("Expected every source stale and none unavailable, got stale %v and unavailable %v", body.Stale, body.Unavailable)
+		}
+	})
+
+	t.Run("expired cache", func(t *testing.T) {
+		a := newTestApp(t)
+		insertTestUser(t, a, "owner")
+		a.SearchCache = app.SearchCache{
+			Users:    patterns.NewLastGood[[]*models.User](time.Nanosecond),
+			Posts:    patterns.NewLastGood[[]*models.Post](time.Nanosecond),
+			Channels: patterns.NewLastGood[[]*models.Channel](time.Nanosecond),
+		}
+		search(a)
+		time.Sleep(time.Millisecond)
+
+		a.DB.Close()
+		rec := search(a)
+		if got := rec.Header().Get(StaleSourcesHeader); got != "" {
+			t.Errorf("Expected nothing served from an expired cache, got %q", got)
+		}
+		var body searchBody
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode search results: %v", err)
+		}
+		if len(body.Users) != 0 || len(body.Unavailable) != searchSources {
+			t.Errorf("Expected empty results with every source unavailable, got %+v", body)
+		}
+	})
+}
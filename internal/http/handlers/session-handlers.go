@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gary-norman/forum/internal/app"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
 )
 
 type SessionHandler struct {
@@ -30,21 +35,24 @@ func (s *SessionHandler) IsAuthenticated(r *http.Request, username string) error
 	}
 	// Get the Session Token from the request cookie
 	st, err := r.Cookie("session_token")
-	if st == nil {
+	if err != nil || st.Value == "" {
 		return errors.New("no session token")
 	}
-	if err != nil || st.Value == "" || st.Value != user.SessionToken {
-		// fmt.Printf(ErrorMsgs.KeyValuePair, "Cookie SessionToken", st.Value)
-		// fmt.Printf(ErrorMsgs.KeyValuePair, "Error", err)
-		// fmt.Printf(ErrorMsgs.KeyValuePair, "User SessionToken", user.SessionToken)
-		return fmt.Errorf("authentication failed: %w", err)
+	session, err := s.App.Sessions.GetByToken(ctx, st.Value)
+	if err != nil {
+		return fmt.Errorf("no active session for %s: %w", user.Username, err)
+	}
+	// a session belongs to one user, and an expired one never matches
+	if session.UserID != user.ID || !tokensMatch(st.Value, session.Token) {
+		return fmt.Errorf("authentication failed: session token mismatch for %s", user.Username)
+	}
+	if !time.Now().Before(session.Expires) {
+		return fmt.Errorf("no active session for %s", user.Username)
 	}
-	// csrf, _ := r.Cookie("csrf_token")
 
 	// Get the CSRF Token from the headers
 	csrfToken := r.Header.Get("x-csrf-token")
-	// fmt.Printf(ErrorMsgs.KeyValuePair, "Header", r.Header)
-	if csrfToken == "" || csrfToken != user.CSRFToken {
+	if !tokensMatch(csrfToken, session.CSRFToken) {
 		authErr := fmt.Errorf("%s%s", successFail, user.Username)
 		models.LogErrorWithContext(ctx, "CSRF token mismatch for user: %s", authErr, user.Username)
 		return authErr
@@ -54,3 +62,80 @@ func (s *SessionHandler) IsAuthenticated(r *http.Request, username string) error
 	models.LogInfoWithContext(ctx, "CSRF token match for user: %s", successFail, user.Username)
 	return nil
 }
+
+// tokensMatch compares a presented token with the stored one in constant time.
+// Empty tokens never match, even each other, so a cleared session cannot be
+// satisfied by leaving the cookie or header blank.
+func tokensMatch(presented, stored string) bool {
+	if presented == "" || stored == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(stored)) == 1
+}
+
+// ListSessions returns the current user's active sessions, one per logged-in
+// device, newest first. The one the request was made from is marked current.
+func (s *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to see your sessions"))
+		return
+	}
+	sessions, err := s.App.Sessions.ForUser(ctx, user.ID)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to list sessions", err, "UserID:", user.ID)
+		writeError(w, err)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Token == user.SessionToken
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"sessions": sessions}); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode sessions", err)
+	}
+}
+
+// RevokeSession logs the current user out of one of their sessions. Revoking the
+// session the request was made from also clears this browser's cookies.
+func (s *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to revoke a session"))
+		return
+	}
+	id, err := models.GetIntFromPathValue(r.PathValue("sessionId"))
+	if err != nil {
+		writeError(w, &RequestError{Field: "sessionId", Msg: "session ID must be a number", err: err})
+		return
+	}
+	current, err := s.App.Sessions.GetByToken(ctx, user.SessionToken)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to look up current session", err, "UserID:", user.ID)
+		writeError(w, err)
+		return
+	}
+
+	if err := s.App.Sessions.Revoke(ctx, user.ID, id); err != nil {
+		models.LogWarnWithContext(ctx, "Failed to revoke session %d: %v", id, err)
+		writeError(w, err)
+		return
+	}
+	if id == current.ID {
+		if err := s.App.Cookies.DeleteCookies(ctx, w, user); err != nil {
+			models.LogErrorWithContext(ctx, "Failed to clear cookies for revoked session", err)
+		}
+	}
+	models.LogInfoWithContext(ctx, "User %s revoked session %d", user.Username, id)
+	w.Header().Set("Content-Type", "application/json")
+	encErr := json.NewEncoder(w).Encode(map[string]any{
+		"code":    http.StatusOK,
+		"message": "session revoked",
+	})
+	if encErr != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode revoke session response", encErr)
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestLogout_InvalidatesSession(t *testing.T) {
+	a := newTestApp(t)
+	insertTestUser(t, a, "leaving")
+	if _, err := a.DB.Exec("UPDATE Sessions SET CSRFToken = 'csrf-leaving' WHERE Token = ?", testSessionToken("leaving")); err != nil {
+		t.Fatalf("Failed to set CSRF token: %v", err)
+	}
+	auth := &AuthHandler{App: a, Session: &SessionHandler{App: a}}
+
+	// request presents the given session cookie and CSRF header for "leaving"
+	request := func(sessionToken, csrfToken string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/protected", nil)
+		r.AddCookie(&http.Cookie{Name: "username", Value: "leaving"})
+		r.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+		r.Header.Set("x-csrf-token", csrfToken)
+		return r
+	}
+	stale := testSessionToken("leaving")
+
+	if err := auth.Session.IsAuthenticated(request(stale, "csrf-leaving"), "leaving"); err != nil {
+		t.Fatalf("Expected the live session to authenticate, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	auth.Logout(rec, request(stale, "csrf-leaving"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected logout to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			t.Errorf("Expected cookie %s to be deleted, got MaxAge %d", c.Name, c.MaxAge)
+		}
+	}
+
+	tests := []struct {
+		name               string
+		sessionToken, csrf string
+	}{
+		{"stale tokens", stale, "csrf-leaving"},
+		{"empty tokens", "", ""},
+		{"empty session with stale csrf", "", "csrf-leaving"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := auth.Session.IsAuthenticated(request(tt.sessionToken, tt.csrf), "leaving"); err == nil {
+				t.Error("Expected IsAuthenticated to fail after logout")
+			}
+			if user, err := mw.ResolveUser(request(tt.sessionToken, tt.csrf), a); err == nil {
+				t.Errorf("Expected ResolveUser to fail after logout, got %s", user.Username)
+			}
+		})
+	}
+}
+
+func TestLogout_RequiresValidSession(t *testing.T) {
+	a := newTestApp(t)
+	insertTestUser(t, a, "victim")
+	auth := &AuthHandler{App: a, Session: &SessionHandler{App: a}}
+
+	r := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.AddCookie(&http.Cookie{Name: "username", Value: "victim"})
+	rec := httptest.NewRecorder()
+	auth.Logout(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a forged username cookie, got %d", rec.Code)
+	}
+
+	if _, err := mw.ResolveUser(withUsername(httptest.NewRequest(http.MethodGet, "/", nil), "victim"), a); err != nil {
+		t.Errorf("Expected the victim's session to survive, got %v", err)
+	}
+}
+
+func TestIsAuthenticated_EmptyTokens(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "blank")
+	s := &SessionHandler{App: a}
+	live := testSessionToken("blank")
+
+	setStored := func(sessionToken, csrfToken string) {
+		t.Helper()
+		if _, err := a.DB.Exec("UPDATE Sessions SET Token = ?, CSRFToken = ? WHERE UserID = ?", sessionToken, csrfToken, user.ID); err != nil {
+			t.Fatalf("Failed to set stored tokens: %v", err)
+		}
+	}
+	request := func(sessionToken, csrfToken string, withCookie bool) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/protected", nil)
+		if withCookie {
+			r.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+		}
+		r.Header.Set("x-csrf-token", csrfToken)
+		return r
+	}
+
+	tests := []struct {
+		name                      string
+		storedSession, storedCSRF string
+		cookieSession, headerCSRF string
+		withCookie                bool
+	}{
+		{"both stored tokens empty, both presented empty", "", "", "", "", true},
+		{"empty stored session, empty cookie", "", "csrf", "", "csrf", true},
+		{"empty stored csrf, empty header", live, "", live, "", true},
+		{"empty cookie against live session", live, "csrf", "", "csrf", true},
+		{"missing cookie", live, "csrf", "", "csrf", false},
+		{"empty header against live csrf", live, "csrf", live, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setStored(tt.storedSession, tt.storedCSRF)
+			if err := s.IsAuthenticated(request(tt.cookieSession, tt.headerCSRF, tt.withCookie), "blank"); err == nil {
+				t.Error("Expected empty tokens to be unauthenticated")
+			}
+		})
+	}
+
+	setStored(live, "csrf")
+	if err := s.IsAuthenticated(request(live, "csrf", true), "blank"); err != nil {
+		t.Errorf("Expected matching tokens to authenticate, got %v", err)
+	}
+}
+
+func TestSessions_SeveralDevices(t *testing.T) {
+	a := newTestApp(t)
+	alice := insertTestUser(t, a, "alice")
+	other := insertTestUser(t, a, "other")
+	hash, err := models.HashPassword("Secret-pass1")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if _, err := a.DB.Exec("UPDATE Users SET HashedPassword = ? WHERE ID = ?", hash, alice.ID); err != nil {
+		t.Fatalf("Failed to set password: %v", err)
+	}
+	auth := &AuthHandler{App: a}
+	s := &SessionHandler{App: a}
+
+	// login signs in from a new device and returns its session cookie
+	login := func(userAgent string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"alice","password":"Secret-pass1"}`))
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		auth.Login(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected login to succeed, got %d: %s", rec.Code, rec.Body.String())
+		}
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "session_token" {
+				return c.Value
+			}
+		}
+		t.Fatal("Expected a session_token cookie")
+		return ""
+	}
+	request := func(method, target, token string) *http.Request {
+		r := httptest.NewRequest(method, target, nil)
+		r.AddCookie(&http.Cookie{Name: "username", Value: "alice"})
+		r.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+		return r
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/sessions", mw.WithUser(http.HandlerFunc(s.ListSessions), a))
+	mux.Handle("DELETE /api/sessions/{sessionId}", mw.WithUser(http.HandlerFunc(s.RevokeSession), a))
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		return rec
+	}
+
+	laptop := login("Laptop")
+	phone := login("Phone")
+	for name, token := range map[string]string{"laptop": laptop, "phone": phone} {
+		if _, err := mw.ResolveUser(request(http.MethodGet, "/", token), a); err != nil {
+			t.Errorf("Expected the %s session to stay valid after logging in elsewhere, got %v", name, err)
+		}
+	}
+
+	rec := serve(request(http.MethodGet, "/api/sessions", phone))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), phone) {
+		t.Error("Expected session tokens to be left out of the listing")
+	}
+	var body struct {
+		Sessions []models.UserSession `json:"sessions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode sessions: %v", err)
+	}
+	// the fixture's session from insertTestUser plus the two logins
+	if len(body.Sessions) != 3 {
+		t.Fatalf("Expected 3 sessions, got %+v", body.Sessions)
+	}
+	var laptopID int64
+	for _, session := range body.Sessions {
+		if session.Current != (session.UserAgent == "Phone") {
+			t.Errorf("Expected only the phone session to be current, got %+v", session)
+		}
+		if session.UserAgent == "Laptop" {
+			laptopID = session.ID
+		}
+	}
+
+	otherSession, err := a.Sessions.GetByToken(context.Background(), testSessionToken(other.Username))
+	if err != nil {
+		t.Fatalf("GetByToken failed: %v", err)
+	}
+	if rec := serve(request(http.MethodDelete, fmt.Sprintf("/api/sessions/%d", otherSession.ID), phone)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking another user's session, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := serve(request(http.MethodDelete, fmt.Sprintf("/api/sessions/%d", laptopID), phone)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the laptop session to be revoked, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := mw.ResolveUser(request(http.MethodGet, "/", laptop), a); err == nil {
+		t.Error("Expected the revoked laptop session to be logged out")
+	}
+	if _, err := mw.ResolveUser(request(http.MethodGet, "/", phone), a); err != nil {
+		t.Errorf("Expected the phone session to survive, got %v", err)
+	}
+
+	// logging out ends only the session it is sent from
+	logout := httptest.NewRecorder()
+	auth.Logout(logout, request(http.MethodPost, "/logout", phone))
+	if logout.Code != http.StatusOK {
+		t.Fatalf("Expected logout to succeed, got %d: %s", logout.Code, logout.Body.String())
+	}
+	if _, err := mw.ResolveUser(request(http.MethodGet, "/", testSessionToken("alice")), a); err != nil {
+		t.Errorf("Expected the remaining session to survive logout elsewhere, got %v", err)
+	}
+}
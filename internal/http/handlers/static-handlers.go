@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"path"
 
 	"github.com/gary-norman/forum/internal/http/middleware"
 )
@@ -22,3 +23,25 @@ func MuxHandler(mux *http.ServeMux, address string) {
 	address = fmt.Sprintf("/%v/", address)
 	mux.Handle(address, use(staticHandler(address)))
 }
+
+// ImageServer serves the files under dir for GET and HEAD. Unlike http.FileServer it
+// never lists directories, and it sets an ETag from the file's size and modification
+// time so clients can revalidate, or check an image with HEAD, without downloading it.
+func ImageServer(dir string) http.Handler {
+	root := http.Dir(dir)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := root.Open(path.Clean("/" + r.URL.Path))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "post-images"), 0o755); err != nil {
+		t.Fatalf("Failed to create image directory: %v", err)
+	}
+	image := []byte("\x89PNG\r\n\x1a\nnot really a png")
+	if err := os.WriteFile(filepath.Join(dir, "post-images", "pic.png"), image, 0o644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	server := ImageServer(dir)
+	serve := func(method, target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	get := serve(http.MethodGet, "/post-images/pic.png", nil)
+	if get.Code != http.StatusOK || get.Body.String() != string(image) {
+		t.Fatalf("Expected GET to return the image, got %d %q", get.Code, get.Body.String())
+	}
+
+	t.Run("HEAD matches GET headers without a body", func(t *testing.T) {
+		head := serve(http.MethodHead, "/post-images/pic.png", nil)
+		if head.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", head.Code)
+		}
+		if head.Body.Len() != 0 {
+			t.Errorf("Expected no body, got %d bytes", head.Body.Len())
+		}
+		for _, name := range []string{"Content-Length", "Content-Type", "ETag", "Last-Modified"} {
+			if get.Header().Get(name) == "" {
+				t.Errorf("Expected GET to set %s", name)
+			}
+			if got, want := head.Header().Get(name), get.Header().Get(name); got != want {
+				t.Errorf("%s: HEAD sent %q, GET sent %q", name, got, want)
+			}
+		}
+	})
+
+	t.Run("ETag revalidation", func(t *testing.T) {
+		rec := serve(http.MethodHead, "/post-images/pic.png", http.Header{"If-None-Match": {get.Header().Get("ETag")}})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 for a matching ETag, got %d", rec.Code)
+		}
+	})
+
+	for _, target := range []string{"/post-images/missing.png", "/post-images/", "/../pic.png"} {
+		t.Run("not found "+target, func(t *testing.T) {
+			for _, method := range []string{http.MethodGet, http.MethodHead} {
+				if rec := serve(method, target, nil); rec.Code != http.StatusNotFound {
+					t.Errorf("%s %s: expected 404, got %d", method, target, rec.Code)
+				}
+			}
+		})
+	}
+}
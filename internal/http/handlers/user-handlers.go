@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/gary-norman/forum/internal/app"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
 	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
 	"github.com/gary-norman/forum/internal/view"
+	"github.com/gary-norman/forum/internal/workers"
 )
 
 type UserHandler struct {
@@ -34,12 +40,7 @@ func (u *UserHandler) GetThisUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userLoggedIn := true
-	currentUser, ok := mw.GetUserFromContext(ctx)
-	if !ok {
-		models.LogWarnWithContext(ctx, "User not authenticated in GetThisUser")
-		userLoggedIn = false
-	}
+	currentUser, userLoggedIn := mw.CurrentUser(ctx)
 
 	// if err != nil {
 	// 	log.Printf(ErrorMsgs.KeyValuePair, "error parsing thisUser ID", err)
@@ -49,48 +50,31 @@ func (u *UserHandler) GetThisUser(w http.ResponseWriter, r *http.Request) {
 	// Fetch the thisUser
 	thisUser, err := u.App.Users.GetUserByID(ctx, userID)
 	if err != nil {
-		view.RenderErrorPage(w, models.NotFoundLocation("user"), 400, models.NotFoundError(userID, "GetThisUser", err))
-	}
-
-	// Fetch thisUser loyalty
-	if err == nil {
-
-		thisUser.Followers, thisUser.Following, err = u.App.Loyalty.CountUsers(ctx, thisUser.ID)
-		if err != nil {
-			view.RenderErrorPage(w, models.NotFoundLocation("user"), 500, models.FetchError("thisUser loyalty", "GetThisUser", err))
-		}
+		view.RenderErrorPage(w, models.NotFoundLocation("user"), statusForError(err), models.NotFoundError(userID, "GetThisUser", err))
+		return
 	}
 
-	// Fetch thisUser userPosts
-	userPosts, err := u.App.Posts.GetPostsByUserID(ctx, thisUser.ID)
+	// Fetch thisUser loyalty; the page still renders, with zero counts, if this fails
+	thisUser.Followers, thisUser.Following, err = u.App.Loyalty.CountUsers(ctx, thisUser.ID)
 	if err != nil {
-		view.RenderErrorPage(w, models.NotFoundLocation("user"), 500, models.FetchError("thisUser userPosts", "GetThisUser", err))
+		models.LogErrorWithContext(ctx, "Failed to count thisUser loyalty", err, "UserID:", thisUser.ID)
+		thisUser.Followers, thisUser.Following = 0, 0
 	}
 
-	// Fetch Reactions for posts
-	userPosts = u.Reaction.GetPostsLikesAndDislikes(userPosts)
-
-	// Retrieve last reaction time for userPosts
-	userPosts, err = u.Reaction.getLastReactionTimeForPosts(userPosts)
+	// Fetch thisUser userPosts with reactions, channels and comments. They are the
+	// page's content, so without them only the error page is rendered.
+	userPosts, err := u.App.PostService.ForUser(ctx, thisUser.ID, sqlite.NoLimit, 0)
 	if err != nil {
-		view.RenderErrorPage(w, models.NotFoundLocation("user"), 500, models.FetchError("last reaction time for posts info", "GetThisUser", err))
+		view.RenderErrorPage(w, models.NotFoundLocation("user"), 500, models.FetchError("thisUser userPosts", "GetThisUser", err))
+		return
 	}
-
-	// Fetch channel name for userPosts
-	for p := range userPosts {
-		userPosts[p].ChannelID, userPosts[p].ChannelName, err = u.Channel.GetChannelInfoFromPostID(userPosts[p].ID)
+	if !userLoggedIn {
+		userPosts, err = publicPosts(ctx, u.App, userPosts)
 		if err != nil {
-			view.RenderErrorPage(w, models.NotFoundLocation("user"), 500, models.FetchError("channel info", "GetThisUser", err))
+			models.LogErrorWithContext(ctx, "Failed to filter private posts for anonymous visitor", err)
 		}
-
-		models.UpdateTimeSince(userPosts[p])
-	}
-
-	// Fetch thisUser post comments
-	userPosts, err = u.Comment.GetPostsComments(userPosts)
-	if err != nil {
-		models.LogErrorWithContext(ctx, "Failed to fetch post comments", err)
 	}
+	userPosts = hideFlaggedPosts(ctx, u.App, currentUser, userPosts)
 
 	models.UpdateTimeSince(&thisUser)
 
@@ -99,18 +83,13 @@ func (u *UserHandler) GetThisUser(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		models.LogErrorWithContext(ctx, "Failed to fetch all channels", err)
 	}
+	if !userLoggedIn {
+		allChannels = publicChannels(allChannels)
+	}
 	for c := range allChannels {
 		models.UpdateTimeSince(allChannels[c])
 	}
 
-	for p := range userPosts {
-		for _, channel := range allChannels {
-			if channel.ID == userPosts[p].ChannelID {
-				userPosts[p].ChannelName = channel.Name
-			}
-		}
-	}
-
 	ownedChannels := make([]*models.Channel, 0)
 	joinedChannels := make([]*models.Channel, 0)
 	ownedAndJoinedChannels := make([]*models.Channel, 0)
@@ -158,7 +137,7 @@ func (u *UserHandler) GetThisUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := models.UserPage{
-		UserID:      models.NewUUIDField(), // Default value of 0 for logged out users
+		UserID:      mw.CurrentUserID(ctx),
 		CurrentUser: currentUser,
 		Instance:    "user-page",
 		ThisUser:    &thisUser,
@@ -175,27 +154,10 @@ func (u *UserHandler) GetThisUser(w http.ResponseWriter, r *http.Request) {
 	view.RenderPageData(w, data)
 }
 
-// GetLoggedInUser gets the currently logged-in user from the session token and returns the user's struct
+// GetLoggedInUser returns the user whose session the request carries. The session
+// token must match, so a username cookie on its own is not enough.
 func (u *UserHandler) GetLoggedInUser(r *http.Request) (*models.User, error) {
-	ctx := r.Context()
-	// Get the username from the request cookie
-	userCookie, getCookieErr := r.Cookie("username")
-	if getCookieErr != nil {
-		return nil, fmt.Errorf("failed to get username cookie: %w", getCookieErr)
-	}
-	var username string
-	if userCookie != nil {
-		username = userCookie.Value
-	}
-	models.LogInfo("Retrieved username from cookie: %s", username)
-	if username == "" {
-		return nil, errors.New("no user is logged in")
-	}
-	user, getUserErr := u.App.Users.GetUserByUsername(ctx, username, "GetLoggedInUser")
-	if getUserErr != nil {
-		return nil, getUserErr
-	}
-	return user, nil
+	return mw.ResolveUser(r, u.App)
 }
 
 func (u *UserHandler) EditUserDetails(w http.ResponseWriter, r *http.Request) {
@@ -206,16 +168,15 @@ func (u *UserHandler) EditUserDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := r.ParseMultipartForm(10 << 20)
-	if err != nil {
+	if err := parseUpload(w, r, u.App.Config.MaxAvatarUploadSize); err != nil {
 		models.LogErrorWithContext(ctx, "Failed to parse multipart form in EditUserDetails", err)
-		http.Error(w, err.Error(), 400)
+		writeError(w, err)
 		return
 	}
 	currentAvatar := user.Avatar
 	prefix := "noimage"
 	models.LogInfoWithContext(ctx, "Current avatar: %v", currentAvatar)
-	user.Avatar = GetFileName(r, "file-drop", "editUserDetails", "user")
+	user.Avatar = GetFileName(r, "file-drop", "editUserDetails", "user", u.App.Config.MaxAvatarUploadSize)
 	// TODO does this check need to be here?
 	if strings.HasPrefix(currentAvatar, prefix) {
 		user.Avatar = currentAvatar
@@ -224,17 +185,99 @@ func (u *UserHandler) EditUserDetails(w http.ResponseWriter, r *http.Request) {
 	if currentDescription != "" {
 		user.Description = currentDescription
 	}
-	currentName := r.FormValue("name")
+	currentName := strings.TrimSpace(r.FormValue("name"))
 	if currentName != "" {
+		var invalid ValidationError
+		if !validUsernameLength(currentName) {
+			invalid.Add("name", usernameLengthMessage)
+		}
+		if err := invalid.Err(); err != nil {
+			writeError(w, err)
+			return
+		}
 		user.Username = currentName
 	}
 	editErr := u.App.Users.Edit(ctx, user)
 	if editErr != nil {
 		models.LogErrorWithContext(ctx, "Failed to edit user details", editErr)
+		writeError(w, editErr)
+		return
 	}
-	ephemeral := true
-	if err, _ := u.App.Cookies.CreateCookies(ctx, w, user, ephemeral); err != nil {
+	if err, _ := u.App.Cookies.RenewCookies(ctx, w, user); err != nil {
 		models.LogErrorWithContext(ctx, "Failed to create cookies", err)
 	}
 	http.Redirect(w, r, "/", http.StatusFound)
 }
+
+// avatarMaxSide is the longest side, in pixels, an uploaded avatar is scaled down to
+const avatarMaxSide = 512
+
+// UpdateAvatar replaces the current user's avatar with the JPEG or PNG in the
+// "avatar" form field. The image is scaled to fit avatarMaxSide, stripped of
+// metadata and saved under a new name; nothing else about the user changes.
+func (u *UserHandler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := mw.GetUserFromContext(ctx)
+	if !ok {
+		writeError(w, withMessage(sqlite.ErrUnauthorized, "you must be logged in to change your avatar"))
+		return
+	}
+	if err := parseUpload(w, r, u.App.Config.MaxAvatarUploadSize); err != nil {
+		models.LogWarnWithContext(ctx, "Rejected avatar upload: %v", err)
+		writeError(w, err)
+		return
+	}
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		writeError(w, &RequestError{Field: "avatar", Msg: "avatar file is required", err: err})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to read avatar upload", err)
+		writeError(w, err)
+		return
+	}
+
+	var resized bytes.Buffer
+	format, err := workers.ResizeImage(data, &resized, avatarMaxSide)
+	if err != nil {
+		models.LogWarnWithContext(ctx, "Rejected avatar image: %v", err)
+		var invalid ValidationError
+		invalid.Add("avatar", "avatar must be a JPEG or PNG image")
+		writeError(w, invalid.Err())
+		return
+	}
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	avatar := renameFileWithUUID("avatar" + ext)
+	path := filepath.Join("db", "userdata", "images", "user-images", avatar)
+	if err := os.WriteFile(path, resized.Bytes(), 0o644); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to save avatar", err)
+		writeError(w, err)
+		return
+	}
+
+	if err := u.App.Users.SetAvatar(ctx, user.ID, avatar); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to update avatar", err)
+		if removeErr := os.Remove(path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			models.LogWarnWithContext(ctx, "Failed to remove unused avatar %s: %v", avatar, removeErr)
+		}
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"code":      http.StatusOK,
+		"message":   "Avatar updated",
+		"avatar":    avatar,
+		"avatarUrl": view.SignedImageURL(u.App, "user-images", avatar),
+	}); err != nil {
+		models.LogErrorWithContext(ctx, "Failed to encode avatar response", err)
+	}
+}
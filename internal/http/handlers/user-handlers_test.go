@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/config"
+	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/view"
+)
+
+func TestGetLoggedInUser_RequiresMatchingSession(t *testing.T) {
+	a := newTestApp(t)
+	insertTestUser(t, a, "victim")
+	u := &UserHandler{App: a}
+
+	request := func(cookies ...*http.Cookie) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		return r
+	}
+	username := &http.Cookie{Name: "username", Value: "victim"}
+
+	tests := []struct {
+		name    string
+		req     *http.Request
+		wantErr error
+	}{
+		{"no cookies", request(), mw.ErrNoSession},
+		{"forged username cookie only", request(username), mw.ErrInvalidSession},
+		{"wrong session token", request(username, &http.Cookie{Name: "session_token", Value: "guess"}), mw.ErrInvalidSession},
+		{"unknown user", request(&http.Cookie{Name: "username", Value: "ghost"}, &http.Cookie{Name: "session_token", Value: "x"}), mw.ErrInvalidSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := u.GetLoggedInUser(tt.req)
+			if user != nil {
+				t.Errorf("Expected no user, got %s", user.Username)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("matching session", func(t *testing.T) {
+		user, err := u.GetLoggedInUser(withUsername(request(), "victim"))
+		if err != nil || user == nil || user.Username != "victim" {
+			t.Errorf("Expected victim, got %v, %v", user, err)
+		}
+	})
+}
+
+func TestWithUser_IgnoresForgedUsernameCookie(t *testing.T) {
+	a := newTestApp(t)
+	insertTestUser(t, a, "victim")
+
+	var resolved bool
+	handler := mw.WithUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, resolved = mw.GetUserFromContext(r.Context())
+	}), a)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "username", Value: "victim"})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if resolved {
+		t.Error("Expected a forged username cookie to leave the request anonymous")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), withUsername(httptest.NewRequest(http.MethodGet, "/", nil), "victim"))
+	if !resolved {
+		t.Error("Expected a valid session to resolve the user")
+	}
+}
+
+func TestResolveUser_SessionBinding(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "bound")
+	a.Config.SessionBinding = models.SessionBinding{IP: true, UserAgent: true}
+
+	request := func(remoteAddr, userAgent string) *http.Request {
+		r := withUsername(httptest.NewRequest(http.MethodGet, "/", nil), "bound")
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("User-Agent", userAgent)
+		return r
+	}
+
+	if _, err := mw.ResolveUser(request("203.0.113.10:4000", "Firefox/130"), a); !errors.Is(err, mw.ErrFingerprintMismatch) {
+		t.Errorf("Expected a session without a captured fingerprint to be rejected, got %v", err)
+	}
+
+	login := mw.ClientFingerprint(request("203.0.113.10:4000", "Firefox/130"))
+	if err := a.Cookies.BindSession(context.Background(), user, login); err != nil {
+		t.Fatalf("BindSession failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		addr, ua  string
+		wantError error
+	}{
+		{"same client, new port", "203.0.113.10:4001", "Firefox/130", nil},
+		{"different ip", "198.51.100.4:4000", "Firefox/130", mw.ErrFingerprintMismatch},
+		{"different user agent", "203.0.113.10:4000", "curl/8.0", mw.ErrFingerprintMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mw.ResolveUser(request(tt.addr, tt.ua), a)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Expected %v, got %v", tt.wantError, err)
+			}
+			if tt.wantError == nil && (got == nil || got.Username != "bound") {
+				t.Errorf("Expected bound, got %v", got)
+			}
+		})
+	}
+}
+
+func TestResolveUser_RejectsExpiredSession(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "lapsed")
+	if _, err := a.DB.Exec("UPDATE Sessions SET Expires = ? WHERE UserID = ?", time.Now().Add(-time.Minute), user.ID); err != nil {
+		t.Fatalf("Failed to expire session: %v", err)
+	}
+
+	r := withUsername(httptest.NewRequest(http.MethodGet, "/", nil), "lapsed")
+	if _, err := mw.ResolveUser(r, a); !errors.Is(err, mw.ErrSessionExpired) {
+		t.Errorf("Expected ErrSessionExpired, got %v", err)
+	}
+}
+
+func TestEditUserDetails_KeepsSessionKind(t *testing.T) {
+	tests := []struct {
+		name       string
+		expiresIn  time.Duration
+		persistent bool
+	}{
+		{"remembered login stays persistent", 80 * 24 * time.Hour, true},
+		{"browser-session login stays ephemeral", time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			h := mw.WithUser(http.HandlerFunc((&UserHandler{App: a}).EditUserDetails), a)
+			user := insertTestUser(t, a, "editor")
+			if _, err := a.DB.Exec("UPDATE Sessions SET Expires = ? WHERE UserID = ?", time.Now().Add(tt.expiresIn), user.ID); err != nil {
+				t.Fatalf("Failed to set session expiry: %v", err)
+			}
+
+			var body bytes.Buffer
+			mp := multipart.NewWriter(&body)
+			mp.WriteField("bio", "new bio")
+			mp.Close()
+			req := httptest.NewRequest(http.MethodPost, "/edituser", &body)
+			req.Header.Set("Content-Type", mp.FormDataContentType())
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, withUsername(req, user.Username))
+			if rec.Code != http.StatusFound {
+				t.Fatalf("Expected 302, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var session *http.Cookie
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == "session_token" {
+					session = c
+				}
+			}
+			if session == nil {
+				t.Fatal("Expected a new session_token cookie")
+			}
+			if persistent := !session.Expires.IsZero(); persistent != tt.persistent {
+				t.Errorf("Expected persistent=%v, got cookie expiring %v", tt.persistent, session.Expires)
+			}
+
+			var stored time.Time
+			if err := a.DB.QueryRow("SELECT Expires FROM Sessions WHERE Token = ?", session.Value).Scan(&stored); err != nil {
+				t.Fatalf("Failed to read session expiry: %v", err)
+			}
+			if remembered := time.Until(stored) > config.DefaultEphemeralSessionLifetime; remembered != tt.persistent {
+				t.Errorf("Expected remembered=%v, got stored expiry %v", tt.persistent, stored)
+			}
+		})
+	}
+}
+
+func TestUploads_RejectFilesAboveConfiguredLimit(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.MaxAvatarUploadSize = 4 << 10
+	a.Config.MaxPostImageUploadSize = 64 << 10
+	user := insertTestUser(t, a, "uploader")
+
+	upload := func(handler http.HandlerFunc, target string, size int) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mp := multipart.NewWriter(&body)
+		mp.WriteField("bio", "new bio")
+		part, _ := mp.CreateFormFile("file-drop", "big.png")
+		part.Write(bytes.Repeat([]byte("x"), size))
+		mp.Close()
+		req := httptest.NewRequest(http.MethodPost, target, &body)
+		req.Header.Set("Content-Type", mp.FormDataContentType())
+		rec := httptest.NewRecorder()
+		mw.WithUser(handler, a).ServeHTTP(rec, withUsername(req, user.Username))
+		return rec
+	}
+
+	t.Run("avatar above its limit", func(t *testing.T) {
+		rec := upload((&UserHandler{App: a}).EditUserDetails, "/edituser", 8<<10)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected 413, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var description string
+		if err := a.DB.QueryRow("SELECT Description FROM Users WHERE ID = ?", user.ID).Scan(&description); err != nil {
+			t.Fatalf("Failed to read user: %v", err)
+		}
+		if description == "new bio" {
+			t.Error("Expected the rejected request to leave the user unchanged")
+		}
+	})
+
+	t.Run("post image limit is separate", func(t *testing.T) {
+		// 8KB is over the avatar limit but within the post image limit, so the post
+		// gets past the upload check and fails validation on its missing fields
+		rec := upload((&PostHandler{App: a}).StorePost, "/posts/create", 8<<10)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected 422, got %d: %s", rec.Code, rec.Body.String())
+		}
+		rec = upload((&PostHandler{App: a}).StorePost, "/posts/create", 128<<10)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected 413, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestUpdateAvatar(t *testing.T) {
+	a := newTestApp(t)
+	user := insertTestUser(t, a, "avatarist")
+	h := mw.WithUser(http.HandlerFunc((&UserHandler{App: a}).UpdateAvatar), a)
+
+	// avatars are saved relative to the working directory
+	t.Chdir(t.TempDir())
+	avatarDir := filepath.Join("db", "userdata", "images", "user-images")
+	if err := os.MkdirAll(avatarDir, 0o755); err != nil {
+		t.Fatalf("Failed to create avatar directory: %v", err)
+	}
+
+	send := func(username, field string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mp := multipart.NewWriter(&body)
+		part, _ := mp.CreateFormFile(field, "upload.png")
+		part.Write(data)
+		mp.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/user/avatar", &body)
+		req.Header.Set("Content-Type", mp.FormDataContentType())
+		if username != "" {
+			req = withUsername(req, username)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	storedAvatar := func() string {
+		t.Helper()
+		var avatar string
+		if err := a.DB.QueryRow("SELECT Avatar FROM Users WHERE ID = ?", user.ID).Scan(&avatar); err != nil {
+			t.Fatalf("Failed to read avatar: %v", err)
+		}
+		return avatar
+	}
+
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, image.NewNRGBA(image.Rect(0, 0, 1024, 512))); err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+
+	t.Run("rejects a file that is not an image", func(t *testing.T) {
+		rec := send(user.Username, "avatar", []byte("definitely not a png"))
+		expectFields(t, decodeValidation(t, rec), "avatar")
+		if avatar := storedAvatar(); avatar != "avatarist.png" {
+			t.Errorf("Expected the avatar to be unchanged, got %q", avatar)
+		}
+	})
+
+	t.Run("rejects a request without a file", func(t *testing.T) {
+		if rec := send(user.Username, "file-drop", picture.Bytes()); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("anonymous is unauthorized", func(t *testing.T) {
+		if rec := send("", "avatar", picture.Bytes()); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", rec.Code)
+		}
+	})
+
+	t.Run("stores a resized avatar", func(t *testing.T) {
+		rec := send(user.Username, "avatar", picture.Bytes())
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Avatar    string `json:"avatar"`
+			AvatarURL string `json:"avatarUrl"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Response is not valid JSON: %v", err)
+		}
+		if body.Avatar == "" || storedAvatar() != body.Avatar || body.AvatarURL == "" {
+			t.Fatalf("Expected the new avatar to be stored and returned, got %+v", body)
+		}
+
+		f, err := os.Open(filepath.Join(avatarDir, body.Avatar))
+		if err != nil {
+			t.Fatalf("Expected the avatar file to be saved: %v", err)
+		}
+		defer f.Close()
+		cfg, err := png.DecodeConfig(f)
+		if err != nil {
+			t.Fatalf("Saved avatar is not a png: %v", err)
+		}
+		if cfg.Width != avatarMaxSide || cfg.Height != avatarMaxSide/2 {
+			t.Errorf("Expected a %dx%d avatar, got %dx%d", avatarMaxSide, avatarMaxSide/2, cfg.Width, cfg.Height)
+		}
+	})
+}
+
+func TestGetThisUser_DegradesOnLoyaltyFailure(t *testing.T) {
+	a := newTestApp(t)
+	author := insertTestUser(t, a, "author")
+	channelID := insertTestChannel(t, a, author.ID, "general")
+	insertTestChannelPost(t, a, author, channelID, "hello")
+
+	// the stub page names the data type it was rendered with, so an error page
+	// followed by a user page would show up as two documents
+	previous := view.Template
+	view.Template = template.Must(template.New("").Parse(`{{define "user-page"}}{{printf "%T" .}}{{end}}`))
+	t.Cleanup(func() { view.Template = previous })
+
+	if _, err := a.DB.Exec("DROP TABLE Followers"); err != nil {
+		t.Fatalf("Failed to drop Followers: %v", err)
+	}
+
+	h := &UserHandler{App: a}
+	req := httptest.NewRequest(http.MethodGet, "/user/"+author.ID.String(), nil)
+	req.SetPathValue("userId", author.ID.String())
+	rec := httptest.NewRecorder()
+	h.GetThisUser(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	dec := json.NewDecoder(rec.Body)
+	var page map[string]any
+	if err := dec.Decode(&page); err != nil {
+		t.Fatalf("Response is not valid JSON: %v", err)
+	}
+	if dec.More() {
+		t.Errorf("Expected a single JSON document, got more after %v", page)
+	}
+	if page[models.ToHTMLVar("user-page")] != "models.UserPage" {
+		t.Errorf("Expected the user page, got %v", page)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gary-norman/forum/internal/app"
@@ -18,27 +19,33 @@ type contextKey string
 
 const userContextKey = contextKey("currentUser")
 
-// Middleware to add the user to the request context
+// observedUserKey holds the *observedUser Observe reads back once the request is done
+const observedUserKey = contextKey("observedUser")
+
+// observedUser is where WithUser, which runs per route inside the mux, leaves the
+// user it resolved for Observe, which wraps the mux and never sees the inner context
+type observedUser struct {
+	user *models.User
+}
+
+// Middleware to add the user to the request context. Requests without a valid
+// session continue anonymously.
 func WithUser(next http.Handler, app *app.App) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		var currentUser *models.User
-		cookie, err := r.Cookie("username")
-		if err != nil || cookie.Value == "" {
-			models.LogWarn("No session cookie found in request: %v", err)
-			next.ServeHTTP(w, r)
-			return
-		}
-		user, err := app.Users.GetUserByUsername(ctx, cookie.Value, "WithUser")
+		currentUser, err := ResolveUser(r, app)
 		if err != nil {
-			models.LogWarn("No user found for username %s: %v", cookie.Value, err)
+			if !errors.Is(err, ErrNoSession) {
+				models.LogWarn("Rejected session: %v", err)
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
-		currentUser = user
 
+		if observed, ok := r.Context().Value(observedUserKey).(*observedUser); ok {
+			observed.user = currentUser
+		}
 		// Store user in context
-		ctx = context.WithValue(r.Context(), userContextKey, currentUser)
+		ctx := context.WithValue(r.Context(), userContextKey, currentUser)
 		// Pass modified request with context to the next handler
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,31 @@
+package middleware
+
+import "net/http"
+
+// Chain composes middlewares so that the first argument is the outermost: a
+// request passes through mws[0], then mws[1], and so on before reaching the
+// handler, and responses unwind in the reverse order.
+//
+// Global middlewares should be applied in this order, outermost first:
+//
+//  1. ClientIP    - settles the client address before anything logs or keys on it
+//  2. Observe     - assigns the request ID every later layer logs with, and
+//     measures status, bytes and duration once for tracing, logging and RequestLogs
+//  3. Recover     - turns panics further down into a 500 that still gets logged
+//  4. CORS        - answers preflight requests before they cost anything further
+//  5. Drain       - refuses new writes once shutdown has begun
+//  6. Rate limit  - rejects abusive clients before any real work is done
+//  7. Compression - wraps the writer so handlers stay unaware of encoding
+//  8. Query count - debug only; counts the handler's database queries
+//  9. Timeout     - bounds the handler's context, innermost so it measures only handler time
+//
+// Auth (WithUser) and CSRF checks are per-route and wrap individual handlers
+// inside the mux, so they always run after the global chain.
+func Chain(mws ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
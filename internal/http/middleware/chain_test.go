@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestChainOrder tests that middlewares run outermost-first on the way in and reverse on the way out
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+" in")
+				next.ServeHTTP(w, r)
+				calls = append(calls, name+" out")
+			})
+		}
+	}
+	handler := Chain(record("tracing"), record("logging"), record("timeout"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := []string{"tracing in", "logging in", "timeout in", "handler", "timeout out", "logging out", "tracing out"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected %v, got %v", want, calls)
+	}
+}
+
+// TestChainEmpty tests that an empty chain returns the handler unchanged
+func TestChainEmpty(t *testing.T) {
+	called := false
+	handler := Chain()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Error("Expected the handler to be called")
+	}
+}
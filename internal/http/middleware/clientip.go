@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const clientIPKey = contextKey("clientIP")
+
+// ClientIP works out the address each request came from, for session binding, rate
+// limiting and logs. It is the connection's RemoteAddr, unless that is one of the
+// trusted proxies: then the forwarding headers are believed, X-Forwarded-For read
+// from the right past any further trusted hops, since the client can prepend
+// whatever it likes. With no trusted proxies the headers are ignored entirely.
+func ClientIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr := resolveClientIP(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey, addr)))
+		})
+	}
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	addr := remoteHost(r)
+	if !isTrusted(addr, trusted) {
+		return addr
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				break
+			}
+			addr = hop
+			if !isTrusted(hop, trusted) {
+				return addr
+			}
+		}
+		return addr
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		if _, err := netip.ParseAddr(xri); err == nil {
+			return xri
+		}
+	}
+	return addr
+}
+
+func isTrusted(addr string, trusted []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost is RemoteAddr without its port
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// clientAddr is the address ClientIP resolved for r, or RemoteAddr without its port
+// when ClientIP has not run
+func clientAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(clientIPKey).(string); ok {
+		return addr
+	}
+	return remoteHost(r)
+}
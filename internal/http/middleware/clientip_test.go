@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		xff        []string
+		realIP     string
+		want       string
+	}{
+		{"no proxies trusted", nil, "192.0.2.7:5000", []string{"198.51.100.4"}, "198.51.100.4", "192.0.2.7"},
+		{"untrusted peer", trusted, "192.0.2.7:5000", []string{"198.51.100.4"}, "", "192.0.2.7"},
+		{"trusted proxy", trusted, "10.0.0.2:5000", []string{"198.51.100.4"}, "", "198.51.100.4"},
+		{"spoofed hop before the proxy's", trusted, "10.0.0.2:5000", []string{"203.0.113.9, 198.51.100.4"}, "", "198.51.100.4"},
+		{"chained proxies", trusted, "10.0.0.2:5000", []string{"198.51.100.4", "10.0.0.3"}, "", "198.51.100.4"},
+		{"real ip from proxy", trusted, "10.0.0.2:5000", nil, "198.51.100.4", "198.51.100.4"},
+		{"garbage header", trusted, "10.0.0.2:5000", []string{"not-an-ip"}, "", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientFingerprint(r).IP
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
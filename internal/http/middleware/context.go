@@ -6,7 +6,11 @@ import (
 	"github.com/gary-norman/forum/internal/models"
 )
 
-// getUserFromContext retrieves the user from the context
+// AnonymousUserID stands in for the user ID of visitors who are not logged in. It is
+// the nil UUID, so it never matches a real user.
+var AnonymousUserID = models.ZeroUUIDField()
+
+// GetUserFromContext retrieves the user from the context
 func GetUserFromContext(ctx context.Context) (*models.User, bool) {
 	user, ok := ctx.Value(userContextKey).(*models.User)
 	if !ok || user == nil {
@@ -14,3 +18,23 @@ func GetUserFromContext(ctx context.Context) (*models.User, bool) {
 	}
 	return user, true
 }
+
+// CurrentUser resolves who a request is made by, for handlers that serve logged-in
+// users and anonymous visitors alike. Anonymous visitors get a nil user, which
+// templates treat as logged out, and isAuthenticated false.
+func CurrentUser(ctx context.Context) (user *models.User, isAuthenticated bool) {
+	user, isAuthenticated = GetUserFromContext(ctx)
+	if !isAuthenticated {
+		models.LogInfoWithContext(ctx, "Serving anonymous visitor")
+	}
+	return user, isAuthenticated
+}
+
+// CurrentUserID returns the ID of the user a request is made by, or AnonymousUserID
+// for anonymous visitors
+func CurrentUserID(ctx context.Context) models.UUIDField {
+	if user, ok := GetUserFromContext(ctx); ok {
+		return user.ID
+	}
+	return AnonymousUserID
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestCurrentUser(t *testing.T) {
+	user := &models.User{ID: models.NewUUIDField(), Username: "alice"}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantUser *models.User
+		wantID   models.UUIDField
+	}{
+		{"authenticated", context.WithValue(context.Background(), userContextKey, user), user, user.ID},
+		{"anonymous", context.Background(), nil, AnonymousUserID},
+		{"nil user in context", context.WithValue(context.Background(), userContextKey, (*models.User)(nil)), nil, AnonymousUserID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CurrentUser(tt.ctx)
+			if got != tt.wantUser || ok != (tt.wantUser != nil) {
+				t.Errorf("CurrentUser() = %v, %v; want %v, %v", got, ok, tt.wantUser, tt.wantUser != nil)
+			}
+			if id := CurrentUserID(tt.ctx); id != tt.wantID {
+				t.Errorf("CurrentUserID() = %v, want %v", id, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newCORSHandler(called *bool) http.Handler {
+	return WithCORS([]string{"https://app.example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+// TestWithCORSAllowedOrigin tests that an allowed origin is echoed with credentials
+func TestWithCORSAllowedOrigin(t *testing.T) {
+	var called bool
+	req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	newCORSHandler(&called).ServeHTTP(rec, req)
+
+	if !called {
+		t.Error("Expected the handler to be called")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected origin to be echoed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected credentials to be allowed, got %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %q", got)
+	}
+}
+
+// TestWithCORSDisallowedOrigin tests that other origins get no CORS headers
+func TestWithCORSDisallowedOrigin(t *testing.T) {
+	var called bool
+	req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	newCORSHandler(&called).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin header, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Expected no Allow-Credentials header, got %q", got)
+	}
+
+	// a disallowed preflight is refused outright
+	called = false
+	req = httptest.NewRequest(http.MethodOptions, "/api/posts", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec = httptest.NewRecorder()
+	newCORSHandler(&called).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for disallowed preflight, got %d", rec.Code)
+	}
+	if called {
+		t.Error("Expected the handler not to be called for a refused preflight")
+	}
+}
+
+// TestWithCORSPreflight tests that preflight requests are answered without reaching the handler
+func TestWithCORSPreflight(t *testing.T) {
+	var called bool
+	req := httptest.NewRequest(http.MethodOptions, "/api/posts", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	rec := httptest.NewRecorder()
+	newCORSHandler(&called).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rec.Code)
+	}
+	if called {
+		t.Error("Expected preflight to be answered by the middleware")
+	}
+	if rec.Header().Get("Access-Control-Allow-Methods") == "" || rec.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Errorf("Expected Allow-Methods and Allow-Headers, got %v", rec.Header())
+	}
+}
+
+// TestWithCORSSameOrigin tests that requests without an Origin pass through untouched
+func TestWithCORSSameOrigin(t *testing.T) {
+	var called bool
+	rec := httptest.NewRecorder()
+	newCORSHandler(&called).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called || rec.Header().Get("Vary") != "" {
+		t.Errorf("Expected untouched pass-through, called=%v headers=%v", called, rec.Header())
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// drainRetryAfter is the Retry-After, in seconds, sent with requests refused while draining
+const drainRetryAfter = "30"
+
+// WriteDraining refuses a request with 503 because the server is shutting down
+func WriteDraining(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", drainRetryAfter)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"code":    http.StatusServiceUnavailable,
+		"message": "server draining",
+	})
+}
+
+// RefuseWhileDraining answers new writes (any method other than GET, HEAD and
+// OPTIONS) with a 503 once draining reports true, so nothing new is started while
+// the server shuts down. Reads still pass, and requests that were already past
+// this check when draining began run to completion.
+func RefuseWhileDraining(draining func() bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				if draining() {
+					WriteDraining(w)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRefuseWhileDraining(t *testing.T) {
+	var draining atomic.Bool
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handler := RefuseWhileDraining(draining.Load)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	if rec := serve(http.MethodPost, "/posts/create"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected writes to pass before draining, got %d", rec.Code)
+	}
+
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() { inFlight <- serve(http.MethodPost, "/slow") }()
+	<-started
+	draining.Store(true)
+
+	t.Run("new writes are refused", func(t *testing.T) {
+		for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			rec := serve(method, "/posts/create")
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("%s: expected 503, got %d", method, rec.Code)
+			}
+			if rec.Header().Get("Retry-After") == "" || !strings.Contains(rec.Body.String(), "server draining") {
+				t.Errorf("%s: expected a draining message with Retry-After, got %v %q", method, rec.Header(), rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("reads still pass", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+			if rec := serve(method, "/"); rec.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d", method, rec.Code)
+			}
+		}
+	})
+
+	t.Run("in-flight request finishes", func(t *testing.T) {
+		close(release)
+		if rec := <-inFlight; rec.Code != http.StatusOK {
+			t.Errorf("Expected the request started before draining to finish with 200, got %d", rec.Code)
+		}
+	})
+}
@@ -1,98 +1,28 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
-	"time"
+	"strings"
 
-	"github.com/gary-norman/forum/internal/models"
 	"github.com/gary-norman/forum/internal/workers"
 )
 
-// ResponseWriter wrapper to capture status code and bytes written
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode   int
-	bytesWritten int64
-}
-
-func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
-	rw.ResponseWriter.WriteHeader(statusCode)
-}
-
-func (rw *responseWriter) Write(b []byte) (int, error) {
-	n, err := rw.ResponseWriter.Write(b)
-	rw.bytesWritten += int64(n)
-	return n, err
-}
-
 // LoggingEnhanced is a middleware that logs detailed request metrics to the database
-func LoggingEnhanced(loggerPool *workers.LoggerPool) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Start timing
-			start := time.Now()
-
-			// Wrap response writer to capture status code and bytes
-			wrapped := &responseWriter{
-				ResponseWriter: w,
-				statusCode:     200, // Default status code
-			}
-
-			// Process request
-			next.ServeHTTP(wrapped, r)
-
-			// Calculate duration
-			duration := time.Since(start).Milliseconds()
-
-			user, ok := r.Context().Value("user").(*models.User)
-			var userID models.UUIDField
-			if ok && user != nil {
-				userID = user.ID
-			} else {
-				userID = models.ZeroUUIDField() // Nil UUID for anonymous
-			}
-
-			// Build request log entry
-			requestLog := models.RequestLog{
-				Timestamp:  start,
-				Method:     r.Method,
-				Path:       r.URL.Path,
-				StatusCode: wrapped.statusCode,
-				Duration:   duration,
-				UserID:     userID,
-				IPAddress:  getClientIP(r),
-				UserAgent:  r.UserAgent(),
-				Referer:    r.Referer(),
-				BytesSent:  wrapped.bytesWritten,
-			}
-
-			// Submit log asynchronously (non-blocking)
-			if err := loggerPool.LogRequest(requestLog); err != nil {
-				// If queue is full, just log to console - don't slow down the request!
-				log.Printf("Warning: Failed to queue request log: %v\n", err)
-			}
-
-			// Also log to console for immediate visibility (optional)
-			log.Printf("[%s] %s - %d (%dms)\n",
-				r.Method, r.URL.Path, wrapped.statusCode, duration)
-		})
-	}
+// and the console. Requests under any of excludePaths (health checks, metrics scrapes)
+// are served but not logged, so polling does not swamp RequestLogs and skew its stats.
+// It is Observe with the LogRequests and PersistRequests sinks; prefer Observe when
+// tracing is wanted too, so the response is only measured once.
+func LoggingEnhanced(loggerPool *workers.LoggerPool, excludePaths ...string) func(http.Handler) http.Handler {
+	return Observe(LogRequests(excludePaths...), PersistRequests(loggerPool, excludePaths...))
 }
 
-// getClientIP extracts the real client IP address from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (for proxies/load balancers)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+// pathExcluded reports whether path is one of excluded or sits beneath one of them
+func pathExcluded(path string, excluded []string) bool {
+	for _, prefix := range excluded {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
 	}
-
-	// Fall back to RemoteAddr
-	return r.RemoteAddr
+	return false
 }
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/gary-norman/forum/internal/sqlite"
+	"github.com/gary-norman/forum/internal/workers"
+)
+
+// TestLoggingEnhancedExcludesPaths tests that excluded monitoring paths never reach RequestLogs
+func TestLoggingEnhancedExcludesPaths(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	for _, migration := range []string{"006_logging_system.sql", "026_log_request_ids.sql"} {
+		schema, err := os.ReadFile("../../../migrations/" + migration)
+		if err != nil {
+			t.Fatalf("Failed to read logging migration %s: %v", migration, err)
+		}
+		if _, err := db.Exec(string(schema)); err != nil {
+			t.Fatalf("Failed to apply logging migration %s: %v", migration, err)
+		}
+	}
+
+	// a single worker writes in submission order, so once the last request is
+	// stored every earlier one would have been too
+	pool := workers.NewLoggerPool(1, 10, db)
+	pool.Start()
+	t.Cleanup(func() { pool.Shutdown(context.Background()) })
+
+	handler := LoggingEnhanced(pool, "/health", "/metrics/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, path := range []string{"/health", "/metrics", "/metrics/db", "/healthcheck", "/posts"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected %s to be served, got %d", path, rec.Code)
+		}
+	}
+
+	logs := &sqlite.LoggingModel{DB: db}
+	var stats *sqlite.RequestStats
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats, err = logs.GetRequestStats(context.Background(), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("GetRequestStats failed: %v", err)
+		}
+		if stats.RequestsPerPath["/posts"] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for /posts to be logged, got %v", stats.RequestsPerPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if stats.TotalRequests != 2 {
+		t.Errorf("Expected 2 logged requests, got %d: %v", stats.TotalRequests, stats.RequestsPerPath)
+	}
+	for _, path := range []string{"/health", "/metrics", "/metrics/db"} {
+		if n, ok := stats.RequestsPerPath[path]; ok {
+			t.Errorf("Expected %s to be excluded from stats, got %d", path, n)
+		}
+	}
+	if stats.RequestsPerPath["/healthcheck"] != 1 {
+		t.Error("Expected /healthcheck to be logged; exclusion is by path segment, not string prefix")
+	}
+}
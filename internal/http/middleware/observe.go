@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/google/uuid"
+)
+
+// responseWriter wraps a ResponseWriter to capture status code and bytes written
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	rw.statusCode = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += int64(n)
+	return n, err
+}
+
+// RequestInfo is what Observe measured about one finished request
+type RequestInfo struct {
+	Request   *http.Request // carries the request ID in its context
+	RequestID string
+	UserID    models.UUIDField // AnonymousUserID unless WithUser resolved a session
+	Start     time.Time
+	Duration  time.Duration
+	Status    int
+	Bytes     int64
+}
+
+// RequestSink consumes the RequestInfo of each finished request
+type RequestSink func(info RequestInfo)
+
+// RequestLogger is the part of workers.LoggerPool that PersistRequests needs
+type RequestLogger interface {
+	LogRequest(log models.RequestLog) error
+}
+
+// Observe is the single observability middleware. It assigns the request ID (or
+// keeps one an outer layer already set), sends it back as X-Request-ID, wraps the
+// ResponseWriter once to capture status and bytes, notes the user if a route's
+// WithUser resolves one, and hands the result to every sink in order. Tracing, logging and RequestLogs persistence are all sinks, so they
+// share one measurement instead of each stacking a writer wrapper of its own.
+func Observe(sinks ...RequestSink) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := models.GetRequestID(r.Context())
+			if requestID == "" {
+				requestID = uuid.New().String()
+				r = r.WithContext(models.WithRequestID(r.Context(), requestID))
+			}
+			w.Header().Set("X-Request-ID", requestID)
+			observed := &observedUser{}
+			r = r.WithContext(context.WithValue(r.Context(), observedUserKey, observed))
+
+			start := time.Now()
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			info := RequestInfo{
+				Request:   r,
+				RequestID: requestID,
+				UserID:    AnonymousUserID,
+				Start:     start,
+				Duration:  time.Since(start),
+				Status:    wrapped.statusCode,
+				Bytes:     wrapped.bytesWritten,
+			}
+			if observed.user != nil {
+				info.UserID = observed.user.ID
+			}
+			for _, sink := range sinks {
+				sink(info)
+			}
+		})
+	}
+}
+
+// TraceSlowRequests logs every request that took longer than threshold
+func TraceSlowRequests(threshold time.Duration) RequestSink {
+	return func(info RequestInfo) {
+		if info.Duration > threshold {
+			log.Printf("⚠️  SLOW REQUEST [%s] %s - took %v", info.RequestID, info.Request.URL.Path, info.Duration)
+		}
+	}
+}
+
+// LogRequests writes a console line per request, except those under excludePaths
+func LogRequests(excludePaths ...string) RequestSink {
+	return func(info RequestInfo) {
+		if pathExcluded(info.Request.URL.Path, excludePaths) {
+			return
+		}
+		log.Printf("[%s] %s - %d (%dms)\n",
+			info.Request.Method, info.Request.URL.Path, info.Status, info.Duration.Milliseconds())
+	}
+}
+
+// PersistRequests queues a RequestLogs row per request, except those under
+// excludePaths, so polling does not swamp the table and skew its stats
+func PersistRequests(logger RequestLogger, excludePaths ...string) RequestSink {
+	return func(info RequestInfo) {
+		if pathExcluded(info.Request.URL.Path, excludePaths) {
+			return
+		}
+		// Submit asynchronously; if the queue is full just say so, don't slow the request
+		if err := logger.LogRequest(newRequestLog(info)); err != nil {
+			log.Printf("Warning: Failed to queue request log: %v\n", err)
+		}
+	}
+}
+
+// newRequestLog builds the RequestLogs row for info
+func newRequestLog(info RequestInfo) models.RequestLog {
+	r := info.Request
+	return models.RequestLog{
+		Timestamp:  info.Start,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		StatusCode: info.Status,
+		Duration:   info.Duration.Milliseconds(),
+		UserID:     info.UserID,
+		IPAddress:  clientAddr(r),
+		UserAgent:  r.UserAgent(),
+		Referer:    r.Referer(),
+		BytesSent:  info.Bytes,
+		RequestID:  info.RequestID,
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/config"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/testutil"
+)
+
+type recordingLogger struct{ logs []models.RequestLog }
+
+func (l *recordingLogger) LogRequest(log models.RequestLog) error {
+	l.logs = append(l.logs, log)
+	return nil
+}
+
+func TestObserve(t *testing.T) {
+	var console bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&console)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	persisted := &recordingLogger{}
+	var seen []RequestInfo
+	capture := func(info RequestInfo) { seen = append(seen, info) }
+	handler := Observe(
+		TraceSlowRequests(-1), // every request counts as slow
+		LogRequests("/health"),
+		PersistRequests(persisted, "/health"),
+		capture,
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if models.GetRequestID(r.Context()) == "" {
+			t.Error("Expected the handler to see a request ID")
+		}
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("hello"))
+			w.Write([]byte(", world"))
+		case "/implicit":
+			w.Write([]byte("ok"))
+		}
+	}))
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBytes  int64
+		persisted  bool
+	}{
+		{"/created", http.StatusCreated, 12, true},
+		{"/implicit", http.StatusOK, 2, true},
+		{"/health", http.StatusOK, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			console.Reset()
+			seen, persisted.logs = nil, nil
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if len(seen) != 1 {
+				t.Fatalf("Expected one RequestInfo, got %d", len(seen))
+			}
+			info := seen[0]
+			if info.Status != tt.wantStatus || info.Bytes != tt.wantBytes || rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d and %d bytes, got %d and %d (recorder %d)",
+					tt.wantStatus, tt.wantBytes, info.Status, info.Bytes, rec.Code)
+			}
+			if id := rec.Header().Get("X-Request-ID"); id == "" || id != info.RequestID {
+				t.Errorf("Expected X-Request-ID %q, got %q", info.RequestID, id)
+			}
+			if !strings.Contains(console.String(), "SLOW REQUEST ["+info.RequestID+"]") {
+				t.Errorf("Expected a trace line for %s, got %q", info.RequestID, console.String())
+			}
+
+			logLine := "GET] " + tt.path + " - "
+			if got := strings.Contains(console.String(), logLine); got != tt.persisted {
+				t.Errorf("Console log line present = %v, want %v: %q", got, tt.persisted, console.String())
+			}
+			if !tt.persisted {
+				if len(persisted.logs) != 0 {
+					t.Errorf("Expected %s to be excluded, got %+v", tt.path, persisted.logs)
+				}
+				return
+			}
+			if len(persisted.logs) != 1 {
+				t.Fatalf("Expected one persisted log, got %d", len(persisted.logs))
+			}
+			got := persisted.logs[0]
+			if got.StatusCode != tt.wantStatus || got.BytesSent != tt.wantBytes || got.Path != tt.path ||
+				got.Method != http.MethodGet || got.RequestID != info.RequestID {
+				t.Errorf("Persisted %+v", got)
+			}
+		})
+	}
+}
+
+func TestObserveKeepsOuterRequestID(t *testing.T) {
+	var inner string
+	handler := WithTracing(Observe()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = models.GetRequestID(r.Context())
+	})))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if inner == "" || rec.Header().Get("X-Request-ID") != inner {
+		t.Errorf("Expected one request ID throughout, got header %q and context %q", rec.Header().Get("X-Request-ID"), inner)
+	}
+}
+
+func TestObserveTagsErrorLogs(t *testing.T) {
+	var stored []models.ErrorLog
+	models.SetErrorLogSink(func(entry models.ErrorLog) { stored = append(stored, entry) })
+	t.Cleanup(func() { models.SetErrorLogSink(nil) })
+
+	handler := Observe()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		models.LogWarnWithContext(r.Context(), "Slow query on %s", "Posts")
+		models.LogErrorWithContext(r.Context(), "Failed to load post", errors.New("boom"), "PostID:", 7)
+		models.LogInfoWithContext(r.Context(), "Not stored")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/post/7", nil))
+
+	want := []models.ErrorLog{
+		{Level: models.LogLevelWarn, Message: "Slow query on Posts"},
+		{Level: models.LogLevelError, Message: "Failed to load post PostID: 7: boom"},
+	}
+	if len(stored) != len(want) {
+		t.Fatalf("Expected %d stored logs, got %+v", len(want), stored)
+	}
+	requestID := rec.Header().Get("X-Request-ID")
+	for i, got := range stored {
+		if got.Level != want[i].Level || got.Message != want[i].Message || got.RequestID != requestID {
+			t.Errorf("Expected %s %q for request %s, got %+v", want[i].Level, want[i].Message, requestID, got)
+		}
+	}
+}
+
+func TestObserveRecordsUser(t *testing.T) {
+	a := app.NewApp(testutil.NewTestDB(t), config.Default())
+	userID := models.NewUUIDField()
+	if _, err := a.DB.Exec(`INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype,
+		IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, 'observed', 'observed@example.com', '', '', '', 'user', 0, '', '', 'hash')`, userID); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	if _, err := a.DB.Exec("INSERT INTO Sessions (Token, UserID, Expires) VALUES ('session-observed', ?, ?)",
+		userID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+
+	// WithUser wraps the route inside the mux, as in routes.NewRouter, so Observe
+	// only learns the user through the context it set up
+	mux := http.NewServeMux()
+	mux.Handle("/", WithUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), a))
+	persisted := &recordingLogger{}
+	handler := Observe(PersistRequests(persisted))(mux)
+
+	loggedIn := httptest.NewRequest(http.MethodGet, "/", nil)
+	loggedIn.AddCookie(&http.Cookie{Name: "username", Value: "observed"})
+	loggedIn.AddCookie(&http.Cookie{Name: "session_token", Value: "session-observed"})
+	handler.ServeHTTP(httptest.NewRecorder(), loggedIn)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(persisted.logs) != 2 {
+		t.Fatalf("Expected two persisted logs, got %d", len(persisted.logs))
+	}
+	if persisted.logs[0].UserID != userID {
+		t.Errorf("Expected the logged-in request to record user %s, got %s", userID, persisted.logs[0].UserID)
+	}
+	if persisted.logs[1].UserID != AnonymousUserID {
+		t.Errorf("Expected the anonymous request to record AnonymousUserID, got %s", persisted.logs[1].UserID)
+	}
+}
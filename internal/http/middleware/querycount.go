@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	forumdb "github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// QueryCountHeader carries the number of database queries a request ran
+const QueryCountHeader = "X-DB-Query-Count"
+
+// queryCountWriter sets QueryCountHeader just before the response headers are sent
+type queryCountWriter struct {
+	http.ResponseWriter
+	counter     *forumdb.QueryCounter
+	wroteHeader bool
+}
+
+func (w *queryCountWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set(QueryCountHeader, strconv.FormatInt(w.counter.Count(), 10))
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *queryCountWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// CountQueries counts the database queries each request runs, for finding N+1
+// query patterns. The header reports the count when the response headers were
+// written, so queries run after the handler starts writing are only in the log.
+// When enabled is false requests pass through untouched.
+func CountQueries(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, counter := forumdb.WithQueryCounter(r.Context())
+			next.ServeHTTP(&queryCountWriter{ResponseWriter: w, counter: counter}, r.WithContext(ctx))
+			models.LogInfoWithContext(ctx, "%s %s ran %d database queries", r.Method, r.URL.Path, counter.Count())
+		})
+	}
+}
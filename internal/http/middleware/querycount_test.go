@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	forumdb "github.com/gary-norman/forum/internal/db"
+)
+
+func TestCountQueries(t *testing.T) {
+	db, err := forumdb.OpenMemory("../../../migrations")
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// runs six statements: two plain queries, one exec, two inside a transaction
+	// and one execution of a prepared statement
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users").Scan(&n); err != nil {
+			t.Errorf("Query failed: %v", err)
+		}
+		rows, err := db.QueryContext(ctx, "SELECT ID FROM Channels")
+		if err != nil {
+			t.Errorf("Query failed: %v", err)
+		} else {
+			rows.Close()
+		}
+		if _, err := db.ExecContext(ctx, "DELETE FROM Channels WHERE ID = 0"); err != nil {
+			t.Errorf("Exec failed: %v", err)
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx failed: %v", err)
+		}
+		_ = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM Posts").Scan(&n)
+		_, _ = tx.ExecContext(ctx, "DELETE FROM Posts WHERE ID = 0")
+		if err := tx.Commit(); err != nil {
+			t.Errorf("Commit failed: %v", err)
+		}
+		stmt, err := db.PrepareContext(ctx, "SELECT COUNT(*) FROM Comments")
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		_ = stmt.QueryRowContext(ctx).Scan(&n)
+		stmt.Close()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		CountQueries(true)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := rec.Header().Get(QueryCountHeader); got != "6" {
+			t.Errorf("Expected %s: 6, got %q", QueryCountHeader, got)
+		}
+	})
+
+	t.Run("counts are per request", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		CountQueries(true)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := rec.Header().Get(QueryCountHeader); got != "6" {
+			t.Errorf("Expected a fresh count of 6, got %q", got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		CountQueries(false)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := rec.Header().Get(QueryCountHeader); got != "" {
+			t.Errorf("Expected no %s header when disabled, got %q", QueryCountHeader, got)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// RateLimiter is a token bucket per client: each starts full with limit.Requests
+// tokens, a request spends one, and tokens come back at limit.Requests per limit.Per
+type RateLimiter struct {
+	limit models.RateLimit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// NewRateLimiter returns a limiter enforcing limit; a disabled limit allows everything
+func NewRateLimiter(limit models.RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Allow spends a token from key's bucket. When the bucket is empty it reports
+// false and how long until a token is back.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if !l.limit.Enabled() {
+		return true, 0
+	}
+	burst := float64(l.limit.Requests)
+	perToken := l.limit.Per / time.Duration(l.limit.Requests)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, seen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+float64(now.Sub(b.seen))/float64(perToken))
+	b.seen = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops, at most once per period, buckets idle long enough to have refilled,
+// since a full bucket behaves the same as none. The caller holds l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.limit.Per {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= l.limit.Per {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitKey identifies who a request counts against: the logged-in user when
+// WithUser has run, otherwise the client IP as ClientIP resolved it, so forwarding
+// headers only matter when a trusted proxy sent them
+func rateLimitKey(r *http.Request) string {
+	if user, ok := GetUserFromContext(r.Context()); ok {
+		return "user:" + user.ID.String()
+	}
+	return "ip:" + clientAddr(r)
+}
+
+// WriteTooManyRequests refuses a request with 429, telling the client to retry
+// after retryAfter
+func WriteTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"code":    http.StatusTooManyRequests,
+		"message": "too many requests",
+	})
+}
+
+// RateLimit answers requests over limiter's limit with 429 and a Retry-After.
+// Requests count against the logged-in user when it wraps a handler inside
+// WithUser, and against the client IP otherwise, including everywhere in the
+// global chain, which runs before any session is resolved.
+func RateLimit(limiter *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := rateLimitKey(r)
+			if ok, retryAfter := limiter.Allow(key); !ok {
+				models.LogWarnWithContext(r.Context(), "Rate limited %s on %s %s", key, r.Method, r.URL.Path)
+				WriteTooManyRequests(w, retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(models.RateLimit{Requests: 3, Per: 3 * time.Second})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("Expected request %d of the burst to pass", i+1)
+		}
+	}
+	ok, retryAfter := limiter.Allow("a")
+	if ok || retryAfter != time.Second {
+		t.Fatalf("Expected the fourth request to wait 1s, got ok=%v retry=%v", ok, retryAfter)
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Error("Expected another key to have its own bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, retryAfter := limiter.Allow("a"); ok || retryAfter != 500*time.Millisecond {
+		t.Errorf("Expected half a token back after 500ms, got ok=%v retry=%v", ok, retryAfter)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Error("Expected a token back after 1s")
+	}
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("Expected an idle client to get its full burst back, request %d refused", i+1)
+		}
+	}
+	if ok, _ := limiter.Allow("a"); ok {
+		t.Error("Expected the refilled bucket to hold no more than the burst")
+	}
+	if _, kept := limiter.buckets["b"]; kept {
+		t.Error("Expected the idle bucket to be swept")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	limiter := NewRateLimiter(models.RateLimit{})
+	for i := 0; i < 100; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatal("Expected a disabled limit to allow everything")
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := NewRateLimiter(models.RateLimit{Requests: 1, Per: time.Minute})
+	handler := RateLimit(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(remoteAddr string, user *models.User) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = remoteAddr
+		if user != nil {
+			r = r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if rec := serve("192.0.2.7:5000", nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the first request to pass, got %d", rec.Code)
+	}
+	rec := serve("192.0.2.7:5001", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 for a second request from the same IP, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After: 60, got %q", got)
+	}
+	if rec := serve("192.0.2.8:5000", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected another IP to pass, got %d", rec.Code)
+	}
+
+	// logged-in users are limited by account, wherever they connect from
+	user := &models.User{ID: models.NewUUIDField()}
+	if rec := serve("192.0.2.7:5002", user); rec.Code != http.StatusOK {
+		t.Errorf("Expected a user's first request to pass despite the IP being limited, got %d", rec.Code)
+	}
+	if rec := serve("198.51.100.1:5000", user); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the user's second request to be limited from a new IP, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwarding(t *testing.T) {
+	limiter := NewRateLimiter(models.RateLimit{Requests: 2, Per: time.Minute})
+	handler := Chain(ClientIP(nil), RateLimit(limiter))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = "192.0.2.7:5000"
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		r.Header.Set("X-Real-IP", fmt.Sprintf("203.0.113.%d", i))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		codes = append(codes, rec.Code)
+	}
+	if codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests || codes[3] != http.StatusTooManyRequests {
+		t.Errorf("Expected rotating forwarding headers not to reset the limit, got %v", codes)
+	}
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Expected one bucket for the one client, got %d", len(limiter.buckets))
+	}
+}
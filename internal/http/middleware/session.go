@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+var (
+	// ErrNoSession is returned when the request carries no session cookies at all
+	ErrNoSession = fmt.Errorf("no session: %w", sqlite.ErrUnauthorized)
+	// ErrInvalidSession is returned when the session token does not belong to the named user
+	ErrInvalidSession = fmt.Errorf("invalid session: %w", sqlite.ErrUnauthorized)
+	// ErrSessionExpired is returned once a session is past the expiry recorded when it was issued
+	ErrSessionExpired = fmt.Errorf("session expired: %w", ErrInvalidSession)
+	// ErrFingerprintMismatch is returned when the session binding policy rejects the client
+	ErrFingerprintMismatch = fmt.Errorf("session fingerprint changed: %w", ErrInvalidSession)
+)
+
+// ResolveUser returns the user the request's session belongs to, with the session's
+// tokens and expiry filled in. The username cookie only says which user to look up;
+// the session_token cookie must name one of that user's sessions, so a forged
+// username cookie on its own gets nothing.
+func ResolveUser(r *http.Request, a *app.App) (*models.User, error) {
+	userCookie, err := r.Cookie("username")
+	if err != nil || userCookie.Value == "" {
+		return nil, ErrNoSession
+	}
+	tokenCookie, err := r.Cookie("session_token")
+	if err != nil || tokenCookie.Value == "" {
+		return nil, fmt.Errorf("no session token for %s: %w", userCookie.Value, ErrInvalidSession)
+	}
+
+	user, err := a.Users.GetUserByUsername(r.Context(), userCookie.Value, "ResolveUser")
+	if err != nil {
+		if errors.Is(err, sqlite.ErrNotFound) {
+			return nil, fmt.Errorf("unknown user %s: %w", userCookie.Value, ErrInvalidSession)
+		}
+		return nil, err
+	}
+
+	session, err := a.Sessions.GetByToken(r.Context(), tokenCookie.Value)
+	if errors.Is(err, sqlite.ErrNotFound) {
+		return nil, fmt.Errorf("session token mismatch for %s: %w", user.Username, ErrInvalidSession)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the token must belong to the user the cookie names, not merely to someone
+	if session.UserID != user.ID {
+		return nil, fmt.Errorf("session token mismatch for %s: %w", user.Username, ErrInvalidSession)
+	}
+	// session cookies carry no expiry of their own, so the stored one is authoritative
+	if !time.Now().Before(session.Expires) {
+		return nil, fmt.Errorf("session for %s ended %s: %w", user.Username, session.Expires.Format(time.RFC3339), ErrSessionExpired)
+	}
+
+	if a.Config.SessionBinding.Enabled() &&
+		!FingerprintMatches(a.Config.SessionBinding, session.Fingerprint(), ClientFingerprint(r)) {
+		return nil, fmt.Errorf("rejected session for %s: %w", user.Username, ErrFingerprintMismatch)
+	}
+	user.SessionToken, user.CSRFToken, user.CookiesExpire = session.Token, session.CSRFToken, session.Expires
+	return user, nil
+}
+
+// ClientFingerprint captures the IP and User-Agent a request was made from
+func ClientFingerprint(r *http.Request) models.SessionFingerprint {
+	return models.SessionFingerprint{
+		IP:        clientAddr(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// FingerprintMatches applies the binding policy to the fingerprint stored at
+// login and the current one. A policy that binds a field never matches when
+// nothing was stored for it, so sessions from before binding was enabled must
+// log in again.
+func FingerprintMatches(policy models.SessionBinding, stored, current models.SessionFingerprint) bool {
+	if policy.UserAgent && (stored.UserAgent == "" || stored.UserAgent != current.UserAgent) {
+		return false
+	}
+	if policy.IP {
+		if stored.IP == "" {
+			return false
+		}
+		if policy.IPSubnet {
+			return sameSubnet(stored.IP, current.IP)
+		}
+		return stored.IP == current.IP
+	}
+	return true
+}
+
+// sameSubnet reports whether two addresses share a /24 (IPv4) or /64 (IPv6) network
+func sameSubnet(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return a == b
+	}
+	if v4A, v4B := ipA.To4(), ipB.To4(); v4A != nil || v4B != nil {
+		if v4A == nil || v4B == nil {
+			return false
+		}
+		mask := net.CIDRMask(24, 32)
+		return v4A.Mask(mask).Equal(v4B.Mask(mask))
+	}
+	mask := net.CIDRMask(64, 128)
+	return ipA.Mask(mask).Equal(ipB.Mask(mask))
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// TestFingerprintMatches tests each binding policy against matching and changed fingerprints
+func TestFingerprintMatches(t *testing.T) {
+	login := models.SessionFingerprint{IP: "203.0.113.10", UserAgent: "Firefox/130"}
+	ipOnly := models.SessionBinding{IP: true}
+	uaOnly := models.SessionBinding{UserAgent: true}
+	subnet := models.SessionBinding{IP: true, IPSubnet: true}
+	both := models.SessionBinding{IP: true, UserAgent: true}
+
+	tests := []struct {
+		name    string
+		policy  models.SessionBinding
+		stored  models.SessionFingerprint
+		current models.SessionFingerprint
+		want    bool
+	}{
+		{"disabled ignores everything", models.SessionBinding{}, login, models.SessionFingerprint{IP: "198.51.100.1"}, true},
+		{"ip unchanged", ipOnly, login, login, true},
+		{"ip changed", ipOnly, login, models.SessionFingerprint{IP: "203.0.113.11", UserAgent: "Firefox/130"}, false},
+		{"ip binding ignores user agent", ipOnly, login, models.SessionFingerprint{IP: "203.0.113.10", UserAgent: "curl"}, true},
+		{"user agent unchanged", uaOnly, login, models.SessionFingerprint{IP: "198.51.100.1", UserAgent: "Firefox/130"}, true},
+		{"user agent changed", uaOnly, login, models.SessionFingerprint{IP: "203.0.113.10", UserAgent: "curl"}, false},
+		{"subnet tolerates mobile hop", subnet, login, models.SessionFingerprint{IP: "203.0.113.200"}, true},
+		{"subnet rejects other network", subnet, login, models.SessionFingerprint{IP: "203.0.114.10"}, false},
+		{"subnet ipv6 same /64", subnet, models.SessionFingerprint{IP: "2001:db8::1"}, models.SessionFingerprint{IP: "2001:db8::ffff"}, true},
+		{"subnet ipv6 other /64", subnet, models.SessionFingerprint{IP: "2001:db8::1"}, models.SessionFingerprint{IP: "2001:db8:0:1::1"}, false},
+		{"both must match", both, login, models.SessionFingerprint{IP: "203.0.113.10", UserAgent: "curl"}, false},
+		{"nothing captured at login", both, models.SessionFingerprint{}, login, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FingerprintMatches(tt.policy, tt.stored, tt.current); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestClientFingerprint tests the address is reduced to a bare IP
+func TestClientFingerprint(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.7:51234"
+	r.Header.Set("User-Agent", "Firefox/130")
+	if fp := ClientFingerprint(r); fp.IP != "192.0.2.7" || fp.UserAgent != "Firefox/130" {
+		t.Errorf("Expected 192.0.2.7 and Firefox/130, got %+v", fp)
+	}
+
+	// the client controls forwarding headers, so they count for nothing by default
+	r.Header.Set("X-Forwarded-For", "198.51.100.4")
+	r.Header.Set("X-Real-IP", "198.51.100.4")
+	if fp := ClientFingerprint(r); fp.IP != "192.0.2.7" {
+		t.Errorf("Expected forwarding headers to be ignored, got %q", fp.IP)
+	}
+}
@@ -14,3 +14,10 @@ func WithTimeout(next http.Handler, timeout time.Duration) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// Timeout adapts WithTimeout for use with Chain
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return WithTimeout(next, timeout)
+	}
+}
@@ -6,17 +6,19 @@ import (
 )
 
 type RouteHandler struct {
-	App      *app.App
-	Auth     *h.AuthHandler
-	Channel  *h.ChannelHandler
-	Comment  *h.CommentHandler
-	Home     *h.HomeHandler
-	Post     *h.PostHandler
-	Reaction *h.ReactionHandler
-	Search   *h.SearchHandler
-	Session  *h.SessionHandler
-	User     *h.UserHandler
-	Mod      *h.ModHandler
+	App          *app.App
+	Auth         *h.AuthHandler
+	Channel      *h.ChannelHandler
+	Comment      *h.CommentHandler
+	Home         *h.HomeHandler
+	Post         *h.PostHandler
+	Reaction     *h.ReactionHandler
+	Search       *h.SearchHandler
+	Session      *h.SessionHandler
+	Notification *h.NotificationHandler
+	User         *h.UserHandler
+	Mod          *h.ModHandler
+	Metrics      *h.MetricsHandler
 }
 
 func NewCommentHandler(app *app.App, reaction *h.ReactionHandler) *h.CommentHandler {
@@ -74,6 +76,18 @@ func NewSessionHandler(app *app.App) *h.SessionHandler {
 	}
 }
 
+func NewNotificationHandler(app *app.App) *h.NotificationHandler {
+	return &h.NotificationHandler{
+		App: app,
+	}
+}
+
+func NewMetricsHandler(app *app.App) *h.MetricsHandler {
+	return &h.MetricsHandler{
+		App: app,
+	}
+}
+
 func NewAuthHandler(app *app.App, session *h.SessionHandler) *h.AuthHandler {
 	return &h.AuthHandler{
 		App:     app,
@@ -109,19 +123,23 @@ func NewRouteHandler(app *app.App) *RouteHandler {
 	homeHandler := NewHomeHandler(app, channelHandler, commentHandler, postHandler, reactionHandler)
 	modHandler := NewModHandler(app, channelHandler, userHandler)
 	searchHandler := NewSearchHandler(app)
+	notificationHandler := NewNotificationHandler(app)
+	metricsHandler := NewMetricsHandler(app)
 
 	// Step 3: Return fully wired router
 	return &RouteHandler{
-		App:      app,
-		Auth:     authHandler,
-		Channel:  channelHandler,
-		Comment:  commentHandler,
-		Home:     homeHandler,
-		Post:     postHandler,
-		Reaction: reactionHandler,
-		Search:   searchHandler,
-		Session:  sessionHandler,
-		User:     userHandler,
-		Mod:      modHandler,
+		App:          app,
+		Auth:         authHandler,
+		Channel:      channelHandler,
+		Comment:      commentHandler,
+		Home:         homeHandler,
+		Post:         postHandler,
+		Reaction:     reactionHandler,
+		Search:       searchHandler,
+		Session:      sessionHandler,
+		Notification: notificationHandler,
+		User:         userHandler,
+		Mod:          modHandler,
+		Metrics:      metricsHandler,
 	}
 }
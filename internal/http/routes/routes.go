@@ -3,11 +3,12 @@ package routes
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gary-norman/forum/internal/app"
-	// "github.com/gary-norman/forum/internal/http/handlers"
+	"github.com/gary-norman/forum/internal/http/handlers"
 	mw "github.com/gary-norman/forum/internal/http/middleware"
+	"github.com/gary-norman/forum/internal/service"
+	"github.com/gary-norman/forum/internal/view"
 	"github.com/gary-norman/forum/internal/workers"
 )
 
@@ -15,17 +16,36 @@ func NewRouter(app *app.App, loggerPool *workers.LoggerPool) http.Handler {
 	mux := http.NewServeMux()
 	r := NewRouteHandler(app)
 
+	// Stricter per-route limits on top of the global per-IP one: authLimit is per
+	// client IP, since nobody is logged in yet, and writeLimit is per user, as it
+	// runs inside WithUser
+	authLimit := mw.RateLimit(mw.NewRateLimiter(app.Config.AuthRateLimit))
+	writeLimit := mw.RateLimit(mw.NewRateLimiter(app.Config.WriteRateLimit))
+
 	// Static
 	// handlers.MuxHandler(mux, "assets")
 	// handlers.MuxHandler(mux, "db")
 	mux.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("./assets"))))
-	mux.Handle("/db/", http.StripPrefix("/db/", http.FileServer(http.Dir("./db"))))
+	// ./db holds the database and uploaded images, so nothing under it is served directly;
+	// images are only served with a valid, unexpired signature (see view "signedImage")
+	mux.Handle("/db/", http.NotFoundHandler())
+	// GET patterns also match HEAD, which ImageServer answers with headers only
+	mux.Handle("GET "+view.PrivateImagePrefix, app.URLSigner.Protect(
+		http.StripPrefix(view.PrivateImagePrefix, handlers.ImageServer("./db/userdata/images"))))
+
+	mux.Handle("GET /ready", handlers.Ready(app))
+	mux.Handle("GET /admin/metrics", mw.WithUser(http.HandlerFunc(r.Metrics.GetAdminMetrics), r.App))
 
 	// Core routes
-	mux.HandleFunc("POST /register", r.Auth.Register)
-	mux.HandleFunc("POST /login", r.Auth.Login)
+	mux.Handle("POST /register", authLimit(http.HandlerFunc(r.Auth.Register)))
+	mux.Handle("POST /login", authLimit(http.HandlerFunc(r.Auth.Login)))
 	mux.HandleFunc("POST /logout", r.Auth.Logout)
 	mux.HandleFunc("POST /protected", r.Auth.Protected)
+	mux.Handle("POST /password-reset", authLimit(http.HandlerFunc(r.Auth.RequestPasswordReset)))
+	mux.Handle("POST "+service.PasswordResetPath+"{token}", authLimit(http.HandlerFunc(r.Auth.ResetPassword)))
+	// a logged-in user signing in with a provider links it to their account
+	mux.Handle("GET /auth/{provider}/login", authLimit(http.HandlerFunc(r.Auth.OAuthLogin)))
+	mux.Handle("GET /auth/{provider}/callback", authLimit(mw.WithUser(http.HandlerFunc(r.Auth.OAuthCallback), r.App)))
 	mux.Handle("/", mw.WithUser(http.HandlerFunc(r.Home.RenderIndex), r.App))
 	mux.Handle("/home", mw.WithUser(http.HandlerFunc(r.Home.GetHome), r.App))
 	mux.Handle("/{invalidString}", mw.WithUser(http.HandlerFunc(r.Home.RenderIndex), r.App))
@@ -33,19 +53,45 @@ func NewRouter(app *app.App, loggerPool *workers.LoggerPool) http.Handler {
 	mux.Handle("GET /search", mw.WithUser(http.HandlerFunc(r.Search.Search), r.App))
 	mux.Handle("GET /post/{postId}", mw.WithUser(http.HandlerFunc(r.Post.GetThisPost), r.App))
 	mux.Handle("GET /user/{userId}", mw.WithUser(http.HandlerFunc(r.User.GetThisUser), r.App))
+	mux.Handle("GET /user/liked-posts", mw.WithUser(http.HandlerFunc(r.Reaction.GetLikedPosts), r.App))
+	mux.Handle("GET /api/posts", mw.WithUser(http.HandlerFunc(r.Post.GetFeedPage), r.App))
+	mux.Handle("POST /api/posts/batch", mw.WithUser(http.HandlerFunc(r.Post.GetPostsBatch), r.App))
+	mux.Handle("GET /api/users/{userId}/posts", mw.WithUser(http.HandlerFunc(r.Post.GetUserPostsPage), r.App))
+	mux.Handle("GET /api/channels/{channelId}/posts", mw.WithUser(http.HandlerFunc(r.Post.GetChannelPostsPage), r.App))
+	mux.Handle("GET /api/sessions", mw.WithUser(http.HandlerFunc(r.Session.ListSessions), r.App))
+	mux.Handle("DELETE /api/sessions/{sessionId}", mw.WithUser(http.HandlerFunc(r.Session.RevokeSession), r.App))
+	mux.Handle("GET /api/notifications", mw.WithUser(http.HandlerFunc(r.Notification.ListNotifications), r.App))
+	mux.Handle("GET /api/notifications/unread", mw.WithUser(http.HandlerFunc(r.Notification.UnreadNotifications), r.App))
+	mux.Handle("POST /api/notifications/read", mw.WithUser(http.HandlerFunc(r.Notification.MarkAllNotificationsRead), r.App))
+	mux.Handle("POST /api/notifications/{notificationId}/read", mw.WithUser(http.HandlerFunc(r.Notification.MarkNotificationRead), r.App))
+	mux.Handle("GET /api/posts/{postId}/reactions", mw.WithUser(http.HandlerFunc(r.Reaction.GetPostReactions), r.App))
 	mux.Handle("GET /channel/{channelId}", mw.WithUser(http.HandlerFunc(r.Channel.GetThisChannel), r.App))
 	// mux.Handle("GET /comments/{commentId}", mw.WithUser(http.HandlerFunc(r.Comment.GetThisComment), r.App))
-	mux.Handle("POST /posts/create", mw.WithUser(http.HandlerFunc(r.Post.StorePost), r.App))
-	mux.Handle("POST /channels/create", mw.WithUser(http.HandlerFunc(r.Channel.StoreChannel), r.App))
-	mux.Handle("POST /store-reaction", mw.WithUser(http.HandlerFunc(r.Reaction.StoreReaction), r.App))
+	mux.Handle("POST /posts/create", mw.WithUser(writeLimit(http.HandlerFunc(r.Post.StorePost)), r.App))
+	mux.Handle("POST /channels/create", mw.WithUser(writeLimit(http.HandlerFunc(r.Channel.StoreChannel)), r.App))
+	mux.Handle("POST /store-reaction", mw.WithUser(writeLimit(http.HandlerFunc(r.Reaction.StoreReaction)), r.App))
 	mux.Handle("POST /edituser", mw.WithUser(http.HandlerFunc(r.User.EditUserDetails), r.App))
+	mux.Handle("POST /api/user/avatar", mw.WithUser(http.HandlerFunc(r.User.UpdateAvatar), r.App))
 	mux.Handle("POST /channels/join", mw.WithUser(http.HandlerFunc(r.Channel.StoreMembership), r.App))
 	mux.Handle("POST /channels/add-rules/{channelId}", mw.WithUser(http.HandlerFunc(r.Channel.CreateAndInsertRule), r.App))
-	mux.Handle("POST /cdx/post/{postId}/store-comment", mw.WithUser(http.HandlerFunc(r.Comment.StoreComment), r.App))
+	mux.Handle("POST /channels/filter-terms/{channelId}", mw.WithUser(http.HandlerFunc(r.Channel.StoreFilterTerm), r.App))
+	mux.Handle("POST /channels/privacy/{channelId}", mw.WithUser(http.HandlerFunc(r.Channel.SetChannelPrivacy), r.App))
+	mux.Handle("POST /cdx/post/{postId}/store-comment", mw.WithUser(writeLimit(http.HandlerFunc(r.Comment.StoreComment)), r.App))
+	mux.Handle("PATCH /api/comments/{commentId}", mw.WithUser(writeLimit(http.HandlerFunc(r.Comment.EditComment)), r.App))
+	mux.Handle("GET /api/comments/{commentId}/revisions", mw.WithUser(http.HandlerFunc(r.Comment.GetCommentRevisions), r.App))
 
-	// Apply middleware chain: Tracing (outermost) -> Logging -> Timeout
-	// Order matters! Tracing must be first so request ID exists before logging
-	timeoutHandler := mw.WithTimeout(mux, 10*time.Second)
-	loggingHandler := mw.LoggingEnhanced(loggerPool)(timeoutHandler)
-	return mw.WithTracing(loggingHandler)
+	// Global middleware, outermost first; see mw.Chain for the expected order
+	return mw.Chain(
+		mw.ClientIP(app.Config.TrustedProxies),
+		mw.Observe(
+			mw.TraceSlowRequests(mw.SlowRequestThreshold),
+			mw.LogRequests(app.Config.LogExcludePaths...),
+			mw.PersistRequests(loggerPool, app.Config.LogExcludePaths...),
+		),
+		mw.WithCORS(app.Config.AllowedOrigins),
+		mw.RefuseWhileDraining(app.Draining),
+		mw.RateLimit(mw.NewRateLimiter(app.Config.RateLimit)),
+		mw.CountQueries(app.Config.DebugQueryCount),
+		mw.Timeout(app.Config.RequestTimeout),
+	)(mux)
 }
@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/app"
+	"github.com/gary-norman/forum/internal/config"
+	forumdb "github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/view"
+	"github.com/gary-norman/forum/internal/workers"
+)
+
+func TestNewRouter_ImagesRequireSignature(t *testing.T) {
+	db, err := forumdb.OpenMemory("../../../migrations")
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	a := app.NewApp(db, config.Default())
+
+	// the routes serve files relative to the working directory
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Join("db", "userdata", "images", "post-images"), 0o755); err != nil {
+		t.Fatalf("Failed to create image directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join("db", "userdata", "images", "post-images", "private.jpg"), []byte("image"), 0o644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join("db", "forum_database.db"), []byte("database"), 0o644); err != nil {
+		t.Fatalf("Failed to write database file: %v", err)
+	}
+
+	router := NewRouter(a, workers.NewLoggerPool(1, 10, nil))
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		want   int
+	}{
+		{"unsigned db path", "/db/userdata/images/post-images/private.jpg", http.StatusNotFound},
+		{"database file", "/db/forum_database.db", http.StatusNotFound},
+		{"unsigned private path", view.PrivateImagePrefix + "post-images/private.jpg", http.StatusForbidden},
+		{"signed private path", view.SignedImageURL(a, "post-images", "private.jpg"), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.target)
+			if rec.Code != tt.want {
+				t.Fatalf("Expected %d for %s, got %d", tt.want, tt.target, rec.Code)
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != "image" {
+				t.Errorf("Expected the image body, got %q", rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("signed HEAD", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, view.SignedImageURL(a, "post-images", "private.jpg"), nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+			t.Fatalf("Expected 200 with no body, got %d with %d bytes", rec.Code, rec.Body.Len())
+		}
+		if rec.Header().Get("Content-Length") != "5" || rec.Header().Get("ETag") == "" {
+			t.Errorf("Expected the image's length and ETag, got %v", rec.Header())
+		}
+	})
+}
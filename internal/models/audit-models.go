@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Audit actions recorded in the AuditLog table
+const (
+	AuditModeratorAdded      = "moderator.added"
+	AuditModerationRequested = "moderation.requested"
+	AuditChannelPrivacy      = "channel.privacy_changed"
+	AuditFilterTermAdded     = "filter.term_added"
+	AuditFilterTermRemoved   = "filter.term_removed"
+	AuditFilterModeChanged   = "filter.mode_changed"
+)
+
+// Kinds of thing an AuditEntry.TargetID refers to
+const (
+	AuditTargetUser    = "user"
+	AuditTargetChannel = "channel"
+)
+
+// AuditEntry records one moderation or admin action. ChannelID is 0 when the action
+// is not tied to a channel.
+type AuditEntry struct {
+	ID         int64          `json:"id"`
+	ActorID    UUIDField      `json:"actorId"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"targetType"`
+	TargetID   string         `json:"targetId"`
+	ChannelID  int64          `json:"channelId,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	Created    time.Time      `json:"created"`
+}
@@ -3,17 +3,20 @@ package models
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Login is a user's credentials. None of it is ever encoded into a response.
 type Login struct {
-	Email          string
-	HashedPassword string
-	SessionToken   string
-	CSRFToken      string
+	Email          string `json:"-"`
+	HashedPassword string `json:"-"`
+	SessionToken   string `json:"-"`
+	CSRFToken      string `json:"-"`
 }
 
 type Session struct {
@@ -21,6 +24,65 @@ type Session struct {
 	Expires  time.Time
 }
 
+// UserSession is one logged-in device. The tokens are never sent to the client
+// when sessions are listed.
+type UserSession struct {
+	ID        int64     `json:"id"`
+	UserID    UUIDField `json:"-"`
+	Token     string    `json:"-"`
+	CSRFToken string    `json:"-"`
+	Expires   time.Time `json:"expires"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	Created   time.Time `json:"created"`
+	Current   bool      `json:"current"` // the session the listing was requested from
+}
+
+// Fingerprint returns the client the session was issued to
+func (s UserSession) Fingerprint() SessionFingerprint {
+	return SessionFingerprint{IP: s.IP, UserAgent: s.UserAgent}
+}
+
+// SessionFingerprint is the client a session was issued to, captured at login
+type SessionFingerprint struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionBinding is the policy for which parts of the login fingerprint a
+// session must keep presenting. The zero value binds nothing.
+type SessionBinding struct {
+	IP        bool
+	UserAgent bool
+	// IPSubnet compares IPs by network (/24 for IPv4, /64 for IPv6) rather than
+	// exactly, so mobile clients moving between carrier addresses stay logged in
+	IPSubnet bool
+}
+
+// Enabled reports whether any part of the fingerprint is checked
+func (b SessionBinding) Enabled() bool {
+	return b.IP || b.UserAgent
+}
+
+// ParseSessionBinding builds a policy from a list of "ip", "ip-subnet" and
+// "user-agent" entries, as read from SESSION_BINDING
+func ParseSessionBinding(items []string) (SessionBinding, error) {
+	var b SessionBinding
+	for _, item := range items {
+		switch strings.ToLower(item) {
+		case "ip":
+			b.IP = true
+		case "ip-subnet":
+			b.IP, b.IPSubnet = true, true
+		case "user-agent":
+			b.UserAgent = true
+		default:
+			return SessionBinding{}, fmt.Errorf("unknown session binding %q", item)
+		}
+	}
+	return b, nil
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
@@ -9,6 +9,7 @@ type Channel struct {
 	OwnerID          UUIDField `db:"ownerId"`
 	Name             string    `db:"name"`
 	Avatar           string    `db:"avatar,omitempty"`
+	AvatarURL        string    // signed link to Avatar, set when channels are sent as JSON
 	Banner           string    `db:"banner,omitempty"`
 	Description      string    `db:"description"`
 	Created          time.Time `db:"created"`
@@ -22,7 +23,8 @@ type Channel struct {
 	IsMuted          bool `db:"isMuted"`
 	IsFlagged        bool `db:"isFlagged,omitempty"`
 	Members          int
-	MembersOnline    int
+	// MembersOnline counts members the channel model's presence provider reports as connected
+	MembersOnline int
 }
 
 func (c Channel) TableName() string { return "channels" }
@@ -33,6 +35,15 @@ func (c *Channel) UpdateTimeSince() {
 	c.TimeSince = getTimeSince(c.Created)
 }
 
+// ChannelPrivacyChange records an owner switching a channel between public and private
+type ChannelPrivacyChange struct {
+	ID        int64     `json:"id"`
+	ChannelID int64     `json:"channelId"`
+	ChangedBy UUIDField `json:"changedBy"`
+	Private   bool      `json:"private"`
+	Created   time.Time `json:"created"`
+}
+
 type ChannelPage struct {
 	UserID                 UUIDField
 	CurrentUser            *User
@@ -76,10 +87,17 @@ type OwnedAndJoinedChannels struct {
 	Channels []Channel
 }
 
+// Membership roles: the channel's owner is a member with MembershipRoleOwner
+const (
+	MembershipRoleMember = "member"
+	MembershipRoleOwner  = "owner"
+)
+
 type Membership struct {
 	ID        int64     `db:"id"`
 	UserID    UUIDField `db:"userId"`
 	ChannelID int64     `db:"channelId"`
+	Role      string    `db:"role"`
 	Created   time.Time `db:"created"`
 	Updated   time.Time `db:"updated"`
 }
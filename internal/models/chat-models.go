@@ -2,9 +2,24 @@ package models
 
 import "time"
 
+// ChatType is the kind of a chat. The values match the Chats.Type CHECK constraint.
+type ChatType string
+
+const (
+	// ChatTypeBuddy is a one-to-one chat; Chat.Buddy is the other user
+	ChatTypeBuddy ChatType = "buddy"
+	// ChatTypeGroup is a chat attached to a group; Chat.Group is set
+	ChatTypeGroup ChatType = "group"
+)
+
+// Valid reports whether t is one of the known chat types
+func (t ChatType) Valid() bool {
+	return t == ChatTypeBuddy || t == ChatTypeGroup
+}
+
 type Chat struct {
 	ID         UUIDField     `json:"id"`
-	ChatType   string        `json:"type"`
+	ChatType   ChatType      `json:"type"`
 	Name       string        `json:"name"`
 	Created    time.Time     `json:"created"`
 	LastActive time.Time     `json:"last_active"`
@@ -19,9 +34,27 @@ type Group struct {
 }
 
 type ChatMessage struct {
-	ID      UUIDField `json:"id"`
-	ChatID  UUIDField `json:"chat_id"`
-	Sender  *User     `json:"sender"`
-	Created time.Time `json:"created"`
-	Content string    `json:"content"`
+	ID     UUIDField `json:"id"`
+	ChatID UUIDField `json:"chat_id"`
+	// Sequence increases by one per message within a chat
+	Sequence int64     `json:"sequence"`
+	Sender   *User     `json:"sender"`
+	Created  time.Time `json:"created"`
+	Content  string    `json:"content"`
+	// Reactions summarises the emoji left on the message, in the order first used
+	Reactions []MessageReactionSummary `json:"reactions,omitempty"`
+}
+
+// MessageReactionSummary is one emoji on a chat message and who left it
+type MessageReactionSummary struct {
+	Emoji   string      `json:"emoji"`
+	Count   int         `json:"count"`
+	UserIDs []UUIDField `json:"user_ids"`
+}
+
+// ChatReadState is how far one participant has read in a chat. LastReadSequence is
+// the Sequence of the last message they have read, 0 if none.
+type ChatReadState struct {
+	UserID           UUIDField `json:"user_id"`
+	LastReadSequence int64     `json:"last_read_sequence"`
 }
@@ -39,3 +39,13 @@ func (c *Comment) React(likes, dislikes int) {
 func (c *Comment) UpdateTimeSince() {
 	c.TimeSince = getTimeSince(c.Created)
 }
+
+// CommentRevision is an earlier version of a comment: the text an edit replaced,
+// who made that edit and when
+type CommentRevision struct {
+	ID        int64     `json:"id"`
+	CommentID int64     `json:"comment_id"`
+	Content   string    `json:"content"`
+	EditorID  UUIDField `json:"editor_id"`
+	Created   time.Time `json:"created"`
+}
@@ -33,15 +33,20 @@ func React(r Reactable, likes, dislikes int) {
 }
 
 // getTimeSince returns a human-readable string representing the time elapsed since the provided creation time.
+// Both sides are compared in UTC, which is how SQLite stores CURRENT_TIMESTAMP values.
 func getTimeSince(created time.Time) string {
-	now := time.Now()
-	hours := now.Sub(created).Hours()
+	return timeSinceAt(created, time.Now().UTC())
+}
+
+// timeSinceAt is getTimeSince measured against now instead of the current time
+func timeSinceAt(created, now time.Time) string {
+	elapsed := now.UTC().Sub(created.UTC())
 	var timeSince string
-	if hours > 24 {
+	if hours := elapsed.Hours(); hours > 24 {
 		timeSince = fmt.Sprintf("%.0f days ago", hours/24)
 	} else if hours > 1 {
 		timeSince = fmt.Sprintf("%.0f hours ago", hours)
-	} else if minutes := now.Sub(created).Minutes(); minutes > 1 {
+	} else if minutes := elapsed.Minutes(); minutes > 1 {
 		timeSince = fmt.Sprintf("%.0f minutes ago", minutes)
 	} else {
 		timeSince = "just now"
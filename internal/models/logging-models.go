@@ -17,6 +17,7 @@ type RequestLog struct {
 	UserAgent  string    `db:"userAgent"`
 	Referer    string    `db:"referer"`
 	BytesSent  int64     `db:"bytesSent"`
+	RequestID  string    `db:"requestId"`
 }
 
 func (r RequestLog) TableName() string { return "requestLogs" }
@@ -33,6 +34,7 @@ type ErrorLog struct {
 	RequestPath string    `db:"requestPath"`
 	UserID      UUIDField `db:"userId"`
 	Context     string    `db:"context"` // JSON
+	RequestID   string    `db:"requestId"` // empty when logged outside a request
 }
 
 func (e ErrorLog) TableName() string { return "errorLogs" }
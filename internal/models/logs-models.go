@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,6 +27,54 @@ func GetRequestID(ctx context.Context) string {
 	return ""
 }
 
+// ErrorLogSink receives an ErrorLogs row for each warning and error logged with a
+// context, typically queueing it for the database
+type ErrorLogSink func(ErrorLog)
+
+var errorLogSink atomic.Pointer[ErrorLogSink]
+
+// SetErrorLogSink makes LogWarnWithContext and LogErrorWithContext also pass sink an
+// ErrorLog carrying the context's request ID, so stored errors can be matched to
+// the request's RequestLogs row. A nil sink keeps them on the console only, which
+// is the default.
+func SetErrorLogSink(sink ErrorLogSink) {
+	if sink == nil {
+		errorLogSink.Store(nil)
+		return
+	}
+	errorLogSink.Store(&sink)
+}
+
+// persistLog hands the installed ErrorLogSink, if any, a row for a context log line
+func persistLog(ctx context.Context, level, msg string, err error, args ...any) {
+	sink := errorLogSink.Load()
+	if sink == nil {
+		return
+	}
+	message := plainMessage(msg, args...)
+	if err != nil {
+		message += ": " + err.Error()
+	}
+	(*sink)(ErrorLog{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   message,
+		RequestID: GetRequestID(ctx),
+	})
+}
+
+// plainMessage renders a log line without colors. Callers pass args either for
+// verbs in msg or, with LogErrorWithContext, as trailing label/value pairs.
+func plainMessage(msg string, args ...any) string {
+	if len(args) == 0 {
+		return msg
+	}
+	if strings.Contains(msg, "%") {
+		return fmt.Sprintf(msg, args...)
+	}
+	return strings.TrimSuffix(fmt.Sprintln(append([]any{msg}, args...)...), "\n")
+}
+
 // LogInfo logs an info message with timestamp, icon, and color
 // Message is neutral, args are blue
 func LogInfo(msg string, args ...any) {
@@ -94,6 +144,7 @@ func LogInfoWithContext(ctx context.Context, msg string, args ...any) {
 // LogWarnWithContext logs a warning message with request ID from context
 // Request ID is colored orange, message is neutral, args are blue
 func LogWarnWithContext(ctx context.Context, msg string, args ...any) {
+	persistLog(ctx, LogLevelWarn, msg, nil, args...)
 	requestID := GetRequestID(ctx)
 	timestamp := time.Now().Format("15:04:05")
 	formattedMsg := formatMessageWithBlueArgs(msg, args...)
@@ -112,6 +163,7 @@ func LogWarnWithContext(ctx context.Context, msg string, args ...any) {
 // LogErrorWithContext logs an error message with request ID from context
 // Request ID is colored red, message is neutral, args are blue, error is neutral
 func LogErrorWithContext(ctx context.Context, msg string, err error, args ...any) {
+	persistLog(ctx, LogLevelError, msg, err, args...)
 	requestID := GetRequestID(ctx)
 	timestamp := time.Now().Format("15:04:05")
 	formattedMsg := formatMessageWithBlueArgs(msg, args...)
@@ -4,29 +4,29 @@ import (
 	"time"
 )
 
-type Notification struct {
-	ID           int64     `db:"id"`
-	Notification string    `db:"notification"`
-	Created      time.Time `db:"created"`
-	Updated      time.Time `db:"updated"`
-	Read         bool      `db:"read"`
-	Archived     bool      `db:"archived"`
-}
-
-func (n Notification) TableName() string { return "notifications" }
-func (n Notification) GetID() int64      { return n.ID }
-func (n *Notification) SetID(id int64)   { n.ID = id }
+// Kinds of Notification
+const (
+	NotificationMention  = "mention"
+	NotificationReply    = "reply"
+	NotificationFollow   = "follow"
+	NotificationReaction = "reaction"
+)
 
-type NotificationUsers struct {
-	ID             int64 `db:"id"`
-	UserID         int64 `db:"userId"`
-	NotificationID int64 `db:"notificationId"`
+// Notification tells UserID that ActorID mentioned them, replied to them, followed
+// them or reacted to their post or comment. PostID and CommentID name what it is
+// about, when anything.
+type Notification struct {
+	ID        int64     `json:"id"`
+	UserID    UUIDField `json:"-"`
+	ActorID   UUIDField `json:"actor_id"`
+	Actor     string    `json:"actor"`
+	Kind      string    `json:"kind"`
+	PostID    *int64    `json:"post_id,omitempty"`
+	CommentID *int64    `json:"comment_id,omitempty"`
+	Created   time.Time `json:"created"`
+	Read      bool      `json:"read"`
 }
 
-func (nu NotificationUsers) TableName() string { return "notificationUsers" }
-func (nu NotificationUsers) GetID() int64      { return nu.ID }
-func (nu *NotificationUsers) SetID(id int64)   { nu.ID = id }
-
 type Notify struct {
 	BadPass      string
 	RegisterOk   string
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -39,6 +43,46 @@ func (p *Post) React(likes, dislikes int) {
 	p.Dislikes += dislikes
 }
 
+// NoImage is stored in Posts.Images when a post has no image
+const NoImage = "noimage"
+
+// ErrInvalidImage is returned for an image name the upload pipeline could not have produced
+var ErrInvalidImage = errors.New("invalid image name")
+
+// uploadedImageName matches the names uploads are saved under: a 16-byte
+// URL-safe base64 token (see GenerateToken) followed by the file extension
+var uploadedImageName = regexp.MustCompile(`^[A-Za-z0-9_-]{22}==\.[A-Za-z0-9]{1,5}$`)
+
+// ParseImages splits a comma-separated Images value into image names, checking each
+// against the names uploads are saved under. An empty value or NoImage has none.
+func ParseImages(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == NoImage {
+		return nil, nil
+	}
+	names := strings.Split(value, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if !uploadedImageName.MatchString(names[i]) {
+			return nil, fmt.Errorf("%q: %w", names[i], ErrInvalidImage)
+		}
+	}
+	return names, nil
+}
+
+// SanitizeImages normalises Images to a comma-separated list of uploaded image names,
+// or NoImage if there are none. An invalid value is replaced with NoImage so it is
+// never rendered, and the error is returned.
+func (p *Post) SanitizeImages() error {
+	names, err := ParseImages(p.Images)
+	if err != nil || len(names) == 0 {
+		p.Images = NoImage
+		return err
+	}
+	p.Images = strings.Join(names, ",")
+	return nil
+}
+
 type PostPage struct {
 	UserID      UUIDField
 	CurrentUser *User
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPostSanitizeImages(t *testing.T) {
+	uploaded := GenerateToken(16) + ".jpg"
+	other := GenerateToken(16) + ".png"
+
+	tests := []struct {
+		name    string
+		images  string
+		want    string
+		invalid bool
+	}{
+		{"empty", "", NoImage, false},
+		{"no image", NoImage, NoImage, false},
+		{"uploaded image", uploaded, uploaded, false},
+		{"list is normalised", " " + uploaded + " , " + other, uploaded + "," + other, false},
+		{"path traversal", "../../forum_database.db", NoImage, true},
+		{"directory", "post-images/" + uploaded, NoImage, true},
+		{"external url", "https://evil.example.com/x.png", NoImage, true},
+		{"markup", `x" onerror="alert(1)`, NoImage, true},
+		{"one bad entry spoils the list", uploaded + ",../secret.png", NoImage, true},
+		{"empty entry", uploaded + ",", NoImage, true},
+		{"token without extension", GenerateToken(16), NoImage, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := Post{Images: tt.images}
+			err := post.SanitizeImages()
+			if invalid := errors.Is(err, ErrInvalidImage); invalid != tt.invalid {
+				t.Errorf("Expected invalid=%v, got %v", tt.invalid, err)
+			}
+			if post.Images != tt.want {
+				t.Errorf("Expected Images %q, got %q", tt.want, post.Images)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Requests requests per Per, in bursts of up to Requests. The
+// zero value allows everything.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Enabled reports whether the limit restricts anything
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// ParseRateLimit reads a limit written as "requests/period", such as "10/1m", or
+// "off" for no limit
+func ParseRateLimit(value string) (RateLimit, error) {
+	if strings.EqualFold(value, "off") {
+		return RateLimit{}, nil
+	}
+	count, period, found := strings.Cut(value, "/")
+	if !found {
+		return RateLimit{}, fmt.Errorf("rate limit %q is not requests/period", value)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: requests must be a positive integer", value)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: period must be a positive duration", value)
+	}
+	return RateLimit{Requests: n, Per: d}, nil
+}
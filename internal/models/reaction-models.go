@@ -1,28 +1,155 @@
 package models
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
 
 type Reaction struct {
-	ID               int64     `db:"id"`
-	Liked            bool      `db:"liked"`
-	Disliked         bool      `db:"disliked"`
-	Created          time.Time `db:"created"`
-	Updated          time.Time `db:"updated"`
-	AuthorID         UUIDField `db:"authorId"`
-	PostID           int64
-	CommentID        int64
-	ReactedPostID    *int64 `db:"reactedPostId,omitempty"`
-	ReactedCommentID *int64 `db:"reactedCommentId,omitempty"`
+	ID       int64          `db:"id"`
+	Liked    bool           `db:"liked"`
+	Disliked bool           `db:"disliked"`
+	Created  time.Time      `db:"created"`
+	Updated  time.Time      `db:"updated"`
+	AuthorID UUIDField      `db:"authorId"`
+	Target   ReactionTarget `db:"-"`
 }
 
+// ReactionTarget is the post or comment a reaction is attached to
+type ReactionTarget struct {
+	Type string // ReactionTargetPost or ReactionTargetComment
+	ID   int64
+}
+
+// PostTarget returns the target for a reaction on post id
+func PostTarget(id int64) ReactionTarget {
+	return ReactionTarget{Type: ReactionTargetPost, ID: id}
+}
+
+// CommentTarget returns the target for a reaction on comment id
+func CommentTarget(id int64) ReactionTarget {
+	return ReactionTarget{Type: ReactionTargetComment, ID: id}
+}
+
+// Valid reports whether t names a known target type and a real ID
+func (t ReactionTarget) Valid() bool {
+	return (t.Type == ReactionTargetPost || t.Type == ReactionTargetComment) && t.ID > 0
+}
+
+// ReactionTargetFromIDs converts the post/comment ID pair used on the wire and in
+// templates, where zero means "not this one". ok is false unless exactly one is set.
+func ReactionTargetFromIDs(postID, commentID int64) (target ReactionTarget, ok bool) {
+	switch {
+	case postID > 0 && commentID == 0:
+		return PostTarget(postID), true
+	case commentID > 0 && postID == 0:
+		return CommentTarget(commentID), true
+	}
+	return ReactionTarget{}, false
+}
+
+// ErrInvalidReactionInput is returned when a reaction payload decodes but does not
+// describe a reaction that can be stored
+var ErrInvalidReactionInput = errors.New("invalid reaction")
+
 type ReactionInput struct {
 	Liked            bool   `json:"liked"`
 	Disliked         bool   `json:"disliked"`
-	AuthorID         string `json:"authorId"` // Convert manually
+	AuthorID         string `json:"authorId"`
 	ReactedPostID    *int64 `json:"reactedPostId,omitempty"`
 	ReactedCommentID *int64 `json:"reactedCommentId,omitempty"`
+	// WithCounts asks for the target's updated like and dislike totals in the response
+	WithCounts bool `json:"withCounts,omitempty"`
+
+	author UUIDField
+}
+
+// UnmarshalJSON decodes a reaction payload and rejects it if it has unknown fields or
+// does not name exactly one target with a positive ID and a well-formed author UUID
+func (in *ReactionInput) UnmarshalJSON(data []byte) error {
+	type plain ReactionInput
+	var decoded plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&decoded); err != nil {
+		return err
+	}
+	input := ReactionInput(decoded)
+
+	switch {
+	case input.ReactedPostID != nil && input.ReactedCommentID != nil:
+		return fmt.Errorf("%w: send only one of reactedPostId or reactedCommentId", ErrInvalidReactionInput)
+	case input.ReactedPostID == nil && input.ReactedCommentID == nil:
+		return fmt.Errorf("%w: one of reactedPostId or reactedCommentId is required", ErrInvalidReactionInput)
+	}
+	if _, ok := input.Target(); !ok {
+		return fmt.Errorf("%w: target ID must be a positive integer", ErrInvalidReactionInput)
+	}
+
+	author, err := UUIDFieldFromString(input.AuthorID)
+	if err != nil {
+		return fmt.Errorf("%w: authorId %q is not a valid UUID", ErrInvalidReactionInput, input.AuthorID)
+	}
+	input.author = author
+
+	*in = input
+	return nil
+}
+
+// Author returns the reacting user's ID as parsed by UnmarshalJSON
+func (in ReactionInput) Author() UUIDField {
+	return in.author
+}
+
+// Target returns the post or comment the input reacts to; ok is false unless exactly one was sent
+func (in ReactionInput) Target() (target ReactionTarget, ok bool) {
+	var postID, commentID int64
+	if in.ReactedPostID != nil {
+		postID = *in.ReactedPostID
+	}
+	if in.ReactedCommentID != nil {
+		commentID = *in.ReactedCommentID
+	}
+	return ReactionTargetFromIDs(postID, commentID)
 }
 
 func (r Reaction) TableName() string { return "reactions" }
 func (r Reaction) GetID() int64      { return r.ID }
 func (r *Reaction) SetID(id int64)   { r.ID = id }
+
+// Reaction event kinds, actions and target types as stored in ReactionEvents
+const (
+	ReactionKindLike    = "like"
+	ReactionKindDislike = "dislike"
+
+	ReactionActionAdd    = "add"
+	ReactionActionRemove = "remove"
+
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+// ReactionEvent is one entry in the append-only history of reaction toggles
+type ReactionEvent struct {
+	ID         int64     `json:"id"`
+	AuthorID   UUIDField `json:"authorId"`
+	TargetType string    `json:"targetType"`
+	TargetID   int64     `json:"targetId"`
+	Kind       string    `json:"kind"`
+	Action     string    `json:"action"`
+	Created    time.Time `json:"created"`
+}
+
+// ReactionDetail is one user's reaction to a post, with only the public parts of
+// their profile
+type ReactionDetail struct {
+	UserID    UUIDField `json:"userId"`
+	Username  string    `json:"username"`
+	Avatar    string    `json:"-"`
+	AvatarURL string    `json:"avatarUrl"`
+	Kind      string    `json:"kind"`
+	Created   time.Time `json:"created"`
+}
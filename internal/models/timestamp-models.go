@@ -0,0 +1,36 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampLayout is how timestamps are stored and exchanged: RFC3339 in UTC with a
+// fixed nine-digit fraction, so comparing two stored values as text orders them in time
+const TimestampLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// timestampInputLayouts are the formats ParseTimestamp accepts. Besides RFC3339 they
+// cover SQLite's DateTime('now') output and what go-sqlite3 writes for a time.Time.
+var timestampInputLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// FormatTimestamp renders t in UTC using TimestampLayout
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampLayout)
+}
+
+// ParseTimestamp parses a stored or user-supplied timestamp and returns it in UTC.
+// Values without a zone offset are taken to be UTC already.
+func ParseTimestamp(value string) (time.Time, error) {
+	for _, layout := range timestampInputLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q: expected RFC3339, e.g. %s", value, TimestampLayout)
+}
@@ -12,6 +12,7 @@ type User struct {
 	Username string    `db:"username"`
 	Login
 	Avatar        string    `db:"avatar,omitempty"`
+	AvatarURL     string    // signed link to Avatar, set when users are sent as JSON
 	Banner        string    `db:"banner,omitempty"`
 	Description   string    `db:"description,omitempty"`
 	Usertype      string    `db:"usertype"`
@@ -24,6 +25,15 @@ type User struct {
 	CookiesExpire time.Time `db:"cookiesexpire"`
 }
 
+// UsertypeAdmin is the Usertype of site administrators. Registration always
+// creates regular users; admins are promoted directly in the database.
+const UsertypeAdmin = "admin"
+
+// IsAdmin reports whether u administers the whole site
+func (u *User) IsAdmin() bool {
+	return u != nil && u.Usertype == UsertypeAdmin
+}
+
 func (u User) TableName() string   { return "users" }
 func (u User) GetID() UUIDField    { return u.ID }
 func (u *User) SetID(id UUIDField) { u.ID = id }
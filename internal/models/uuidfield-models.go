@@ -116,6 +116,12 @@ func (u *NullableUUIDField) Scan(value any) error {
 		u.Valid = false
 		return nil
 	case []byte:
+		// UUIDField.Value stores the raw 16 bytes; older rows may hold the text form
+		if len(v) == len(uuid.UUID{}) {
+			copy(u.UUID.UUID[:], v)
+			u.Valid = true
+			return nil
+		}
 		parsed, err := uuid.ParseBytes(v)
 		if err != nil {
 			return err
@@ -0,0 +1,69 @@
+// Package moderation contains automatic content checks applied before content is stored.
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Filter modes, chosen per channel
+const (
+	ModeReject = "reject" // matching content is refused
+	ModeFlag   = "flag"   // matching content is stored but flagged for moderator review
+)
+
+// FlagTypeContentFilter is the Flags.FlagType used for flags raised by a ContentFilter
+const FlagTypeContentFilter = "content_filter"
+
+// ContentFilter decides whether text may be posted. reason explains a rejection.
+type ContentFilter interface {
+	Check(text string) (allowed bool, reason string)
+}
+
+// WordListFilter rejects text containing any of its terms. Plain words match whole
+// words only ("ass" does not match "class"); terms containing a dot, slash or space
+// ("spam.com", "http://", "buy now") match anywhere in the text.
+type WordListFilter struct {
+	words     map[string]struct{}
+	fragments []string
+}
+
+// NewWordListFilter builds a case-insensitive filter from terms
+func NewWordListFilter(terms []string) *WordListFilter {
+	f := &WordListFilter{words: make(map[string]struct{})}
+	for _, t := range terms {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if strings.ContainsAny(t, "./ ") {
+			f.fragments = append(f.fragments, t)
+		} else {
+			f.words[t] = struct{}{}
+		}
+	}
+	return f
+}
+
+// Check implements ContentFilter
+func (f *WordListFilter) Check(text string) (bool, string) {
+	lower := strings.ToLower(text)
+	for _, frag := range f.fragments {
+		if strings.Contains(lower, frag) {
+			return false, fmt.Sprintf("content contains banned term %q", frag)
+		}
+	}
+	if len(f.words) == 0 {
+		return true, ""
+	}
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if _, banned := f.words[w]; banned {
+			return false, fmt.Sprintf("content contains banned word %q", w)
+		}
+	}
+	return true, ""
+}
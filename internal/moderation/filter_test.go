@@ -0,0 +1,51 @@
+package moderation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWordListFilter_Allowed(t *testing.T) {
+	f := NewWordListFilter([]string{"spam", "scam.example"})
+
+	tests := []string{
+		"A perfectly normal post",
+		"Spammy is a different word", // whole-word match only
+		"",
+	}
+	for _, text := range tests {
+		if allowed, reason := f.Check(text); !allowed {
+			t.Errorf("Expected %q to be allowed, got rejected: %s", text, reason)
+		}
+	}
+}
+
+func TestWordListFilter_Blocked(t *testing.T) {
+	f := NewWordListFilter([]string{"Spam", " scam.example ", "http://"})
+
+	tests := []struct {
+		text string
+		term string
+	}{
+		{"this is SPAM!", "spam"},
+		{"visit www.Scam.Example/deal", "scam.example"},
+		{"link: http://anything", "http://"},
+	}
+	for _, tt := range tests {
+		allowed, reason := f.Check(tt.text)
+		if allowed {
+			t.Errorf("Expected %q to be blocked", tt.text)
+			continue
+		}
+		if !strings.Contains(reason, tt.term) {
+			t.Errorf("Expected reason to mention %q, got %q", tt.term, reason)
+		}
+	}
+}
+
+func TestWordListFilter_EmptyListAllowsEverything(t *testing.T) {
+	var f ContentFilter = NewWordListFilter(nil)
+	if allowed, _ := f.Check("anything goes http://x.y"); !allowed {
+		t.Error("Expected an empty filter to allow all content")
+	}
+}
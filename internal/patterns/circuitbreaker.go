@@ -15,6 +15,19 @@ const (
 	StateHalfOpen              // Testing if service recovered
 )
 
+// String names the state as it is reported by monitoring
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
 var (
 	ErrCircuitOpen     = errors.New("circuit breaker is open")
 	ErrTooManyRequests = errors.New("too many requests in half-open state")
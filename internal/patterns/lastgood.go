@@ -0,0 +1,40 @@
+package patterns
+
+import (
+	"sync"
+	"time"
+)
+
+// LastGood remembers the most recent successful result of an operation so callers
+// can fall back to it, for up to ttl after it was stored, while the operation fails
+type LastGood[T any] struct {
+	ttl      time.Duration
+	value    T
+	storedAt time.Time
+	stored   bool
+	now      func() time.Time
+	mu       sync.RWMutex
+}
+
+// NewLastGood creates an empty LastGood whose values expire ttl after being stored
+func NewLastGood[T any](ttl time.Duration) *LastGood[T] {
+	return &LastGood[T]{ttl: ttl, now: time.Now}
+}
+
+// Store replaces the remembered value
+func (c *LastGood[T]) Store(value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value, c.storedAt, c.stored = value, c.now(), true
+}
+
+// Load returns the remembered value and when it was stored. ok is false when
+// nothing has been stored yet or the value is older than ttl.
+func (c *LastGood[T]) Load() (value T, storedAt time.Time, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.stored || c.now().Sub(c.storedAt) > c.ttl {
+		return value, storedAt, false
+	}
+	return c.value, c.storedAt, true
+}
@@ -0,0 +1,40 @@
+package patterns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLastGood(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewLastGood[[]string](time.Minute)
+	c.now = func() time.Time { return now }
+
+	if _, _, ok := c.Load(); ok {
+		t.Fatal("Expected an empty cache to miss")
+	}
+
+	c.Store([]string{"a"})
+	value, storedAt, ok := c.Load()
+	if !ok || len(value) != 1 || value[0] != "a" {
+		t.Fatalf("Expected the stored value, got %v (ok=%v)", value, ok)
+	}
+	if !storedAt.Equal(now) {
+		t.Errorf("Expected storedAt %v, got %v", now, storedAt)
+	}
+
+	now = now.Add(time.Minute)
+	if _, _, ok := c.Load(); !ok {
+		t.Error("Expected a value exactly ttl old to still be served")
+	}
+
+	now = now.Add(time.Second)
+	if _, _, ok := c.Load(); ok {
+		t.Error("Expected a value older than ttl to miss")
+	}
+
+	c.Store([]string{"b"})
+	if value, _, ok := c.Load(); !ok || value[0] != "b" {
+		t.Errorf("Expected a fresh store to be served, got %v (ok=%v)", value, ok)
+	}
+}
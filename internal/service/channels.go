@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// ChannelService creates channels together with the rows that must exist alongside them
+type ChannelService struct {
+	Channels *sqlite.ChannelModel
+}
+
+// Create inserts channel and joins its owner to it with the owner role, atomically.
+// On success channel.ID holds the new channel's ID.
+func (s *ChannelService) Create(ctx context.Context, channel *models.Channel) error {
+	if err := s.Channels.InsertWithOwner(ctx, channel); err != nil {
+		return fmt.Errorf("failed to create channel %s: %w", channel.Name, err)
+	}
+	return nil
+}
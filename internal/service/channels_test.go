@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	forumdb "github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+func TestChannelServiceCreate(t *testing.T) {
+	db, err := forumdb.OpenMemory("../../migrations")
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &ChannelService{Channels: &sqlite.ChannelModel{DB: db}}
+	memberships := &sqlite.MembershipModel{DB: db}
+	ctx := context.Background()
+
+	ownerID := models.NewUUIDField()
+	mustExec(t, db, `INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype, IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, 'owner', 'owner@example.com', '', '', '', 'user', 0, '', '', 'hash')`, ownerID)
+
+	channel := &models.Channel{OwnerID: ownerID, Name: "general", Avatar: "noimage", Banner: "default.png"}
+	if err := s.Create(ctx, channel); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if channel.ID == 0 {
+		t.Fatal("Expected Create to set the channel ID")
+	}
+
+	owned, err := memberships.UserMemberships(ctx, ownerID)
+	if err != nil {
+		t.Fatalf("UserMemberships failed: %v", err)
+	}
+	if len(owned) != 1 || owned[0].ChannelID != channel.ID || owned[0].Role != models.MembershipRoleOwner {
+		t.Errorf("Expected the owner to be a member of channel %d with the owner role, got %+v", channel.ID, owned)
+	}
+
+	t.Run("joining again keeps the owner role", func(t *testing.T) {
+		created, err := memberships.Insert(ctx, ownerID, channel.ID)
+		if err != nil || created {
+			t.Fatalf("Expected no new membership, got created=%v, %v", created, err)
+		}
+		owned, _ := memberships.UserMemberships(ctx, ownerID)
+		if len(owned) != 1 || owned[0].Role != models.MembershipRoleOwner {
+			t.Errorf("Expected the owner role to survive a rejoin, got %+v", owned)
+		}
+	})
+
+	t.Run("failed insert leaves no membership", func(t *testing.T) {
+		duplicate := &models.Channel{OwnerID: ownerID, Name: "general"}
+		if err := s.Create(ctx, duplicate); !errors.Is(err, sqlite.ErrConflict) {
+			t.Fatalf("Expected ErrConflict for a duplicate name, got %v", err)
+		}
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM Memberships").Scan(&count); err != nil || count != 1 {
+			t.Errorf("Expected only the first channel's membership, got %d (%v)", count, err)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// Email is a plain-text message to one recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// EmailSender delivers emails to users
+type EmailSender interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// LogSender writes emails to the log instead of sending them; used when no SMTP
+// server is configured, so reset links can still be followed in development
+type LogSender struct{}
+
+// Send implements EmailSender
+func (LogSender) Send(ctx context.Context, email Email) error {
+	models.LogInfoWithContext(ctx, "Email to %s not sent (SMTP_ADDR not set): %s\n%s", email.To, email.Subject, email.Body)
+	return nil
+}
+
+// SMTPSender sends emails through an SMTP server, authenticating with PLAIN auth
+// when a username is set
+type SMTPSender struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+// Send implements EmailSender
+func (s *SMTPSender) Send(_ context.Context, email Email) error {
+	// header injection: nothing user-supplied may start a new header line
+	if strings.ContainsAny(email.To+email.Subject, "\r\n") {
+		return fmt.Errorf("refusing to send email with a line break in its headers")
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address %q: %w", s.Addr, err)
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	msg := "From: " + s.From + "\r\n" +
+		"To: " + email.To + "\r\n" +
+		"Subject: " + email.Subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + strings.ReplaceAll(email.Body, "\n", "\r\n")
+	if err := smtp.SendMail(s.Addr, auth, s.From, []string{email.To}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", email.To, err)
+	}
+	return nil
+}
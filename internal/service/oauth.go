@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gary-norman/forum/internal/auth/oauth"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// ErrUnverifiedEmail is returned for a provider account without a verified email
+// address, which cannot start a new user
+var ErrUnverifiedEmail = errors.New("oauth account has no verified email address")
+
+// ErrEmailRegistered is returned when a provider account that has not been linked
+// has the email of an existing user. Local emails are never verified, so the match
+// proves nothing: whoever registered it may not own the address, and would keep
+// password access to an account the provider's owner goes on to use. The user must
+// log in with their password and link the provider account with Link instead.
+var ErrEmailRegistered = errors.New("an account is already registered to that email address")
+
+// Bounds of the usernames OAuthService makes up, matching those registration accepts
+const (
+	minUsernameLength = 5
+	maxUsernameLength = 16
+)
+
+// OAuthService resolves a provider account that has signed in to the local user it
+// logs in as, creating one the first time it is seen, and links provider accounts
+// to users who are already logged in
+type OAuthService struct {
+	Users *sqlite.UserModel
+}
+
+// Login returns the user profile, an account at provider, logs in as. An account seen
+// before logs in as the user it was linked to; otherwise a new user is created for it
+// with a username based on its name. An account whose email is unverified returns
+// ErrUnverifiedEmail, and one whose email an existing user has returns
+// ErrEmailRegistered, rather than being linked to that user.
+func (s *OAuthService) Login(ctx context.Context, provider string, profile *oauth.Profile) (*models.User, error) {
+	user, err := s.Users.GetUserByIdentity(ctx, provider, profile.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, sqlite.ErrNotFound) {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(profile.Email))
+	if email == "" || !profile.EmailVerified {
+		return nil, fmt.Errorf("%s account %s: %w", provider, profile.Subject, ErrUnverifiedEmail)
+	}
+	_, registered, err := s.Users.QueryUserEmailExists(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if registered {
+		return nil, fmt.Errorf("%s account %s: %w", provider, profile.Subject, ErrEmailRegistered)
+	}
+
+	username, err := s.freeUsername(ctx, profile.Name, email)
+	if err != nil {
+		return nil, err
+	}
+	// the user signs in through the provider, so the password only has to be unguessable
+	user, err = NewUser(username, email, models.GenerateToken(32))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user for %s account: %w", provider, err)
+	}
+	if err := s.Users.InsertWithIdentity(ctx, user, provider, profile.Subject); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Link lets profile, an account at provider, log in as user from now on. The user
+// must be logged in already, which is what proves the two belong together. Linking
+// an account already linked to user does nothing; one linked to someone else
+// returns sqlite.ErrConflict.
+func (s *OAuthService) Link(ctx context.Context, user *models.User, provider string, profile *oauth.Profile) error {
+	linked, err := s.Users.GetUserByIdentity(ctx, provider, profile.Subject)
+	switch {
+	case err == nil && linked.ID == user.ID:
+		return nil
+	case err == nil:
+		return fmt.Errorf("%s account %s belongs to another user: %w", provider, profile.Subject, sqlite.ErrConflict)
+	case !errors.Is(err, sqlite.ErrNotFound):
+		return err
+	}
+	return s.Users.LinkIdentity(ctx, provider, profile.Subject, user.ID, profile.Email)
+}
+
+// freeUsername makes a username no one has yet from the account's name, or failing
+// that the email's local part, adding digits when it is taken
+func (s *OAuthService) freeUsername(ctx context.Context, name, email string) (string, error) {
+	base := usernameBase(name)
+	if len(base) < minUsernameLength {
+		base = usernameBase(email[:strings.IndexByte(email+"@", '@')])
+	}
+	if len(base) < minUsernameLength {
+		base += "user"
+	}
+
+	candidate := base
+	for range 10 {
+		_, taken, err := s.Users.QueryUserNameExists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken && len(candidate) >= minUsernameLength {
+			return candidate, nil
+		}
+		suffix := strconv.Itoa(1000 + rand.IntN(9000))
+		candidate = base[:min(len(base), maxUsernameLength-len(suffix))] + suffix
+	}
+	return "", fmt.Errorf("no free username for %s: %w", base, sqlite.ErrConflict)
+}
+
+// usernameBase keeps the letters, digits and underscores of s, lowercased, up to the
+// longest username allowed
+func usernameBase(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			b.WriteRune(r)
+		}
+		if b.Len() == maxUsernameLength {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/auth/oauth"
+	forumdb "github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+func TestOAuthServiceLogin(t *testing.T) {
+	db, err := forumdb.OpenMemory("../../migrations")
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &OAuthService{Users: &sqlite.UserModel{DB: db}}
+	ctx := context.Background()
+
+	existingID := models.NewUUIDField()
+	mustExec(t, db, `INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype, IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, 'adalovelace', 'ada@example.com', '', '', '', 'user', 0, '', '', 'hash')`, existingID)
+
+	t.Run("registered email is not linked", func(t *testing.T) {
+		// whoever registered the email locally never proved they own it
+		profile := &oauth.Profile{Subject: "g-1", Email: "Ada@Example.com", EmailVerified: true, Name: "Ada"}
+		if _, err := s.Login(ctx, oauth.ProviderGoogle, profile); !errors.Is(err, ErrEmailRegistered) {
+			t.Fatalf("Expected ErrEmailRegistered, got %v", err)
+		}
+		if _, err := s.Users.GetUserByIdentity(ctx, oauth.ProviderGoogle, "g-1"); !errors.Is(err, sqlite.ErrNotFound) {
+			t.Errorf("Expected no identity to be linked, got %v", err)
+		}
+	})
+
+	t.Run("logged-in user links the account", func(t *testing.T) {
+		existing := &models.User{ID: existingID}
+		profile := &oauth.Profile{Subject: "g-1", Email: "ada@example.com", EmailVerified: true}
+		if err := s.Link(ctx, existing, oauth.ProviderGoogle, profile); err != nil {
+			t.Fatalf("Link failed: %v", err)
+		}
+		if err := s.Link(ctx, existing, oauth.ProviderGoogle, profile); err != nil {
+			t.Errorf("Expected linking again to do nothing, got %v", err)
+		}
+		other := &models.User{ID: models.NewUUIDField()}
+		if err := s.Link(ctx, other, oauth.ProviderGoogle, profile); !errors.Is(err, sqlite.ErrConflict) {
+			t.Errorf("Expected ErrConflict linking another user's account, got %v", err)
+		}
+	})
+
+	t.Run("linked identity logs in after an email change", func(t *testing.T) {
+		profile := &oauth.Profile{Subject: "g-1", Email: "ada@elsewhere.example", EmailVerified: true}
+		user, err := s.Login(ctx, oauth.ProviderGoogle, profile)
+		if err != nil || user.ID != existingID {
+			t.Fatalf("Expected the linked user, got %+v, %v", user, err)
+		}
+	})
+
+	t.Run("unverified email is refused", func(t *testing.T) {
+		profile := &oauth.Profile{Subject: "gh-1", Email: "ada@example.com", Name: "ada"}
+		if _, err := s.Login(ctx, oauth.ProviderGitHub, profile); !errors.Is(err, ErrUnverifiedEmail) {
+			t.Fatalf("Expected ErrUnverifiedEmail, got %v", err)
+		}
+		if _, err := s.Users.GetUserByIdentity(ctx, oauth.ProviderGitHub, "gh-1"); !errors.Is(err, sqlite.ErrNotFound) {
+			t.Errorf("Expected no identity to be linked, got %v", err)
+		}
+	})
+
+	t.Run("new email creates a user with a free username", func(t *testing.T) {
+		profile := &oauth.Profile{Subject: "gh-2", Email: "grace@example.com", EmailVerified: true, Name: "Ada-Lovelace"}
+		user, err := s.Login(ctx, oauth.ProviderGitHub, profile)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		if user.ID == existingID || user.Email != "grace@example.com" {
+			t.Fatalf("Expected a new user for grace@example.com, got %+v", user)
+		}
+		if len(user.Username) != len("adalovelace")+4 || user.Username[:len("adalovelace")] != "adalovelace" {
+			t.Errorf("Expected the taken username adalovelace with digits added, got %q", user.Username)
+		}
+		again, err := s.Login(ctx, oauth.ProviderGitHub, profile)
+		if err != nil || again.ID != user.ID {
+			t.Errorf("Expected the next login to find the created user, got %+v, %v", again, err)
+		}
+	})
+}
+
+func TestUsernameBase(t *testing.T) {
+	tests := map[string]string{
+		"Ada Lovelace":            "adalovelace",
+		"grace.hopper-1906":       "gracehopper1906",
+		"Ünïcödé":                 "ncd",
+		"a_very_long_name_indeed": "a_very_long_name",
+	}
+	for in, want := range tests {
+		if got := usernameBase(in); got != want {
+			t.Errorf("usernameBase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/signedurl"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// PasswordResetPath is the path reset links point at; the token is appended to it
+const PasswordResetPath = "/password-reset/"
+
+// PasswordResetService emails password reset links and applies the new password when
+// one is followed. A link is the token's path signed with an expiry, so a tampered or
+// stale link is refused before the database is consulted, and the stored token is
+// single use.
+type PasswordResetService struct {
+	Users  *sqlite.UserModel
+	Resets *sqlite.PasswordResetModel
+	Mailer EmailSender
+	Signer *signedurl.Signer
+	TTL    time.Duration // how long a link works
+	// Scheme and host prefixed to the link path, e.g. https://codex.example
+	BaseURL string
+}
+
+// Request emails a reset link to the account registered to email. An unknown address
+// is not an error: callers must respond the same either way, so the endpoint does not
+// reveal which addresses have accounts.
+func (s *PasswordResetService) Request(ctx context.Context, email string) error {
+	user, err := s.Users.GetUserByEmail(ctx, strings.ToLower(email), "password reset")
+	if errors.Is(err, sqlite.ErrNotFound) {
+		models.LogInfoWithContext(ctx, "Password reset requested for unknown email %s", email)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up user for password reset: %w", err)
+	}
+
+	token := models.GenerateToken(32)
+	if err := s.Resets.Insert(ctx, user.ID, token, time.Now().Add(s.TTL)); err != nil {
+		return err
+	}
+	link := strings.TrimSuffix(s.BaseURL, "/") + s.Signer.Sign(PasswordResetPath+token, s.TTL)
+	return s.Mailer.Send(ctx, Email{
+		To:      user.Email,
+		Subject: "Reset your Codex password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Codex account.\n\n"+
+			"Follow this link within %s to choose a new one:\n%s\n\n"+
+			"If it was not you, ignore this email and your password will stay the same.\n", s.TTL, link),
+	})
+}
+
+// Reset sets password as the new password of the user the link was sent to. expires
+// and sig are the link's query parameters. A link that is tampered with, expired,
+// already used or unknown returns an error wrapping sqlite.ErrResetTokenInvalid.
+func (s *PasswordResetService) Reset(ctx context.Context, token, expires, sig, password string) (models.UUIDField, error) {
+	if err := s.Signer.Verify(PasswordResetPath+token, expires, sig); err != nil {
+		return models.ZeroUUIDField(), fmt.Errorf("%w: %w", sqlite.ErrResetTokenInvalid, err)
+	}
+	hashedPassword, err := models.HashPassword(password)
+	if err != nil {
+		return models.ZeroUUIDField(), fmt.Errorf("failed to hash new password: %w", err)
+	}
+	return s.Resets.Consume(ctx, token, hashedPassword)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// PostService fetches posts ready for display: reaction counts, last reaction time,
+// channel, comments with their replies, and time since creation are all filled in
+type PostService struct {
+	Posts     *sqlite.PostModel
+	Reactions *sqlite.ReactionModel
+	Comments  *sqlite.CommentModel
+	Channels  *sqlite.ChannelModel
+}
+
+// Feed returns a page of every post, newest first, enriched. Pass sqlite.NoLimit for
+// every post.
+func (s *PostService) Feed(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	posts, err := s.Posts.All(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for feed: %w", err)
+	}
+	return s.Enrich(ctx, posts)
+}
+
+// ForUser returns a page of the posts written by userID, newest first, enriched
+func (s *PostService) ForUser(ctx context.Context, userID models.UUIDField, limit, offset int) ([]*models.Post, error) {
+	posts, err := s.Posts.GetPostsByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for user %s: %w", userID, err)
+	}
+	return s.Enrich(ctx, posts)
+}
+
+// ForChannel returns a page of the posts in channelID, newest first, enriched
+func (s *PostService) ForChannel(ctx context.Context, channelID int64, limit, offset int) ([]*models.Post, error) {
+	posts, err := s.Posts.GetPostsByChannel(ctx, channelID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for channel %d: %w", channelID, err)
+	}
+	return s.Enrich(ctx, posts)
+}
+
+// Enrich fills in the display fields of posts in place and returns them. Reaction
+// lookups that fail are logged and leave zero values; a post that belongs to no
+// channel keeps a zero ChannelID, and an invalid Images value is replaced with
+// NoImage. Only a failure to load comments is returned.
+func (s *PostService) Enrich(ctx context.Context, posts []*models.Post) ([]*models.Post, error) {
+	channelNames := make(map[int64]string)
+	for _, post := range posts {
+		target := models.PostTarget(post.ID)
+		likes, dislikes, err := s.Reactions.CountReactions(ctx, target)
+		if err != nil {
+			models.LogErrorWithContext(ctx, "Failed to count reactions for post", err, "PostID:", post.ID)
+			likes, dislikes = 0, 0
+		}
+		models.React(post, likes, dislikes)
+
+		post.LastReaction = nil
+		lastReaction, err := s.Reactions.GetLastReaction(ctx, target)
+		if err != nil {
+			models.LogErrorWithContext(ctx, "Failed to get last reaction time for post", err, "PostID:", post.ID)
+		} else if !lastReaction.Created.IsZero() {
+			post.LastReaction = &lastReaction.Created
+		}
+
+		if err := s.setChannel(ctx, post, channelNames); err != nil {
+			models.LogWarnWithContext(ctx, "Post %d has no channel: %v", post.ID, err)
+		}
+
+		comments, err := s.Comments.GetCommentByPostID(ctx, post.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch comments for post %d: %w", post.ID, err)
+		}
+		post.Comments = s.withReplies(ctx, comments)
+		post.CommentsCount = len(post.Comments)
+
+		if err := post.SanitizeImages(); err != nil {
+			models.LogWarnWithContext(ctx, "Post %d has an invalid image, not rendering it: %v", post.ID, err)
+		}
+		models.UpdateTimeSince(post)
+	}
+	return posts, nil
+}
+
+// setChannel sets the post's channel to the first one it was shared to, caching names by ID
+func (s *PostService) setChannel(ctx context.Context, post *models.Post, names map[int64]string) error {
+	channelIDs, err := s.Channels.GetChannelIDFromPost(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	channelID := channelIDs[0]
+	name, ok := names[channelID]
+	if !ok {
+		if name, err = s.Channels.GetChannelNameFromID(ctx, channelID); err != nil {
+			return err
+		}
+		names[channelID] = name
+	}
+	post.ChannelID, post.ChannelName = channelID, name
+	return nil
+}
+
+// withReplies adds reaction counts, time since and, recursively, replies to each comment
+func (s *PostService) withReplies(ctx context.Context, comments []models.Comment) []models.Comment {
+	ids := make([]int64, len(comments))
+	for c := range comments {
+		ids[c] = comments[c].ID
+	}
+	counts, err := s.Reactions.CountReactionsForComments(ctx, ids)
+	if err != nil {
+		models.LogErrorWithContext(ctx, "Failed to count reactions for comments", err, "Comments:", len(ids))
+	}
+
+	for c := range comments {
+		// a nil map reads as zero counts, the same default as a failed count
+		count := counts[comments[c].ID]
+		models.React(&comments[c], count.Likes, count.Dislikes)
+		models.UpdateTimeSince(&comments[c])
+
+		replies, err := s.Comments.GetCommentByCommentID(ctx, comments[c].ID)
+		if err != nil {
+			models.LogErrorWithContext(ctx, "Failed to fetch replies for comment", err, "CommentID:", comments[c].ID)
+			continue
+		}
+		if len(replies) > 0 {
+			comments[c].Replies = s.withReplies(ctx, replies)
+		}
+	}
+	return comments
+}
+
+// HideFlagged drops flagged posts, comments and replies unless they are in one of the
+// moderated channels, and returns the posts that remain. Comments are filtered in
+// place and CommentsCount is kept in step.
+func HideFlagged(posts []*models.Post, moderated map[int64]bool) []*models.Post {
+	visible := make([]*models.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.IsFlagged && !moderated[post.ChannelID] {
+			continue
+		}
+		post.Comments = hideFlaggedComments(post.Comments, moderated)
+		post.CommentsCount = len(post.Comments)
+		visible = append(visible, post)
+	}
+	return visible
+}
+
+func hideFlaggedComments(comments []models.Comment, moderated map[int64]bool) []models.Comment {
+	visible := comments[:0]
+	for _, comment := range comments {
+		if comment.IsFlagged && !moderated[comment.ChannelID] {
+			continue
+		}
+		comment.Replies = hideFlaggedComments(comment.Replies, moderated)
+		visible = append(visible, comment)
+	}
+	return visible
+}
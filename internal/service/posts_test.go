@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	forumdb "github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// newTestPostService returns a PostService over an in-memory database with every migration applied
+func newTestPostService(t *testing.T) (*PostService, *sql.DB) {
+	t.Helper()
+	db, err := forumdb.OpenMemory("../../migrations")
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &PostService{
+		Posts:     &sqlite.PostModel{DB: db},
+		Reactions: &sqlite.ReactionModel{DB: db},
+		Comments:  &sqlite.CommentModel{DB: db},
+		Channels:  &sqlite.ChannelModel{DB: db},
+	}, db
+}
+
+// mustExec runs a seeding statement and returns the new row ID
+func mustExec(t *testing.T, db *sql.DB, query string, args ...any) int64 {
+	t.Helper()
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("Failed to seed %q: %v", query, err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+func TestPostService(t *testing.T) {
+	s, db := newTestPostService(t)
+	ctx := context.Background()
+
+	author := models.NewUUIDField()
+	mustExec(t, db, `INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype,
+		IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, 'author', 'author@example.com', 'author.png', '', '', 'user', 0, '', '', 'hash')`, author)
+	channelID := mustExec(t, db, `INSERT INTO Channels (OwnerID, Name, Avatar, Banner, Description, Privacy, IsMuted, IsFlagged)
+		VALUES (?, 'cooking', '', '', 'test channel', 0, 0, 0)`, author)
+	postID := mustExec(t, db, `INSERT INTO Posts (Title, Content, Images, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged)
+		VALUES ('hello', 'test content', '', 1, 'author', ?, '', 0)`, author)
+	mustExec(t, db, `INSERT INTO PostChannels (ChannelID, PostID) VALUES (?, ?)`, channelID, postID)
+	mustExec(t, db, `INSERT INTO Reactions (Liked, Disliked, AuthorID, ReactedPostID) VALUES (1, 0, ?, ?)`, author, postID)
+	commentID := mustExec(t, db, `INSERT INTO Comments (Content, CommentedPostID, IsCommentable, IsFlagged, IsReply,
+		Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+		VALUES ('top', ?, 1, 0, 0, 'author', ?, '', 'cooking', ?)`, postID, author, channelID)
+	mustExec(t, db, `INSERT INTO Comments (Content, CommentedCommentID, IsCommentable, IsFlagged, IsReply,
+		Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+		VALUES ('reply', ?, 1, 0, 1, 'author', ?, '', 'cooking', ?)`, commentID, author, channelID)
+	mustExec(t, db, `INSERT INTO Reactions (Liked, Disliked, AuthorID, ReactedCommentID) VALUES (0, 1, ?, ?)`, author, commentID)
+
+	fetches := map[string]func() ([]*models.Post, error){
+		"Feed":       func() ([]*models.Post, error) { return s.Feed(ctx, sqlite.NoLimit, 0) },
+		"ForUser":    func() ([]*models.Post, error) { return s.ForUser(ctx, author, sqlite.NoLimit, 0) },
+		"ForChannel": func() ([]*models.Post, error) { return s.ForChannel(ctx, channelID, sqlite.NoLimit, 0) },
+	}
+	for name, fetch := range fetches {
+		t.Run(name, func(t *testing.T) {
+			posts, err := fetch()
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if len(posts) != 1 {
+				t.Fatalf("Expected 1 post, got %d", len(posts))
+			}
+			post := posts[0]
+			if post.Likes != 1 || post.Dislikes != 0 {
+				t.Errorf("Expected 1 like and 0 dislikes, got %d and %d", post.Likes, post.Dislikes)
+			}
+			if post.LastReaction == nil {
+				t.Error("Expected the last reaction time to be set")
+			}
+			if post.ChannelID != channelID || post.ChannelName != "cooking" {
+				t.Errorf("Expected channel %d cooking, got %d %q", channelID, post.ChannelID, post.ChannelName)
+			}
+			if post.TimeSince == "" {
+				t.Error("Expected TimeSince to be set")
+			}
+			if post.CommentsCount != 1 || len(post.Comments) != 1 {
+				t.Fatalf("Expected 1 top-level comment, got %d", len(post.Comments))
+			}
+			comment := post.Comments[0]
+			if comment.Dislikes != 1 {
+				t.Errorf("Expected the comment to have 1 dislike, got %d", comment.Dislikes)
+			}
+			if len(comment.Replies) != 1 || comment.Replies[0].Content != "reply" {
+				t.Errorf("Expected the reply under the comment, got %+v", comment.Replies)
+			}
+			if comment.Replies[0].TimeSince == "" {
+				t.Error("Expected the reply TimeSince to be set")
+			}
+		})
+	}
+
+	t.Run("post without a channel", func(t *testing.T) {
+		orphanID := mustExec(t, db, `INSERT INTO Posts (Title, Content, Images, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged)
+			VALUES ('orphan', 'test content', '', 1, 'author', ?, '', 0)`, author)
+		post, err := s.Posts.GetPostByID(ctx, orphanID)
+		if err != nil {
+			t.Fatalf("GetPostByID failed: %v", err)
+		}
+		posts, err := s.Enrich(ctx, []*models.Post{&post})
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if posts[0].ChannelID != 0 || len(posts[0].Comments) != 0 {
+			t.Errorf("Expected no channel or comments, got %+v", posts[0])
+		}
+	})
+}
@@ -1,4 +1,4 @@
-// Package service contains business logic for user management in the forum application.
+// Package service contains business logic that sits between the HTTP handlers and the sqlite models.
 package service
 
 import (
@@ -0,0 +1,109 @@
+package signedurl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedSigner returns a signer whose clock can be moved by the test
+func fixedSigner(now *time.Time) *Signer {
+	s := NewSigner("test-secret")
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func splitSigned(t *testing.T, signed string) (string, url.Values) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("Signed URL does not parse: %v", err)
+	}
+	return u.Path, u.Query()
+}
+
+func TestVerify_ValidSignature(t *testing.T) {
+	now := time.Now()
+	s := fixedSigner(&now)
+
+	path, q := splitSigned(t, s.Sign("/private-images/post-images/a.jpg", time.Minute))
+	if err := s.Verify(path, q.Get("expires"), q.Get("sig")); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+func TestVerify_Expired(t *testing.T) {
+	now := time.Now()
+	s := fixedSigner(&now)
+
+	path, q := splitSigned(t, s.Sign("/private-images/post-images/a.jpg", time.Minute))
+	now = now.Add(2 * time.Minute)
+	if err := s.Verify(path, q.Get("expires"), q.Get("sig")); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	now := time.Now()
+	s := fixedSigner(&now)
+	path, q := splitSigned(t, s.Sign("/private-images/post-images/a.jpg", time.Minute))
+
+	tests := []struct {
+		name           string
+		path, exp, sig string
+		wantErr        error
+	}{
+		{"other path", "/private-images/post-images/b.jpg", q.Get("expires"), q.Get("sig"), ErrInvalidSignature},
+		{"extended expiry", path, "9999999999", q.Get("sig"), ErrInvalidSignature},
+		{"altered signature", path, q.Get("expires"), strings.Repeat("0", 64), ErrInvalidSignature},
+		{"missing signature", path, q.Get("expires"), "", ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Verify(tt.path, tt.exp, tt.sig); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerify_DifferentSecret(t *testing.T) {
+	signed := NewSigner("one").Sign("/private-images/x.png", time.Minute)
+	path, q := splitSigned(t, signed)
+	if err := NewSigner("two").Verify(path, q.Get("expires"), q.Get("sig")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	now := time.Now()
+	s := fixedSigner(&now)
+	handler := s.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	signed := s.Sign("/private-images/a.jpg", time.Minute)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signed, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Valid link: expected 200, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/private-images/a.jpg", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Unsigned link: expected 403, got %d", rec.Code)
+	}
+
+	now = now.Add(time.Hour)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signed, nil))
+	if rec.Code != http.StatusGone {
+		t.Errorf("Expired link: expected 410, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// AuditLogModel stores the record of moderation and admin actions
+type AuditLogModel struct {
+	DB *sql.DB
+}
+
+// Insert records entry and returns its ID. A zero ChannelID is stored as NULL.
+func (m *AuditLogModel) Insert(ctx context.Context, entry models.AuditEntry) (int64, error) {
+	metadata := []byte("{}")
+	if len(entry.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(entry.Metadata); err != nil {
+			return 0, fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+	}
+	var channelID sql.NullInt64
+	if entry.ChannelID != 0 {
+		channelID = sql.NullInt64{Int64: entry.ChannelID, Valid: true}
+	}
+
+	stmt := "INSERT INTO AuditLog (ActorID, Action, TargetType, TargetID, ChannelID, Metadata) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := m.DB.ExecContext(ctx, stmt, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, channelID, string(metadata))
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID for AuditLog: %w", err)
+	}
+	return id, nil
+}
+
+// ForActor returns up to limit entries recorded for actions by actorID, newest first
+func (m *AuditLogModel) ForActor(ctx context.Context, actorID models.UUIDField, limit int) ([]models.AuditEntry, error) {
+	return m.query(ctx, "WHERE ActorID = ?", actorID, limit)
+}
+
+// ForChannel returns up to limit entries recorded for actions in channelID, newest first
+func (m *AuditLogModel) ForChannel(ctx context.Context, channelID int64, limit int) ([]models.AuditEntry, error) {
+	return m.query(ctx, "WHERE ChannelID = ?", channelID, limit)
+}
+
+func (m *AuditLogModel) query(ctx context.Context, where string, arg any, limit int) ([]models.AuditEntry, error) {
+	stmt := "SELECT ID, ActorID, Action, TargetType, TargetID, ChannelID, Metadata, Created FROM AuditLog " +
+		where + " ORDER BY ID DESC LIMIT ?"
+	rows, err := m.DB.QueryContext(ctx, stmt, arg, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var channelID sql.NullInt64
+		var metadata string
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &channelID, &metadata, &e.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		e.ChannelID = channelID.Int64
+		if err := json.Unmarshal([]byte(metadata), &e.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata of audit entry %d: %w", e.ID, err)
+		}
+		if len(e.Metadata) == 0 {
+			e.Metadata = nil
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestAuditLog(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	ctx := context.Background()
+	m := AuditLogModel{DB: db}
+
+	admin := models.NewUUIDField()
+	other := models.NewUUIDField()
+	entries := []models.AuditEntry{
+		{ActorID: admin, Action: models.AuditChannelPrivacy, TargetType: models.AuditTargetChannel, TargetID: "7",
+			ChannelID: 7, Metadata: map[string]any{"private": true}},
+		{ActorID: other, Action: models.AuditModeratorAdded, TargetType: models.AuditTargetUser, TargetID: other.String(),
+			ChannelID: 8},
+		{ActorID: admin, Action: models.AuditModerationRequested, TargetType: models.AuditTargetUser, TargetID: "owner"},
+	}
+	for _, e := range entries {
+		if _, err := m.Insert(ctx, e); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	got, err := m.ForActor(ctx, admin, 10)
+	if err != nil {
+		t.Fatalf("ForActor: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 entries for actor, got %+v", got)
+	}
+	if got[0].Action != models.AuditModerationRequested || got[0].ChannelID != 0 || got[0].Metadata != nil {
+		t.Errorf("newest entry = %+v", got[0])
+	}
+	if got[1].Action != models.AuditChannelPrivacy || got[1].TargetID != "7" ||
+		!reflect.DeepEqual(got[1].Metadata, map[string]any{"private": true}) || got[1].Created.IsZero() {
+		t.Errorf("oldest entry = %+v", got[1])
+	}
+
+	if got, _ := m.ForActor(ctx, admin, 1); len(got) != 1 {
+		t.Errorf("limit ignored: %d entries", len(got))
+	}
+
+	got, err = m.ForChannel(ctx, 8, 10)
+	if err != nil {
+		t.Fatalf("ForChannel: %v", err)
+	}
+	if len(got) != 1 || got[0].ActorID != other || got[0].Action != models.AuditModeratorAdded {
+		t.Errorf("channel entries = %+v", got)
+	}
+}
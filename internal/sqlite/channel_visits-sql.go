@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// ChannelVisitModel tracks when each user last opened each channel
+type ChannelVisitModel struct {
+	DB *sql.DB
+}
+
+// RecordVisit sets the last visit of userID to channelID to now
+func (m *ChannelVisitModel) RecordVisit(ctx context.Context, userID models.UUIDField, channelID int64) error {
+	query := `INSERT INTO ChannelVisits (UserID, ChannelID, LastVisited) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (UserID, ChannelID) DO UPDATE SET LastVisited = excluded.LastVisited`
+	if _, err := m.DB.ExecContext(ctx, query, userID, channelID); err != nil {
+		return fmt.Errorf("failed to record visit of user %s to channel %d: %w", userID, channelID, err)
+	}
+	return nil
+}
+
+// GetLastVisit returns when userID last visited channelID, or ErrNotFound if they never have
+func (m *ChannelVisitModel) GetLastVisit(ctx context.Context, userID models.UUIDField, channelID int64) (time.Time, error) {
+	var lastVisited time.Time
+	query := "SELECT LastVisited FROM ChannelVisits WHERE UserID = ? AND ChannelID = ?"
+	err := m.DB.QueryRowContext(ctx, query, userID, channelID).Scan(&lastVisited)
+	if errors.Is(err, sql.ErrNoRows) {
+		return lastVisited, fmt.Errorf("no visit by user %s to channel %d: %w", userID, channelID, ErrNotFound)
+	}
+	if err != nil {
+		return lastVisited, fmt.Errorf("failed to get last visit of user %s to channel %d: %w", userID, channelID, err)
+	}
+	return lastVisited.UTC(), nil
+}
+
+// NewPostCounts returns, for every channel userID has joined, how many of its posts were
+// created after the user's last visit. Channels never visited count all their posts.
+func (m *ChannelVisitModel) NewPostCounts(ctx context.Context, userID models.UUIDField) (map[int64]int, error) {
+	query := `SELECT m.ChannelID, COUNT(p.ID)
+		FROM Memberships m
+		LEFT JOIN ChannelVisits v ON v.UserID = m.UserID AND v.ChannelID = m.ChannelID
+		LEFT JOIN PostChannels pc ON pc.ChannelID = m.ChannelID
+		LEFT JOIN Posts p ON p.ID = pc.PostID AND p.DeletedAt IS NULL
+			AND (v.LastVisited IS NULL OR datetime(p.Created) > datetime(v.LastVisited))
+		WHERE m.UserID = ?
+		GROUP BY m.ChannelID`
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count new posts for user %s: %w", userID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in NewPostCounts: %v", closeErr)
+		}
+	}()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var channelID int64
+		var count int
+		if err := rows.Scan(&channelID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan new post count: %w", err)
+		}
+		counts[channelID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate new post counts: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChannelVisitNewPostCounts(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelVisitModel{DB: db}
+	memberships := &MembershipModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	reader := insertTestUser(t, db, "reader")
+	visited := insertTestChannel(t, db, owner, "visited")
+	unvisited := insertTestChannel(t, db, owner, "unvisited")
+	unjoined := insertTestChannel(t, db, owner, "unjoined")
+	for _, channelID := range []int64{visited, unvisited} {
+		if _, err := memberships.Insert(ctx, reader, channelID); err != nil {
+			t.Fatalf("Failed to join channel: %v", err)
+		}
+	}
+
+	// seed posts an hour either side of the visit, stored like CURRENT_TIMESTAMP
+	seed := func(channelID int64, title string, offset time.Duration) {
+		postID := insertTestPost(t, db, owner, title)
+		created := time.Now().UTC().Add(offset).Format("2006-01-02 15:04:05")
+		if _, err := db.Exec(`UPDATE Posts SET Created = ? WHERE ID = ?`, created, postID); err != nil {
+			t.Fatalf("Failed to set post time: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO PostChannels (ChannelID, PostID) VALUES (?, ?)`, channelID, postID); err != nil {
+			t.Fatalf("Failed to link post to channel: %v", err)
+		}
+	}
+	seed(visited, "old", -time.Hour)
+	seed(visited, "older", -2*time.Hour)
+	seed(unvisited, "unseen", -time.Hour)
+	seed(unjoined, "elsewhere", time.Hour)
+
+	if _, err := m.GetLastVisit(ctx, reader, visited); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound before the first visit, got %v", err)
+	}
+	if err := m.RecordVisit(ctx, reader, visited); err != nil {
+		t.Fatalf("RecordVisit failed: %v", err)
+	}
+	lastVisit, err := m.GetLastVisit(ctx, reader, visited)
+	if err != nil {
+		t.Fatalf("GetLastVisit failed: %v", err)
+	}
+	if since := time.Since(lastVisit); since < -time.Second || since > time.Minute {
+		t.Errorf("Expected the last visit to be now, got %v", lastVisit)
+	}
+	seed(visited, "new", time.Hour)
+
+	counts, err := m.NewPostCounts(ctx, reader)
+	if err != nil {
+		t.Fatalf("NewPostCounts failed: %v", err)
+	}
+	want := map[int64]int{visited: 1, unvisited: 1}
+	if len(counts) != len(want) {
+		t.Fatalf("Expected counts for %d joined channels, got %v", len(want), counts)
+	}
+	for channelID, n := range want {
+		if counts[channelID] != n {
+			t.Errorf("Channel %d: expected %d new posts, got %d", channelID, n, counts[channelID])
+		}
+	}
+
+	// a second visit replaces the first
+	if err := m.RecordVisit(ctx, reader, visited); err != nil {
+		t.Fatalf("RecordVisit failed: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM ChannelVisits WHERE UserID = ?`, reader).Scan(&rows); err != nil {
+		t.Fatalf("Failed to count visits: %v", err)
+	}
+	if rows != 1 {
+		t.Errorf("Expected one visit row, got %d", rows)
+	}
+}
@@ -3,33 +3,165 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"math/rand"
+	"strings"
+	"time"
 
 	"github.com/gary-norman/forum/internal/models"
 )
 
 type ChannelModel struct {
 	DB *sql.DB
+	// Presence reports who is connected; when nil every channel has no members online
+	Presence PresenceProvider
 }
 
-// RandomInt Function to get a random integer between 0 and the max number, for go templates
-func RandomInt(max int) int {
-	return rand.Intn(max)
+// PresenceProvider reports which users currently have a live connection. It is
+// implemented outside this package so the model layer does not depend on transport.
+type PresenceProvider interface {
+	OnlineUserIDs() []models.UUIDField
 }
 
 func (m *ChannelModel) Insert(ctx context.Context, ownerID models.UUIDField, name, description, avatar, banner string, privacy, isFlagged, isMuted bool) error {
 	stmt := "INSERT INTO Channels (OwnerID, Name, Description, Created, Avatar, Banner, Privacy, IsFlagged, IsMuted) VALUES (?, ?, ?, DateTime('now'), ?, ?, ?, ?, ?)"
 	_, err := m.DB.ExecContext(ctx, stmt, ownerID, name, description, avatar, banner, privacy, isFlagged, isMuted)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("channel %s already exists: %w", name, ErrConflict)
+	}
 	return err
 }
 
+// InsertWithOwner creates channel and makes its owner a member with the owner role
+// in one transaction, so a channel never exists without its owner in it. The new
+// channel's ID is set on channel.
+func (m *ChannelModel) InsertWithOwner(ctx context.Context, channel *models.Channel) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for InsertWithOwner: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO Channels (OwnerID, Name, Description, Created, Avatar, Banner, Privacy, IsFlagged, IsMuted) VALUES (?, ?, ?, DateTime('now'), ?, ?, ?, ?, ?)",
+		channel.OwnerID, channel.Name, channel.Description, channel.Avatar, channel.Banner, channel.Privacy, channel.IsFlagged, channel.IsMuted)
+	if isUniqueViolation(err) {
+		err = fmt.Errorf("channel %s already exists: %w", channel.Name, ErrConflict)
+		return err
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to insert channel %s: %w", channel.Name, err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		err = fmt.Errorf("failed to read ID of channel %s: %w", channel.Name, err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO Memberships (UserID, ChannelID, Role, Created) VALUES (?, ?, ?, DateTime('now'))",
+		channel.OwnerID, id, models.MembershipRoleOwner)
+	if err != nil {
+		err = fmt.Errorf("failed to add owner %s to channel %d: %w", channel.OwnerID, id, err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for InsertWithOwner: %w", err)
+	}
+	channel.ID = id
+	return nil
+}
+
+// SetPrivacy switches a channel between public and private on behalf of userID, who
+// must own it, and records the change in ChannelPrivacyChanges. Setting the current
+// value again is a no-op and is not recorded. Existing members, moderators and posts
+// are left as they are. A private channel, and any post only found in private
+// channels, is hidden from anonymous visitors, and its moderation requests need the
+// owner's approval.
+func (m *ChannelModel) SetPrivacy(ctx context.Context, channelID int64, userID models.UUIDField, private bool) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for SetPrivacy: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var ownerID models.UUIDField
+	var current bool
+	err = tx.QueryRowContext(ctx, "SELECT OwnerID, Privacy FROM Channels WHERE ID = ?", channelID).Scan(&ownerID, &current)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("no channel found for ID %d: %w", channelID, ErrChannelNotFound)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up channel %d: %w", channelID, err)
+	}
+	if ownerID != userID {
+		err = fmt.Errorf("only the owner can change the privacy of channel %d: %w", channelID, ErrForbidden)
+		return err
+	}
+	if current == private {
+		return tx.Commit()
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE Channels SET Privacy = ?, Updated = CURRENT_TIMESTAMP WHERE ID = ?", private, channelID); err != nil {
+		return fmt.Errorf("failed to update privacy of channel %d: %w", channelID, err)
+	}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO ChannelPrivacyChanges (ChannelID, ChangedBy, Private) VALUES (?, ?, ?)",
+		channelID, userID, private); err != nil {
+		return fmt.Errorf("failed to record privacy change for channel %d: %w", channelID, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for SetPrivacy: %w", err)
+	}
+	return nil
+}
+
+// PrivacyChanges returns the recorded privacy changes for a channel, oldest first
+func (m *ChannelModel) PrivacyChanges(ctx context.Context, channelID int64) ([]models.ChannelPrivacyChange, error) {
+	stmt := "SELECT ID, ChannelID, ChangedBy, Private, Created FROM ChannelPrivacyChanges WHERE ChannelID = ? ORDER BY ID"
+	rows, err := m.DB.QueryContext(ctx, stmt, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query privacy changes: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	var changes []models.ChannelPrivacyChange
+	for rows.Next() {
+		var c models.ChannelPrivacyChange
+		if err := rows.Scan(&c.ID, &c.ChannelID, &c.ChangedBy, &c.Private, &c.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan privacy change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating privacy changes: %w", err)
+	}
+	return changes, nil
+}
+
 func (m *ChannelModel) OwnedOrJoinedByCurrentUser(ctx context.Context, ID models.UUIDField) ([]*models.Channel, error) {
-	stmt := `
-	SELECT c.*,
-	COUNT(m.UserID) AS MemberCount
-	From Channels c
-	LEFT JOIN Memberships m ON c.ID = m.ChannelID
+	stmt := channelSelect + `
 	WHERE c.ID IN (
 		SELECT ChannelID FROM Memberships WHERE UserID = ?
 	)
@@ -52,9 +184,6 @@ func (m *ChannelModel) OwnedOrJoinedByCurrentUser(ctx context.Context, ID models
 		}
 		// FIXME: This is a temporary fix to set the channel as joined:we need to come up with a more robust solution
 		c.Joined = true
-		// TODO (realtime) get this data from websockets
-		rnd := RandomInt(1800)
-		c.MembersOnline = rnd
 		channels = append(channels, c)
 	}
 
@@ -62,7 +191,7 @@ func (m *ChannelModel) OwnedOrJoinedByCurrentUser(ctx context.Context, ID models
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return channels, nil
+	return channels, m.setMembersOnline(ctx, channels...)
 }
 
 func (m *ChannelModel) IsUserMemberOfChannel(ctx context.Context, userID models.UUIDField, channelID int64) (bool, error) {
@@ -80,89 +209,140 @@ func (m *ChannelModel) IsUserMemberOfChannel(ctx context.Context, userID models.
 	return exists == 1, nil
 }
 
-func (m *ChannelModel) GetChannelsByID(ctx context.Context, id int64) ([]*models.Channel, error) {
-	stmt := `
-	SELECT c.*,
-  COUNT(m.UserID) AS MemberCount
+// channelSelect is the column list every channel query scans with parseChannelRow(s),
+// followed by the member count join. Callers append their WHERE and GROUP BY c.ID.
+const channelSelect = `
+	SELECT c.ID, c.OwnerID, c.Name, c.Avatar, c.Banner, c.Description, c.Created, c.Updated,
+		c.Privacy, c.IsMuted, c.IsFlagged,
+		COUNT(m.UserID) AS MemberCount
 	FROM Channels c
-	LEFT JOIN Memberships m ON c.ID = m.ChannelID
+	LEFT JOIN Memberships m ON c.ID = m.ChannelID`
+
+// GetChannelByID returns a single channel with its member count
+func (m *ChannelModel) GetChannelByID(ctx context.Context, id int64) (*models.Channel, error) {
+	stmt := channelSelect + `
 	WHERE c.ID = ?
-	GROUP BY c.ID;
-	`
-	rows, err := m.DB.QueryContext(ctx, stmt, id)
+	GROUP BY c.ID`
+	channel, err := parseChannelRow(m.DB.QueryRowContext(ctx, stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no channel found for ID %d: %w", id, ErrChannelNotFound)
+	}
 	if err != nil {
+		return nil, fmt.Errorf("failed to get channel %d: %w", id, err)
+	}
+	if err := m.setMembersOnline(ctx, channel); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return channel, nil
+}
 
-	// Parse results
-	channels := make([]*models.Channel, 0) // Pre-allocate slice
+// setMembersOnline sets MembersOnline on each channel to how many of its members
+// the presence provider reports as connected
+func (m *ChannelModel) setMembersOnline(ctx context.Context, channels ...*models.Channel) error {
+	if m.Presence == nil || len(channels) == 0 {
+		return nil
+	}
+	online := m.Presence.OnlineUserIDs()
+	if len(online) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(channels)+len(online))
+	for _, c := range channels {
+		args = append(args, c.ID)
+	}
+	for _, id := range online {
+		args = append(args, id)
+	}
+	stmt := `SELECT ChannelID, COUNT(*) FROM Memberships
+	WHERE ChannelID IN (` + strings.TrimSuffix(strings.Repeat("?,", len(channels)), ",") + `)
+	AND UserID IN (` + strings.TrimSuffix(strings.Repeat("?,", len(online)), ",") + `)
+	GROUP BY ChannelID`
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return fmt.Errorf("failed to count members online: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	counts := make(map[int64]int, len(channels))
 	for rows.Next() {
-		c, err := parseChannelRows(rows)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing row: %w", err)
+		var channelID int64
+		var count int
+		if err := rows.Scan(&channelID, &count); err != nil {
+			return fmt.Errorf("failed to scan members online: %w", err)
 		}
-		// TODO (realtime) get this data from websockets
-		rnd := RandomInt(1800)
-		c.MembersOnline = rnd
-		channels = append(channels, c)
+		counts[channelID] = count
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return fmt.Errorf("error iterating members online: %w", err)
 	}
-
-	return channels, nil
+	for _, c := range channels {
+		c.MembersOnline = counts[c.ID]
+	}
+	return nil
 }
 
-func (m *ChannelModel) GetChannelByID(ctx context.Context, id int64) (*models.Channel, error) {
-	stmt := `
-	SELECT c.*,
-  COUNT(m.UserID) AS MemberCount
-	FROM Channels c
-	LEFT JOIN Memberships m ON c.ID = m.ChannelID
-	WHERE c.ID = ?
-	GROUP BY c.ID;
-	`
-	rows, err := m.DB.QueryContext(ctx, stmt, id)
+// GetChannelsByIDs returns the channels with the given IDs in one query, in the
+// order the IDs were given. IDs with no channel are skipped.
+func (m *ChannelModel) GetChannelsByIDs(ctx context.Context, ids ...int64) ([]*models.Channel, error) {
+	channels := make([]*models.Channel, 0, len(ids))
+	if len(ids) == 0 {
+		return channels, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	stmt := channelSelect + `
+	WHERE c.ID IN (` + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + `)
+	GROUP BY c.ID`
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query channels by ID: %w", err)
 	}
-	defer rows.Close()
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
 
-	var channel models.Channel             // Pre-allocate slice
-	channels := make([]*models.Channel, 0) // Pre-allocate slice
+	byID := make(map[int64]*models.Channel, len(ids))
 	for rows.Next() {
 		c, err := parseChannelRows(rows)
 		if err != nil {
 			return nil, err
 		}
-		// TODO (realtime) get this data from websockets
-		rnd := RandomInt(1800)
-		c.MembersOnline = rnd
-		channels = append(channels, c)
+		byID[c.ID] = c
 	}
-	if len(channels) == 0 {
-		return &channel, fmt.Errorf("no channel found for ID %d", id)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channels: %w", err)
 	}
-	return channels[0], nil
+
+	for _, id := range ids {
+		if c, ok := byID[id]; ok {
+			channels = append(channels, c)
+			delete(byID, id)
+		}
+	}
+	return channels, m.setMembersOnline(ctx, channels...)
 }
 
+// GetNameOfChannel returns the name of a channel
 func (m *ChannelModel) GetNameOfChannel(ctx context.Context, channelID int64) (string, error) {
-	stmt := "SELECT Name FROM Channels WHERE ID = ?)"
-	rows, err := m.DB.QueryContext(ctx, stmt, channelID)
-	if err != nil {
-		return "", err
+	var name string
+	err := m.DB.QueryRowContext(ctx, "SELECT Name FROM Channels WHERE ID = ?", channelID).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("no channel found for ID %d: %w", channelID, ErrChannelNotFound)
 	}
-	defer rows.Close()
-
-	var username string
-	for rows.Next() {
-		if err := rows.Scan(&username); err != nil {
-			return "", err
-		}
+	if err != nil {
+		return "", fmt.Errorf("failed to get name of channel %d: %w", channelID, err)
 	}
-	return username, nil
+	return name, nil
 }
 
 func (m *ChannelModel) GetNameOfChannelOwner(ctx context.Context, channelID int64) (string, error) {
@@ -183,14 +363,8 @@ func (m *ChannelModel) GetNameOfChannelOwner(ctx context.Context, channelID int6
 }
 
 func (m *ChannelModel) All(ctx context.Context) ([]*models.Channel, error) {
-	stmt := `
--- 	SELECT c.*,
-SELECT c.ID, c.OwnerID, c.Name, c.Avatar, c.Banner, c.Description, c.Created, c.Updated, c.Privacy, c.IsMuted,  c.IsFlagged,
-  COUNT(m.UserID) AS MemberCount
-	FROM Channels c
-	LEFT JOIN Memberships m ON c.ID = m.ChannelID
-	GROUP BY c.ID;
-	`
+	stmt := channelSelect + `
+	GROUP BY c.ID`
 	rows, err := m.DB.QueryContext(ctx, stmt)
 	if err != nil {
 		return nil, err
@@ -208,13 +382,58 @@ SELECT c.ID, c.OwnerID, c.Name, c.Avatar, c.Banner, c.Description, c.Created, c.
 		if err != nil {
 			return nil, err
 		}
-		// TODO (realtime) get this data freom websockets
-		rnd := RandomInt(1800)
-		c.MembersOnline = rnd
 		channels = append(channels, c)
 	}
 	// fmt.Printf(ErrorMsgs.KeyValuePair, "Total channels", len(Channels))
-	return channels, nil
+	return channels, m.setMembersOnline(ctx, channels...)
+}
+
+// GetTrending returns up to limit public channels ranked by activity since the given
+// time: posts shared to the channel plus members who joined, with recent posts
+// breaking ties. Channels with no activity in the window are left out.
+func (m *ChannelModel) GetTrending(ctx context.Context, since time.Time, limit int) ([]*models.Channel, error) {
+	channels := make([]*models.Channel, 0)
+	if limit <= 0 {
+		return channels, nil
+	}
+
+	stmt := `
+	WITH activity AS (
+		SELECT c.ID AS ChannelID,
+			(SELECT COUNT(*) FROM PostChannels pc JOIN Posts p ON p.ID = pc.PostID
+				WHERE pc.ChannelID = c.ID AND p.DeletedAt IS NULL
+					AND datetime(p.Created) >= datetime(?1)) AS RecentPosts,
+			(SELECT COUNT(*) FROM Memberships nm
+				WHERE nm.ChannelID = c.ID AND datetime(nm.Created) >= datetime(?1)) AS NewMembers
+		FROM Channels c
+		WHERE c.Privacy = 0
+	)` + channelSelect + `
+	JOIN activity a ON a.ChannelID = c.ID
+	WHERE a.RecentPosts + a.NewMembers > 0
+	GROUP BY c.ID
+	ORDER BY a.RecentPosts + a.NewMembers DESC, a.RecentPosts DESC, c.ID
+	LIMIT ?2`
+	rows, err := m.DB.QueryContext(ctx, stmt, since.UTC().Format(time.DateTime), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trending channels: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in GetTrending: %v", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		c, err := parseChannelRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trending channels: %w", err)
+	}
+	return channels, m.setMembersOnline(ctx, channels...)
 }
 
 func isValidColumn(column string) bool {
@@ -285,6 +504,138 @@ func (m *ChannelModel) GetChannelIDFromPost(ctx context.Context, postID int64) (
 	return channelIDs, nil
 }
 
+// SetPostChannels reconciles the channels postID is shared to with channelIDs on
+// behalf of userID: links to new channels are added and links to dropped ones
+// removed, leaving unchanged links as they are. The post's author may make any
+// change; otherwise userID must moderate one of the post's current channels and
+// every channel added or removed. A post must keep at least one channel.
+func (m *ChannelModel) SetPostChannels(ctx context.Context, postID int64, userID models.UUIDField, channelIDs []int64) error {
+	if len(channelIDs) == 0 {
+		return fmt.Errorf("post %d must belong to at least one channel", postID)
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for SetPostChannels: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var authorID models.UUIDField
+	err = tx.QueryRowContext(ctx, "SELECT AuthorID FROM Posts WHERE ID = ? AND DeletedAt IS NULL", postID).Scan(&authorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("no post found for ID %d: %w", postID, ErrPostNotFound)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up post %d: %w", postID, err)
+	}
+
+	var current map[int64]bool
+	if current, err = queryIDSet(ctx, tx, "SELECT ChannelID FROM PostChannels WHERE PostID = ?", postID); err != nil {
+		return fmt.Errorf("failed to get channels of post %d: %w", postID, err)
+	}
+	wanted := make(map[int64]bool, len(channelIDs))
+	var added, removed []int64
+	for _, id := range channelIDs {
+		if !wanted[id] && !current[id] {
+			added = append(added, id)
+		}
+		wanted[id] = true
+	}
+	for id := range current {
+		if !wanted[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	if authorID != userID {
+		var moderated map[int64]bool
+		if moderated, err = queryIDSet(ctx, tx, "SELECT ChannelID FROM Mods WHERE UserID = ?", userID); err != nil {
+			return fmt.Errorf("failed to get channels moderated by user %s: %w", userID, err)
+		}
+		if err = checkModerates(moderated, current, append(added, removed...)); err != nil {
+			err = fmt.Errorf("user %s cannot change the channels of post %d: %w", userID, postID, err)
+			return err
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return tx.Commit()
+	}
+
+	for _, id := range added {
+		var exists bool
+		if err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM Channels WHERE ID = ?)", id).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to look up channel %d: %w", id, err)
+		}
+		if !exists {
+			err = fmt.Errorf("no channel found for ID %d: %w", id, ErrChannelNotFound)
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO PostChannels (ChannelID, PostID, Created) VALUES (?, ?, DateTime('now'))", id, postID); err != nil {
+			return fmt.Errorf("failed to add post %d to channel %d: %w", postID, id, err)
+		}
+	}
+	for _, id := range removed {
+		if _, err = tx.ExecContext(ctx, "DELETE FROM PostChannels WHERE ChannelID = ? AND PostID = ?", id, postID); err != nil {
+			return fmt.Errorf("failed to remove post %d from channel %d: %w", postID, id, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for SetPostChannels: %w", err)
+	}
+	return nil
+}
+
+// checkModerates returns ErrForbidden unless moderated holds one of the post's
+// current channels and every changed channel
+func checkModerates(moderated, current map[int64]bool, changed []int64) error {
+	modOfPost := false
+	for id := range current {
+		modOfPost = modOfPost || moderated[id]
+	}
+	if !modOfPost {
+		return fmt.Errorf("not the author or a moderator of the post: %w", ErrForbidden)
+	}
+	for _, id := range changed {
+		if !moderated[id] {
+			return fmt.Errorf("not a moderator of channel %d: %w", id, ErrForbidden)
+		}
+	}
+	return nil
+}
+
+// queryIDSet runs a query selecting a single integer column and returns the values as a set
+func queryIDSet(ctx context.Context, tx *sql.Tx, query string, args ...any) (map[int64]bool, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
 func (m *ChannelModel) GetChannelNameFromID(ctx context.Context, id int64) (string, error) {
 	var name string
 	stmt := "SELECT Name FROM Channels WHERE ID = ?"
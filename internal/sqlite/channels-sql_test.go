@@ -0,0 +1,520 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestGetChannelByID(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	channelID := insertTestChannel(t, db, owner, "general")
+
+	t.Run("returns the channel", func(t *testing.T) {
+		channel, err := m.GetChannelByID(ctx, channelID)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if channel == nil || channel.ID != channelID || channel.Name != "general" {
+			t.Errorf("Unexpected channel: %+v", channel)
+		}
+	})
+
+	t.Run("counts members", func(t *testing.T) {
+		for _, name := range []string{"first", "second"} {
+			member := insertTestUser(t, db, name)
+			if _, err := db.Exec(`INSERT INTO Memberships (UserID, ChannelID) VALUES (?, ?)`, member, channelID); err != nil {
+				t.Fatalf("Failed to insert membership: %v", err)
+			}
+		}
+		channel, err := m.GetChannelByID(ctx, channelID)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if channel.Members != 2 || channel.OwnerID != owner {
+			t.Errorf("Expected 2 members and the owner set, got %+v", channel)
+		}
+	})
+
+	t.Run("missing ID returns nil and ErrChannelNotFound", func(t *testing.T) {
+		channel, err := m.GetChannelByID(ctx, channelID+100)
+		if channel != nil {
+			t.Errorf("Expected nil channel, got %+v", channel)
+		}
+		if !errors.Is(err, ErrChannelNotFound) {
+			t.Errorf("Expected ErrChannelNotFound, got %v", err)
+		}
+	})
+}
+
+func TestGetChannelsByIDs(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	first := insertTestChannel(t, db, owner, "first")
+	second := insertTestChannel(t, db, owner, "second")
+	third := insertTestChannel(t, db, owner, "third")
+	if _, err := db.Exec(`INSERT INTO Memberships (UserID, ChannelID) VALUES (?, ?)`, owner, second); err != nil {
+		t.Fatalf("Failed to insert membership: %v", err)
+	}
+
+	t.Run("returns channels in the requested order", func(t *testing.T) {
+		channels, err := m.GetChannelsByIDs(ctx, third, first, second)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if len(channels) != 3 || channels[0].ID != third || channels[1].ID != first || channels[2].ID != second {
+			t.Fatalf("Unexpected channels: %v", channels)
+		}
+		if channels[2].Members != 1 || channels[1].Members != 0 {
+			t.Errorf("Expected member counts 0 and 1, got %d and %d", channels[1].Members, channels[2].Members)
+		}
+	})
+
+	t.Run("skips missing IDs and duplicates", func(t *testing.T) {
+		channels, err := m.GetChannelsByIDs(ctx, first, third+100, first)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if len(channels) != 1 || channels[0].ID != first {
+			t.Errorf("Expected only the first channel, got %v", channels)
+		}
+	})
+
+	t.Run("no IDs", func(t *testing.T) {
+		channels, err := m.GetChannelsByIDs(ctx)
+		if err != nil || len(channels) != 0 {
+			t.Errorf("Expected an empty result, got %v, %v", channels, err)
+		}
+	})
+}
+
+func TestGetNameOfChannel(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	channelID := insertTestChannel(t, db, owner, "general")
+
+	name, err := m.GetNameOfChannel(ctx, channelID)
+	if err != nil || name != "general" {
+		t.Errorf("Expected general, got %q, %v", name, err)
+	}
+	if _, err := m.GetNameOfChannel(ctx, channelID+100); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("Expected ErrChannelNotFound, got %v", err)
+	}
+}
+
+func TestSetPrivacy(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	member := insertTestUser(t, db, "member")
+	channelID := insertTestChannel(t, db, owner, "general")
+
+	privacy := func() bool {
+		t.Helper()
+		channel, err := m.GetChannelByID(ctx, channelID)
+		if err != nil {
+			t.Fatalf("GetChannelByID failed: %v", err)
+		}
+		return channel.Privacy
+	}
+
+	t.Run("owner toggles privacy and each change is recorded", func(t *testing.T) {
+		if err := m.SetPrivacy(ctx, channelID, owner, true); err != nil {
+			t.Fatalf("SetPrivacy failed: %v", err)
+		}
+		if !privacy() {
+			t.Error("Expected the channel to be private")
+		}
+		if err := m.SetPrivacy(ctx, channelID, owner, false); err != nil {
+			t.Fatalf("SetPrivacy failed: %v", err)
+		}
+		if privacy() {
+			t.Error("Expected the channel to be public again")
+		}
+
+		changes, err := m.PrivacyChanges(ctx, channelID)
+		if err != nil {
+			t.Fatalf("PrivacyChanges failed: %v", err)
+		}
+		if len(changes) != 2 || !changes[0].Private || changes[1].Private {
+			t.Fatalf("Expected [private, public], got %+v", changes)
+		}
+		for _, c := range changes {
+			if c.ChangedBy != owner || c.Created.IsZero() {
+				t.Errorf("Expected a dated change by the owner, got %+v", c)
+			}
+		}
+	})
+
+	t.Run("setting the current value is not recorded", func(t *testing.T) {
+		if err := m.SetPrivacy(ctx, channelID, owner, false); err != nil {
+			t.Fatalf("SetPrivacy failed: %v", err)
+		}
+		changes, _ := m.PrivacyChanges(ctx, channelID)
+		if len(changes) != 2 {
+			t.Errorf("Expected 2 recorded changes, got %d", len(changes))
+		}
+	})
+
+	t.Run("non-owner is refused", func(t *testing.T) {
+		err := m.SetPrivacy(ctx, channelID, member, true)
+		if !errors.Is(err, ErrForbidden) {
+			t.Fatalf("Expected ErrForbidden, got %v", err)
+		}
+		if privacy() {
+			t.Error("Expected the channel to stay public")
+		}
+	})
+
+	t.Run("missing channel", func(t *testing.T) {
+		if err := m.SetPrivacy(ctx, channelID+100, owner, true); !errors.Is(err, ErrChannelNotFound) {
+			t.Errorf("Expected ErrChannelNotFound, got %v", err)
+		}
+	})
+}
+
+func TestChannelQueriesDoNotInventOnlineCounts(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	channelID := insertTestChannel(t, db, owner, "general")
+
+	byID, err := m.GetChannelByID(ctx, channelID)
+	if err != nil {
+		t.Fatalf("GetChannelByID failed: %v", err)
+	}
+	all, err := m.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	owned, err := m.OwnedOrJoinedByCurrentUser(ctx, owner)
+	if err != nil {
+		t.Fatalf("OwnedOrJoinedByCurrentUser failed: %v", err)
+	}
+	listed, err := m.GetChannelsByIDs(ctx, channelID)
+	if err != nil {
+		t.Fatalf("GetChannelsByIDs failed: %v", err)
+	}
+
+	channels := append([]*models.Channel{byID}, all...)
+	channels = append(channels, owned...)
+	channels = append(channels, listed...)
+	for _, c := range channels {
+		// without a presence provider no channel has anyone online
+		if c.MembersOnline != 0 {
+			t.Errorf("Channel %d reports %d members online, want 0", c.ID, c.MembersOnline)
+		}
+	}
+}
+
+// fakePresence reports a fixed set of users as online
+type fakePresence []models.UUIDField
+
+func (p fakePresence) OnlineUserIDs() []models.UUIDField { return p }
+
+func TestChannelQueriesCountMembersOnline(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	general := insertTestChannel(t, db, owner, "general")
+	quiet := insertTestChannel(t, db, owner, "quiet")
+	online := insertTestUser(t, db, "online")
+	offline := insertTestUser(t, db, "offline")
+	outsider := insertTestUser(t, db, "outsider")
+	for _, membership := range []struct {
+		user    models.UUIDField
+		channel int64
+	}{{owner, general}, {online, general}, {offline, general}, {offline, quiet}} {
+		if _, err := db.Exec(`INSERT INTO Memberships (UserID, ChannelID) VALUES (?, ?)`, membership.user, membership.channel); err != nil {
+			t.Fatalf("Failed to insert membership: %v", err)
+		}
+	}
+	m := &ChannelModel{DB: db, Presence: fakePresence{owner, online, outsider}}
+
+	want := map[int64]int{general: 2, quiet: 0}
+	check := func(t *testing.T, channels ...*models.Channel) {
+		t.Helper()
+		for _, c := range channels {
+			if c.MembersOnline != want[c.ID] {
+				t.Errorf("Channel %d reports %d members online, want %d", c.ID, c.MembersOnline, want[c.ID])
+			}
+		}
+	}
+
+	t.Run("GetChannelByID", func(t *testing.T) {
+		c, err := m.GetChannelByID(ctx, general)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		check(t, c)
+	})
+
+	t.Run("list variants", func(t *testing.T) {
+		all, err := m.All(ctx)
+		if err != nil {
+			t.Fatalf("All failed: %v", err)
+		}
+		listed, err := m.GetChannelsByIDs(ctx, quiet, general)
+		if err != nil {
+			t.Fatalf("GetChannelsByIDs failed: %v", err)
+		}
+		joined, err := m.OwnedOrJoinedByCurrentUser(ctx, offline)
+		if err != nil {
+			t.Fatalf("OwnedOrJoinedByCurrentUser failed: %v", err)
+		}
+		if len(all) != 2 || len(listed) != 2 || len(joined) != 2 {
+			t.Fatalf("Expected both channels from every query, got %d, %d and %d", len(all), len(listed), len(joined))
+		}
+		check(t, append(append(all, listed...), joined...)...)
+	})
+
+	t.Run("nobody online", func(t *testing.T) {
+		m := &ChannelModel{DB: db, Presence: fakePresence{}}
+		c, err := m.GetChannelByID(ctx, general)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if c.MembersOnline != 0 {
+			t.Errorf("Expected 0 members online, got %d", c.MembersOnline)
+		}
+	})
+}
+
+func TestSetPostChannels(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	general := insertTestChannel(t, db, author, "general")
+	news := insertTestChannel(t, db, author, "news")
+	random := insertTestChannel(t, db, author, "random")
+
+	// newPost creates a post by author shared to the given channels
+	newPost := func(t *testing.T, channelIDs ...int64) int64 {
+		t.Helper()
+		postID := insertTestPost(t, db, author, "post")
+		for _, id := range channelIDs {
+			if err := m.AddPostToChannel(ctx, id, postID); err != nil {
+				t.Fatalf("Failed to add post to channel: %v", err)
+			}
+		}
+		return postID
+	}
+	expectChannels := func(t *testing.T, postID int64, want ...int64) {
+		t.Helper()
+		got, err := m.GetChannelIDFromPost(ctx, postID)
+		if err != nil {
+			t.Fatalf("Failed to get channels of post: %v", err)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected channels %v, got %v", want, got)
+		}
+	}
+	linkIDs := func(t *testing.T, postID int64) []int64 {
+		t.Helper()
+		rows, err := db.Query("SELECT ID FROM PostChannels WHERE PostID = ? ORDER BY ID", postID)
+		if err != nil {
+			t.Fatalf("Failed to query links: %v", err)
+		}
+		defer rows.Close()
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				t.Fatalf("Failed to scan link: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	t.Run("adds new channels", func(t *testing.T) {
+		postID := newPost(t, general)
+		if err := m.SetPostChannels(ctx, postID, author, []int64{general, news, random}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		expectChannels(t, postID, general, news, random)
+	})
+
+	t.Run("removes dropped channels", func(t *testing.T) {
+		postID := newPost(t, general, news, random)
+		if err := m.SetPostChannels(ctx, postID, author, []int64{news}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		expectChannels(t, postID, news)
+	})
+
+	t.Run("unchanged channels are a no-op", func(t *testing.T) {
+		postID := newPost(t, general, news)
+		before := linkIDs(t, postID)
+		if err := m.SetPostChannels(ctx, postID, author, []int64{news, general, news}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if after := linkIDs(t, postID); !slices.Equal(before, after) {
+			t.Errorf("Expected links %v to be kept, got %v", before, after)
+		}
+	})
+
+	t.Run("moderator of every changed channel", func(t *testing.T) {
+		mod := insertTestUser(t, db, "mod")
+		for _, id := range []int64{general, news} {
+			if _, err := db.Exec("INSERT INTO Mods (UserID, ChannelID) VALUES (?, ?)", mod, id); err != nil {
+				t.Fatalf("Failed to insert moderator: %v", err)
+			}
+		}
+		postID := newPost(t, general)
+		if err := m.SetPostChannels(ctx, postID, mod, []int64{news}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		expectChannels(t, postID, news)
+
+		err := m.SetPostChannels(ctx, postID, mod, []int64{news, random})
+		if !errors.Is(err, ErrForbidden) {
+			t.Fatalf("Expected ErrForbidden adding an unmoderated channel, got %v", err)
+		}
+		expectChannels(t, postID, news)
+	})
+
+	t.Run("other users are forbidden", func(t *testing.T) {
+		stranger := insertTestUser(t, db, "stranger")
+		postID := newPost(t, general)
+		for _, channelIDs := range [][]int64{{general}, {general, news}} {
+			if err := m.SetPostChannels(ctx, postID, stranger, channelIDs); !errors.Is(err, ErrForbidden) {
+				t.Errorf("Expected ErrForbidden for %v, got %v", channelIDs, err)
+			}
+		}
+		expectChannels(t, postID, general)
+	})
+
+	t.Run("rejects bad input without changes", func(t *testing.T) {
+		postID := newPost(t, general)
+		if err := m.SetPostChannels(ctx, postID, author, []int64{news, random + 100}); !errors.Is(err, ErrChannelNotFound) {
+			t.Errorf("Expected ErrChannelNotFound, got %v", err)
+		}
+		if err := m.SetPostChannels(ctx, postID, author, nil); err == nil {
+			t.Error("Expected an error removing every channel")
+		}
+		expectChannels(t, postID, general)
+
+		if err := m.SetPostChannels(ctx, postID+100, author, []int64{general}); !errors.Is(err, ErrPostNotFound) {
+			t.Errorf("Expected ErrPostNotFound, got %v", err)
+		}
+	})
+}
+
+func TestGetTrending(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	since := now.Add(-24 * time.Hour)
+	recent := now.Add(-time.Hour).Format(time.DateTime)
+	old := now.Add(-72 * time.Hour).Format(time.DateTime)
+
+	owner := insertTestUser(t, db, "owner")
+	busy := insertTestChannel(t, db, owner, "busy")
+	growing := insertTestChannel(t, db, owner, "growing")
+	chatty := insertTestChannel(t, db, owner, "chatty")
+	stale := insertTestChannel(t, db, owner, "stale")
+	hidden := insertTestChannel(t, db, owner, "hidden")
+	if _, err := db.Exec("UPDATE Channels SET Privacy = 1 WHERE ID = ?", hidden); err != nil {
+		t.Fatalf("Failed to make channel private: %v", err)
+	}
+
+	addPosts := func(channelID int64, n int, created string) {
+		t.Helper()
+		for range n {
+			postID := insertTestPost(t, db, owner, "post")
+			if _, err := db.Exec("UPDATE Posts SET Created = ? WHERE ID = ?", created, postID); err != nil {
+				t.Fatalf("Failed to backdate post: %v", err)
+			}
+			if err := m.AddPostToChannel(ctx, channelID, postID); err != nil {
+				t.Fatalf("Failed to add post to channel: %v", err)
+			}
+		}
+	}
+	members := 0
+	addMembers := func(channelID int64, n int, created string) {
+		t.Helper()
+		for range n {
+			members++
+			user := insertTestUser(t, db, fmt.Sprintf("member%d", members))
+			if _, err := db.Exec("INSERT INTO Memberships (UserID, ChannelID, Created) VALUES (?, ?, ?)",
+				user, channelID, created); err != nil {
+				t.Fatalf("Failed to insert membership: %v", err)
+			}
+		}
+	}
+
+	// busy scores 5, growing 4, chatty 4 but with more posts than growing
+	addPosts(busy, 3, recent)
+	addMembers(busy, 2, recent)
+	addMembers(growing, 4, recent)
+	addPosts(growing, 5, old)
+	addPosts(chatty, 3, recent)
+	addMembers(chatty, 1, recent)
+	// stale has only activity from before the window, hidden is private
+	addPosts(stale, 10, old)
+	addMembers(stale, 10, old)
+	addPosts(hidden, 10, recent)
+
+	t.Run("ranks active public channels", func(t *testing.T) {
+		channels, err := m.GetTrending(ctx, since, 10)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		var got []int64
+		for _, c := range channels {
+			got = append(got, c.ID)
+		}
+		if want := []int64{busy, chatty, growing}; !slices.Equal(got, want) {
+			t.Fatalf("Expected ranking %v, got %v", want, got)
+		}
+		if channels[2].Members != 4 {
+			t.Errorf("Expected growing to report 4 members, got %d", channels[2].Members)
+		}
+	})
+
+	t.Run("respects the limit", func(t *testing.T) {
+		channels, err := m.GetTrending(ctx, since, 1)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if len(channels) != 1 || channels[0].ID != busy {
+			t.Errorf("Expected only busy, got %v", channels)
+		}
+	})
+
+	t.Run("wider window counts older activity", func(t *testing.T) {
+		channels, err := m.GetTrending(ctx, now.Add(-7*24*time.Hour), 1)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if len(channels) != 1 || channels[0].ID != stale {
+			t.Errorf("Expected stale to lead a week-long window, got %v", channels)
+		}
+	})
+}
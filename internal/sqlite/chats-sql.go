@@ -3,7 +3,10 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/gary-norman/forum/internal/models"
 )
@@ -12,10 +15,26 @@ type ChatModel struct {
 	DB *sql.DB
 }
 
-func (c *ChatModel) CreateChat(ctx context.Context, chatType, name string, groupID, buddyID models.UUIDField) (models.UUIDField, error) {
+// errInvalidChatType is returned when a chat is created with an unknown type
+var errInvalidChatType = errors.New("chat type must be buddy or group")
+
+// CreateChat stores a new chat. A buddy chat keeps only buddyID and a group chat only
+// groupID; the other is stored as NULL. LastActive starts out equal to Created, so a
+// chat nobody has written in yet sorts by when it was opened.
+func (c *ChatModel) CreateChat(ctx context.Context, chatType models.ChatType, name string, groupID, buddyID models.UUIDField) (models.UUIDField, error) {
+	if !chatType.Valid() {
+		return models.UUIDField{}, fmt.Errorf("%w, got %q", errInvalidChatType, chatType)
+	}
+	var group, buddy models.NullableUUIDField
+	if chatType == models.ChatTypeGroup {
+		group = models.NullableUUIDField{UUID: groupID, Valid: true}
+	} else {
+		buddy = models.NullableUUIDField{UUID: buddyID, Valid: true}
+	}
+
 	chatID := models.NewUUIDField()
-	query := "INSERT INTO Chats (ID, Type, Name, GroupID, BuddyID, Created) VALUES (?, ?, ?, ?, ?, DateTime('now'))"
-	_, err := c.DB.ExecContext(ctx, query, chatID, chatType, name, groupID, buddyID)
+	query := "INSERT INTO Chats (ID, Type, Name, GroupID, BuddyID, Created, LastActive) VALUES (?, ?, ?, ?, ?, DateTime('now'), DateTime('now'))"
+	_, err := c.DB.ExecContext(ctx, query, chatID, chatType, name, group, buddy)
 	if err != nil {
 		return models.UUIDField{}, fmt.Errorf("failed to insert chat: %w", err)
 	}
@@ -23,47 +42,106 @@ func (c *ChatModel) CreateChat(ctx context.Context, chatType, name string, group
 	return chatID, nil
 }
 
-func (c *ChatModel) CreateChatMessage(ctx context.Context, chatID, userID models.UUIDField, message string) (models.UUIDField, error) {
+// CreateChatMessage stores a message and returns its ID together with its sequence
+// number, which is one more than the highest in the chat. The number is assigned by
+// the INSERT itself, so concurrent senders to one chat always get distinct, increasing
+// values and clients can order live messages by it.
+func (c *ChatModel) CreateChatMessage(ctx context.Context, chatID, userID models.UUIDField, message string) (models.UUIDField, int64, error) {
 	messageID := models.NewUUIDField()
-	query := "INSERT INTO Messages (ID, ChatID, UserID, Created, Content) VALUES (?, ?, ?, DateTime('now'), ?)"
-	_, err := c.DB.ExecContext(ctx, query, messageID, chatID, userID, message)
+	query := `INSERT INTO Messages (ID, ChatID, UserID, Created, Content, Sequence)
+		SELECT ?, ?, ?, DateTime('now'), ?, COALESCE(MAX(Sequence), 0) + 1
+		FROM Messages WHERE ChatID = ?
+		RETURNING Sequence`
+	var sequence int64
+	err := c.DB.QueryRowContext(ctx, query, messageID, chatID, userID, message, chatID).Scan(&sequence)
 	if err != nil {
-		return models.UUIDField{}, fmt.Errorf("failed to insert message: %w", err)
+		return models.UUIDField{}, 0, fmt.Errorf("failed to insert message: %w", err)
 	}
 
-	return messageID, nil
+	return messageID, sequence, nil
 }
 
 func (c *ChatModel) AttachUserToChat(ctx context.Context, chatID, userID models.UUIDField) error {
 	query := "INSERT INTO ChatUsers (ChatID, UserID) VALUES (?, ?)"
 	_, err := c.DB.ExecContext(ctx, query, chatID, userID)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("user %s is already in chat %s: %w", userID, chatID, ErrConflict)
+		}
 		return fmt.Errorf("failed to attach user to chat: %w", err)
 	}
 
 	return nil
 }
 
+// GetUserChatIDs returns only the IDs of the chats userID belongs to. It is the
+// cheap lookup for subscribing a newly connected client to its chats, where
+// GetUserChats would load every chat row just to discard it.
 func (c *ChatModel) GetUserChatIDs(ctx context.Context, userID models.UUIDField) ([]models.UUIDField, error) {
 	query := `SELECT ChatID FROM ChatUsers WHERE UserID = ?`
 	rows, err := c.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user chat IDs: %w", err)
 	}
-	defer rows.Close()
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
 
 	var chatIDs []models.UUIDField
 	for rows.Next() {
+		// a client that disconnects mid-lookup should not keep the query running
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("get user chat IDs cancelled: %w", err)
+		}
 		var chatID models.UUIDField
 		if err := rows.Scan(&chatID); err != nil {
 			return nil, fmt.Errorf("failed to scan chat ID: %w", err)
 		}
 		chatIDs = append(chatIDs, chatID)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat IDs: %w", err)
+	}
 
 	return chatIDs, nil
 }
 
+// GetContacts returns the distinct users who share at least one chat with userID,
+// buddy or group, excluding userID itself. It feeds presence: these are the users
+// whose online status userID gets to see.
+func (c *ChatModel) GetContacts(ctx context.Context, userID models.UUIDField) ([]models.UUIDField, error) {
+	query := `SELECT DISTINCT other.UserID
+		FROM ChatUsers mine
+		INNER JOIN ChatUsers other ON other.ChatID = mine.ChatID
+		WHERE mine.UserID = ? AND other.UserID != mine.UserID
+		ORDER BY other.UserID`
+	rows, err := c.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contacts: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	var contacts []models.UUIDField
+	for rows.Next() {
+		var contact models.UUIDField
+		if err := rows.Scan(&contact); err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		contacts = append(contacts, contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contacts: %w", err)
+	}
+
+	return contacts, nil
+}
+
 // GetChat retrieves a single chat by its ID
 func (c *ChatModel) GetChat(ctx context.Context, chatID models.UUIDField) (*models.Chat, error) {
 	// Begin the transaction
@@ -93,7 +171,7 @@ func (c *ChatModel) GetChat(ctx context.Context, chatID models.UUIDField) (*mode
 	err = row.Scan(&chat.ID, &chat.ChatType, &chat.Name, &chat.Created, &chat.LastActive, &groupID, &buddyID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("chat not found: %s", chatID)
+			return nil, fmt.Errorf("chat %s: %w", chatID, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to scan chat: %w", err)
 	}
@@ -114,7 +192,9 @@ func (c *ChatModel) GetChat(ctx context.Context, chatID models.UUIDField) (*mode
 	return &chat, nil
 }
 
-// GetUserChats retrieves all chats for a specific user
+// GetUserChats retrieves all chats for a specific user, most recently active first.
+// A chat without a LastActive counts as active when it was created, and ties are
+// broken by Created and then ID so the order is stable.
 func (c *ChatModel) GetUserChats(ctx context.Context, userID models.UUIDField) ([]models.Chat, error) {
 	// Begin the transaction
 	tx, err := c.DB.BeginTx(ctx, nil)
@@ -135,11 +215,11 @@ func (c *ChatModel) GetUserChats(ctx context.Context, userID models.UUIDField) (
 	}()
 
 	query := `
-		SELECT c.ID, c.Type, c.Name, c.Created, c.LastActive, c.GroupID, c.BuddyID
+		SELECT c.ID, c.Type, COALESCE(c.Name, ''), c.Created, c.LastActive, c.GroupID, c.BuddyID
 		FROM Chats c
 		INNER JOIN ChatUsers cu ON c.ID = cu.ChatID
 		WHERE cu.UserID = ?
-		ORDER BY c.LastActive DESC
+		ORDER BY COALESCE(c.LastActive, c.Created) DESC, c.Created DESC, c.ID DESC
 	`
 
 	rows, err := tx.QueryContext(ctx, query, userID)
@@ -152,11 +232,16 @@ func (c *ChatModel) GetUserChats(ctx context.Context, userID models.UUIDField) (
 	for rows.Next() {
 		var chat models.Chat
 		var buddyID, groupID models.NullableUUIDField
+		var lastActive sql.NullTime
 
-		err := rows.Scan(&chat.ID, &chat.ChatType, &chat.Name, &chat.Created, &chat.LastActive, &groupID, &buddyID)
+		err := rows.Scan(&chat.ID, &chat.ChatType, &chat.Name, &chat.Created, &lastActive, &groupID, &buddyID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chat: %w", err)
 		}
+		chat.LastActive = chat.Created
+		if lastActive.Valid {
+			chat.LastActive = lastActive.Time
+		}
 
 		if groupID.Valid {
 			chat.Group.ID = groupID.UUID
@@ -199,14 +284,14 @@ func (c *ChatModel) GetChatMessages(ctx context.Context, chatID models.UUIDField
 
 	query := `
 		SELECT
-			m.ID, m.ChatID, m.Created, m.Content,
+			m.ID, m.ChatID, m.Sequence, m.Created, m.Content,
 			u.ID, u.Username, u.EmailAddress, u.Avatar, u.Banner,
 			u.Description, u.Usertype, u.Created, u.Updated, u.IsFlagged,
 			u.SessionToken, u.CSRFToken, u.HashedPassword
 		FROM Messages m
 		LEFT JOIN Users u ON m.UserID = u.ID
 		WHERE m.ChatID = ?
-		ORDER BY m.Created ASC
+		ORDER BY m.Sequence ASC
 	`
 
 	rows, err := tx.QueryContext(ctx, query, chatID)
@@ -222,7 +307,7 @@ func (c *ChatModel) GetChatMessages(ctx context.Context, chatID models.UUIDField
 
 		// Use sql.Null types for potentially NULL user fields
 		var (
-			userID         sql.NullString
+			userID         models.NullableUUIDField
 			username       sql.NullString
 			email          sql.NullString
 			avatar         sql.NullString
@@ -238,7 +323,7 @@ func (c *ChatModel) GetChatMessages(ctx context.Context, chatID models.UUIDField
 		)
 
 		err := rows.Scan(
-			&message.ID, &message.ChatID, &message.Created, &message.Content,
+			&message.ID, &message.ChatID, &message.Sequence, &message.Created, &message.Content,
 			&userID, &username, &email, &avatar, &banner,
 			&description, &usertype, &userCreated, &userUpdated, &isFlagged,
 			&sessionToken, &csrfToken, &hashedPassword,
@@ -249,12 +334,7 @@ func (c *ChatModel) GetChatMessages(ctx context.Context, chatID models.UUIDField
 
 		// Only populate Sender if user exists (LEFT JOIN might return NULLs)
 		if userID.Valid {
-			id, err := models.UUIDFieldFromString(userID.String)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse user ID: %w", err)
-			}
-
-			user.ID = id
+			user.ID = userID.UUID
 			user.Username = username.String
 			user.Email = email.String
 			user.Avatar = avatar.String
@@ -276,6 +356,13 @@ func (c *ChatModel) GetChatMessages(ctx context.Context, chatID models.UUIDField
 
 		messages = append(messages, message)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat messages: %w", err)
+	}
+
+	if err = attachMessageReactions(ctx, tx, chatID, messages); err != nil {
+		return nil, err
+	}
 
 	// Commit the transaction
 	commitErr := tx.Commit()
@@ -285,3 +372,166 @@ func (c *ChatModel) GetChatMessages(ctx context.Context, chatID models.UUIDField
 
 	return messages, nil
 }
+
+// attachMessageReactions fills in Reactions on messages, all of which belong to chatID
+func attachMessageReactions(ctx context.Context, tx *sql.Tx, chatID models.UUIDField, messages []models.ChatMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	query := `SELECT r.MessageID, r.Emoji, r.UserID
+		FROM MessageReactions r
+		INNER JOIN Messages m ON m.ID = r.MessageID
+		WHERE m.ChatID = ?
+		ORDER BY r.Created, r.rowid`
+	rows, err := tx.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to query message reactions: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	byMessage := make(map[models.UUIDField]*models.ChatMessage, len(messages))
+	for i := range messages {
+		byMessage[messages[i].ID] = &messages[i]
+	}
+	for rows.Next() {
+		var messageID, userID models.UUIDField
+		var emoji string
+		if err := rows.Scan(&messageID, &emoji, &userID); err != nil {
+			return fmt.Errorf("failed to scan message reaction: %w", err)
+		}
+		message, ok := byMessage[messageID]
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(message.Reactions, func(r models.MessageReactionSummary) bool { return r.Emoji == emoji })
+		if i < 0 {
+			message.Reactions = append(message.Reactions, models.MessageReactionSummary{Emoji: emoji})
+			i = len(message.Reactions) - 1
+		}
+		message.Reactions[i].Count++
+		message.Reactions[i].UserIDs = append(message.Reactions[i].UserIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating message reactions: %w", err)
+	}
+	return nil
+}
+
+// maxEmojiBytes bounds a message reaction; enough for multi-codepoint emoji such as
+// families and flags, but not for arbitrary text
+const maxEmojiBytes = 32
+
+// errInvalidEmoji is returned when a message reaction is empty or too long
+var errInvalidEmoji = fmt.Errorf("emoji must be between 1 and %d bytes", maxEmojiBytes)
+
+// ReactToMessage toggles userID's emoji on messageID and reports whether the
+// reaction is now present (true) or was removed (false). Only participants of the
+// message's chat may react: a missing message returns ErrNotFound and a user outside
+// the chat ErrForbidden.
+func (c *ChatModel) ReactToMessage(ctx context.Context, messageID, userID models.UUIDField, emoji string) (bool, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || len(emoji) > maxEmojiBytes {
+		return false, errInvalidEmoji
+	}
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction for ReactToMessage: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var participant bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (
+			SELECT 1 FROM ChatUsers cu WHERE cu.ChatID = m.ChatID AND cu.UserID = ?
+		) FROM Messages m WHERE m.ID = ?`, userID, messageID).Scan(&participant)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+		return false, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up message %s: %w", messageID, err)
+	}
+	if !participant {
+		err = fmt.Errorf("user %s is not in the chat of message %s: %w", userID, messageID, ErrForbidden)
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM MessageReactions WHERE MessageID = ? AND UserID = ? AND Emoji = ?",
+		messageID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove message reaction: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check removed message reaction: %w", err)
+	}
+	if removed == 0 {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO MessageReactions (MessageID, UserID, Emoji) VALUES (?, ?, ?)",
+			messageID, userID, emoji); err != nil {
+			return false, fmt.Errorf("failed to add message reaction: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction for ReactToMessage: %w", err)
+	}
+	return removed == 0, nil
+}
+
+// MarkRead records that userID has read chatID up to the message numbered sequence and
+// returns the position now stored. The position never moves backwards and never past
+// the chat's latest message. A user outside the chat gets ErrForbidden.
+func (c *ChatModel) MarkRead(ctx context.Context, chatID, userID models.UUIDField, sequence int64) (int64, error) {
+	query := `UPDATE ChatUsers SET LastReadSequence = MAX(LastReadSequence,
+			MIN(?, (SELECT COALESCE(MAX(Sequence), 0) FROM Messages WHERE ChatID = ?)))
+		WHERE ChatID = ? AND UserID = ?
+		RETURNING LastReadSequence`
+	var stored int64
+	err := c.DB.QueryRowContext(ctx, query, sequence, chatID, chatID, userID).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("user %s is not in chat %s: %w", userID, chatID, ErrForbidden)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark chat %s read for user %s: %w", chatID, userID, err)
+	}
+	return stored, nil
+}
+
+// GetReadStates returns how far each participant of chatID has read, ordered by user ID
+func (c *ChatModel) GetReadStates(ctx context.Context, chatID models.UUIDField) ([]models.ChatReadState, error) {
+	query := "SELECT UserID, LastReadSequence FROM ChatUsers WHERE ChatID = ? ORDER BY UserID"
+	rows, err := c.DB.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query read states for chat %s: %w", chatID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in GetReadStates: %v", closeErr)
+		}
+	}()
+
+	var states []models.ChatReadState
+	for rows.Next() {
+		var state models.ChatReadState
+		if err := rows.Scan(&state.UserID, &state.LastReadSequence); err != nil {
+			return nil, fmt.Errorf("failed to scan read state: %w", err)
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate read states: %w", err)
+	}
+	return states, nil
+}
@@ -0,0 +1,402 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestGetUserChatIDs(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChatModel{DB: db}
+
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	carol := insertTestUser(t, db, "carol")
+	withBob := insertTestBuddyChat(t, db, alice, bob)
+	withCarol := insertTestBuddyChat(t, db, alice, carol)
+
+	t.Run("returns every chat the user is in", func(t *testing.T) {
+		ids, err := m.GetUserChatIDs(context.Background(), alice)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		got := map[string]bool{}
+		for _, id := range ids {
+			got[id.String()] = true
+		}
+		if len(ids) != 2 || !got[withBob.String()] || !got[withCarol.String()] {
+			t.Errorf("Expected both chats, got %v", ids)
+		}
+	})
+
+	t.Run("returns only the user's chats", func(t *testing.T) {
+		ids, err := m.GetUserChatIDs(context.Background(), bob)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if len(ids) != 1 || ids[0] != withBob {
+			t.Errorf("Expected only %s, got %v", withBob, ids)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ids, err := m.GetUserChatIDs(ctx, alice)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if ids != nil {
+			t.Errorf("Expected no IDs, got %v", ids)
+		}
+	})
+}
+
+func TestGetContacts(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChatModel{DB: db}
+	ctx := context.Background()
+
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	carol := insertTestUser(t, db, "carol")
+	dave := insertTestUser(t, db, "dave")
+	erin := insertTestUser(t, db, "erin")
+
+	// bob is both alice's buddy and in her group; dave only shares a chat with erin
+	insertTestBuddyChat(t, db, alice, bob)
+	insertTestBuddyChat(t, db, dave, erin)
+	group, err := m.CreateChat(ctx, models.ChatTypeGroup, "group", models.NewUUIDField(), models.UUIDField{})
+	if err != nil {
+		t.Fatalf("CreateChat failed: %v", err)
+	}
+	for _, member := range []models.UUIDField{alice, bob, carol} {
+		if err := m.AttachUserToChat(ctx, group, member); err != nil {
+			t.Fatalf("AttachUserToChat failed: %v", err)
+		}
+	}
+
+	sorted := func(ids ...models.UUIDField) []models.UUIDField {
+		slices.SortFunc(ids, func(a, b models.UUIDField) int { return bytes.Compare(a.UUID[:], b.UUID[:]) })
+		return ids
+	}
+	tests := []struct {
+		name string
+		user models.UUIDField
+		want []models.UUIDField
+	}{
+		{"buddy and group overlap once", alice, sorted(bob, carol)},
+		{"group member sees the whole group", carol, sorted(alice, bob)},
+		{"separate chats stay separate", dave, []models.UUIDField{erin}},
+		{"user without chats", insertTestUser(t, db, "frank"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.GetContacts(ctx, tt.user)
+			if err != nil {
+				t.Fatalf("GetContacts failed: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCreateChat_Type(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChatModel{DB: db}
+	ctx := context.Background()
+
+	buddy := insertTestUser(t, db, "buddy")
+	group := models.NewUUIDField()
+
+	for _, chatType := range []models.ChatType{models.ChatTypeBuddy, models.ChatTypeGroup} {
+		t.Run(string(chatType), func(t *testing.T) {
+			id, err := m.CreateChat(ctx, chatType, "chat", group, buddy)
+			if err != nil {
+				t.Fatalf("CreateChat failed: %v", err)
+			}
+			chat, err := m.GetChat(ctx, id)
+			if err != nil {
+				t.Fatalf("GetChat failed: %v", err)
+			}
+			if chat.ChatType != chatType {
+				t.Errorf("Expected type %q, got %q", chatType, chat.ChatType)
+			}
+			isGroup := chatType == models.ChatTypeGroup
+			if (chat.Group.ID == group) != isGroup || (chat.Buddy != nil) == isGroup {
+				t.Errorf("Expected only the %s side set, got group %v and buddy %v", chatType, chat.Group.ID, chat.Buddy)
+			}
+		})
+	}
+
+	t.Run("invalid type is rejected", func(t *testing.T) {
+		for _, chatType := range []models.ChatType{"", "Buddy", "buddies"} {
+			if _, err := m.CreateChat(ctx, chatType, "chat", group, buddy); !errors.Is(err, errInvalidChatType) {
+				t.Errorf("%q: expected errInvalidChatType, got %v", chatType, err)
+			}
+		}
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM Chats").Scan(&n); err != nil {
+			t.Fatalf("Failed to count chats: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("Expected only the two valid chats to be stored, got %d", n)
+		}
+	})
+}
+
+func TestGetUserChats_Order(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChatModel{DB: db}
+
+	alice := insertTestUser(t, db, "alice")
+	chat := func(buddy, created, lastActive string) models.UUIDField {
+		t.Helper()
+		id := insertTestBuddyChat(t, db, alice, insertTestUser(t, db, buddy))
+		if _, err := db.Exec("UPDATE Chats SET Created = ?, LastActive = ? WHERE ID = ?", created, lastActive, id); err != nil {
+			t.Fatalf("Failed to set chat times: %v", err)
+		}
+		return id
+	}
+
+	// two never-messaged chats opened in the same second, one older empty chat, and
+	// one old chat with a recent message
+	emptyA := chat("bob", "2026-01-02 10:00:00", "2026-01-02 10:00:00")
+	emptyB := chat("carol", "2026-01-02 10:00:00", "2026-01-02 10:00:00")
+	older := chat("dave", "2026-01-01 08:00:00", "2026-01-01 08:00:00")
+	active := chat("erin", "2026-01-01 09:00:00", "2026-01-03 12:00:00")
+
+	tied := []models.UUIDField{emptyA, emptyB}
+	if bytes.Compare(emptyA.UUID[:], emptyB.UUID[:]) < 0 {
+		tied = []models.UUIDField{emptyB, emptyA}
+	}
+	want := []models.UUIDField{active, tied[0], tied[1], older}
+
+	for range 3 {
+		chats, err := m.GetUserChats(context.Background(), alice)
+		if err != nil {
+			t.Fatalf("GetUserChats failed: %v", err)
+		}
+		var got []models.UUIDField
+		for _, c := range chats {
+			got = append(got, c.ID)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("Expected order %v, got %v", want, got)
+		}
+	}
+}
+
+func TestCreateChatMessage_Sequence(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChatModel{DB: db}
+	ctx := context.Background()
+
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	carol := insertTestUser(t, db, "carol")
+	chatID := insertTestBuddyChat(t, db, alice, bob)
+	otherChatID := insertTestBuddyChat(t, db, alice, carol)
+
+	const senders = 20
+	sequences := make([]int64, senders)
+	var wg sync.WaitGroup
+	errs := make(chan error, senders)
+	for i := range senders {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sender := alice
+			if i%2 == 1 {
+				sender = bob
+			}
+			_, seq, err := m.CreateChatMessage(ctx, chatID, sender, fmt.Sprintf("message %d", i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			sequences[i] = seq
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("CreateChatMessage failed: %v", err)
+	}
+
+	seen := make(map[int64]bool)
+	for i, seq := range sequences {
+		if seq < 1 || seq > senders || seen[seq] {
+			t.Fatalf("Message %d got sequence %d; want a unique value in 1..%d", i, seq, senders)
+		}
+		seen[seq] = true
+	}
+
+	messages, err := m.GetChatMessages(ctx, chatID)
+	if err != nil {
+		t.Fatalf("GetChatMessages failed: %v", err)
+	}
+	if len(messages) != senders {
+		t.Fatalf("Expected %d messages, got %d", senders, len(messages))
+	}
+	for i, msg := range messages {
+		if msg.Sequence != int64(i+1) {
+			t.Errorf("Expected message %d to have sequence %d, got %d", i, i+1, msg.Sequence)
+		}
+	}
+
+	// each chat numbers its own messages
+	_, seq, err := m.CreateChatMessage(ctx, otherChatID, carol, "hello")
+	if err != nil {
+		t.Fatalf("CreateChatMessage failed: %v", err)
+	}
+	if seq != 1 {
+		t.Errorf("Expected the first message in another chat to be 1, got %d", seq)
+	}
+}
+
+func TestReactToMessage(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChatModel{DB: db}
+	ctx := context.Background()
+
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	outsider := insertTestUser(t, db, "outsider")
+	chatID := insertTestBuddyChat(t, db, alice, bob)
+	first, _, err := m.CreateChatMessage(ctx, chatID, alice, "hello")
+	if err != nil {
+		t.Fatalf("CreateChatMessage failed: %v", err)
+	}
+	second, _, err := m.CreateChatMessage(ctx, chatID, bob, "hi")
+	if err != nil {
+		t.Fatalf("CreateChatMessage failed: %v", err)
+	}
+
+	react := func(messageID, userID models.UUIDField, emoji string, wantAdded bool) {
+		t.Helper()
+		added, err := m.ReactToMessage(ctx, messageID, userID, emoji)
+		if err != nil {
+			t.Fatalf("ReactToMessage failed: %v", err)
+		}
+		if added != wantAdded {
+			t.Fatalf("Expected added=%v, got %v", wantAdded, added)
+		}
+	}
+	summaries := func() map[models.UUIDField][]models.MessageReactionSummary {
+		t.Helper()
+		messages, err := m.GetChatMessages(ctx, chatID)
+		if err != nil {
+			t.Fatalf("GetChatMessages failed: %v", err)
+		}
+		got := map[models.UUIDField][]models.MessageReactionSummary{}
+		for _, msg := range messages {
+			got[msg.ID] = msg.Reactions
+		}
+		return got
+	}
+
+	t.Run("add and summarise", func(t *testing.T) {
+		react(first, bob, "👍", true)
+		react(first, alice, "❤️", true)
+		react(first, alice, "👍", true)
+		got := summaries()
+		want := []models.MessageReactionSummary{
+			{Emoji: "👍", Count: 2, UserIDs: []models.UUIDField{bob, alice}},
+			{Emoji: "❤️", Count: 1, UserIDs: []models.UUIDField{alice}},
+		}
+		if !reflect.DeepEqual(got[first], want) {
+			t.Errorf("Expected %+v, got %+v", want, got[first])
+		}
+		if got[second] != nil {
+			t.Errorf("Expected no reactions on the second message, got %+v", got[second])
+		}
+	})
+
+	t.Run("reacting again removes it", func(t *testing.T) {
+		react(first, bob, "👍", false)
+		react(first, alice, "❤️", false)
+		want := []models.MessageReactionSummary{{Emoji: "👍", Count: 1, UserIDs: []models.UUIDField{alice}}}
+		if got := summaries()[first]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("rejected reactions", func(t *testing.T) {
+		if _, err := m.ReactToMessage(ctx, first, outsider, "👍"); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden for a non-participant, got %v", err)
+		}
+		if _, err := m.ReactToMessage(ctx, models.NewUUIDField(), alice, "👍"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a missing message, got %v", err)
+		}
+		for _, emoji := range []string{"", "  ", strings.Repeat("👍", 9)} {
+			if _, err := m.ReactToMessage(ctx, first, alice, emoji); !errors.Is(err, errInvalidEmoji) {
+				t.Errorf("%q: expected errInvalidEmoji, got %v", emoji, err)
+			}
+		}
+	})
+}
+
+func TestMarkRead(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ChatModel{DB: db}
+	ctx := context.Background()
+
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	carol := insertTestUser(t, db, "carol")
+	chatID := insertTestBuddyChat(t, db, alice, bob)
+	for i := range 3 {
+		if _, _, err := m.CreateChatMessage(ctx, chatID, alice, fmt.Sprintf("message %d", i)); err != nil {
+			t.Fatalf("CreateChatMessage failed: %v", err)
+		}
+	}
+
+	steps := []struct {
+		name     string
+		sequence int64
+		want     int64
+	}{
+		{"reads up to a message", 2, 2},
+		{"an older receipt does not go back", 1, 2},
+		{"cannot read past the latest message", 10, 3},
+	}
+	for _, step := range steps {
+		got, err := m.MarkRead(ctx, chatID, bob, step.sequence)
+		if err != nil {
+			t.Fatalf("%s: MarkRead failed: %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: expected %d, got %d", step.name, step.want, got)
+		}
+	}
+
+	if _, err := m.MarkRead(ctx, chatID, carol, 1); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden for a user outside the chat, got %v", err)
+	}
+
+	states, err := m.GetReadStates(ctx, chatID)
+	if err != nil {
+		t.Fatalf("GetReadStates failed: %v", err)
+	}
+	want := map[models.UUIDField]int64{alice: 0, bob: 3}
+	if len(states) != len(want) {
+		t.Fatalf("Expected %d read states, got %+v", len(want), states)
+	}
+	for _, state := range states {
+		if seq, ok := want[state.UserID]; !ok || seq != state.LastReadSequence {
+			t.Errorf("Unexpected read state %+v", state)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/gary-norman/forum/internal/models"
 )
@@ -13,30 +14,36 @@ type CommentModel struct {
 	DB *sql.DB
 }
 
+// commentColumns are the Comments columns the comment queries scan, in order
+const commentColumns = `ID, Content, Created, Updated, CommentedPostID, CommentedCommentID, IsCommentable,
+	IsFlagged, IsReply, Author, AuthorID, AuthorAvatar, ChannelName, ChannelID`
+
 // Upsert inserts or updates a reaction for a specific combination of AuthorID and the parent fields (ChannelID, ReactedPostID, ReactedCommentID). It uses Exists to determine if the reaction already exists.
-func (m *CommentModel) Upsert(ctx context.Context, comment models.Comment) error {
+// It returns the ID of the inserted comment, or 0 when an existing comment was updated.
+func (m *CommentModel) Upsert(ctx context.Context, comment models.Comment) (int64, error) {
 	// Check if the reaction exists
 	exists, err := m.Exists(ctx, comment)
 	if err != nil {
-		return fmt.Errorf("failed to check existence of comment: %w", err)
+		return 0, fmt.Errorf("failed to check existence of comment: %w", err)
 	}
 
 	if exists {
 		// If the reaction exists, update it
 		// fmt.Println("Updating a reaction which already exists (reactions.go :53)")
-		return m.Update(ctx, comment)
+		return 0, m.Update(ctx, comment)
 	}
 	// fmt.Println("Inserting a reaction (reactions.go :56)")
 
 	return m.Insert(ctx, comment)
 }
 
-func (m *CommentModel) Insert(ctx context.Context, comment models.Comment) error {
+// Insert stores a new comment and returns its ID
+func (m *CommentModel) Insert(ctx context.Context, comment models.Comment) (int64, error) {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
 	// fmt.Println("Beginning INSERT INTO transaction")
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction for Insert in Comments: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction for Insert in Comments: %w", err)
 	}
 
 	// Ensure rollback on failure
@@ -57,7 +64,7 @@ func (m *CommentModel) Insert(ctx context.Context, comment models.Comment) error
 		VALUES (?, DateTime('now'), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	// Execute the query, dereferencing the pointers is handled by database/sql
-	_, err = tx.ExecContext(ctx, query,
+	result, err := tx.ExecContext(ctx, query,
 		comment.Content,
 		comment.Author,
 		comment.AuthorID,
@@ -72,17 +79,22 @@ func (m *CommentModel) Insert(ctx context.Context, comment models.Comment) error
 	)
 	// fmt.Printf("Inserting row:\nLiked: %v, Disliked: %v, userID: %v, PostID: %v\n", liked, disliked, authorID, parentPostID)
 	if err != nil {
-		return fmt.Errorf("failed to execute Insert query: %w", err)
+		return 0, fmt.Errorf("failed to execute Insert query: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID for Comments: %w", err)
 	}
 
 	// Commit the transaction
 	err = tx.Commit()
 	// fmt.Println("Committing INSERT INTO transaction")
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction for Insert in Comments: %w", err)
+		return 0, fmt.Errorf("failed to commit transaction for Insert in Comments: %w", err)
 	}
 
-	return nil
+	return id, nil
 }
 
 func (m *CommentModel) Update(ctx context.Context, comment models.Comment) error {
@@ -107,25 +119,24 @@ func (m *CommentModel) Update(ctx context.Context, comment models.Comment) error
 		}
 	}()
 
-	// TODO add Updated field, which should be populated on update
 	// Define the SQL statement
 	query := `UPDATE Comments
-		SET Content = ?, IsCommentable = ?, IsFlagged = ?, Author = ?, AuthorAvatar = ?, ChannelName = ?, ChannelID = ?
-		WHERE AuthorID = ? AND (CommentedPostID = ? OR CommentedCommentID = ?)`
+		SET Content = ?, IsCommentable = ?, IsFlagged = ?, Author = ?, AuthorAvatar = ?, ChannelName = ?, ChannelID = ?,
+			Updated = CURRENT_TIMESTAMP
+		WHERE AuthorID = ? AND (CommentedPostID = ? OR CommentedCommentID = ?) AND DeletedAt IS NULL`
 
 	// Execute the query
 	_, err = tx.ExecContext(ctx, query,
 		comment.Content,
+		comment.IsCommentable,
+		comment.IsFlagged,
 		comment.Author,
-		comment.AuthorID,
 		comment.AuthorAvatar,
 		comment.ChannelName,
 		comment.ChannelID,
+		comment.AuthorID,
 		comment.CommentedPostID,
-		comment.CommentedCommentID,
-		comment.IsCommentable,
-		comment.IsFlagged,
-		comment.IsReply)
+		comment.CommentedCommentID)
 	// fmt.Printf("Updating Comments, where reactionID: %v, PostID: %v and UserID: %v with Liked: %v, Disliked: %v\n", reactionID, reactedPostID, authorID, liked, disliked)
 	if err != nil {
 		return fmt.Errorf("failed to execute Update query: %w", err)
@@ -140,6 +151,114 @@ func (m *CommentModel) Update(ctx context.Context, comment models.Comment) error
 	return nil
 }
 
+// GetByID returns the comment with id; a deleted comment is not found
+func (m *CommentModel) GetByID(ctx context.Context, id int64) (models.Comment, error) {
+	stmt := "SELECT " + commentColumns + " FROM Comments WHERE ID = ? AND DeletedAt IS NULL"
+	c := models.Comment{}
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(
+		&c.ID,
+		&c.Content,
+		&c.Created,
+		&c.Updated,
+		&c.CommentedPostID,
+		&c.CommentedCommentID,
+		&c.IsCommentable,
+		&c.IsFlagged,
+		&c.IsReply,
+		&c.Author,
+		&c.AuthorID,
+		&c.AuthorAvatar,
+		&c.ChannelName,
+		&c.ChannelID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, fmt.Errorf("comment %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to get comment %d: %w", id, err)
+	}
+	return c, nil
+}
+
+// Edit replaces the content of comment id, which only its author may do, and returns
+// the edited comment. The text being replaced is kept as a revision. Flagged marks the
+// new text as caught by a content filter; an edit never clears an existing flag.
+// Saving the same text again changes nothing.
+func (m *CommentModel) Edit(ctx context.Context, id int64, editorID models.UUIDField, content string, flagged bool) (comment models.Comment, err error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return comment, fmt.Errorf("failed to begin transaction for Edit in Comments: %w", err)
+	}
+
+	// Ensure rollback on failure
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarn("Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var previous string
+	var authorID models.UUIDField
+	err = tx.QueryRowContext(ctx, "SELECT Content, AuthorID FROM Comments WHERE ID = ? AND DeletedAt IS NULL", id).Scan(&previous, &authorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return comment, fmt.Errorf("comment %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return comment, fmt.Errorf("failed to get comment %d: %w", id, err)
+	}
+	if authorID != editorID {
+		return comment, fmt.Errorf("only the author may edit comment %d: %w", id, ErrForbidden)
+	}
+
+	if previous != content {
+		stmt := "INSERT INTO CommentRevisions (CommentID, Content, EditorID) VALUES (?, ?, ?)"
+		if _, err = tx.ExecContext(ctx, stmt, id, previous, editorID); err != nil {
+			return comment, fmt.Errorf("failed to store revision of comment %d: %w", id, err)
+		}
+		stmt = "UPDATE Comments SET Content = ?, IsFlagged = IsFlagged OR ?, Updated = CURRENT_TIMESTAMP WHERE ID = ?"
+		if _, err = tx.ExecContext(ctx, stmt, content, flagged, id); err != nil {
+			return comment, fmt.Errorf("failed to edit comment %d: %w", id, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return comment, fmt.Errorf("failed to commit transaction for Edit in Comments: %w", err)
+	}
+	return m.GetByID(ctx, id)
+}
+
+// Revisions returns the earlier versions of comment id, oldest first
+func (m *CommentModel) Revisions(ctx context.Context, id int64) ([]models.CommentRevision, error) {
+	stmt := `SELECT ID, CommentID, Content, EditorID, Created FROM CommentRevisions
+		WHERE CommentID = ? ORDER BY Created, ID`
+	rows, err := m.DB.QueryContext(ctx, stmt, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions of comment %d: %w", id, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in Revisions: %v", closeErr)
+		}
+	}()
+
+	revisions := []models.CommentRevision{}
+	for rows.Next() {
+		var r models.CommentRevision
+		if err := rows.Scan(&r.ID, &r.CommentID, &r.Content, &r.EditorID, &r.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan revision of comment %d: %w", id, err)
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revisions of comment %d: %w", id, err)
+	}
+	return revisions, nil
+}
+
 // Exists helps avoid creating duplicate comments by determining whether a comment for the specific combination of AuthorID, PostID/CommentID and Content
 func (m *CommentModel) Exists(ctx context.Context, comment models.Comment) (bool, error) {
 	// SQL query to check if the comment exists with the provided parameters
@@ -148,7 +267,8 @@ func (m *CommentModel) Exists(ctx context.Context, comment models.Comment) (bool
                 WHERE AuthorID = ? AND
                       CommentedPostID = ? AND
                       CommentedCommentID = ? AND
-                      Content = ?)`
+                      Content = ? AND
+                      DeletedAt IS NULL)`
 
 	var exists bool
 	err := m.DB.QueryRowContext(ctx, stmt,
@@ -160,12 +280,28 @@ func (m *CommentModel) Exists(ctx context.Context, comment models.Comment) (bool
 	return exists, err
 }
 
-// Delete removes a comment from the database by ID
+// Delete marks comment commentID as deleted. The row is kept, so replies below it stay
+// attached to the thread; deleted comments are left out of the comment listings, and
+// AdminPurge removes them for good.
 func (m *CommentModel) Delete(ctx context.Context, commentID int64) error {
-	// Begin the transaction
+	stmt := "UPDATE Comments SET DeletedAt = CURRENT_TIMESTAMP WHERE ID = ? AND DeletedAt IS NULL"
+	result, err := m.DB.ExecContext(ctx, stmt, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment %d: %w", commentID, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("comment %d: %w", commentID, ErrNotFound)
+	}
+	return nil
+}
+
+// AdminPurge permanently removes comment commentID, which must already be deleted,
+// together with every reply below it and the reactions, bookmarks, flags and edit
+// history that refer to them
+func (m *CommentModel) AdminPurge(ctx context.Context, commentID int64) (err error) {
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction for Delete in Comments: %w", err)
+		return fmt.Errorf("failed to begin transaction for AdminPurge in Comments: %w", err)
 	}
 
 	// Ensure rollback on failure
@@ -179,20 +315,89 @@ func (m *CommentModel) Delete(ctx context.Context, commentID int64) error {
 		}
 	}()
 
-	query := `DELETE FROM Comments WHERE ID = ?`
-	// Execute the query, dereferencing the pointers for ID values
-	_, err = m.DB.ExecContext(ctx, query, commentID)
-	// fmt.Printf("Deleting from Reactions where commentID: %v\n", commentID)
+	var deleted bool
+	err = tx.QueryRowContext(ctx, "SELECT DeletedAt IS NOT NULL FROM Comments WHERE ID = ?", commentID).Scan(&deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("comment %d: %w", commentID, ErrNotFound)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to execute Delete query: %w", err)
+		return fmt.Errorf("failed to look up comment %d: %w", commentID, err)
+	}
+	if !deleted {
+		return fmt.Errorf("comment %d must be deleted before it is purged: %w", commentID, ErrConflict)
 	}
 
-	// Commit the transaction
-	err = tx.Commit()
+	ids, err := commentSubtree(ctx, tx, "ID = ?", commentID)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction for Delete in Comments: %w", err)
+		return err
+	}
+	if err = purgeComments(ctx, tx, ids); err != nil {
+		return err
 	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for AdminPurge in Comments: %w", err)
+	}
+	return nil
+}
+
+// commentSubtree returns the IDs of the comments matching where, and of every reply
+// below them
+func commentSubtree(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]any, error) {
+	stmt := `WITH RECURSIVE subtree(ID, Depth) AS (
+			SELECT ID, 0 FROM Comments WHERE ` + where + `
+			UNION
+			SELECT c.ID, subtree.Depth + 1
+			FROM Comments c
+			JOIN subtree ON c.CommentedCommentID = subtree.ID
+			WHERE subtree.Depth < ?
+		)
+		SELECT DISTINCT ID FROM subtree`
+	rows, err := tx.QueryContext(ctx, stmt, append(args, maxCommentDepth)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comment subtree: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in commentSubtree: %v", closeErr)
+		}
+	}()
+
+	var ids []any
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan comment subtree: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comment subtree: %w", err)
+	}
+	return ids, nil
+}
+
+// purgeComments deletes the comments ids and the rows that refer to them. Foreign key
+// cascades only fire on connections that have foreign_keys enabled, so the dependent
+// rows are deleted explicitly, before the comments themselves.
+func purgeComments(ctx context.Context, tx *sql.Tx, ids []any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")"
+	for _, stmt := range []string{
+		"DELETE FROM Reactions WHERE ReactedCommentID IN " + in,
+		"DELETE FROM ReactionEvents WHERE TargetType = 'comment' AND TargetID IN " + in,
+		"DELETE FROM Bookmarks WHERE CommentID IN " + in,
+		"DELETE FROM Flags WHERE FlaggedCommentID IN " + in,
+		"DELETE FROM PostReplies WHERE ReplyID IN " + in,
+		"DELETE FROM CommentRevisions WHERE CommentID IN " + in,
+		"DELETE FROM Comments WHERE ID IN " + in,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, ids...); err != nil {
+			return fmt.Errorf("failed to purge comments: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -206,9 +411,10 @@ func (m *CommentModel) GetCommentByPostID(ctx context.Context, id int64) ([]mode
 	if m == nil {
 		return nil, fmt.Errorf("database connection is not initialized")
 	}
-	stmt := "SELECT * FROM Comments WHERE CommentedPostID = ? ORDER BY ID DESC"
-	rows, err := m.DB.QueryContext(ctx, stmt, id)
+	stmt := "SELECT " + commentColumns + " FROM Comments WHERE CommentedPostID = ? AND DeletedAt IS NULL ORDER BY ID DESC"
+	rows, err := tx.QueryContext(ctx, stmt, id)
 	if err != nil {
+		_ = tx.Rollback()
 		return nil, fmt.Errorf("failed to query comments by post ID %d: %w", id, err)
 	}
 	defer func() {
@@ -243,7 +449,8 @@ func (m *CommentModel) GetCommentByPostID(ctx context.Context, id int64) ([]mode
 			&c.ChannelID,
 		)
 		if scanErr != nil {
-			return nil, fmt.Errorf("failed to scan comment row: %w", scanErr)
+			err = fmt.Errorf("failed to scan comment row: %w", scanErr)
+			return nil, err
 		}
 		comments = append(comments, c)
 	}
@@ -266,9 +473,10 @@ func (m *CommentModel) GetCommentByCommentID(ctx context.Context, id int64) ([]m
 	if m == nil {
 		return nil, fmt.Errorf("database connection is not initialized")
 	}
-	stmt := "SELECT * FROM Comments WHERE CommentedCommentID = ? ORDER BY ID DESC"
-	rows, err := m.DB.QueryContext(ctx, stmt, id)
+	stmt := "SELECT " + commentColumns + " FROM Comments WHERE CommentedCommentID = ? AND DeletedAt IS NULL ORDER BY ID DESC"
+	rows, err := tx.QueryContext(ctx, stmt, id)
 	if err != nil {
+		_ = tx.Rollback()
 		return nil, fmt.Errorf("failed to query comments by comment ID %d: %w", id, err)
 	}
 	defer func() {
@@ -291,19 +499,20 @@ func (m *CommentModel) GetCommentByCommentID(ctx context.Context, id int64) ([]m
 			&c.Content,
 			&c.Created,
 			&c.Updated,
-			&c.AuthorID,
-			&c.ChannelID,
-			&c.IsReply,
 			&c.CommentedPostID,
 			&c.CommentedCommentID,
+			&c.IsCommentable,
 			&c.IsFlagged,
+			&c.IsReply,
 			&c.Author,
+			&c.AuthorID,
 			&c.AuthorAvatar,
 			&c.ChannelName,
-			&c.IsCommentable,
+			&c.ChannelID,
 		)
 		if scanErr != nil {
-			return nil, fmt.Errorf("failed to scan comment row: %w", scanErr)
+			err = fmt.Errorf("failed to scan comment row: %w", scanErr)
+			return nil, err
 		}
 		comments = append(comments, c)
 	}
@@ -316,6 +525,75 @@ func (m *CommentModel) GetCommentByCommentID(ctx context.Context, id int64) ([]m
 	return comments, nil
 }
 
+// maxCommentDepth bounds the walk in GetAncestors so a reply cycle cannot recurse forever
+const maxCommentDepth = 1000
+
+// GetAncestors returns the comments above commentID, ordered from the top-level
+// comment down to its direct parent, along with the ID of the post the thread hangs from.
+// A top-level comment has no ancestors.
+func (m *CommentModel) GetAncestors(ctx context.Context, commentID int64) ([]models.Comment, int64, error) {
+	stmt := `WITH RECURSIVE chain(ID, Depth) AS (
+			SELECT ID, 0 FROM Comments WHERE ID = ?
+			UNION ALL
+			SELECT c.CommentedCommentID, chain.Depth + 1
+			FROM Comments c
+			JOIN chain ON c.ID = chain.ID
+			WHERE c.CommentedCommentID IS NOT NULL AND chain.Depth < ?
+		)
+		SELECT c.ID, c.Content, c.Created, c.Updated, c.CommentedPostID, c.CommentedCommentID,
+			c.IsCommentable, c.IsFlagged, c.IsReply, c.Author, c.AuthorID, c.AuthorAvatar,
+			c.ChannelName, c.ChannelID
+		FROM chain
+		JOIN Comments c ON c.ID = chain.ID
+		ORDER BY chain.Depth DESC`
+	rows, err := m.DB.QueryContext(ctx, stmt, commentID, maxCommentDepth)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query ancestors of comment %d: %w", commentID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	var chain []models.Comment
+	for rows.Next() {
+		c := models.Comment{}
+		if err := rows.Scan(
+			&c.ID,
+			&c.Content,
+			&c.Created,
+			&c.Updated,
+			&c.CommentedPostID,
+			&c.CommentedCommentID,
+			&c.IsCommentable,
+			&c.IsFlagged,
+			&c.IsReply,
+			&c.Author,
+			&c.AuthorID,
+			&c.AuthorAvatar,
+			&c.ChannelName,
+			&c.ChannelID,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan ancestor of comment %d: %w", commentID, err)
+		}
+		chain = append(chain, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating ancestors of comment %d: %w", commentID, err)
+	}
+	if len(chain) == 0 {
+		return nil, 0, fmt.Errorf("comment %d: %w", commentID, ErrNotFound)
+	}
+
+	// The chain ends with the comment itself; the first entry is the top of the thread
+	root := chain[0].CommentedPostID
+	if !root.Valid {
+		return nil, 0, fmt.Errorf("thread of comment %d is not attached to a post: %w", commentID, ErrPostNotFound)
+	}
+	return chain[:len(chain)-1], root.Int64, nil
+}
+
 func (m *CommentModel) All(ctx context.Context) ([]models.Comment, error) {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
@@ -323,7 +601,7 @@ func (m *CommentModel) All(ctx context.Context) ([]models.Comment, error) {
 		return nil, fmt.Errorf("failed to begin transaction for All in Comments: %w", err)
 	}
 
-	stmt := "SELECT ID, Content, Created, Author, AuthorID, AuthorAvatar, ChannelName, ChannelID, CommentedPostID, CommentedCommentID, IsCommentable, IsFlagged FROM Comments ORDER BY ID DESC"
+	stmt := "SELECT ID, Content, Created, Author, AuthorID, AuthorAvatar, ChannelName, ChannelID, CommentedPostID, CommentedCommentID, IsCommentable, IsFlagged FROM Comments WHERE DeletedAt IS NULL ORDER BY ID DESC"
 
 	if m == nil {
 		return nil, fmt.Errorf("database connection is not initialized")
@@ -393,7 +671,8 @@ func (m *CommentModel) GetComment(ctx context.Context, authorID int, reactedPost
 		stmt = `SELECT ID, Created, AuthorID, CommentedPostID, CommentedCommentID, IsCommentable, IsFlagged
 				FROM Comments
 				WHERE AuthorID = ? AND
-				      CommentedPostID = ?`
+				      CommentedPostID = ? AND
+				      DeletedAt IS NULL`
 	} else if reactedCommentID != 0 {
 		stmt = `SELECT ID, Liked, Disliked, AuthorID, Created, ReactedPostID, ReactedCommentID
 				FROM Reactions
@@ -409,7 +688,8 @@ func (m *CommentModel) GetComment(ctx context.Context, authorID int, reactedPost
 		row = m.DB.QueryRowContext(ctx, stmt, authorID, reactedCommentID)
 	}
 
-	err := row.Scan(&reaction.ID, &reaction.Liked, &reaction.Disliked, &reaction.AuthorID, &reaction.Created, &reaction.ReactedPostID, &reaction.ReactedCommentID)
+	var postID, commentID sql.NullInt64
+	err := row.Scan(&reaction.ID, &reaction.Liked, &reaction.Disliked, &reaction.AuthorID, &reaction.Created, &postID, &commentID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// No reaction found
@@ -418,6 +698,7 @@ func (m *CommentModel) GetComment(ctx context.Context, authorID int, reactedPost
 		// Other errors
 		return nil, fmt.Errorf("failed to fetch reaction: %w", err)
 	}
+	reaction.Target = scanTarget(postID, commentID)
 
 	// Return the existing reaction
 	return &reaction, nil
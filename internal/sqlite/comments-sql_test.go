@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGetAncestors(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &CommentModel{DB: db}
+	ctx := context.Background()
+	userID := insertTestUser(t, db, "alice")
+	channelID := insertTestChannel(t, db, userID, "general")
+	postID := insertTestPost(t, db, userID, "thread")
+
+	reply := func(parentID int64) int64 {
+		t.Helper()
+		res, err := db.Exec(`INSERT INTO Comments (Content, CommentedCommentID, IsCommentable, IsFlagged, IsReply,
+			Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+			VALUES ('reply', ?, 1, 0, 1, 'author', ?, '', 'channel', ?)`, parentID, userID, channelID)
+		if err != nil {
+			t.Fatalf("Failed to insert reply: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+
+	want := []int64{insertTestComment(t, db, userID, postID, channelID)}
+	for range 4 {
+		want = append(want, reply(want[len(want)-1]))
+	}
+	deepest := reply(want[len(want)-1])
+
+	t.Run("deeply nested comment", func(t *testing.T) {
+		ancestors, rootPostID, err := m.GetAncestors(ctx, deepest)
+		if err != nil {
+			t.Fatalf("GetAncestors failed: %v", err)
+		}
+		if rootPostID != postID {
+			t.Errorf("Expected root post %d, got %d", postID, rootPostID)
+		}
+		var got []int64
+		for _, c := range ancestors {
+			got = append(got, c.ID)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected ancestors %v, got %v", want, got)
+		}
+		if ancestors[0].Content != "test comment" || ancestors[0].AuthorID != userID {
+			t.Errorf("Expected ancestor rows to be fully populated, got %+v", ancestors[0])
+		}
+	})
+
+	t.Run("top-level comment", func(t *testing.T) {
+		ancestors, rootPostID, err := m.GetAncestors(ctx, want[0])
+		if err != nil || len(ancestors) != 0 || rootPostID != postID {
+			t.Errorf("Expected no ancestors under post %d, got %v, %d, %v", postID, ancestors, rootPostID, err)
+		}
+	})
+
+	t.Run("missing comment", func(t *testing.T) {
+		if _, _, err := m.GetAncestors(ctx, 9999); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("reply cycle", func(t *testing.T) {
+		a := reply(want[0])
+		b := reply(a)
+		if _, err := db.Exec("UPDATE Comments SET CommentedCommentID = ? WHERE ID = ?", b, a); err != nil {
+			t.Fatalf("Failed to create cycle: %v", err)
+		}
+		if _, _, err := m.GetAncestors(ctx, b); !errors.Is(err, ErrPostNotFound) {
+			t.Errorf("Expected ErrPostNotFound for a detached thread, got %v", err)
+		}
+	})
+}
+
+func TestCommentEdit(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &CommentModel{DB: db}
+	ctx := context.Background()
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	channelID := insertTestChannel(t, db, alice, "general")
+	postID := insertTestPost(t, db, alice, "thread")
+	commentID := insertTestComment(t, db, alice, postID, channelID)
+	if _, err := db.Exec("UPDATE Comments SET Updated = '2000-01-01 00:00:00' WHERE ID = ?", commentID); err != nil {
+		t.Fatalf("Failed to backdate comment: %v", err)
+	}
+
+	if _, err := m.Edit(ctx, commentID, bob, "not yours", false); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected a non-author edit to be forbidden, got %v", err)
+	}
+	if _, err := m.Edit(ctx, commentID+100, alice, "missing", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected editing a missing comment to fail, got %v", err)
+	}
+
+	edited, err := m.Edit(ctx, commentID, alice, "second draft", false)
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if edited.Content != "second draft" || edited.Updated.Year() == 2000 {
+		t.Errorf("Expected the new content and a fresh Updated time, got %q at %v", edited.Content, edited.Updated)
+	}
+	if _, err := m.Edit(ctx, commentID, alice, "second draft", false); err != nil {
+		t.Fatalf("Edit with unchanged content failed: %v", err)
+	}
+	if edited, err = m.Edit(ctx, commentID, alice, "final", true); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if !edited.IsFlagged {
+		t.Error("Expected a filtered edit to flag the comment")
+	}
+	if edited, err = m.Edit(ctx, commentID, alice, "final, reworded", false); err != nil || !edited.IsFlagged {
+		t.Errorf("Expected a later edit to keep the flag, got %v (%v)", edited.IsFlagged, err)
+	}
+
+	revisions, err := m.Revisions(ctx, commentID)
+	if err != nil {
+		t.Fatalf("Revisions failed: %v", err)
+	}
+	var got []string
+	for _, r := range revisions {
+		got = append(got, r.Content)
+		if r.EditorID != alice || r.CommentID != commentID {
+			t.Errorf("Unexpected revision %+v", r)
+		}
+	}
+	if want := []string{"test comment", "second draft", "final"}; !slices.Equal(got, want) {
+		t.Errorf("Expected revisions %v, got %v", want, got)
+	}
+}
+
+func TestCommentSoftDelete(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &CommentModel{DB: db}
+	ctx := context.Background()
+	alice := insertTestUser(t, db, "alice")
+	channelID := insertTestChannel(t, db, alice, "general")
+	postID := insertTestPost(t, db, alice, "thread")
+	commentID := insertTestComment(t, db, alice, postID, channelID)
+	res, err := db.Exec(`INSERT INTO Comments (Content, CommentedCommentID, IsCommentable, IsFlagged, IsReply,
+		Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+		VALUES ('reply', ?, 1, 0, 1, 'author', ?, '', 'general', ?)`, commentID, alice, channelID)
+	if err != nil {
+		t.Fatalf("Failed to insert reply: %v", err)
+	}
+	replyID, _ := res.LastInsertId()
+	if _, err := db.Exec("INSERT INTO Reactions (Liked, Disliked, AuthorID, ReactedCommentID) VALUES (1, 0, ?, ?)", alice, replyID); err != nil {
+		t.Fatalf("Failed to insert reaction: %v", err)
+	}
+	if _, err := m.Edit(ctx, commentID, alice, "edited", false); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+
+	if err := m.Delete(ctx, commentID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := m.Delete(ctx, commentID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleting twice to fail, got %v", err)
+	}
+	if comments, err := m.GetCommentByPostID(ctx, postID); err != nil || len(comments) != 0 {
+		t.Errorf("Expected the deleted comment to be left out, got %d (%v)", len(comments), err)
+	}
+	if _, err := m.GetByID(ctx, commentID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a deleted comment to be not found, got %v", err)
+	}
+	if _, err := m.Edit(ctx, commentID, alice, "again", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected editing a deleted comment to fail, got %v", err)
+	}
+	if replies, err := m.GetCommentByCommentID(ctx, commentID); err != nil || len(replies) != 1 {
+		t.Errorf("Expected the reply to stay attached, got %d (%v)", len(replies), err)
+	}
+
+	if err := m.AdminPurge(ctx, replyID); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected purging a live comment to be refused, got %v", err)
+	}
+	if err := m.AdminPurge(ctx, commentID); err != nil {
+		t.Fatalf("AdminPurge failed: %v", err)
+	}
+	var rows int
+	err = db.QueryRow(`SELECT (SELECT COUNT(*) FROM Comments) + (SELECT COUNT(*) FROM Reactions)
+		+ (SELECT COUNT(*) FROM CommentRevisions)`).Scan(&rows)
+	if err != nil || rows != 0 {
+		t.Errorf("Expected the comment, its reply and what refers to them to be purged, found %d rows (%v)", rows, err)
+	}
+}
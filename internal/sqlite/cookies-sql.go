@@ -8,11 +8,15 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gary-norman/forum/internal/config"
 	"github.com/gary-norman/forum/internal/models"
 )
 
 type CookieModel struct {
 	DB *sql.DB
+	// Session lifetimes; zero falls back to the config defaults
+	RememberedLifetime time.Duration
+	EphemeralLifetime  time.Duration
 }
 
 var (
@@ -23,32 +27,51 @@ var (
 	successFail                           = fmt.Sprintf(" --> %s%s%s", dbUpdatedColor, dbUpdated, Colors.Reset)
 )
 
+// sessionLifetime is how long a new session lasts. An ephemeral session's cookies
+// die with the browser, but its stored expiry ends it server-side regardless.
+func (m *CookieModel) sessionLifetime(ephemeral bool) time.Duration {
+	if ephemeral {
+		if m.EphemeralLifetime > 0 {
+			return m.EphemeralLifetime
+		}
+		return config.DefaultEphemeralSessionLifetime
+	}
+	if m.RememberedLifetime > 0 {
+		return m.RememberedLifetime
+	}
+	return config.DefaultRememberedSessionLifetime
+}
+
+// CreateCookies issues a new session for user alongside any they have on other
+// devices, and makes it user's current session. Remembered sessions get persistent
+// cookies; ephemeral ones get session cookies (no Expires) so they die with the
+// browser. Both record their expiry for ResolveUser to enforce.
 func (m *CookieModel) CreateCookies(ctx context.Context, w http.ResponseWriter, user *models.User, ephemeral bool) (error, time.Time) {
 	sessionToken := models.GenerateToken(32)
 	csrfToken := models.GenerateToken(32)
-	var expires time.Time
-	if ephemeral {
-		expires = time.Now().Add(24 * time.Hour)
-	} else {
-		expires = time.Now().AddDate(0, 3, 0)
+	expires := time.Now().Add(m.sessionLifetime(ephemeral))
+	var cookieExpires time.Time
+	if !ephemeral {
+		cookieExpires = expires
 	}
 
+	// a zero Expires leaves the attribute out, making these session cookies
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_token",
 		Value:    sessionToken,
-		Expires:  expires,
+		Expires:  cookieExpires,
 		HttpOnly: true,
 	})
 	http.SetCookie(w, &http.Cookie{
 		Name:     "username",
 		Value:    user.Username,
-		Expires:  expires,
+		Expires:  cookieExpires,
 		HttpOnly: true,
 	})
 	http.SetCookie(w, &http.Cookie{
 		Name:     "csrf_token",
 		Value:    csrfToken,
-		Expires:  expires,
+		Expires:  cookieExpires,
 		HttpOnly: false,
 	})
 
@@ -56,25 +79,46 @@ func (m *CookieModel) CreateCookies(ctx context.Context, w http.ResponseWriter,
 		models.LogErrorWithContext(ctx, "Failed to update cookies for user", err, "UserID:", user.ID)
 		return err, time.Now()
 	}
+	user.SessionToken, user.CSRFToken, user.CookiesExpire = sessionToken, csrfToken, expires
 	return nil, expires
 }
 
+// RenewCookies issues a fresh session for user while keeping the kind they logged in
+// with. Only a remembered session can have more time left than an ephemeral one lasts.
+// The new session replaces the current one and keeps its fingerprint.
+func (m *CookieModel) RenewCookies(ctx context.Context, w http.ResponseWriter, user *models.User) (error, time.Time) {
+	sessions := m.sessions()
+	old := user.SessionToken
+	current, err := sessions.GetByToken(ctx, old)
+	if err != nil {
+		return fmt.Errorf("failed to renew session for user %s: %w", user.Username, err), time.Now()
+	}
+	ephemeral := time.Until(user.CookiesExpire) <= m.sessionLifetime(true)
+	err, expires := m.CreateCookies(ctx, w, user, ephemeral)
+	if err != nil {
+		return err, expires
+	}
+	if err := sessions.SetFingerprint(ctx, user.SessionToken, current.Fingerprint()); err != nil {
+		return err, expires
+	}
+	return sessions.DeleteByToken(ctx, old), expires
+}
+
+func (m *CookieModel) sessions() *SessionModel {
+	return &SessionModel{DB: m.DB}
+}
+
 func (m *CookieModel) QueryCookies(w http.ResponseWriter, r *http.Request, user *models.User) bool {
 	var success bool
 	ctx := r.Context()
-	stmt := "SELECT CookiesExpire FROM Users WHERE Username = ?"
-	rows, err := m.DB.QueryContext(ctx, stmt, user.Username)
+	var expire time.Time
+	session, err := m.sessions().GetByToken(ctx, user.SessionToken)
 	if err != nil {
 		models.LogErrorWithContext(ctx, "Failed to query cookie expiration", err, "Username:", user.Username)
 		return false
 	}
-	defer rows.Close()
-
-	var expire time.Time
-	for rows.Next() {
-		if err := rows.Scan(&expire); err != nil {
-			models.LogErrorWithContext(ctx, "Failed to scan cookie expiration row", err)
-		}
+	if session.UserID == user.ID {
+		expire = session.Expires
 	}
 
 	// Get the Session Token from the request cookie
@@ -114,58 +158,64 @@ func (m *CookieModel) QueryCookies(w http.ResponseWriter, r *http.Request, user
 	return success
 }
 
+// UpdateCookies stores a new session for user with the given tokens and expiry
 func (m *CookieModel) UpdateCookies(ctx context.Context, user *models.User, sessionToken, csrfToken string, expires time.Time) error {
 	if m == nil || m.DB == nil {
 		models.LogErrorWithContext(ctx, "CookieModel or DB is nil in UpdateCookies", nil, "Username:", user.Username)
 		return errors.New("UserModel or DB is nil in UpdateCookies")
 	}
-	var stmt string
 	fmt.Printf(Colors.Blue+"Updating DB Cookies for: "+Colors.Text+"%v\n"+Colors.Reset, user.Username)
-	stmt = "UPDATE Users SET SessionToken = ?, CsrfToken = ?, CookiesExpire = ? WHERE Username = ?"
-	result, err := m.DB.ExecContext(ctx, stmt, sessionToken, csrfToken, expires, user.Username)
+	_, err := m.sessions().Insert(ctx, models.UserSession{
+		Token:     sessionToken,
+		CSRFToken: csrfToken,
+		UserID:    user.ID,
+		Expires:   expires,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update cookies for user %s: %w", user.Username, err)
 	}
-	rows, _ := result.RowsAffected()
-	if rows > 0 {
-		dbUpdated = "✔ Success!"
-		dbUpdatedColor = Colors.Green
-	}
+	dbUpdated = "✔ Success!"
+	dbUpdatedColor = Colors.Green
 	models.LogInfoWithContext(ctx, "Updating cookies for user: %s%s", user.Username, successFail)
 
 	return nil
 }
 
+// BindSession stores the fingerprint of the client user's current session was issued to
+func (m *CookieModel) BindSession(ctx context.Context, user *models.User, fp models.SessionFingerprint) error {
+	if err := m.sessions().SetFingerprint(ctx, user.SessionToken, fp); err != nil {
+		return fmt.Errorf("failed to bind session for user %s: %w", user.Username, err)
+	}
+	return nil
+}
+
+// DeleteCookies ends the user's current session: its row is deleted so nothing
+// presented later, stale or empty, can match it, and the browser is told to drop
+// its cookies. Sessions on the user's other devices are left alone.
 func (m *CookieModel) DeleteCookies(ctx context.Context, w http.ResponseWriter, user *models.User) error {
-	expires := time.Now().Add(time.Hour - 1000)
-	stmt := "UPDATE Users SET SessionToken = '', CsrfToken = '' WHERE Username = ?"
-	result, err := m.DB.ExecContext(ctx, stmt, user.Username)
-	if err != nil {
+	if err := m.sessions().DeleteByToken(ctx, user.SessionToken); err != nil {
 		return fmt.Errorf("failed to delete cookies for user %s: %w", user.Username, err)
 	}
-	rows, _ := result.RowsAffected()
-	if rows > 0 {
-		dbUpdated = "✔ Success!"
-		dbUpdatedColor = Colors.Green
-	}
+	dbUpdated = "✔ Success!"
+	dbUpdatedColor = Colors.Green
 	models.LogInfoWithContext(ctx, "Deleting cookies for user: %s%s", user.Username, successFail)
-	// Set Session, Username, and CSRF Token cookies
+	// Clear Session, Username, and CSRF Token cookies
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_token",
 		Value:    "",
-		Expires:  expires,
+		MaxAge:   -1,
 		HttpOnly: true,
 	})
 	http.SetCookie(w, &http.Cookie{
 		Name:     "username",
 		Value:    "",
-		Expires:  expires,
+		MaxAge:   -1,
 		HttpOnly: true,
 	})
 	http.SetCookie(w, &http.Cookie{
 		Name:     "csrf_token",
 		Value:    "",
-		Expires:  expires,
+		MaxAge:   -1,
 		HttpOnly: false,
 	})
 	return nil
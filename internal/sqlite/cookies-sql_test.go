@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/config"
+)
+
+func TestCreateCookies_SessionVsRemembered(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	users := &UserModel{DB: db}
+	cookies := &CookieModel{DB: db}
+	ctx := context.Background()
+
+	insertTestUser(t, db, "alice")
+	user, err := users.GetUserByUsername(ctx, "alice", "test")
+	if err != nil {
+		t.Fatalf("GetUserByUsername failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		ephemeral   bool
+		wantExpires bool
+		maxLifetime time.Duration
+	}{
+		{"ephemeral uses session cookies", true, false, config.DefaultEphemeralSessionLifetime},
+		{"remembered persists", false, true, 100 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			err, expires := cookies.CreateCookies(ctx, rec, user, tt.ephemeral)
+			if err != nil {
+				t.Fatalf("CreateCookies failed: %v", err)
+			}
+
+			headers := rec.Result().Header.Values("Set-Cookie")
+			if len(headers) != 3 {
+				t.Fatalf("Expected 3 cookies, got %d", len(headers))
+			}
+			for _, h := range headers {
+				if got := strings.Contains(h, "Expires="); got != tt.wantExpires {
+					t.Errorf("Expires present = %v, want %v in %q", got, tt.wantExpires, h)
+				}
+				if strings.Contains(h, "Max-Age=") {
+					t.Errorf("Expected no Max-Age in %q", h)
+				}
+			}
+
+			// the expiry is recorded server-side either way
+			stored, err := (&SessionModel{DB: db}).GetByToken(ctx, user.SessionToken)
+			if err != nil {
+				t.Fatalf("GetByToken failed: %v", err)
+			}
+			if !stored.Expires.Equal(expires) {
+				t.Errorf("Expected stored expiry %v, got %v", expires, stored.Expires)
+			}
+			if lifetime := time.Until(expires); lifetime <= 0 || lifetime > tt.maxLifetime {
+				t.Errorf("Unexpected session lifetime %v", lifetime)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Sentinel errors returned (wrapped) by the model layer. Callers should test for
+// them with errors.Is rather than matching on error strings.
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write would violate a uniqueness constraint
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthorized is returned when the caller is not allowed to perform the operation
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned when the caller is known but lacks the right to act on the row
+	ErrForbidden = errors.New("forbidden")
+)
+
+var (
+	// ErrChannelNotFound is returned when no channel matches the requested ID
+	ErrChannelNotFound = fmt.Errorf("channel %w", ErrNotFound)
+	// ErrPostNotFound is returned when no post matches the requested ID
+	ErrPostNotFound = fmt.Errorf("post %w", ErrNotFound)
+	// ErrPredefinedRule is returned when a channel owner tries to change a rule shared by every channel
+	ErrPredefinedRule = fmt.Errorf("predefined rule: %w", ErrForbidden)
+)
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE or PRIMARY KEY constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	ctx := context.Background()
+	users := &UserModel{DB: db}
+	channels := &ChannelModel{DB: db}
+
+	ownerID := insertTestUser(t, db, "owner")
+	channelID := insertTestChannel(t, db, ownerID, "general")
+
+	t.Run("missing user by username is ErrNotFound", func(t *testing.T) {
+		_, err := users.GetUserByUsername(ctx, "nobody", "test")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("missing user by ID is ErrNotFound", func(t *testing.T) {
+		_, err := users.GetUserByID(ctx, models.NewUUIDField())
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("missing channel is ErrNotFound", func(t *testing.T) {
+		_, err := channels.GetChannelByID(ctx, channelID+100)
+		if !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrChannelNotFound) {
+			t.Errorf("Expected ErrChannelNotFound wrapping ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("duplicate username is ErrConflict", func(t *testing.T) {
+		err := users.Insert(ctx, models.NewUUIDField(), "owner", "other@example.com", "", "", "", "user", "", "", "hash")
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("wrapped ErrUnauthorized is detected", func(t *testing.T) {
+		err := fmt.Errorf("edit channel %d: %w", channelID, ErrUnauthorized)
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected ErrUnauthorized, got %v", err)
+		}
+	})
+}
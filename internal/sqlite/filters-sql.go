@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/moderation"
+)
+
+// ContentFilterModel stores the banned terms each channel filters on
+type ContentFilterModel struct {
+	DB *sql.DB
+}
+
+// TermsForChannel returns the banned terms configured for a channel
+func (m *ContentFilterModel) TermsForChannel(ctx context.Context, channelID int64) ([]string, error) {
+	rows, err := m.DB.QueryContext(ctx, "SELECT Term FROM ChannelFilterTerms WHERE ChannelID = ? ORDER BY Term", channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query filter terms for channel %d: %w", channelID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	terms := make([]string, 0)
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, fmt.Errorf("failed to scan filter term: %w", err)
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
+
+// AddTerm adds a banned term to a channel; adding an existing term is a no-op
+func (m *ContentFilterModel) AddTerm(ctx context.Context, channelID int64, term string) error {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if term == "" {
+		return fmt.Errorf("filter term cannot be empty")
+	}
+	query := "INSERT INTO ChannelFilterTerms (ChannelID, Term) VALUES (?, ?) ON CONFLICT (ChannelID, Term) DO NOTHING"
+	if _, err := m.DB.ExecContext(ctx, query, channelID, term); err != nil {
+		return fmt.Errorf("failed to add filter term for channel %d: %w", channelID, err)
+	}
+	return nil
+}
+
+// DeleteTerm removes a banned term from a channel
+func (m *ContentFilterModel) DeleteTerm(ctx context.Context, channelID int64, term string) error {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if _, err := m.DB.ExecContext(ctx, "DELETE FROM ChannelFilterTerms WHERE ChannelID = ? AND Term = ?", channelID, term); err != nil {
+		return fmt.Errorf("failed to delete filter term for channel %d: %w", channelID, err)
+	}
+	return nil
+}
+
+// ModeForChannel returns the channel's filter mode, defaulting to moderation.ModeReject
+func (m *ContentFilterModel) ModeForChannel(ctx context.Context, channelID int64) (string, error) {
+	var mode string
+	err := m.DB.QueryRowContext(ctx, "SELECT Mode FROM ChannelFilterSettings WHERE ChannelID = ?", channelID).Scan(&mode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return moderation.ModeReject, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query filter mode for channel %d: %w", channelID, err)
+	}
+	return mode, nil
+}
+
+// SetMode sets the channel's filter mode to moderation.ModeReject or moderation.ModeFlag
+func (m *ContentFilterModel) SetMode(ctx context.Context, channelID int64, mode string) error {
+	if mode != moderation.ModeReject && mode != moderation.ModeFlag {
+		return fmt.Errorf("invalid filter mode %q", mode)
+	}
+	query := `INSERT INTO ChannelFilterSettings (ChannelID, Mode, Updated) VALUES (?, ?, DateTime('now'))
+		ON CONFLICT (ChannelID) DO UPDATE SET Mode = excluded.Mode, Updated = excluded.Updated`
+	if _, err := m.DB.ExecContext(ctx, query, channelID, mode); err != nil {
+		return fmt.Errorf("failed to set filter mode for channel %d: %w", channelID, err)
+	}
+	return nil
+}
@@ -12,11 +12,12 @@ type FlagModel struct {
 	DB *sql.DB
 }
 
-func (m *FlagModel) Insert(ctx context.Context, flagType, content string, approved bool, authorID, channelID, flaggedUserID, flaggedPostID, flaggedCommentID int) error {
+// Insert stores a flag and returns its ID. Exactly one of the Flagged* fields is normally set.
+func (m *FlagModel) Insert(ctx context.Context, flag models.Flag) (int64, error) {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction for Insert in Flags: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction for Insert in Flags: %w", err)
 	}
 
 	// Ensure rollback on failure
@@ -30,18 +31,23 @@ func (m *FlagModel) Insert(ctx context.Context, flagType, content string, approv
 		}
 	}()
 
-	stmt := "INSERT INTO Flags (Flag_type, Content, Created, Approved, AuthorID, ChannelID, Flagged_userID, Flagged_postID, Flagged_commentID) VALUES (?, ?, DateTime('now'), ?, ?, ?, ?, ?, ?)"
-	_, err = tx.Exec(stmt, flagType, content, approved, authorID, channelID, flaggedUserID, flaggedPostID, flaggedCommentID)
+	stmt := "INSERT INTO Flags (FlagType, Content, Created, Approved, AuthorID, ChannelID, FlaggedUserID, FlaggedPostID, FlaggedCommentID) VALUES (?, ?, DateTime('now'), ?, ?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, stmt, flag.FlagType, flag.Content, flag.Approved, flag.AuthorID, flag.ChannelID, flag.FlaggedUserID, flag.FlaggedPostID, flag.FlaggedCommentID)
 	if err != nil {
-		return fmt.Errorf("failed to execute statement for Insert in Flags: %w", err)
+		return 0, fmt.Errorf("failed to execute statement for Insert in Flags: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID for Flags: %w", err)
 	}
 
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction for Insert in Flags: %w", err)
+		return 0, fmt.Errorf("failed to commit transaction for Insert in Flags: %w", err)
 	}
 
-	return nil
+	return id, nil
 }
 
 func (m *FlagModel) All(ctx context.Context) ([]models.Flag, error) {
@@ -62,7 +68,7 @@ func (m *FlagModel) All(ctx context.Context) ([]models.Flag, error) {
 		}
 	}()
 
-	stmt := "SELECT ID, Flag_type, Content, Created, Approved, AuthorID, ChannelID, Flagged_userID, Flagged_postID, Flagged_commentID FROM Flags ORDER BY ID DESC"
+	stmt := "SELECT ID, FlagType, Content, Created, Approved, AuthorID, ChannelID, FlaggedUserID, FlaggedPostID, FlaggedCommentID FROM Flags ORDER BY ID DESC"
 	rows, err := tx.QueryContext(ctx, stmt)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/testutil"
+)
+
+// setupMigratedTestDB opens an in-memory database with every migration applied
+func setupMigratedTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	return testutil.NewTestDB(t)
+}
+
+// insertTestUser creates a user with every nullable column populated and returns its ID
+func insertTestUser(t *testing.T, db *sql.DB, username string) models.UUIDField {
+	t.Helper()
+	id := models.NewUUIDField()
+	_, err := db.Exec(`INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype,
+		IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, ?, ?, ?, '', '', 'user', 0, '', '', 'hash')`, id, username, username+"@example.com", username+".png")
+	if err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	return id
+}
+
+// insertTestChannel creates a public channel owned by ownerID and returns its ID
+func insertTestChannel(t *testing.T, db *sql.DB, ownerID models.UUIDField, name string) int64 {
+	t.Helper()
+	res, err := db.Exec(`INSERT INTO Channels (OwnerID, Name, Avatar, Banner, Description, Privacy, IsMuted, IsFlagged)
+		VALUES (?, ?, '', '', 'test channel', 0, 0, 0)`, ownerID, name)
+	if err != nil {
+		t.Fatalf("Failed to insert channel: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+// insertTestPost creates a commentable post by authorID and returns its ID
+func insertTestPost(t *testing.T, db *sql.DB, authorID models.UUIDField, title string) int64 {
+	t.Helper()
+	res, err := db.Exec(`INSERT INTO Posts (Title, Content, Images, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged)
+		VALUES (?, 'test content', '', 1, 'author', ?, '', 0)`, title, authorID)
+	if err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+// insertTestBuddyChat creates a buddy chat between the given users and returns its ID
+func insertTestBuddyChat(t *testing.T, db *sql.DB, userID, buddyID models.UUIDField) models.UUIDField {
+	t.Helper()
+	chatID := models.NewUUIDField()
+	if _, err := db.Exec(`INSERT INTO Chats (ID, Type, BuddyID) VALUES (?, 'buddy', ?)`, chatID, buddyID); err != nil {
+		t.Fatalf("Failed to insert chat: %v", err)
+	}
+	for _, id := range []models.UUIDField{userID, buddyID} {
+		if _, err := db.Exec(`INSERT INTO ChatUsers (ChatID, UserID) VALUES (?, ?)`, chatID, id); err != nil {
+			t.Fatalf("Failed to attach user to chat: %v", err)
+		}
+	}
+	return chatID
+}
+
+// insertTestComment creates a top-level comment on postID and returns its ID
+func insertTestComment(t *testing.T, db *sql.DB, authorID models.UUIDField, postID, channelID int64) int64 {
+	t.Helper()
+	res, err := db.Exec(`INSERT INTO Comments (Content, CommentedPostID, IsCommentable, IsFlagged, IsReply,
+		Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+		VALUES ('test comment', ?, 1, 0, 0, 'author', ?, '', 'channel', ?)`, postID, authorID, channelID)
+	if err != nil {
+		t.Fatalf("Failed to insert comment: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"path/filepath"
 
 	"github.com/gary-norman/forum/internal/models"
 )
@@ -98,3 +99,38 @@ func (m *ImageModel) All(ctx context.Context) ([]models.Image, error) {
 
 	return Images, nil
 }
+
+// ReferencedNames returns the file name of every uploaded image a row still points
+// at: user and channel avatars and banners, the author avatars copied onto posts and
+// comments, post images and processed image paths. Names are compared without their
+// directory; uploads are renamed to random tokens, so they do not collide.
+func (m *ImageModel) ReferencedNames(ctx context.Context) (map[string]bool, error) {
+	query := `SELECT Avatar FROM Users UNION SELECT Banner FROM Users
+		UNION SELECT Avatar FROM Channels UNION SELECT Banner FROM Channels
+		UNION SELECT AuthorAvatar FROM Posts UNION SELECT AuthorAvatar FROM Comments
+		UNION SELECT Images FROM Posts UNION SELECT Path FROM Images`
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referenced images: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in ReferencedNames: %v", closeErr)
+		}
+	}()
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan referenced image: %w", err)
+		}
+		if name.String != "" {
+			names[filepath.Base(name.String)] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating referenced images: %w", err)
+	}
+	return names, nil
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// IntegrityModel checks the database for corruption and rows that refer to
+// something that no longer exists
+type IntegrityModel struct {
+	DB *sql.DB
+}
+
+// IntegrityIssue is one problem found by Check. RowID is 0 for problems that are
+// not tied to a single row.
+type IntegrityIssue struct {
+	Table   string
+	RowID   int64
+	Problem string
+}
+
+func (i IntegrityIssue) String() string {
+	if i.RowID == 0 {
+		return fmt.Sprintf("%s: %s", i.Table, i.Problem)
+	}
+	return fmt.Sprintf("%s row %d: %s", i.Table, i.RowID, i.Problem)
+}
+
+// orphanChecks find rows the foreign keys cannot catch: references spread over
+// several nullable columns, and parents that must have at least one child
+var orphanChecks = []struct {
+	table, problem, query string
+}{
+	{"Posts", "is not in any channel",
+		"SELECT ID FROM Posts p WHERE NOT EXISTS (SELECT 1 FROM PostChannels pc WHERE pc.PostID = p.ID)"},
+	{"Comments", "is attached to neither a post nor a comment",
+		"SELECT ID FROM Comments WHERE CommentedPostID IS NULL AND CommentedCommentID IS NULL"},
+	{"Reactions", "is attached to neither a post nor a comment",
+		"SELECT ID FROM Reactions WHERE ReactedPostID IS NULL AND ReactedCommentID IS NULL"},
+}
+
+// Check runs PRAGMA integrity_check and PRAGMA foreign_key_check, then looks for
+// orphaned rows the schema does not constrain, and returns everything it found.
+// An empty result means the database is consistent.
+func (m *IntegrityModel) Check(ctx context.Context) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+
+	messages, err := queryColumn[string](ctx, m.DB, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	for _, msg := range messages {
+		if msg != "ok" {
+			issues = append(issues, IntegrityIssue{Table: "database", Problem: msg})
+		}
+	}
+
+	fkIssues, err := m.foreignKeyIssues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, fkIssues...)
+
+	for _, check := range orphanChecks {
+		ids, err := queryColumn[int64](ctx, m.DB, check.query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s for orphans: %w", check.table, err)
+		}
+		for _, id := range ids {
+			issues = append(issues, IntegrityIssue{Table: check.table, RowID: id, Problem: check.problem})
+		}
+	}
+	return issues, nil
+}
+
+// foreignKeyIssues reports each row whose foreign key points at a missing parent
+func (m *IntegrityModel) foreignKeyIssues(ctx context.Context) ([]IntegrityIssue, error) {
+	rows, err := m.DB.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run foreign key check: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	var issues []IntegrityIssue
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key check: %w", err)
+		}
+		issues = append(issues, IntegrityIssue{
+			Table:   table,
+			RowID:   rowID.Int64,
+			Problem: fmt.Sprintf("refers to a missing %s row", parent),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign key check: %w", err)
+	}
+	return issues, nil
+}
+
+// queryColumn returns the single column of every row the query produces
+func queryColumn[T any](ctx context.Context, db *sql.DB, query string) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	var values []T
+	for rows.Next() {
+		var v T
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
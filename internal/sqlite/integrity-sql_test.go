@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIntegrityCheck(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	ctx := context.Background()
+	checker := IntegrityModel{DB: db}
+
+	author := insertTestUser(t, db, "checker")
+	channelID := insertTestChannel(t, db, author, "checked")
+	postID := insertTestPost(t, db, author, "in a channel")
+	if _, err := db.Exec("INSERT INTO PostChannels (PostID, ChannelID) VALUES (?, ?)", postID, channelID); err != nil {
+		t.Fatalf("link post: %v", err)
+	}
+
+	issues, err := checker.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("clean database reported %v", issues)
+	}
+
+	// Seed a reaction whose post does not exist, bypassing the foreign key
+	if _, err := db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatalf("disable foreign keys: %v", err)
+	}
+	res, err := db.Exec("INSERT INTO Reactions (Liked, Disliked, AuthorID, ReactedPostID) VALUES (1, 0, ?, 999)", author)
+	if err != nil {
+		t.Fatalf("insert orphan reaction: %v", err)
+	}
+	reactionID, _ := res.LastInsertId()
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatalf("enable foreign keys: %v", err)
+	}
+	loosePost := insertTestPost(t, db, author, "no channel")
+
+	issues, err = checker.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	found := map[string]int64{}
+	for _, issue := range issues {
+		found[issue.Table] = issue.RowID
+	}
+	if len(issues) != 2 || found["Reactions"] != reactionID || found["Posts"] != loosePost {
+		t.Fatalf("want orphan reaction %d and post %d, got %v", reactionID, loosePost, issues)
+	}
+}
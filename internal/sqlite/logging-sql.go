@@ -3,11 +3,25 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
+	"time"
 
 	"github.com/gary-norman/forum/internal/models"
 )
 
+// Export formats accepted by ExportLogs
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json" // newline-delimited JSON, one object per row
+)
+
+// exportFlushEvery controls how many CSV rows are buffered before flushing to the writer
+const exportFlushEvery = 500
+
 // LoggingModel provides database operations for all logging tables
 type LoggingModel struct {
 	DB *sql.DB
@@ -33,13 +47,13 @@ func (m *LoggingModel) InsertRequestLog(ctx context.Context, log models.RequestL
 	}()
 
 	query := `INSERT INTO RequestLogs
-		(Timestamp, Method, Path, StatusCode, Duration, UserID, IPAddress, UserAgent, Referer, BytesSent)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		(Timestamp, Method, Path, StatusCode, Duration, UserID, IPAddress, UserAgent, Referer, BytesSent, RequestID)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))`
 
 	_, err = tx.ExecContext(
 		ctx,
 		query,
-		log.Timestamp,
+		models.FormatTimestamp(log.Timestamp),
 		log.Method,
 		log.Path,
 		log.StatusCode,
@@ -49,6 +63,7 @@ func (m *LoggingModel) InsertRequestLog(ctx context.Context, log models.RequestL
 		log.UserAgent,
 		log.Referer,
 		log.BytesSent,
+		log.RequestID,
 	)
 
 	// Commit the transaction
@@ -79,19 +94,20 @@ func (m *LoggingModel) InsertErrorLog(ctx context.Context, log models.ErrorLog)
 	}()
 
 	query := `INSERT INTO ErrorLogs
-		(Timestamp, Level, Message, StackTrace, RequestPath, UserID, Context)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`
+		(Timestamp, Level, Message, StackTrace, RequestPath, UserID, Context, RequestID)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))`
 
 	_, err = tx.ExecContext(
 		ctx,
 		query,
-		log.Timestamp,
+		models.FormatTimestamp(log.Timestamp),
 		log.Level,
 		log.Message,
 		log.StackTrace,
 		log.RequestPath,
 		log.UserID,
 		log.Context,
+		log.RequestID,
 	)
 
 	// Commit the transaction
@@ -129,7 +145,7 @@ func (m *LoggingModel) InsertSystemMetric(ctx context.Context, metric models.Sys
 	_, err = tx.ExecContext(
 		ctx,
 		query,
-		metric.Timestamp,
+		models.FormatTimestamp(metric.Timestamp),
 		metric.MetricType,
 		metric.MetricName,
 		metric.MetricValue,
@@ -147,7 +163,7 @@ func (m *LoggingModel) InsertSystemMetric(ctx context.Context, metric models.Sys
 }
 
 // GetRequestLogsSince retrieves request logs after a given timestamp
-func (m *LoggingModel) GetRequestLogsSince(ctx context.Context, since string, limit int) ([]models.RequestLog, error) {
+func (m *LoggingModel) GetRequestLogsSince(ctx context.Context, since time.Time, limit int) ([]models.RequestLog, error) {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -165,13 +181,14 @@ func (m *LoggingModel) GetRequestLogsSince(ctx context.Context, since string, li
 		}
 	}()
 
-	query := `SELECT ID, Timestamp, Method, Path, StatusCode, Duration, UserID, IPAddress, UserAgent, Referer, BytesSent
+	query := `SELECT ID, Timestamp, Method, Path, StatusCode, Duration, UserID, IPAddress, UserAgent, Referer, BytesSent,
+			COALESCE(RequestID, '')
 		FROM RequestLogs
 		WHERE Timestamp >= ?
 		ORDER BY Timestamp DESC
 		LIMIT ?`
 
-	rows, err := m.DB.Query(query, since, limit)
+	rows, err := tx.QueryContext(ctx, query, models.FormatTimestamp(since), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +209,7 @@ func (m *LoggingModel) GetRequestLogsSince(ctx context.Context, since string, li
 			&log.UserAgent,
 			&log.Referer,
 			&log.BytesSent,
+			&log.RequestID,
 		)
 		if err != nil {
 			return nil, err
@@ -209,7 +227,7 @@ func (m *LoggingModel) GetRequestLogsSince(ctx context.Context, since string, li
 }
 
 // GetErrorLogsSince retrieves error logs after a given timestamp
-func (m *LoggingModel) GetErrorLogsSince(ctx context.Context, since string, limit int) ([]models.ErrorLog, error) {
+func (m *LoggingModel) GetErrorLogsSince(ctx context.Context, since time.Time, limit int) ([]models.ErrorLog, error) {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -227,13 +245,13 @@ func (m *LoggingModel) GetErrorLogsSince(ctx context.Context, since string, limi
 		}
 	}()
 
-	query := `SELECT ID, Timestamp, Level, Message, StackTrace, RequestPath, UserID, Context
+	query := `SELECT ID, Timestamp, Level, Message, StackTrace, RequestPath, UserID, Context, COALESCE(RequestID, '')
 		FROM ErrorLogs
 		WHERE Timestamp >= ?
 		ORDER BY Timestamp DESC
 		LIMIT ?`
 
-	rows, err := m.DB.Query(query, since, limit)
+	rows, err := tx.QueryContext(ctx, query, models.FormatTimestamp(since), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +269,7 @@ func (m *LoggingModel) GetErrorLogsSince(ctx context.Context, since string, limi
 			&log.RequestPath,
 			&log.UserID,
 			&log.Context,
+			&log.RequestID,
 		)
 		if err != nil {
 			return nil, err
@@ -268,7 +287,7 @@ func (m *LoggingModel) GetErrorLogsSince(ctx context.Context, since string, limi
 }
 
 // GetSystemMetricsSince retrieves system metrics after a given timestamp
-func (m *LoggingModel) GetSystemMetricsSince(ctx context.Context, since string, limit int) ([]models.SystemMetric, error) {
+func (m *LoggingModel) GetSystemMetricsSince(ctx context.Context, since time.Time, limit int) ([]models.SystemMetric, error) {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -292,7 +311,7 @@ func (m *LoggingModel) GetSystemMetricsSince(ctx context.Context, since string,
 		ORDER BY Timestamp DESC
 		LIMIT ?`
 
-	rows, err := m.DB.Query(query, since, limit)
+	rows, err := tx.QueryContext(ctx, query, models.FormatTimestamp(since), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -334,7 +353,7 @@ type RequestStats struct {
 	RequestsPerPath map[string]int64
 }
 
-func (m *LoggingModel) GetRequestStats(ctx context.Context, since string) (*RequestStats, error) {
+func (m *LoggingModel) GetRequestStats(ctx context.Context, since time.Time) (*RequestStats, error) {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -355,22 +374,23 @@ func (m *LoggingModel) GetRequestStats(ctx context.Context, since string) (*Requ
 	stats := &RequestStats{
 		RequestsPerPath: make(map[string]int64),
 	}
+	from := models.FormatTimestamp(since)
 
 	// Total requests and average duration
-	err = m.DB.QueryRow(`
-		SELECT COUNT(*), AVG(Duration)
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(AVG(Duration), 0)
 		FROM RequestLogs
-		WHERE Timestamp >= ?`, since).Scan(&stats.TotalRequests, &stats.AvgDuration)
+		WHERE Timestamp >= ?`, from).Scan(&stats.TotalRequests, &stats.AvgDuration)
 	if err != nil {
 		return nil, err
 	}
 
 	// Error rate
 	var errorCount int64
-	err = m.DB.QueryRow(`
+	err = tx.QueryRowContext(ctx, `
 		SELECT COUNT(*)
 		FROM RequestLogs
-		WHERE Timestamp >= ? AND StatusCode >= 400`, since).Scan(&errorCount)
+		WHERE Timestamp >= ? AND StatusCode >= 400`, from).Scan(&errorCount)
 	if err != nil {
 		return nil, err
 	}
@@ -379,35 +399,42 @@ func (m *LoggingModel) GetRequestStats(ctx context.Context, since string) (*Requ
 	}
 
 	// Unique users
-	err = m.DB.QueryRow(`
+	err = tx.QueryRowContext(ctx, `
 		SELECT COUNT(DISTINCT UserID)
 		FROM RequestLogs
-		WHERE Timestamp >= ? AND UserID IS NOT NULL`, since).Scan(&stats.UniqueUsers)
+		WHERE Timestamp >= ? AND UserID IS NOT NULL`, from).Scan(&stats.UniqueUsers)
 	if err != nil {
 		return nil, err
 	}
 
 	// Requests per path
-	rows, err := m.DB.Query(`
+	rows, err := tx.QueryContext(ctx, `
 		SELECT Path, COUNT(*)
 		FROM RequestLogs
 		WHERE Timestamp >= ?
 		GROUP BY Path
 		ORDER BY COUNT(*) DESC
-		LIMIT 20`, since)
+		LIMIT 20`, from)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
 
 	for rows.Next() {
 		var path string
 		var count int64
-		if err := rows.Scan(&path, &count); err != nil {
+		if err = rows.Scan(&path, &count); err != nil {
 			return nil, err
 		}
 		stats.RequestsPerPath[path] = count
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
 	// Commit the transaction
 	commitErr := tx.Commit()
@@ -415,7 +442,199 @@ func (m *LoggingModel) GetRequestStats(ctx context.Context, since string) (*Requ
 		return nil, fmt.Errorf("failed to commit transaction for GetRequestStats: %w", commitErr)
 	}
 
-	return stats, rows.Err()
+	return stats, nil
+}
+
+// LogExportRecord is a single archived log row. Request and error logs share
+// one shape so both can be written to the same CSV/NDJSON stream.
+type LogExportRecord struct {
+	Source     string    `json:"source"` // "request" or "error"
+	ID         int64     `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Method     string    `json:"method,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Duration   int64     `json:"duration,omitempty"`
+	UserID     string    `json:"userId,omitempty"`
+	IPAddress  string    `json:"ipAddress,omitempty"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	Referer    string    `json:"referer,omitempty"`
+	BytesSent  int64     `json:"bytesSent,omitempty"`
+	Level      string    `json:"level,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	StackTrace string    `json:"stackTrace,omitempty"`
+	Context    string    `json:"context,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+}
+
+var logExportHeader = []string{
+	"Source", "ID", "Timestamp", "Method", "Path", "StatusCode", "Duration", "UserID",
+	"IPAddress", "UserAgent", "Referer", "BytesSent", "Level", "Message", "StackTrace", "Context",
+	"RequestID",
+}
+
+func (r LogExportRecord) csvRow() []string {
+	return []string{
+		r.Source,
+		strconv.FormatInt(r.ID, 10),
+		r.Timestamp.UTC().Format(time.RFC3339Nano),
+		r.Method,
+		r.Path,
+		strconv.Itoa(r.StatusCode),
+		strconv.FormatInt(r.Duration, 10),
+		r.UserID,
+		r.IPAddress,
+		r.UserAgent,
+		r.Referer,
+		strconv.FormatInt(r.BytesSent, 10),
+		r.Level,
+		r.Message,
+		r.StackTrace,
+		r.Context,
+		r.RequestID,
+	}
+}
+
+// ExportLogs streams RequestLogs followed by ErrorLogs with since <= Timestamp < until to w,
+// as CSV or newline-delimited JSON. Rows are written as they are scanned, so the full result
+// set is never held in memory. Intended to be run before CleanupOldLogs to archive old rows.
+func (m *LoggingModel) ExportLogs(ctx context.Context, since, until time.Time, w io.Writer, format string) error {
+	var write func(LogExportRecord) error
+	var flush func() error
+
+	switch format {
+	case ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(logExportHeader); err != nil {
+			return fmt.Errorf("failed to write CSV header for ExportLogs: %w", err)
+		}
+		written := 0
+		write = func(r LogExportRecord) error {
+			if err := cw.Write(r.csvRow()); err != nil {
+				return err
+			}
+			written++
+			if written%exportFlushEvery == 0 {
+				cw.Flush()
+				return cw.Error()
+			}
+			return nil
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case ExportFormatJSON:
+		enc := json.NewEncoder(w)
+		write = func(r LogExportRecord) error { return enc.Encode(r) }
+		flush = func() error { return nil }
+	default:
+		return fmt.Errorf("unsupported export format %q: expected %q or %q", format, ExportFormatCSV, ExportFormatJSON)
+	}
+
+	if err := m.exportRequestLogs(ctx, since, until, write); err != nil {
+		return err
+	}
+	if err := m.exportErrorLogs(ctx, since, until, write); err != nil {
+		return err
+	}
+
+	if err := flush(); err != nil {
+		return fmt.Errorf("failed to flush ExportLogs output: %w", err)
+	}
+	return nil
+}
+
+// exportRequestLogs scans RequestLogs in the given window, handing each row to write
+func (m *LoggingModel) exportRequestLogs(ctx context.Context, since, until time.Time, write func(LogExportRecord) error) error {
+	rows, err := m.DB.QueryContext(ctx, `SELECT ID, Timestamp, Method, Path, StatusCode, Duration, UserID, IPAddress, UserAgent, Referer, BytesSent,
+			COALESCE(RequestID, '')
+		FROM RequestLogs
+		WHERE Timestamp >= ? AND Timestamp < ?
+		ORDER BY Timestamp ASC, ID ASC`, models.FormatTimestamp(since), models.FormatTimestamp(until))
+	if err != nil {
+		return fmt.Errorf("failed to query RequestLogs for ExportLogs: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			rec                           LogExportRecord
+			userID                        []byte
+			ipAddress, userAgent, referer sql.NullString
+			bytesSent                     sql.NullInt64
+		)
+		if err := rows.Scan(&rec.ID, &rec.Timestamp, &rec.Method, &rec.Path, &rec.StatusCode, &rec.Duration,
+			&userID, &ipAddress, &userAgent, &referer, &bytesSent, &rec.RequestID); err != nil {
+			return fmt.Errorf("failed to scan RequestLogs row for ExportLogs: %w", err)
+		}
+		rec.Source = "request"
+		rec.UserID = exportUserID(userID)
+		rec.IPAddress = ipAddress.String
+		rec.UserAgent = userAgent.String
+		rec.Referer = referer.String
+		rec.BytesSent = bytesSent.Int64
+
+		if err := write(rec); err != nil {
+			return fmt.Errorf("failed to write RequestLogs row %d for ExportLogs: %w", rec.ID, err)
+		}
+	}
+	return rows.Err()
+}
+
+// exportErrorLogs scans ErrorLogs in the given window, handing each row to write
+func (m *LoggingModel) exportErrorLogs(ctx context.Context, since, until time.Time, write func(LogExportRecord) error) error {
+	rows, err := m.DB.QueryContext(ctx, `SELECT ID, Timestamp, Level, Message, StackTrace, RequestPath, UserID, Context,
+			COALESCE(RequestID, '')
+		FROM ErrorLogs
+		WHERE Timestamp >= ? AND Timestamp < ?
+		ORDER BY Timestamp ASC, ID ASC`, models.FormatTimestamp(since), models.FormatTimestamp(until))
+	if err != nil {
+		return fmt.Errorf("failed to query ErrorLogs for ExportLogs: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			rec                                     LogExportRecord
+			userID                                  []byte
+			stackTrace, requestPath, contextDetails sql.NullString
+		)
+		if err := rows.Scan(&rec.ID, &rec.Timestamp, &rec.Level, &rec.Message, &stackTrace,
+			&requestPath, &userID, &contextDetails, &rec.RequestID); err != nil {
+			return fmt.Errorf("failed to scan ErrorLogs row for ExportLogs: %w", err)
+		}
+		rec.Source = "error"
+		rec.Path = requestPath.String
+		rec.UserID = exportUserID(userID)
+		rec.StackTrace = stackTrace.String
+		rec.Context = contextDetails.String
+
+		if err := write(rec); err != nil {
+			return fmt.Errorf("failed to write ErrorLogs row %d for ExportLogs: %w", rec.ID, err)
+		}
+	}
+	return rows.Err()
+}
+
+// exportUserID renders a BLOB user ID as a UUID string, or "" for anonymous rows
+func exportUserID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	var id models.UUIDField
+	if err := id.Scan(b); err != nil {
+		return ""
+	}
+	return id.String()
 }
 
 // CleanupOldLogs deletes logs older than the specified number of days
@@ -437,20 +656,20 @@ func (m *LoggingModel) CleanupOldLogs(ctx context.Context, daysToKeep int) error
 		}
 	}()
 
-	cutoff := `datetime('now', '-` + string(rune(daysToKeep+'0')) + ` days')`
+	cutoff := models.FormatTimestamp(time.Now().AddDate(0, 0, -daysToKeep))
 
 	// Clean request logs
-	if _, err := tx.ExecContext(ctx, `DELETE FROM RequestLogs WHERE Timestamp < `+cutoff); err != nil {
+	if _, err = tx.ExecContext(ctx, `DELETE FROM RequestLogs WHERE Timestamp < ?`, cutoff); err != nil {
 		return err
 	}
 
 	// Clean error logs
-	if _, err := tx.ExecContext(ctx, `DELETE FROM ErrorLogs WHERE Timestamp < `+cutoff); err != nil {
+	if _, err = tx.ExecContext(ctx, `DELETE FROM ErrorLogs WHERE Timestamp < ?`, cutoff); err != nil {
 		return err
 	}
 
 	// Clean system metrics
-	if _, err := tx.ExecContext(ctx, `DELETE FROM SystemMetrics WHERE Timestamp < `+cutoff); err != nil {
+	if _, err = tx.ExecContext(ctx, `DELETE FROM SystemMetrics WHERE Timestamp < ?`, cutoff); err != nil {
 		return err
 	}
 
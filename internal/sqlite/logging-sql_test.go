@@ -0,0 +1,311 @@
+package sqlite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestExportLogsCSVMatchesDatabase(t *testing.T) {
+	ctx := context.Background()
+	m := &LoggingModel{DB: setupMigratedTestDB(t)}
+
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	userID := models.NewUUIDField()
+
+	if err := m.InsertRequestLog(ctx, models.RequestLog{
+		Timestamp: base, Method: "GET", Path: "/posts/1", StatusCode: 200, Duration: 12,
+		UserID: userID, IPAddress: "10.0.0.1", UserAgent: "test-agent", Referer: "/", BytesSent: 512,
+		RequestID: "req-1",
+	}); err != nil {
+		t.Fatalf("InsertRequestLog failed: %v", err)
+	}
+	// outside the export window
+	if err := m.InsertRequestLog(ctx, models.RequestLog{
+		Timestamp: base.Add(48 * time.Hour), Method: "GET", Path: "/later", StatusCode: 200, UserID: userID,
+	}); err != nil {
+		t.Fatalf("InsertRequestLog failed: %v", err)
+	}
+	if err := m.InsertErrorLog(ctx, models.ErrorLog{
+		Timestamp: base.Add(time.Minute), Level: models.LogLevelError, Message: "boom",
+		RequestPath: "/posts/1", UserID: userID, Context: `{"k":"v"}`, RequestID: "req-1",
+	}); err != nil {
+		t.Fatalf("InsertErrorLog failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.ExportLogs(ctx, base.Add(-time.Hour), base.Add(24*time.Hour), &buf, ExportFormatCSV); err != nil {
+		t.Fatalf("ExportLogs failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Exported CSV is invalid: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header + 2 rows, got %d rows", len(records))
+	}
+
+	req := records[1]
+	if req[0] != "request" || req[3] != "GET" || req[4] != "/posts/1" || req[5] != "200" ||
+		req[7] != userID.String() || req[8] != "10.0.0.1" || req[11] != "512" || req[16] != "req-1" {
+		t.Errorf("Request row does not match database: %v", req)
+	}
+	errRow := records[2]
+	if errRow[0] != "error" || errRow[4] != "/posts/1" || errRow[12] != models.LogLevelError ||
+		errRow[13] != "boom" || errRow[15] != `{"k":"v"}` || errRow[16] != "req-1" {
+		t.Errorf("Error row does not match database: %v", errRow)
+	}
+}
+
+func TestExportLogsJSONStreamsLargeResultSet(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratedTestDB(t)
+	m := &LoggingModel{DB: db}
+
+	const total = 5000
+	base := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO RequestLogs (Timestamp, Method, Path, StatusCode, Duration) VALUES (?, 'GET', '/', 200, 1)`)
+	if err != nil {
+		t.Fatalf("Failed to prepare insert: %v", err)
+	}
+	for i := 0; i < total; i++ {
+		if _, err := stmt.Exec(models.FormatTimestamp(base.Add(time.Duration(i) * time.Second))); err != nil {
+			t.Fatalf("Failed to insert row %d: %v", i, err)
+		}
+	}
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit rows: %v", err)
+	}
+
+	w := &countingWriter{}
+	january := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := m.ExportLogs(ctx, january, january.AddDate(0, 1, 0), w, ExportFormatJSON); err != nil {
+		t.Fatalf("ExportLogs failed: %v", err)
+	}
+
+	// one Write per row shows rows go out as they are scanned rather than in one buffer
+	if w.writes < total {
+		t.Errorf("Expected at least %d writes, got %d", total, w.writes)
+	}
+
+	scanner := bufio.NewScanner(&w.buf)
+	lines := 0
+	var last int64
+	for scanner.Scan() {
+		var rec LogExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %v", lines+1, err)
+		}
+		if rec.ID <= last {
+			t.Fatalf("Rows out of order: %d after %d", rec.ID, last)
+		}
+		last = rec.ID
+		lines++
+	}
+	if lines != total {
+		t.Errorf("Expected %d exported rows, got %d", total, lines)
+	}
+}
+
+func TestExportLogsRejectsUnknownFormat(t *testing.T) {
+	m := &LoggingModel{DB: setupMigratedTestDB(t)}
+	january := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := m.ExportLogs(context.Background(), january, january.AddDate(0, 1, 0), &bytes.Buffer{}, "xml"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestLogTimestampsAreUTC(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratedTestDB(t)
+	m := &LoggingModel{DB: db}
+
+	// 12:00 at UTC+5 is 07:00 UTC; the since values below straddle it from other zones
+	east := time.FixedZone("UTC+5", 5*60*60)
+	west := time.FixedZone("UTC-5", -5*60*60)
+	logged := time.Date(2025, 1, 10, 12, 0, 0, 0, east)
+	if err := m.InsertRequestLog(ctx, models.RequestLog{Timestamp: logged, Method: "GET", Path: "/", StatusCode: 200}); err != nil {
+		t.Fatalf("InsertRequestLog failed: %v", err)
+	}
+	if err := m.InsertErrorLog(ctx, models.ErrorLog{Timestamp: logged, Level: models.LogLevelError, Message: "boom"}); err != nil {
+		t.Fatalf("InsertErrorLog failed: %v", err)
+	}
+
+	// CAST skips the driver's DATETIME parsing so the raw stored text is compared
+	var stored string
+	if err := db.QueryRow(`SELECT CAST(Timestamp AS TEXT) FROM RequestLogs`).Scan(&stored); err != nil {
+		t.Fatalf("Failed to read stored timestamp: %v", err)
+	}
+	if stored != "2025-01-10T07:00:00.000000000Z" {
+		t.Errorf("Expected the timestamp stored as UTC RFC3339, got %q", stored)
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  int
+	}{
+		{"earlier instant, later wall clock", time.Date(2025, 1, 10, 8, 30, 0, 0, east), 1},
+		{"later instant, earlier wall clock", time.Date(2025, 1, 10, 2, 30, 0, 0, west), 0},
+		{"same instant in UTC", time.Date(2025, 1, 10, 7, 0, 0, 0, time.UTC), 1},
+		{"one millisecond later", time.Date(2025, 1, 10, 7, 0, 0, int(time.Millisecond), time.UTC), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, err := m.GetRequestLogsSince(ctx, tt.since, 10)
+			if err != nil {
+				t.Fatalf("GetRequestLogsSince failed: %v", err)
+			}
+			errs, err := m.GetErrorLogsSince(ctx, tt.since, 10)
+			if err != nil {
+				t.Fatalf("GetErrorLogsSince failed: %v", err)
+			}
+			if len(requests) != tt.want || len(errs) != tt.want {
+				t.Fatalf("Expected %d rows, got %d request and %d error logs", tt.want, len(requests), len(errs))
+			}
+			for _, got := range []time.Time{firstRequestTime(requests), firstErrorTime(errs)} {
+				if tt.want > 0 && (!got.Equal(logged) || got.Location() != time.UTC) {
+					t.Errorf("Expected %v in UTC, got %v", logged.UTC(), got)
+				}
+			}
+		})
+	}
+}
+
+func firstRequestTime(logs []models.RequestLog) time.Time {
+	if len(logs) == 0 {
+		return time.Time{}
+	}
+	return logs[0].Timestamp
+}
+
+func firstErrorTime(logs []models.ErrorLog) time.Time {
+	if len(logs) == 0 {
+		return time.Time{}
+	}
+	return logs[0].Timestamp
+}
+
+func TestLogRequestIDs(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratedTestDB(t)
+	m := &LoggingModel{DB: db}
+
+	now := time.Now()
+	if err := m.InsertRequestLog(ctx, models.RequestLog{Timestamp: now, Method: "GET", Path: "/", StatusCode: 500, RequestID: "req-1"}); err != nil {
+		t.Fatalf("InsertRequestLog failed: %v", err)
+	}
+	if err := m.InsertErrorLog(ctx, models.ErrorLog{Timestamp: now, Level: models.LogLevelError, Message: "in request", RequestID: "req-1"}); err != nil {
+		t.Fatalf("InsertErrorLog failed: %v", err)
+	}
+	if err := m.InsertErrorLog(ctx, models.ErrorLog{Timestamp: now.Add(-time.Second), Level: models.LogLevelWarn, Message: "at startup"}); err != nil {
+		t.Fatalf("InsertErrorLog failed: %v", err)
+	}
+
+	var joined string
+	if err := db.QueryRow(`SELECT e.Message FROM ErrorLogs e JOIN RequestLogs r ON r.RequestID = e.RequestID`).Scan(&joined); err != nil || joined != "in request" {
+		t.Errorf("Expected the error to join its request, got %q, %v", joined, err)
+	}
+	var unset int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM ErrorLogs WHERE RequestID IS NULL`).Scan(&unset); err != nil || unset != 1 {
+		t.Errorf("Expected an error outside a request to store a NULL RequestID, got %d, %v", unset, err)
+	}
+
+	requests, err := m.GetRequestLogsSince(ctx, now.Add(-time.Minute), 10)
+	if err != nil || len(requests) != 1 || requests[0].RequestID != "req-1" {
+		t.Errorf("Expected the request log to read back req-1, got %+v, %v", requests, err)
+	}
+	errs, err := m.GetErrorLogsSince(ctx, now.Add(-time.Minute), 10)
+	if err != nil || len(errs) != 2 || errs[0].RequestID != "req-1" || errs[1].RequestID != "" {
+		t.Errorf("Expected error logs with req-1 and no request ID, got %+v, %v", errs, err)
+	}
+}
+
+func TestCleanupOldLogsKeepsRecentRows(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratedTestDB(t)
+	m := &LoggingModel{DB: db}
+
+	now := time.Now()
+	for _, age := range []int{2, 12, 40} {
+		if err := m.InsertRequestLog(ctx, models.RequestLog{
+			Timestamp: now.AddDate(0, 0, -age), Method: "GET", Path: fmt.Sprintf("/%d", age), StatusCode: 200,
+		}); err != nil {
+			t.Fatalf("InsertRequestLog failed: %v", err)
+		}
+	}
+
+	if err := m.CleanupOldLogs(ctx, 30); err != nil {
+		t.Fatalf("CleanupOldLogs failed: %v", err)
+	}
+	logs, err := m.GetRequestLogsSince(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("GetRequestLogsSince failed: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Path != "/2" || logs[1].Path != "/12" {
+		t.Errorf("Expected the 2 and 12 day old rows to remain, got %v", logs)
+	}
+}
+
+func TestUTCLogTimestampsMigration(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	rows := map[string]string{
+		"/offset":  "2025-01-10 12:00:00.250+05:00",
+		"/default": "2025-01-10 07:30:00",
+		"/current": "2025-01-10T08:00:00.123456789Z",
+	}
+	for path, ts := range rows {
+		if _, err := db.Exec(`INSERT INTO RequestLogs (Timestamp, Method, Path, StatusCode, Duration) VALUES (?, 'GET', ?, 200, 1)`, ts, path); err != nil {
+			t.Fatalf("Failed to insert %s: %v", path, err)
+		}
+	}
+
+	migration, err := os.ReadFile("../../migrations/014_utc_log_timestamps.sql")
+	if err != nil {
+		t.Fatalf("Failed to read migration: %v", err)
+	}
+	if _, err := db.Exec(string(migration)); err != nil {
+		t.Fatalf("Failed to apply migration: %v", err)
+	}
+
+	want := map[string]string{
+		"/offset":  "2025-01-10T07:00:00.250000000Z",
+		"/default": "2025-01-10T07:30:00.000000000Z",
+		"/current": "2025-01-10T08:00:00.123456789Z",
+	}
+	for path, expected := range want {
+		var got string
+		if err := db.QueryRow(`SELECT CAST(Timestamp AS TEXT) FROM RequestLogs WHERE Path = ?`, path).Scan(&got); err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, got)
+		}
+	}
+}
+
+// countingWriter records how many times Write was called
+type countingWriter struct {
+	buf    bytes.Buffer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.buf.Write(p)
+}
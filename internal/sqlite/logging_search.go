@@ -286,7 +286,7 @@ func (m *LoggingModel) GetMostActiveUsers(days int, limit int) ([]struct {
 			MAX(Timestamp) as last_activity
 		FROM SystemMetrics
 		WHERE MetricType = 'user_activity'
-			AND Timestamp > datetime('now', '-' || ? || ' days')
+			AND Timestamp > ?
 			AND json_extract(Details, '$.user_id') IS NOT NULL
 			AND json_extract(Details, '$.user_id') != ''
 		GROUP BY json_extract(Details, '$.user_id')
@@ -294,7 +294,7 @@ func (m *LoggingModel) GetMostActiveUsers(days int, limit int) ([]struct {
 		LIMIT ?
 	`
 
-	rows, err := m.DB.Query(query, days, limit)
+	rows, err := m.DB.Query(query, models.FormatTimestamp(time.Now().AddDate(0, 0, -days)), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -336,14 +336,14 @@ func (m *LoggingModel) GetPopularPosts(days int, limit int) ([]struct {
 		FROM SystemMetrics
 		WHERE MetricType = 'user_activity'
 			AND MetricName = 'post_viewed'
-			AND Timestamp > datetime('now', '-' || ? || ' days')
+			AND Timestamp > ?
 			AND json_extract(Details, '$.post_id') IS NOT NULL
 		GROUP BY json_extract(Details, '$.post_id')
 		ORDER BY view_count DESC
 		LIMIT ?
 	`
 
-	rows, err := m.DB.Query(query, days, limit)
+	rows, err := m.DB.Query(query, models.FormatTimestamp(time.Now().AddDate(0, 0, -days)), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -26,6 +26,12 @@ func (m *LoyaltyModel) InsertLoyalty(ctx context.Context, follower, following mo
 		return errors.New(err.Error())
 	}
 
+	// the follow has been stored, so a failed notification is only logged
+	follow := models.Notification{UserID: following, ActorID: follower, Kind: models.NotificationFollow}
+	if notifyErr := insertNotification(ctx, m.DB, follow); notifyErr != nil {
+		models.LogErrorWithContext(ctx, "Failed to notify user of new follower", notifyErr)
+	}
+
 	return err
 }
 
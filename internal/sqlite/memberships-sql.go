@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/gary-norman/forum/internal/models"
 )
@@ -11,15 +12,25 @@ type MembershipModel struct {
 	DB *sql.DB
 }
 
-func (m *MembershipModel) Insert(ctx context.Context, userID models.UUIDField, channelID int64) error {
-	query := "INSERT INTO Memberships (UserID, ChannelID, Created) VALUES (?, ?, DateTime('now'))"
-	_, err := m.DB.ExecContext(ctx, query, userID, channelID)
-	return err
+// Insert makes userID a member of channelID. Joining a channel the user is already
+// in leaves the existing membership alone; created reports whether a new one was made.
+func (m *MembershipModel) Insert(ctx context.Context, userID models.UUIDField, channelID int64) (created bool, err error) {
+	query := `INSERT INTO Memberships (UserID, ChannelID, Created) VALUES (?, ?, DateTime('now'))
+		ON CONFLICT (UserID, ChannelID) DO NOTHING`
+	res, err := m.DB.ExecContext(ctx, query, userID, channelID)
+	if err != nil {
+		return false, fmt.Errorf("failed to add user %s to channel %d: %w", userID, channelID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check membership of user %s in channel %d: %w", userID, channelID, err)
+	}
+	return n == 1, nil
 }
 
 func (m *MembershipModel) UserMemberships(ctx context.Context, userID models.UUIDField) ([]models.Membership, error) {
 	// fmt.Printf(ErrorMsgs.KeyValuePair, "Checking memberships for UserID", userID)
-	query := "SELECT ID, UserID, ChannelID, Created FROM Memberships WHERE UserID = ?"
+	query := "SELECT ID, UserID, ChannelID, Role, Created FROM Memberships WHERE UserID = ?"
 	rows, queryErr := m.DB.QueryContext(ctx, query, userID)
 	if queryErr != nil {
 		return nil, queryErr
@@ -32,7 +43,7 @@ func (m *MembershipModel) UserMemberships(ctx context.Context, userID models.UUI
 	var memberships []models.Membership
 	for rows.Next() {
 		p := models.Membership{}
-		scanErr := rows.Scan(&p.ID, &p.UserID, &p.ChannelID, &p.Created)
+		scanErr := rows.Scan(&p.ID, &p.UserID, &p.ChannelID, &p.Role, &p.Created)
 		if scanErr != nil {
 			return nil, scanErr
 		}
@@ -45,9 +56,14 @@ func (m *MembershipModel) UserMemberships(ctx context.Context, userID models.UUI
 	return memberships, nil
 }
 
-func (m *MembershipModel) All(ctx context.Context) ([]models.Membership, error) {
-	query := "SELECT ID, UserID, ChannelID, Created FROM Memberships ORDER BY ID DESC"
-	rows, err := m.DB.QueryContext(ctx, query)
+// All returns one page of memberships across every channel, newest first. It is
+// meant for admin and debugging views; request handlers should use UserMemberships.
+func (m *MembershipModel) All(ctx context.Context, limit, offset int) ([]models.Membership, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+	query := "SELECT ID, UserID, ChannelID, Role, Created FROM Memberships ORDER BY ID DESC LIMIT ? OFFSET ?"
+	rows, err := m.DB.QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +77,7 @@ func (m *MembershipModel) All(ctx context.Context) ([]models.Membership, error)
 	var Memberships []models.Membership
 	for rows.Next() {
 		p := models.Membership{}
-		err = rows.Scan(&p.ID, &p.UserID, &p.ChannelID, &p.Created)
+		err = rows.Scan(&p.ID, &p.UserID, &p.ChannelID, &p.Role, &p.Created)
 		if err != nil {
 			return nil, err
 		}
@@ -74,3 +90,12 @@ func (m *MembershipModel) All(ctx context.Context) ([]models.Membership, error)
 
 	return Memberships, nil
 }
+
+// Count returns the total number of memberships, for paging through All
+func (m *MembershipModel) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Memberships").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count memberships: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestMembershipsAllPaging(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &MembershipModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	for i := 0; i < 5; i++ {
+		channelID := insertTestChannel(t, db, owner, fmt.Sprintf("channel-%d", i))
+		if _, err := m.Insert(ctx, owner, channelID); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	total, err := m.Count(ctx)
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 5 {
+		t.Fatalf("Expected 5 memberships, got %d", total)
+	}
+
+	seen := map[int64]bool{}
+	var pages []int
+	for offset := 0; offset < total; offset += 2 {
+		page, err := m.All(ctx, 2, offset)
+		if err != nil {
+			t.Fatalf("All(2, %d) failed: %v", offset, err)
+		}
+		pages = append(pages, len(page))
+		for _, membership := range page {
+			if seen[membership.ID] {
+				t.Errorf("Membership %d returned on more than one page", membership.ID)
+			}
+			seen[membership.ID] = true
+		}
+	}
+	if fmt.Sprint(pages) != "[2 2 1]" {
+		t.Errorf("Expected page sizes [2 2 1], got %v", pages)
+	}
+	if len(seen) != total {
+		t.Errorf("Expected to page through %d memberships, saw %d", total, len(seen))
+	}
+
+	if _, err := m.All(ctx, 0, 0); err == nil {
+		t.Error("Expected an error for a zero limit")
+	}
+}
+
+func TestMembershipInsertIsIdempotent(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &MembershipModel{DB: db}
+	channels := &ChannelModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	member := insertTestUser(t, db, "member")
+	channelID := insertTestChannel(t, db, owner, "general")
+
+	for i, want := range []bool{true, false, false} {
+		created, err := m.Insert(ctx, member, channelID)
+		if err != nil {
+			t.Fatalf("Join %d failed: %v", i+1, err)
+		}
+		if created != want {
+			t.Errorf("Join %d: expected created=%v, got %v", i+1, want, created)
+		}
+	}
+
+	memberships, err := m.UserMemberships(ctx, member)
+	if err != nil {
+		t.Fatalf("UserMemberships failed: %v", err)
+	}
+	if len(memberships) != 1 {
+		t.Errorf("Expected 1 membership, got %d", len(memberships))
+	}
+	channel, err := channels.GetChannelByID(ctx, channelID)
+	if err != nil {
+		t.Fatalf("GetChannelByID failed: %v", err)
+	}
+	if channel.Members != 1 {
+		t.Errorf("Expected a member count of 1, got %d", channel.Members)
+	}
+}
@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -92,6 +93,34 @@ func (m *ModModel) GetModdedChannelID(ID models.UUIDField) ([]int64, error) {
 	return ids, nil
 }
 
+// ModeratedChannelIDs returns the channels userID may moderate: those they own and
+// those they are a moderator of
+func (m *ModModel) ModeratedChannelIDs(ctx context.Context, userID models.UUIDField) (map[int64]bool, error) {
+	stmt := "SELECT ID FROM Channels WHERE OwnerID = ? UNION SELECT ChannelID FROM Mods WHERE UserID = ?"
+	rows, err := m.DB.QueryContext(ctx, stmt, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moderated channels: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	moderated := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan moderated channel ID: %w", err)
+		}
+		moderated[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating moderated channels: %w", err)
+	}
+	return moderated, nil
+}
+
 func (m *ModModel) GetModerator(ID int64) ([]models.UUIDField, error) {
 	stmt := ("SELECT UserID FROM Mods WHERE ChannelID = ?")
 
@@ -0,0 +1,171 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// NotificationModel stores the notifications shown to each user
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertNotificationStmt adds a notification unless its recipient is the actor, or
+// the recipient already has the same one unread, so toggling a like or following
+// again does not pile up copies
+const insertNotificationStmt = `INSERT INTO Notifications (UserID, ActorID, Kind, PostID, CommentID)
+	SELECT ?1, ?2, ?3, ?4, ?5
+	WHERE ?1 != ?2 AND NOT EXISTS (
+		SELECT 1 FROM Notifications
+		WHERE UserID = ?1 AND ActorID = ?2 AND Kind = ?3 AND ReadAt IS NULL
+			AND PostID IS ?4 AND CommentID IS ?5
+	)`
+
+// insertNotification stores n using e, so it can share a transaction with the
+// change it reports
+func insertNotification(ctx context.Context, e execer, n models.Notification) error {
+	if _, err := e.ExecContext(ctx, insertNotificationStmt, n.UserID, n.ActorID, n.Kind, n.PostID, n.CommentID); err != nil {
+		return fmt.Errorf("failed to insert %s notification for user %s: %w", n.Kind, n.UserID, err)
+	}
+	return nil
+}
+
+// Insert stores n for n.UserID. Users are not notified of their own actions.
+func (m *NotificationModel) Insert(ctx context.Context, n models.Notification) error {
+	return insertNotification(ctx, m.DB, n)
+}
+
+// InsertMentions stores n for each user named in usernames, compared without regard
+// to case. Names that match no user are skipped.
+func (m *NotificationModel) InsertMentions(ctx context.Context, n models.Notification, usernames []string) error {
+	if len(usernames) == 0 {
+		return nil
+	}
+	args := make([]any, len(usernames))
+	for i, name := range usernames {
+		args[i] = name
+	}
+	stmt := "SELECT ID FROM Users WHERE Username COLLATE NOCASE IN (" + strings.TrimSuffix(strings.Repeat("?,", len(usernames)), ",") + ")"
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return fmt.Errorf("failed to look up mentioned users: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in InsertMentions: %v", closeErr)
+		}
+	}()
+
+	var mentioned []models.UUIDField
+	for rows.Next() {
+		var id models.UUIDField
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan mentioned user: %w", err)
+		}
+		mentioned = append(mentioned, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating mentioned users: %w", err)
+	}
+
+	for _, id := range mentioned {
+		n.UserID = id
+		if err := m.Insert(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForUser returns a page of userID's notifications, newest first
+func (m *NotificationModel) ForUser(ctx context.Context, userID models.UUIDField, limit, offset int) ([]models.Notification, error) {
+	stmt := `SELECT n.ID, n.UserID, n.ActorID, COALESCE(u.Username, ''), n.Kind, n.PostID, n.CommentID,
+			n.Created, n.ReadAt IS NOT NULL
+		FROM Notifications n
+		LEFT JOIN Users u ON u.ID = n.ActorID
+		WHERE n.UserID = ?
+		ORDER BY n.Created DESC, n.ID DESC
+		LIMIT ? OFFSET ?`
+	rows, err := m.DB.QueryContext(ctx, stmt, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications for user %s: %w", userID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in ForUser: %v", closeErr)
+		}
+	}()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var postID, commentID sql.NullInt64
+		if err := rows.Scan(&n.ID, &n.UserID, &n.ActorID, &n.Actor, &n.Kind, &postID, &commentID,
+			&n.Created, &n.Read); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if postID.Valid {
+			n.PostID = &postID.Int64
+		}
+		if commentID.Valid {
+			n.CommentID = &commentID.Int64
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// Count returns how many notifications userID has, read or not
+func (m *NotificationModel) Count(ctx context.Context, userID models.UUIDField) (int, error) {
+	var total int
+	if err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Notifications WHERE UserID = ?", userID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count notifications for user %s: %w", userID, err)
+	}
+	return total, nil
+}
+
+// UnreadCount returns how many of userID's notifications are unread
+func (m *NotificationModel) UnreadCount(ctx context.Context, userID models.UUIDField) (int, error) {
+	var unread int
+	stmt := "SELECT COUNT(*) FROM Notifications WHERE UserID = ? AND ReadAt IS NULL"
+	if err := m.DB.QueryRowContext(ctx, stmt, userID).Scan(&unread); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications for user %s: %w", userID, err)
+	}
+	return unread, nil
+}
+
+// MarkRead marks notification id, which must belong to userID, as read. Marking one
+// that is already read is not an error.
+func (m *NotificationModel) MarkRead(ctx context.Context, userID models.UUIDField, id int64) error {
+	stmt := "UPDATE Notifications SET ReadAt = COALESCE(ReadAt, ?) WHERE ID = ? AND UserID = ?"
+	result, err := m.DB.ExecContext(ctx, stmt, time.Now().UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification %d read: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("notification %d of user %s: %w", id, userID, ErrNotFound)
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of userID as read
+func (m *NotificationModel) MarkAllRead(ctx context.Context, userID models.UUIDField) error {
+	stmt := "UPDATE Notifications SET ReadAt = ? WHERE UserID = ? AND ReadAt IS NULL"
+	if _, err := m.DB.ExecContext(ctx, stmt, time.Now().UTC(), userID); err != nil {
+		return fmt.Errorf("failed to mark notifications read for user %s: %w", userID, err)
+	}
+	return nil
+}
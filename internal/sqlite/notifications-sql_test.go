@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestNotificationModel(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &NotificationModel{DB: db}
+	ctx := context.Background()
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	carol := insertTestUser(t, db, "carol")
+	postID := insertTestPost(t, db, alice, "thread")
+
+	liked := models.Notification{UserID: alice, ActorID: bob, Kind: models.NotificationReaction, PostID: &postID}
+	for range 2 {
+		if err := m.Insert(ctx, liked); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := m.Insert(ctx, models.Notification{UserID: alice, ActorID: alice, Kind: models.NotificationReaction, PostID: &postID}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	mention := models.Notification{ActorID: bob, Kind: models.NotificationMention, PostID: &postID}
+	if err := m.InsertMentions(ctx, mention, []string{"ALICE", "carol", "bob", "nobody"}); err != nil {
+		t.Fatalf("InsertMentions failed: %v", err)
+	}
+	if err := (&LoyaltyModel{DB: db}).InsertLoyalty(ctx, carol, alice); err != nil {
+		t.Fatalf("InsertLoyalty failed: %v", err)
+	}
+
+	notifications, err := m.ForUser(ctx, alice, 10, 0)
+	if err != nil {
+		t.Fatalf("ForUser failed: %v", err)
+	}
+	kinds := map[string]int{}
+	for _, n := range notifications {
+		kinds[n.Kind]++
+	}
+	if len(notifications) != 3 || kinds[models.NotificationReaction] != 1 || kinds[models.NotificationMention] != 1 || kinds[models.NotificationFollow] != 1 {
+		t.Fatalf("Expected one like, one mention and one follow for alice, got %+v", notifications)
+	}
+	for _, n := range notifications {
+		if n.Kind == models.NotificationFollow && (n.Actor != "carol" || n.PostID != nil) {
+			t.Errorf("Expected a follow from carol about no post, got %+v", n)
+		}
+		if n.Kind == models.NotificationReaction && (n.Actor != "bob" || n.PostID == nil || *n.PostID != postID) {
+			t.Errorf("Expected bob's like on post %d, got %+v", postID, n)
+		}
+	}
+	if others, err := m.ForUser(ctx, carol, 10, 0); err != nil || len(others) != 1 {
+		t.Errorf("Expected carol to have her mention, got %d (%v)", len(others), err)
+	}
+	if mine, err := m.ForUser(ctx, bob, 10, 0); err != nil || len(mine) != 0 {
+		t.Errorf("Expected bob not to be notified of his own mention, got %d (%v)", len(mine), err)
+	}
+
+	if err := m.MarkRead(ctx, carol, notifications[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected carol to be unable to read alice's notification, got %v", err)
+	}
+	if err := m.MarkRead(ctx, alice, notifications[0].ID); err != nil {
+		t.Fatalf("MarkRead failed: %v", err)
+	}
+	if unread, err := m.UnreadCount(ctx, alice); err != nil || unread != 2 {
+		t.Errorf("Expected 2 unread, got %d (%v)", unread, err)
+	}
+	if err := m.MarkAllRead(ctx, alice); err != nil {
+		t.Fatalf("MarkAllRead failed: %v", err)
+	}
+	if unread, err := m.UnreadCount(ctx, alice); err != nil || unread != 0 {
+		t.Errorf("Expected nothing unread, got %d (%v)", unread, err)
+	}
+
+	// once the like has been seen, a new one is news again
+	if err := m.Insert(ctx, liked); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if unread, err := m.UnreadCount(ctx, alice); err != nil || unread != 1 {
+		t.Errorf("Expected the repeated like to be unread, got %d (%v)", unread, err)
+	}
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// ErrResetTokenInvalid is returned when a password reset token is unknown, used or expired
+var ErrResetTokenInvalid = fmt.Errorf("password reset token %w", ErrNotFound)
+
+// PasswordResetModel stores the tokens emailed in password reset links. Tokens are
+// stored hashed; the plain token only ever exists in the link.
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Insert records token as a reset for userID that can be used until expires
+func (m *PasswordResetModel) Insert(ctx context.Context, userID models.UUIDField, token string, expires time.Time) error {
+	stmt := "INSERT INTO PasswordResets (TokenHash, UserID, Expires) VALUES (?, ?, ?)"
+	if _, err := m.DB.ExecContext(ctx, stmt, hashResetToken(token), userID, expires.UTC()); err != nil {
+		return fmt.Errorf("failed to insert password reset for user %s: %w", userID, err)
+	}
+	return nil
+}
+
+// Consume sets the password of the user token was issued to and returns their ID. The
+// user's sessions on every device are ended, and every reset outstanding for them is
+// marked used, so neither an old login nor another emailed link survives the change.
+func (m *PasswordResetModel) Consume(ctx context.Context, token, hashedPassword string) (userID models.UUIDField, err error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return userID, fmt.Errorf("failed to begin transaction for Consume: %w", err)
+	}
+
+	// Ensure rollback on failure
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	now := time.Now().UTC()
+	query := "SELECT UserID FROM PasswordResets WHERE TokenHash = ? AND Used IS NULL AND Expires > ?"
+	err = tx.QueryRowContext(ctx, query, hashResetToken(token), now).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userID, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return userID, fmt.Errorf("failed to look up password reset: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE Users SET HashedPassword = ? WHERE ID = ?", hashedPassword, userID); err != nil {
+		return userID, fmt.Errorf("failed to reset password for user %s: %w", userID, err)
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM Sessions WHERE UserID = ?", userID); err != nil {
+		return userID, fmt.Errorf("failed to end sessions for user %s: %w", userID, err)
+	}
+	stmt := "UPDATE PasswordResets SET Used = ? WHERE UserID = ? AND Used IS NULL"
+	if _, err = tx.ExecContext(ctx, stmt, now, userID); err != nil {
+		return userID, fmt.Errorf("failed to mark password resets used for user %s: %w", userID, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return userID, fmt.Errorf("failed to commit password reset: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetModel(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PasswordResetModel{DB: db}
+	ctx := context.Background()
+	alice := insertTestUser(t, db, "alice")
+	if _, err := db.Exec("INSERT INTO Sessions (Token, UserID, Expires) VALUES ('live', ?, ?)", alice, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+
+	if err := m.Insert(ctx, alice, "first", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := m.Insert(ctx, alice, "second", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := m.Insert(ctx, alice, "stale", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	var stored int
+	if err := db.QueryRow("SELECT COUNT(*) FROM PasswordResets WHERE TokenHash IN ('first', 'second', 'stale')").Scan(&stored); err != nil || stored != 0 {
+		t.Errorf("Expected tokens to be stored hashed, found %d in plain text (%v)", stored, err)
+	}
+
+	if _, err := m.Consume(ctx, "stale", "new-hash"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("Expected an expired token to be refused, got %v", err)
+	}
+	if _, err := m.Consume(ctx, "unknown", "new-hash"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("Expected an unknown token to be refused, got %v", err)
+	}
+
+	userID, err := m.Consume(ctx, "first", "new-hash")
+	if err != nil {
+		t.Fatalf("Consume failed: %v", err)
+	}
+	if userID != alice {
+		t.Errorf("Expected user %s, got %s", alice, userID)
+	}
+	var hash string
+	var sessions int
+	if err := db.QueryRow("SELECT HashedPassword, (SELECT COUNT(*) FROM Sessions WHERE UserID = Users.ID) FROM Users WHERE ID = ?", alice).Scan(&hash, &sessions); err != nil {
+		t.Fatalf("Failed to read user: %v", err)
+	}
+	if hash != "new-hash" || sessions != 0 {
+		t.Errorf("Expected the new password and no sessions, got %q and %d sessions", hash, sessions)
+	}
+
+	// a token works once, and using one retires the others sent to the same user
+	for _, token := range []string{"first", "second"} {
+		if _, err := m.Consume(ctx, token, "other-hash"); !errors.Is(err, ErrResetTokenInvalid) {
+			t.Errorf("Expected %q to be refused after a reset, got %v", token, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/gary-norman/forum/internal/models"
 )
@@ -13,10 +14,15 @@ type PostModel struct {
 	DB *sql.DB
 }
 
-// Insert a new post into the database
+// Insert a new post into the database. images must be empty, NoImage or names
+// produced by the upload pipeline; anything else is rejected with ErrInvalidImage.
 func (m *PostModel) Insert(ctx context.Context, title, content, images, author, authorAvatar string, authorID models.UUIDField, commentable, isFlagged bool) (int64, error) {
+	post := models.Post{Images: images}
+	if err := post.SanitizeImages(); err != nil {
+		return 0, fmt.Errorf("failed to insert post: %w", err)
+	}
 	stmt := "INSERT INTO Posts (Title, Content, Images, Created, Author, AuthorAvatar, AuthorID, IsCommentable, IsFlagged) VALUES (?, ?, ?, DateTime('now'), ?, ?, ?, ?, ?)"
-	result, err := m.DB.ExecContext(ctx, stmt, title, content, images, author, authorAvatar, authorID, commentable, isFlagged)
+	result, err := m.DB.ExecContext(ctx, stmt, title, content, post.Images, author, authorAvatar, authorID, commentable, isFlagged)
 	if err != nil {
 		return 0, err
 	}
@@ -30,95 +36,87 @@ func (m *PostModel) Insert(ctx context.Context, title, content, images, author,
 	return int64(id), nil
 }
 
-func (m *PostModel) All(ctx context.Context) ([]*models.Post, error) {
-	stmt := "SELECT * FROM Posts ORDER BY Created DESC"
-	rows, selectErr := m.DB.QueryContext(ctx, stmt)
-	if selectErr != nil {
-		return nil, fmt.Errorf("failed to query all posts: %w", selectErr)
-	}
+// postColumns are the Posts columns queryPosts and GetPostByID scan, in order
+const postColumns = "ID, Title, Content, Images, Created, Updated, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged"
 
-	defer func() {
-		if closeErr := rows.Close(); closeErr != nil {
-			models.LogWarn("Failed to close rows: %v", closeErr)
-		}
-	}()
+// NoLimit passed as a limit to the paged post queries returns every row after offset
+const NoLimit = -1
 
-	var Posts []*models.Post
-	for rows.Next() {
-		p := models.Post{}
-		scanErr := rows.Scan(
-			&p.ID,
-			&p.Title,
-			&p.Content,
-			&p.Images,
-			&p.Created,
-			&p.Updated,
-			&p.IsCommentable,
-			&p.Author,
-			&p.AuthorID,
-			&p.AuthorAvatar,
-			&p.IsFlagged)
-		if scanErr != nil {
-			return nil, fmt.Errorf("failed to scan post row: %w", scanErr)
-		}
-		Posts = append(Posts, &p)
+// All returns a page of every post, newest first. Deleted posts are left out of this
+// and the other post listings.
+func (m *PostModel) All(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	stmt := "SELECT " + postColumns + " FROM Posts WHERE DeletedAt IS NULL ORDER BY Created DESC, ID DESC LIMIT ? OFFSET ?"
+	posts, err := m.queryPosts(ctx, stmt, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query all posts: %w", err)
 	}
+	return posts, nil
+}
 
-	if rowsErr := rows.Err(); rowsErr != nil {
-		return nil, fmt.Errorf("error iterating post rows: %w", rowsErr)
+// Count returns the total number of posts
+func (m *PostModel) Count(ctx context.Context) (int, error) {
+	var total int
+	if err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Posts WHERE DeletedAt IS NULL").Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count posts: %w", err)
 	}
-
-	return Posts, nil
+	return total, nil
 }
 
-func (m *PostModel) GetPostsByUserID(ctx context.Context, user models.UUIDField) ([]*models.Post, error) {
-	stmt := "SELECT * FROM posts WHERE AuthorID = ? ORDER BY ID DESC"
-	rows, err := m.DB.QueryContext(ctx, stmt, user)
+// GetPostsByUserID returns a page of the posts written by user, newest first
+func (m *PostModel) GetPostsByUserID(ctx context.Context, user models.UUIDField, limit, offset int) ([]*models.Post, error) {
+	stmt := "SELECT " + postColumns + " FROM Posts WHERE AuthorID = ? AND DeletedAt IS NULL ORDER BY ID DESC LIMIT ? OFFSET ?"
+	posts, err := m.queryPosts(ctx, stmt, user, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query posts by user ID: %w", err)
 	}
-	defer func() {
-		if closeErr := rows.Close(); closeErr != nil {
-			models.LogWarn("Failed to close rows: %v", closeErr)
-		}
-	}()
+	return posts, nil
+}
 
-	var Posts []*models.Post
-	for rows.Next() {
-		p := models.Post{}
-		scanErr := rows.Scan(
-			&p.ID,
-			&p.Title,
-			&p.Content,
-			&p.Images,
-			&p.Created,
-			&p.Updated,
-			&p.IsCommentable,
-			&p.Author,
-			&p.AuthorID,
-			&p.AuthorAvatar,
-			&p.IsFlagged)
-		if scanErr != nil {
-			return nil, fmt.Errorf("failed to scan post row: %w", scanErr)
-		}
-		Posts = append(Posts, &p)
+// CountByUserID returns the number of posts written by user
+func (m *PostModel) CountByUserID(ctx context.Context, user models.UUIDField) (int, error) {
+	var total int
+	if err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Posts WHERE AuthorID = ? AND DeletedAt IS NULL", user).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count posts by user ID: %w", err)
 	}
-	return Posts, nil
+	return total, nil
 }
 
-func (m *PostModel) GetPostsByChannel(ctx context.Context, channel int64) ([]*models.Post, error) {
-	stmt := "SELECT * FROM Posts WHERE ID IN (SELECT PostID FROM PostChannels WHERE ChannelID = ?) ORDER BY Created DESC"
-	rows, err := m.DB.QueryContext(ctx, stmt, channel)
+// GetPostsByChannel returns a page of the posts in channel, newest first
+func (m *PostModel) GetPostsByChannel(ctx context.Context, channel int64, limit, offset int) ([]*models.Post, error) {
+	stmt := `SELECT ` + postColumns + ` FROM Posts
+		WHERE ID IN (SELECT PostID FROM PostChannels WHERE ChannelID = ?) AND DeletedAt IS NULL
+		ORDER BY Created DESC, ID DESC LIMIT ? OFFSET ?`
+	posts, err := m.queryPosts(ctx, stmt, channel, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query posts by channel: %w", err)
 	}
+	return posts, nil
+}
+
+// CountByChannel returns the number of posts in channel
+func (m *PostModel) CountByChannel(ctx context.Context, channel int64) (int, error) {
+	var total int
+	stmt := `SELECT COUNT(*) FROM PostChannels pc JOIN Posts p ON p.ID = pc.PostID
+		WHERE pc.ChannelID = ? AND p.DeletedAt IS NULL`
+	if err := m.DB.QueryRowContext(ctx, stmt, channel).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count posts by channel: %w", err)
+	}
+	return total, nil
+}
+
+// queryPosts runs stmt, which must select postColumns, and scans the rows
+func (m *PostModel) queryPosts(ctx context.Context, stmt string, args ...any) ([]*models.Post, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
 			models.LogWarn("Failed to close rows: %v", closeErr)
 		}
 	}()
 
-	var Posts []*models.Post
+	var posts []*models.Post
 	for rows.Next() {
 		p := models.Post{}
 		scanErr := rows.Scan(
@@ -136,14 +134,17 @@ func (m *PostModel) GetPostsByChannel(ctx context.Context, channel int64) ([]*mo
 		if scanErr != nil {
 			return nil, fmt.Errorf("failed to scan post row: %w", scanErr)
 		}
-		Posts = append(Posts, &p)
+		posts = append(posts, &p)
 	}
-
-	return Posts, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post rows: %w", err)
+	}
+	return posts, nil
 }
 
+// GetPostByID returns the post with id; a deleted post is not found
 func (m *PostModel) GetPostByID(ctx context.Context, id int64) (models.Post, error) {
-	stmt := "SELECT * FROM Posts WHERE ID = ?"
+	stmt := "SELECT " + postColumns + " FROM Posts WHERE ID = ? AND DeletedAt IS NULL"
 	row := m.DB.QueryRowContext(ctx, stmt, id)
 	p := models.Post{}
 	err := row.Scan(
@@ -159,37 +160,174 @@ func (m *PostModel) GetPostByID(ctx context.Context, id int64) (models.Post, err
 		&p.AuthorAvatar,
 		&p.IsFlagged)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, fmt.Errorf("no post found for ID %d: %w", id, ErrPostNotFound)
+		}
 		return p, fmt.Errorf("failed to get post by ID %d: %w", id, err)
 	}
 
 	return p, nil
 }
 
-func (m *PostModel) GetAllChannelPostsForUser(ctx context.Context, ID models.UUIDField) ([]models.Post, error) {
-	stmt := "SELECT * From posts WHERE ID IN (SELECT ChannelID FROM Memberships WHERE UserID = ?)"
-	rows, err := m.DB.QueryContext(ctx, stmt, ID)
+// GetPostsByIDs returns the posts with the given IDs in one query, keyed by ID.
+// IDs with no post, or a deleted one, are left out of the map; see PostsInOrder to list the rest in the
+// order they were asked for.
+func (m *PostModel) GetPostsByIDs(ctx context.Context, ids []int64) (map[int64]*models.Post, error) {
+	posts := make(map[int64]*models.Post, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	stmt := `SELECT ID, Title, Content, Images, Created, Updated, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged
+		FROM Posts
+		WHERE ID IN (` + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + `) AND DeletedAt IS NULL`
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query posts by ID: %w", err)
 	}
-	defer rows.Close()
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
 
-	// Parse results
-	posts := make([]models.Post, 0) // Pre-allocate slice
 	for rows.Next() {
-		c, err := parsePostRows(rows)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing row: %w", err)
+		var p models.Post
+		var images, avatar sql.NullString
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &images, &p.Created, &p.Updated, &p.IsCommentable,
+			&p.Author, &p.AuthorID, &avatar, &p.IsFlagged); err != nil {
+			return nil, fmt.Errorf("failed to scan post row: %w", err)
+		}
+		p.Images = images.String
+		p.AuthorAvatar = avatar.String
+		posts[p.ID] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posts by ID: %w", err)
+	}
+	return posts, nil
+}
+
+// PostsInOrder lists the posts in byID in the order of ids, skipping IDs that are
+// missing and repeats of an ID already listed
+func PostsInOrder(ids []int64, byID map[int64]*models.Post) []*models.Post {
+	ordered := make([]*models.Post, 0, len(byID))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok && !seen[id] {
+			ordered = append(ordered, p)
+			seen[id] = true
 		}
-		posts = append(posts, *c)
 	}
+	return ordered
+}
+
+// GetPostsForJoinedChannels returns a page of posts from every channel userID is a
+// member of, newest first. A post shared to several of those channels is returned
+// once, under the lowest channel ID. Reaction and comment counts are filled in by
+// the same query.
+func (m *PostModel) GetPostsForJoinedChannels(ctx context.Context, userID models.UUIDField, limit, offset int) ([]*models.Post, error) {
+	stmt := `
+		WITH joined AS (
+			SELECT pc.PostID, MIN(pc.ChannelID) AS ChannelID
+			FROM PostChannels pc
+			INNER JOIN Memberships m ON m.ChannelID = pc.ChannelID
+			WHERE m.UserID = ?
+			GROUP BY pc.PostID
+		)
+		SELECT p.ID, p.Title, p.Content, p.Images, p.Created, p.Updated, p.IsCommentable,
+			p.Author, p.AuthorID, p.AuthorAvatar, p.IsFlagged,
+			c.ID, c.Name,
+			COALESCE(r.Likes, 0), COALESCE(r.Dislikes, 0), COALESCE(cm.Total, 0)
+		FROM joined j
+		INNER JOIN Posts p ON p.ID = j.PostID
+		INNER JOIN Channels c ON c.ID = j.ChannelID
+		LEFT JOIN (
+			SELECT ReactedPostID AS PostID, SUM(Liked) AS Likes, SUM(Disliked) AS Dislikes
+			FROM Reactions
+			WHERE ReactedPostID IS NOT NULL
+			GROUP BY ReactedPostID
+		) r ON r.PostID = p.ID
+		LEFT JOIN (
+			SELECT CommentedPostID AS PostID, COUNT(*) AS Total
+			FROM Comments
+			WHERE CommentedPostID IS NOT NULL AND DeletedAt IS NULL
+			GROUP BY CommentedPostID
+		) cm ON cm.PostID = p.ID
+		WHERE p.DeletedAt IS NULL
+		ORDER BY p.Created DESC, p.ID DESC
+		LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posts for joined channels: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
 
+	posts := make([]*models.Post, 0)
+	for rows.Next() {
+		var p models.Post
+		var images, avatar sql.NullString
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &images, &p.Created, &p.Updated, &p.IsCommentable,
+			&p.Author, &p.AuthorID, &avatar, &p.IsFlagged,
+			&p.ChannelID, &p.ChannelName,
+			&p.Likes, &p.Dislikes, &p.CommentsCount); err != nil {
+			return nil, fmt.Errorf("failed to scan joined channel post: %w", err)
+		}
+		p.Images = images.String
+		p.AuthorAvatar = avatar.String
+		models.UpdateTimeSince(&p)
+		posts = append(posts, &p)
+	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return nil, fmt.Errorf("error iterating joined channel posts: %w", err)
 	}
 
 	return posts, nil
 }
 
+// PrivatePostIDs returns the IDs of posts that are not in any public channel. These
+// are the posts an anonymous visitor must not see.
+func (m *PostModel) PrivatePostIDs(ctx context.Context) (map[int64]bool, error) {
+	stmt := `
+		SELECT p.ID FROM Posts p
+		WHERE NOT EXISTS (
+			SELECT 1 FROM PostChannels pc
+			INNER JOIN Channels c ON c.ID = pc.ChannelID
+			WHERE pc.PostID = p.ID AND c.Privacy = 0
+		)`
+	rows, err := m.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query private posts: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	private := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan private post ID: %w", err)
+		}
+		private[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating private posts: %w", err)
+	}
+	return private, nil
+}
+
 // FindCurrentPost queries the database for any post column that contains the values and returns that post
 func (m *PostModel) FindCurrentPost(ctx context.Context, column string, value any) ([]models.Post, error) {
 	// Validate column name to prevent SQL injection
@@ -212,7 +350,7 @@ func (m *PostModel) FindCurrentPost(ctx context.Context, column string, value an
 	}
 
 	// Base query
-	query := fmt.Sprintf("SELECT id, title, content, images, created, isCommentable, author, authorID, authorAvatar,  isFlagged FROM posts WHERE %s = ? LIMIT 1", column)
+	query := fmt.Sprintf("SELECT id, title, content, images, created, isCommentable, author, authorID, authorAvatar,  isFlagged FROM posts WHERE %s = ? AND DeletedAt IS NULL LIMIT 1", column)
 
 	row := m.DB.QueryRowContext(ctx, query, value)
 
@@ -248,31 +386,78 @@ func (m *PostModel) FindCurrentPost(ctx context.Context, column string, value an
 	return posts, nil
 }
 
-func parsePostRows(rows *sql.Rows) (*models.Post, error) {
-	var post models.Post
+// Delete marks post id as deleted. The post and its comments are kept, but left out
+// of the post listings; AdminPurge removes them for good.
+func (m *PostModel) Delete(ctx context.Context, id int64) error {
+	stmt := "UPDATE Posts SET DeletedAt = CURRENT_TIMESTAMP WHERE ID = ? AND DeletedAt IS NULL"
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete post %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("no post found for ID %d: %w", id, ErrPostNotFound)
+	}
+	return nil
+}
 
-	if err := rows.Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		&post.Images,
-		&post.Created,
-		&post.Updated,
-		&post.IsCommentable,
-		&post.Author,
-		&post.AuthorID,
-		&post.IsFlagged,
-		&post.ChannelID,
-		&post.ChannelName,
-		&post.Likes,
-		&post.Dislikes,
-		&post.CommentsCount,
-		&post.AuthorAvatar,
-		&post.Comments,
-	); err != nil {
-		return nil, err
+// AdminPurge permanently removes post id, which must already be deleted, together with
+// its comments and every row that refers to them. The dependent rows are deleted
+// explicitly, since the foreign key cascade only fires on connections that have
+// foreign_keys enabled.
+func (m *PostModel) AdminPurge(ctx context.Context, id int64) (err error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for AdminPurge in Posts: %w", err)
 	}
 
-	models.UpdateTimeSince(&post)
-	return &post, nil
+	// Ensure rollback on failure
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarn("Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var deleted bool
+	err = tx.QueryRowContext(ctx, "SELECT DeletedAt IS NOT NULL FROM Posts WHERE ID = ?", id).Scan(&deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no post found for ID %d: %w", id, ErrPostNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up post %d: %w", id, err)
+	}
+	if !deleted {
+		return fmt.Errorf("post %d must be deleted before it is purged: %w", id, ErrConflict)
+	}
+
+	comments, err := commentSubtree(ctx, tx, "CommentedPostID = ?", id)
+	if err != nil {
+		return err
+	}
+	if err = purgeComments(ctx, tx, comments); err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		"DELETE FROM Reactions WHERE ReactedPostID = ?",
+		"DELETE FROM ReactionEvents WHERE TargetType = 'post' AND TargetID = ?",
+		"DELETE FROM Bookmarks WHERE PostID = ?",
+		"DELETE FROM Flags WHERE FlaggedPostID = ?",
+		"DELETE FROM PostChannels WHERE PostID = ?",
+		"DELETE FROM PostImages WHERE PostID = ?",
+		"DELETE FROM Images WHERE PostID = ?",
+		"DELETE FROM PostReplies WHERE ParentPostID = ?",
+		"DELETE FROM Posts WHERE ID = ?",
+	} {
+		if _, err = tx.ExecContext(ctx, stmt, id); err != nil {
+			return fmt.Errorf("failed to purge post %d: %w", id, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for AdminPurge in Posts: %w", err)
+	}
+	return nil
 }
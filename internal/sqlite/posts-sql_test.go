@@ -0,0 +1,410 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestGetPostByID(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	postID := insertTestPost(t, db, author, "hello")
+
+	t.Run("returns the post", func(t *testing.T) {
+		post, err := m.GetPostByID(ctx, postID)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if post.ID != postID || post.Title != "hello" {
+			t.Errorf("Unexpected post: %+v", post)
+		}
+	})
+
+	t.Run("missing ID returns ErrPostNotFound", func(t *testing.T) {
+		_, err := m.GetPostByID(ctx, postID+100)
+		if !errors.Is(err, ErrPostNotFound) {
+			t.Errorf("Expected ErrPostNotFound, got %v", err)
+		}
+	})
+
+	t.Run("database failure is not ErrPostNotFound", func(t *testing.T) {
+		broken := setupMigratedTestDB(t)
+		broken.Close()
+		_, err := (&PostModel{DB: broken}).GetPostByID(ctx, postID)
+		if err == nil {
+			t.Fatal("Expected an error from a closed database")
+		}
+		if errors.Is(err, ErrPostNotFound) || errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected a non-sentinel error, got %v", err)
+		}
+	})
+}
+
+func TestGetPostsForJoinedChannels(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+	ctx := context.Background()
+
+	owner := insertTestUser(t, db, "owner")
+	reader := insertTestUser(t, db, "reader")
+	cooking := insertTestChannel(t, db, owner, "cooking")
+	hiking := insertTestChannel(t, db, owner, "hiking")
+	unjoined := insertTestChannel(t, db, owner, "unjoined")
+	for _, channelID := range []int64{cooking, hiking} {
+		if _, err := db.Exec(`INSERT INTO Memberships (UserID, ChannelID) VALUES (?, ?)`, reader, channelID); err != nil {
+			t.Fatalf("Failed to insert membership: %v", err)
+		}
+	}
+
+	// post creates a post at the given offset from a fixed time in each listed channel
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	post := func(title string, minutes int, channels ...int64) int64 {
+		id := insertTestPost(t, db, owner, title)
+		if _, err := db.Exec(`UPDATE Posts SET Created = ? WHERE ID = ?`, base.Add(time.Duration(minutes)*time.Minute), id); err != nil {
+			t.Fatalf("Failed to date post: %v", err)
+		}
+		for _, channelID := range channels {
+			if _, err := db.Exec(`INSERT INTO PostChannels (PostID, ChannelID) VALUES (?, ?)`, id, channelID); err != nil {
+				t.Fatalf("Failed to link post to channel: %v", err)
+			}
+		}
+		return id
+	}
+	oldest := post("oldest", 0, cooking)
+	post("hidden", 1, unjoined)
+	middle := post("middle", 2, hiking)
+	shared := post("shared", 3, hiking, cooking)
+	newest := post("newest", 4, hiking)
+
+	if _, err := db.Exec(`INSERT INTO Reactions (Liked, Disliked, AuthorID, ReactedPostID) VALUES (1, 0, ?, ?), (0, 1, ?, ?)`,
+		owner, middle, reader, middle); err != nil {
+		t.Fatalf("Failed to insert reactions: %v", err)
+	}
+	insertTestComment(t, db, owner, middle, hiking)
+	insertTestComment(t, db, reader, middle, hiking)
+
+	all, err := m.GetPostsForJoinedChannels(ctx, reader, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPostsForJoinedChannels failed: %v", err)
+	}
+	want := []int64{newest, shared, middle, oldest}
+	if len(all) != len(want) {
+		t.Fatalf("Expected %d posts, got %d", len(want), len(all))
+	}
+	for i, p := range all {
+		if p.ID != want[i] {
+			t.Errorf("Position %d: expected post %d, got %d (%s)", i, want[i], p.ID, p.Title)
+		}
+	}
+
+	byID := map[int64]*models.Post{}
+	for _, p := range all {
+		byID[p.ID] = p
+	}
+	if p := byID[middle]; p.Likes != 1 || p.Dislikes != 1 || p.CommentsCount != 2 || p.ChannelName != "hiking" {
+		t.Errorf("Unexpected counts for middle: likes=%d dislikes=%d comments=%d channel=%q",
+			p.Likes, p.Dislikes, p.CommentsCount, p.ChannelName)
+	}
+	if p := byID[shared]; p.ChannelID != cooking {
+		t.Errorf("Expected the shared post under channel %d, got %d", cooking, p.ChannelID)
+	}
+	if p := byID[oldest]; p.Likes != 0 || p.Dislikes != 0 || p.CommentsCount != 0 {
+		t.Errorf("Expected no counts for oldest, got %+v", p)
+	}
+
+	page, err := m.GetPostsForJoinedChannels(ctx, reader, 2, 2)
+	if err != nil {
+		t.Fatalf("GetPostsForJoinedChannels failed: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != middle || page[1].ID != oldest {
+		t.Errorf("Expected the second page to be [%d %d], got %v", middle, oldest, page)
+	}
+
+	none, err := m.GetPostsForJoinedChannels(ctx, owner, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPostsForJoinedChannels failed: %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("Expected no posts for a user without memberships, got %d", len(none))
+	}
+}
+
+func TestPrivatePostIDs(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+
+	owner := insertTestUser(t, db, "owner")
+	public := insertTestChannel(t, db, owner, "public")
+	private := insertTestChannel(t, db, owner, "private")
+	if _, err := db.Exec(`UPDATE Channels SET Privacy = 1 WHERE ID = ?`, private); err != nil {
+		t.Fatalf("Failed to make channel private: %v", err)
+	}
+	post := func(title string, channels ...int64) int64 {
+		id := insertTestPost(t, db, owner, title)
+		for _, channelID := range channels {
+			if _, err := db.Exec(`INSERT INTO PostChannels (PostID, ChannelID) VALUES (?, ?)`, id, channelID); err != nil {
+				t.Fatalf("Failed to link post to channel: %v", err)
+			}
+		}
+		return id
+	}
+	publicOnly := post("public", public)
+	privateOnly := post("private", private)
+	both := post("both", public, private)
+	orphan := post("orphan")
+
+	got, err := m.PrivatePostIDs(context.Background())
+	if err != nil {
+		t.Fatalf("PrivatePostIDs failed: %v", err)
+	}
+	want := map[int64]bool{privateOnly: true, orphan: true}
+	if len(got) != len(want) || !got[privateOnly] || !got[orphan] {
+		t.Errorf("Expected private posts %v, got %v", want, got)
+	}
+	if got[publicOnly] || got[both] {
+		t.Error("A post in a public channel was reported as private")
+	}
+}
+
+func TestPostTimeSinceUsesUTC(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+
+	// run as if the host were well away from UTC
+	local := time.Local
+	time.Local = time.FixedZone("UTC+9", 9*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	author := insertTestUser(t, db, "author")
+	postID := insertTestPost(t, db, author, "hello")
+	// stored the way CURRENT_TIMESTAMP stores it: UTC with no zone suffix
+	created := time.Now().UTC().Add(-3 * time.Hour).Format("2006-01-02 15:04:05")
+	if _, err := db.Exec(`UPDATE Posts SET Created = ? WHERE ID = ?`, created, postID); err != nil {
+		t.Fatalf("Failed to backdate post: %v", err)
+	}
+
+	post, err := m.GetPostByID(context.Background(), postID)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	post.UpdateTimeSince()
+	if post.TimeSince != "3 hours ago" {
+		t.Errorf("Expected %q, got %q", "3 hours ago", post.TimeSince)
+	}
+}
+
+func TestInsertPostValidatesImages(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+	ctx := context.Background()
+	author := insertTestUser(t, db, "author")
+
+	insert := func(images string) (int64, error) {
+		return m.Insert(ctx, "title", "content", images, "author", "", author, true, false)
+	}
+
+	uploaded := models.GenerateToken(16) + ".png"
+	for images, want := range map[string]string{uploaded: uploaded, "": models.NoImage} {
+		id, err := insert(images)
+		if err != nil {
+			t.Fatalf("Insert(%q) failed: %v", images, err)
+		}
+		post, err := m.GetPostByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetPostByID failed: %v", err)
+		}
+		if post.Images != want {
+			t.Errorf("Insert(%q) stored %q, want %q", images, post.Images, want)
+		}
+	}
+
+	if _, err := insert("../../forum_database.db"); !errors.Is(err, models.ErrInvalidImage) {
+		t.Errorf("Expected ErrInvalidImage for an injected path, got %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM Posts").Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected the injected post not to be stored, got %d posts (%v)", count, err)
+	}
+}
+
+func TestGetPostsByIDs(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+	ctx := context.Background()
+	userID := insertTestUser(t, db, "alice")
+	first := insertTestPost(t, db, userID, "first")
+	second := insertTestPost(t, db, userID, "second")
+	third := insertTestPost(t, db, userID, "third")
+
+	ids := []int64{third, 9999, first, third, 8888}
+	byID, err := m.GetPostsByIDs(ctx, ids)
+	if err != nil {
+		t.Fatalf("GetPostsByIDs failed: %v", err)
+	}
+	if len(byID) != 2 || byID[first] == nil || byID[third] == nil {
+		t.Fatalf("Expected posts %d and %d only, got %v", first, third, byID)
+	}
+	if byID[second] != nil {
+		t.Errorf("Expected post %d, which was not asked for, to be left out", second)
+	}
+	if byID[third].Title != "third" || byID[third].AuthorID != userID {
+		t.Errorf("Expected a fully populated post, got %+v", byID[third])
+	}
+
+	var got []int64
+	for _, p := range PostsInOrder(ids, byID) {
+		got = append(got, p.ID)
+	}
+	if want := []int64{third, first}; !slices.Equal(got, want) {
+		t.Errorf("Expected posts in requested order %v, got %v", want, got)
+	}
+
+	empty, err := m.GetPostsByIDs(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected no posts for no IDs, got %v, %v", empty, err)
+	}
+}
+
+func TestPagedPostQueries(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+	ctx := context.Background()
+
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+	cooking := insertTestChannel(t, db, alice, "cooking")
+
+	// five posts a minute apart, oldest first; alice writes the even ones, all but
+	// the last are in cooking
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var ids []int64
+	for i := range 5 {
+		author := alice
+		if i%2 == 1 {
+			author = bob
+		}
+		id := insertTestPost(t, db, author, fmt.Sprintf("post %d", i))
+		if _, err := db.Exec(`UPDATE Posts SET Created = ? WHERE ID = ?`, base.Add(time.Duration(i)*time.Minute), id); err != nil {
+			t.Fatalf("Failed to date post: %v", err)
+		}
+		if i < 4 {
+			if _, err := db.Exec(`INSERT INTO PostChannels (PostID, ChannelID) VALUES (?, ?)`, id, cooking); err != nil {
+				t.Fatalf("Failed to add post to channel: %v", err)
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	postIDs := func(posts []*models.Post, err error) []int64 {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		out := make([]int64, len(posts))
+		for i, p := range posts {
+			out[i] = p.ID
+		}
+		return out
+	}
+	count := func(total int, err error) int {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		return total
+	}
+
+	pages := []struct {
+		name      string
+		got       []int64
+		want      []int64
+		total     int
+		wantTotal int
+	}{
+		{"all, first page", postIDs(m.All(ctx, 2, 0)), []int64{ids[4], ids[3]}, count(m.Count(ctx)), 5},
+		{"all, last page", postIDs(m.All(ctx, 2, 4)), []int64{ids[0]}, count(m.Count(ctx)), 5},
+		{"all, no limit", postIDs(m.All(ctx, NoLimit, 1)), []int64{ids[3], ids[2], ids[1], ids[0]}, count(m.Count(ctx)), 5},
+		{"by user", postIDs(m.GetPostsByUserID(ctx, alice, 2, 1)), []int64{ids[2], ids[0]}, count(m.CountByUserID(ctx, alice)), 3},
+		{"by channel", postIDs(m.GetPostsByChannel(ctx, cooking, 3, 0)), []int64{ids[3], ids[2], ids[1]}, count(m.CountByChannel(ctx, cooking)), 4},
+		{"past the end", postIDs(m.GetPostsByChannel(ctx, cooking, 3, 10)), nil, count(m.CountByChannel(ctx, cooking)), 4},
+	}
+	for _, tt := range pages {
+		if !slices.Equal(tt.got, tt.want) || tt.total != tt.wantTotal {
+			t.Errorf("%s: expected %v of %d, got %v of %d", tt.name, tt.want, tt.wantTotal, tt.got, tt.total)
+		}
+	}
+}
+
+func TestPostSoftDelete(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &PostModel{DB: db}
+	ctx := context.Background()
+	author := insertTestUser(t, db, "author")
+	channelID := insertTestChannel(t, db, author, "general")
+	kept := insertTestPost(t, db, author, "kept")
+	gone := insertTestPost(t, db, author, "gone")
+	for _, id := range []int64{kept, gone} {
+		if _, err := db.Exec("INSERT INTO PostChannels (ChannelID, PostID) VALUES (?, ?)", channelID, id); err != nil {
+			t.Fatalf("Failed to link post: %v", err)
+		}
+	}
+	commentID := insertTestComment(t, db, author, gone, channelID)
+	if _, err := db.Exec(`INSERT INTO Comments (Content, CommentedCommentID, IsCommentable, IsFlagged, IsReply,
+		Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+		VALUES ('reply', ?, 1, 0, 1, 'author', ?, '', 'general', ?)`, commentID, author, channelID); err != nil {
+		t.Fatalf("Failed to insert reply: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO Reactions (Liked, Disliked, AuthorID, ReactedPostID) VALUES (1, 0, ?, ?)", author, gone); err != nil {
+		t.Fatalf("Failed to insert reaction: %v", err)
+	}
+
+	if err := m.Delete(ctx, gone); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := m.Delete(ctx, gone); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected deleting twice to fail, got %v", err)
+	}
+
+	all, err := m.All(ctx, NoLimit, 0)
+	if err != nil || len(all) != 1 || all[0].ID != kept {
+		t.Errorf("Expected only the kept post to be listed, got %v (%v)", all, err)
+	}
+	inChannel, err := m.GetPostsByChannel(ctx, channelID, NoLimit, 0)
+	if err != nil || len(inChannel) != 1 {
+		t.Errorf("Expected one post in the channel, got %d (%v)", len(inChannel), err)
+	}
+	if total, err := m.CountByChannel(ctx, channelID); err != nil || total != 1 {
+		t.Errorf("Expected the channel count to leave out the deleted post, got %d (%v)", total, err)
+	}
+	if _, err := m.GetPostByID(ctx, gone); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected a deleted post to be not found, got %v", err)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM Comments WHERE ID = ? OR CommentedCommentID = ?", commentID, commentID).Scan(&rows); err != nil || rows != 2 {
+		t.Errorf("Expected the deleted post's comments to be kept, found %d (%v)", rows, err)
+	}
+
+	if err := m.AdminPurge(ctx, kept); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected purging a live post to be refused, got %v", err)
+	}
+	if err := m.AdminPurge(ctx, gone); err != nil {
+		t.Fatalf("AdminPurge failed: %v", err)
+	}
+	err = db.QueryRow(`SELECT (SELECT COUNT(*) FROM Posts WHERE ID = ?) + (SELECT COUNT(*) FROM Comments)
+		+ (SELECT COUNT(*) FROM Reactions) + (SELECT COUNT(*) FROM PostChannels WHERE PostID = ?)`, gone, gone).Scan(&rows)
+	if err != nil || rows != 0 {
+		t.Errorf("Expected the post and everything under it to be purged, found %d rows (%v)", rows, err)
+	}
+	if err := m.AdminPurge(ctx, gone); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected purging twice to fail, got %v", err)
+	}
+}
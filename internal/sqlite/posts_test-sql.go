@@ -27,7 +27,7 @@ func TestPostModelAll(t *testing.T) {
 			m := &PostModel{
 				DB: tt.fields.DB,
 			}
-			got, err := m.All(ctx)
+			got, err := m.All(ctx, NoLimit, 0)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("All() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -61,7 +61,7 @@ func TestPostModelGetPostsByChannel(t *testing.T) {
 			m := &PostModel{
 				DB: tt.fields.DB,
 			}
-			got, err := m.GetPostsByChannel(ctx, tt.args.channel)
+			got, err := m.GetPostsByChannel(ctx, tt.args.channel, NoLimit, 0)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetPostsByChannel() error = %v, wantErr %v", err, tt.wantErr)
 				return
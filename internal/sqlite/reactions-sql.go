@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/gary-norman/forum/internal/models"
 )
@@ -14,13 +15,30 @@ type ReactionModel struct {
 	DB *sql.DB
 }
 
+// errInvalidReactionTarget is returned when a reaction query is given an invalid target
+var errInvalidReactionTarget = errors.New("reaction target must be a post or comment with a non-zero ID")
+
 type ReactionStatus struct {
 	Liked    bool
 	Disliked bool
 }
 
-func (m *ReactionModel) GetLastReaction(ctx context.Context, reactedPostID, reactedCommentID int64) (models.Reaction, error) {
-	whereArgs, arg := preparePostChannelDynamicWhere(reactedPostID, reactedCommentID)
+// ReactionCount holds the like and dislike totals for a single target
+type ReactionCount struct {
+	Likes    int
+	Dislikes int
+}
+
+// reactionBatchSize caps the number of IDs bound into a single IN (...) query
+const reactionBatchSize = 500
+
+// GetLastReaction returns the most recent reaction on target. The zero Reaction is
+// returned when there are none.
+func (m *ReactionModel) GetLastReaction(ctx context.Context, target models.ReactionTarget) (models.Reaction, error) {
+	if !target.Valid() {
+		return models.Reaction{}, errInvalidReactionTarget
+	}
+	whereArgs, arg := targetWhere(target)
 
 	stmt := fmt.Sprintf(`
 	SELECT
@@ -39,6 +57,7 @@ func (m *ReactionModel) GetLastReaction(ctx context.Context, reactedPostID, reac
 	row := m.DB.QueryRowContext(ctx, stmt, arg)
 
 	var reaction models.Reaction
+	var postID, commentID sql.NullInt64
 
 	err := row.Scan(
 		&reaction.ID,
@@ -46,8 +65,8 @@ func (m *ReactionModel) GetLastReaction(ctx context.Context, reactedPostID, reac
 		&reaction.Disliked,
 		&reaction.Created,
 		&reaction.AuthorID,
-		&reaction.ReactedPostID,
-		&reaction.ReactedCommentID,
+		&postID,
+		&commentID,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -56,20 +75,22 @@ func (m *ReactionModel) GetLastReaction(ctx context.Context, reactedPostID, reac
 		}
 		return models.Reaction{}, err
 	}
-
-	// fmt.Println("Reaction: ", reaction)
+	reaction.Target = scanTarget(postID, commentID)
 
 	return reaction, nil
 }
 
-func (m *ReactionModel) GetReactionStatus(ctx context.Context, authorID models.UUIDField, reactedPostID, reactedCommentID int64) (ReactionStatus, error) {
+func (m *ReactionModel) GetReactionStatus(ctx context.Context, authorID models.UUIDField, target models.ReactionTarget) (ReactionStatus, error) {
 	var liked, disliked int
 	var reactions ReactionStatus
 	if m == nil || m.DB == nil {
 		return reactions, fmt.Errorf("reaction model or database is nil")
 	}
+	if !target.Valid() {
+		return reactions, errInvalidReactionTarget
+	}
 
-	whereArgs, arg := preparePostChannelDynamicWhere(reactedPostID, reactedCommentID)
+	whereArgs, arg := targetWhere(target)
 
 	stmt := fmt.Sprintf(`
 	SELECT
@@ -89,27 +110,42 @@ func (m *ReactionModel) GetReactionStatus(ctx context.Context, authorID models.U
 	return reactions, nil
 }
 
-func (m *ReactionModel) Upsert(ctx context.Context, liked, disliked bool, authorID models.UUIDField, reactedPostID, reactedCommentID int64) error {
-	if !isValidParent(reactedPostID, reactedCommentID) {
-		return fmt.Errorf("only one of ReactedPostID or ReactedCommentID must be non-zero")
-	}
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
-	var (
-		query string
-		args  []any
-	)
+// Upsert toggles authorID's like or dislike on target: pressing an active button
+// clears it, pressing the other one switches to it. An existing row is updated in
+// place, so its ID and Created survive the toggle and only Updated moves.
+func (m *ReactionModel) Upsert(ctx context.Context, liked, disliked bool, authorID models.UUIDField, target models.ReactionTarget) error {
+	_, err := m.upsert(ctx, liked, disliked, authorID, target, false)
+	return err
+}
+
+// UpsertWithCounts applies the same toggle as Upsert and returns target's like and
+// dislike totals, read in the same transaction so they include the toggle and no
+// concurrent write can slip in between.
+func (m *ReactionModel) UpsertWithCounts(ctx context.Context, liked, disliked bool, authorID models.UUIDField, target models.ReactionTarget) (ReactionCount, error) {
+	return m.upsert(ctx, liked, disliked, authorID, target, true)
+}
+
+// upsert implements Upsert, also counting target's reactions when withCounts is set
+func (m *ReactionModel) upsert(ctx context.Context, liked, disliked bool, authorID models.UUIDField, target models.ReactionTarget, withCounts bool) (counts ReactionCount, err error) {
+	if !target.Valid() {
+		return counts, errInvalidReactionTarget
+	}
 
-	// TODO refactor so that query inserts ID/NULL to PostID AND CommentID
-	if reactedPostID != 0 {
-		query = `
+	column := targetColumn(target)
+	query := fmt.Sprintf(`
 		WITH existing AS (
     SELECT ID,
     COALESCE(Liked, 0) AS existing_liked,
     COALESCE(Disliked, 0) AS existing_disliked
     FROM Reactions
-    WHERE AuthorID = ? AND ReactedPostID = ?
+    WHERE AuthorID = ? AND %[1]s = ?
 		)
-		INSERT OR REPLACE INTO Reactions (ID, Liked, Disliked, Created, AuthorID, ReactedPostID)
+		INSERT INTO Reactions (ID, Liked, Disliked, Created, AuthorID, %[1]s)
 		VALUES (
 			(SELECT ID FROM existing),
 			CASE WHEN (SELECT existing_liked FROM existing) + 1 = 2 THEN 0 ELSE ? END,
@@ -117,45 +153,155 @@ func (m *ReactionModel) Upsert(ctx context.Context, liked, disliked bool, author
 			CURRENT_TIMESTAMP,
 			?,
 			?
-		);
-		`
-		args = []any{authorID, reactedPostID, liked, disliked, authorID, reactedPostID}
-	} else {
-		query = `
-		WITH existing AS (
-    SELECT ID,
-    COALESCE(Liked, 0) AS existing_liked,
-    COALESCE(Disliked, 0) AS existing_disliked
-    FROM Reactions
-    WHERE AuthorID = ? AND ReactedCommentID = ?
 		)
-		INSERT OR REPLACE INTO Reactions (ID, Liked, Disliked, Created, AuthorID, ReactedCommentID)
-		VALUES (
-			(SELECT ID FROM existing),
-			CASE WHEN (SELECT existing_liked FROM existing) + 1 = 2 THEN 0 ELSE ? END,
-			CASE WHEN (SELECT existing_disliked FROM existing) + 1 = 2 THEN 0 ELSE ? END,
-			CURRENT_TIMESTAMP,
-			?,
-			?
-		);
-		`
-		args = []any{authorID, reactedCommentID, liked, disliked, authorID, reactedCommentID}
-	}
+		ON CONFLICT(ID) DO UPDATE SET Liked = excluded.Liked, Disliked = excluded.Disliked;
+		`, column)
+	args := []any{authorID, target.ID, liked, disliked, authorID, target.ID}
 
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to upsert reaction: %w", err)
+		return counts, fmt.Errorf("failed to begin transaction for Upsert: %w", err)
 	}
 
+	// Ensure rollback on failure
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		return counts, fmt.Errorf("failed to upsert reaction: %w", err)
+	}
+
+	if err = recordReactionEvent(ctx, tx, liked, authorID, target); err != nil {
+		return counts, err
+	}
+
+	if withCounts {
+		if counts.Likes, counts.Dislikes, err = countReactions(ctx, tx, target); err != nil {
+			return counts, fmt.Errorf("failed to count reactions after upsert: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return counts, fmt.Errorf("failed to commit transaction for Upsert: %w", err)
+	}
+
+	return counts, nil
+}
+
+// recordReactionEvent appends the toggle just applied by Upsert to ReactionEvents.
+// The action is read back from the upserted row, since pressing an active button
+// clears it rather than setting it.
+func recordReactionEvent(ctx context.Context, tx *sql.Tx, liked bool, authorID models.UUIDField, target models.ReactionTarget) error {
+	whereArgs, arg := targetWhere(target)
+
+	var nowLiked, nowDisliked bool
+	stmt := fmt.Sprintf("SELECT Liked, Disliked FROM Reactions WHERE AuthorID = ? AND %s", whereArgs)
+	if err := tx.QueryRowContext(ctx, stmt, authorID, arg).Scan(&nowLiked, &nowDisliked); err != nil {
+		return fmt.Errorf("failed to read back reaction: %w", err)
+	}
+
+	kind, active := models.ReactionKindDislike, nowDisliked
+	if liked {
+		kind, active = models.ReactionKindLike, nowLiked
+	}
+	action := models.ReactionActionRemove
+	if active {
+		action = models.ReactionActionAdd
+	}
+
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO ReactionEvents (AuthorID, TargetType, TargetID, Kind, Action) VALUES (?, ?, ?, ?, ?)",
+		authorID, target.Type, target.ID, kind, action)
+	if err != nil {
+		return fmt.Errorf("failed to record reaction event: %w", err)
+	}
 	return nil
 }
 
-func (m *ReactionModel) CountReactions(ctx context.Context, reactedPostID, reactedCommentID int64) (likes, dislikes int, err error) {
-	if !isValidParent(reactedPostID, reactedCommentID) {
-		return 0, 0, fmt.Errorf("only one of  ReactedPostID, or ReactedCommentID must be non-zero")
+// ReactionHistory returns every reaction toggle made by authorID, oldest first
+func (m *ReactionModel) ReactionHistory(ctx context.Context, authorID models.UUIDField) ([]models.ReactionEvent, error) {
+	stmt := `SELECT ID, AuthorID, TargetType, TargetID, Kind, Action, Created
+		FROM ReactionEvents WHERE AuthorID = ? ORDER BY ID ASC`
+	rows, err := m.DB.QueryContext(ctx, stmt, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reaction history: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	var events []models.ReactionEvent
+	for rows.Next() {
+		var e models.ReactionEvent
+		if err := rows.Scan(&e.ID, &e.AuthorID, &e.TargetType, &e.TargetID, &e.Kind, &e.Action, &e.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reaction history: %w", err)
 	}
 
-	whereArgs, arg := preparePostChannelDynamicWhere(reactedPostID, reactedCommentID)
+	return events, nil
+}
+
+// GetReactionsForPost returns who currently likes or dislikes postID, newest first.
+// Cleared reactions are left out.
+func (m *ReactionModel) GetReactionsForPost(ctx context.Context, postID int64) ([]models.ReactionDetail, error) {
+	stmt := `SELECT u.ID, u.Username, COALESCE(u.Avatar, ''), r.Liked, r.Created
+		FROM Reactions r
+		JOIN Users u ON u.ID = r.AuthorID
+		WHERE r.ReactedPostID = ? AND (r.Liked = 1 OR r.Disliked = 1)
+		ORDER BY r.Created DESC, r.ID DESC`
+	rows, err := m.DB.QueryContext(ctx, stmt, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions for post %d: %w", postID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	details := make([]models.ReactionDetail, 0)
+	for rows.Next() {
+		var d models.ReactionDetail
+		var liked bool
+		if err := rows.Scan(&d.UserID, &d.Username, &d.Avatar, &liked, &d.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction for post %d: %w", postID, err)
+		}
+		d.Kind = models.ReactionKindDislike
+		if liked {
+			d.Kind = models.ReactionKindLike
+		}
+		details = append(details, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reactions for post %d: %w", postID, err)
+	}
+	return details, nil
+}
+
+// CountReactions returns the like and dislike totals for target
+func (m *ReactionModel) CountReactions(ctx context.Context, target models.ReactionTarget) (likes, dislikes int, err error) {
+	if !target.Valid() {
+		return 0, 0, errInvalidReactionTarget
+	}
+	return countReactions(ctx, m.DB, target)
+}
+
+// countReactions sums the likes and dislikes on target using q
+func countReactions(ctx context.Context, q rowQuerier, target models.ReactionTarget) (likes, dislikes int, err error) {
+	whereArgs, arg := targetWhere(target)
 
 	stmt := fmt.Sprintf(`
 		SELECT
@@ -166,7 +312,7 @@ func (m *ReactionModel) CountReactions(ctx context.Context, reactedPostID, react
 	var likesSum, dislikesSum sql.NullInt64
 
 	// Run the query
-	err = m.DB.QueryRowContext(ctx, stmt, arg).Scan(&likesSum, &dislikesSum)
+	err = q.QueryRowContext(ctx, stmt, arg).Scan(&likesSum, &dislikesSum)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -176,6 +322,122 @@ func (m *ReactionModel) CountReactions(ctx context.Context, reactedPostID, react
 	return likes, dislikes, err
 }
 
+// CountReactionsForComments returns the like and dislike totals for every comment in
+// commentIDs using one query per reactionBatchSize IDs, rather than one per comment.
+// Comments without reactions are present in the map with zero counts.
+func (m *ReactionModel) CountReactionsForComments(ctx context.Context, commentIDs []int64) (map[int64]ReactionCount, error) {
+	counts := make(map[int64]ReactionCount, len(commentIDs))
+	for _, id := range commentIDs {
+		counts[id] = ReactionCount{}
+	}
+
+	for start := 0; start < len(commentIDs); start += reactionBatchSize {
+		end := min(start+reactionBatchSize, len(commentIDs))
+		batch := commentIDs[start:end]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		stmt := fmt.Sprintf(`
+			SELECT ReactedCommentID, SUM(Liked), SUM(Disliked)
+			FROM Reactions
+			WHERE ReactedPostID IS NULL AND ReactedCommentID IN (%s)
+			GROUP BY ReactedCommentID`, placeholders)
+
+		if err := m.scanReactionCounts(ctx, stmt, args, counts); err != nil {
+			return nil, err
+		}
+	}
+
+	return counts, nil
+}
+
+// scanReactionCounts runs a (targetID, likes, dislikes) query and stores each row in counts
+func (m *ReactionModel) scanReactionCounts(ctx context.Context, stmt string, args []any, counts map[int64]ReactionCount) error {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return fmt.Errorf("failed to count reactions: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var id int64
+		var likes, dislikes sql.NullInt64
+		if err := rows.Scan(&id, &likes, &dislikes); err != nil {
+			return fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		counts[id] = ReactionCount{Likes: int(likes.Int64), Dislikes: int(dislikes.Int64)}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating reaction counts: %w", err)
+	}
+	return nil
+}
+
+// GetLikedPostsByUser returns a page of the posts authorID currently likes, most
+// recently liked first. Likes that were later toggled off are not included.
+func (m *ReactionModel) GetLikedPostsByUser(ctx context.Context, authorID models.UUIDField, limit, offset int) ([]models.Post, error) {
+	stmt := `
+		SELECT p.ID, p.Title, p.Content, p.Images, p.Created, p.Updated, p.IsCommentable,
+			p.Author, p.AuthorID, p.AuthorAvatar, p.IsFlagged
+		FROM Reactions r
+		INNER JOIN Posts p ON p.ID = r.ReactedPostID
+		WHERE r.AuthorID = ? AND r.Liked = 1 AND r.ReactedCommentID IS NULL AND p.DeletedAt IS NULL
+		ORDER BY r.Created DESC, r.ID DESC
+		LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, authorID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query liked posts: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		var p models.Post
+		var images, avatar sql.NullString
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &images, &p.Created, &p.Updated, &p.IsCommentable,
+			&p.Author, &p.AuthorID, &avatar, &p.IsFlagged); err != nil {
+			return nil, fmt.Errorf("failed to scan liked post: %w", err)
+		}
+		p.Images = images.String
+		p.AuthorAvatar = avatar.String
+		models.UpdateTimeSince(&p)
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating liked posts: %w", err)
+	}
+
+	return posts, nil
+}
+
+// CountLikedPostsByUser returns how many posts authorID currently likes
+func (m *ReactionModel) CountLikedPostsByUser(ctx context.Context, authorID models.UUIDField) (int, error) {
+	stmt := `
+		SELECT COUNT(*)
+		FROM Reactions r
+		INNER JOIN Posts p ON p.ID = r.ReactedPostID
+		WHERE r.AuthorID = ? AND r.Liked = 1 AND r.ReactedCommentID IS NULL AND p.DeletedAt IS NULL`
+
+	var total int
+	if err := m.DB.QueryRowContext(ctx, stmt, authorID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count liked posts: %w", err)
+	}
+	return total, nil
+}
+
 // Delete removes a reaction from the database by ID
 func (m *ReactionModel) Delete(ctx context.Context, reactionID int64) error {
 	stmt := `DELETE FROM Reactions WHERE ID = ?`
@@ -189,6 +451,25 @@ func (m *ReactionModel) Delete(ctx context.Context, reactionID int64) error {
 	return err
 }
 
+// deleteReactionsByAuthorStmt is shared with UserModel.Delete, which runs it in the
+// same transaction as the user delete rather than relying on the foreign key cascade
+const deleteReactionsByAuthorStmt = `DELETE FROM Reactions WHERE AuthorID = ?`
+
+// DeleteAllByAuthor removes every reaction authorID has made and returns how many
+// were removed. Like and dislike counts are derived from Reactions, so the affected
+// posts and comments lose the user's contribution immediately.
+func (m *ReactionModel) DeleteAllByAuthor(ctx context.Context, authorID models.UUIDField) (int64, error) {
+	result, err := m.DB.ExecContext(ctx, deleteReactionsByAuthorStmt, authorID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete reactions by %s: %w", authorID, err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted reactions: %w", err)
+	}
+	return removed, nil
+}
+
 func (m *ReactionModel) All(ctx context.Context) ([]models.Reaction, error) {
 	stmt := "SELECT ID, Liked, Disliked, AuthorID, Created, ReactedPostID, ReactedCommentID FROM Reactions ORDER BY ID DESC"
 	rows, err := m.DB.QueryContext(ctx, stmt)
@@ -205,10 +486,12 @@ func (m *ReactionModel) All(ctx context.Context) ([]models.Reaction, error) {
 	var Reactions []models.Reaction
 	for rows.Next() {
 		p := models.Reaction{}
-		err = rows.Scan(&p.ID, &p.Liked, &p.Disliked, &p.AuthorID, &p.Created, &p.ReactedPostID, &p.ReactedCommentID)
+		var postID, commentID sql.NullInt64
+		err = rows.Scan(&p.ID, &p.Liked, &p.Disliked, &p.AuthorID, &p.Created, &postID, &commentID)
 		if err != nil {
 			return nil, err
 		}
+		p.Target = scanTarget(postID, commentID)
 		Reactions = append(Reactions, p)
 	}
 
@@ -221,25 +504,29 @@ func (m *ReactionModel) All(ctx context.Context) ([]models.Reaction, error) {
 
 // ***** helper functions *****
 
-// Ensure only one parent ID is present when inserting a reaction
-func isValidParent(reactedPostID, reactedCommentID int64) bool {
-	// Ensure only one parent ID is non-zero
-	nonZeroCount := 0
-	if reactedPostID != 0 {
-		nonZeroCount++
+// targetColumn is the Reactions column holding the ID of target's type
+func targetColumn(target models.ReactionTarget) string {
+	if target.Type == models.ReactionTargetComment {
+		return "ReactedCommentID"
 	}
-	if reactedCommentID != 0 {
-		nonZeroCount++
+	return "ReactedPostID"
+}
+
+// targetWhere returns the WHERE condition matching reactions on target and its argument.
+// Callers must check target.Valid first.
+func targetWhere(target models.ReactionTarget) (string, int64) {
+	if target.Type == models.ReactionTargetComment {
+		return "ReactedPostID IS NULL AND ReactedCommentID = ?", target.ID
 	}
-	return nonZeroCount == 1
+	return "ReactedPostID = ? AND ReactedCommentID IS NULL", target.ID
 }
 
-// preparePostChannelDynamicWhere prepares the tail of the UPDATE statement
-func preparePostChannelDynamicWhere(post, comment int64) (string, int64) {
-	if post == 0 {
-		return "ReactedPostID IS NULL AND ReactedCommentID = ?", comment
+// scanTarget rebuilds a reaction's target from its nullable ID columns
+func scanTarget(postID, commentID sql.NullInt64) models.ReactionTarget {
+	if postID.Valid {
+		return models.PostTarget(postID.Int64)
 	}
-	return "ReactedPostID = ? AND ReactedCommentID IS NULL", post
+	return models.CommentTarget(commentID.Int64)
 }
 
 // Helper function to safely dereference an integer pointer
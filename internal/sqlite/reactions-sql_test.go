@@ -0,0 +1,455 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestReactionHistory(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	reader := insertTestUser(t, db, "reader")
+	postID := insertTestPost(t, db, author, "hello")
+
+	t.Run("like, unlike, like produces three events", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			if err := m.Upsert(ctx, true, false, reader, models.PostTarget(postID)); err != nil {
+				t.Fatalf("Upsert %d failed: %v", i+1, err)
+			}
+		}
+
+		events, err := m.ReactionHistory(ctx, reader)
+		if err != nil {
+			t.Fatalf("ReactionHistory failed: %v", err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("Expected 3 events, got %d", len(events))
+		}
+		wantActions := []string{models.ReactionActionAdd, models.ReactionActionRemove, models.ReactionActionAdd}
+		for i, e := range events {
+			if e.Kind != models.ReactionKindLike || e.Action != wantActions[i] {
+				t.Errorf("Event %d: expected like/%s, got %s/%s", i, wantActions[i], e.Kind, e.Action)
+			}
+			if e.TargetType != models.ReactionTargetPost || e.TargetID != postID || e.AuthorID != reader {
+				t.Errorf("Event %d has the wrong target or author: %+v", i, e)
+			}
+		}
+
+		likes, _, err := m.CountReactions(ctx, models.PostTarget(postID))
+		if err != nil {
+			t.Fatalf("CountReactions failed: %v", err)
+		}
+		if likes != 1 {
+			t.Errorf("Expected the current state to be one like, got %d", likes)
+		}
+	})
+
+	t.Run("history is per author", func(t *testing.T) {
+		events, err := m.ReactionHistory(ctx, author)
+		if err != nil {
+			t.Fatalf("ReactionHistory failed: %v", err)
+		}
+		if len(events) != 0 {
+			t.Errorf("Expected no events for an author who never reacted, got %d", len(events))
+		}
+	})
+}
+
+func TestGetLastReaction(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	first := insertTestUser(t, db, "first")
+	second := insertTestUser(t, db, "second")
+	channelID := insertTestChannel(t, db, author, "general")
+	postID := insertTestPost(t, db, author, "hello")
+	commentID := insertTestComment(t, db, author, postID, channelID)
+	otherComment := insertTestComment(t, db, author, postID, channelID)
+
+	mustUpsert := func(liked bool, user models.UUIDField, target models.ReactionTarget) {
+		t.Helper()
+		if err := m.Upsert(ctx, liked, !liked, user, target); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	mustUpsert(true, first, models.CommentTarget(commentID))
+	mustUpsert(false, second, models.CommentTarget(commentID))
+	mustUpsert(true, first, models.CommentTarget(otherComment))
+	// the newest reaction overall is on the post and must not leak into the comment branch
+	mustUpsert(true, second, models.PostTarget(postID))
+
+	t.Run("comment branch returns the comment's latest reaction", func(t *testing.T) {
+		r, err := m.GetLastReaction(ctx, models.CommentTarget(commentID))
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if r.AuthorID != second || !r.Disliked || r.Target != models.CommentTarget(commentID) {
+			t.Errorf("Expected second's dislike on comment %d, got %+v", commentID, r)
+		}
+	})
+
+	t.Run("post branch returns the post's latest reaction", func(t *testing.T) {
+		r, err := m.GetLastReaction(ctx, models.PostTarget(postID))
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if r.AuthorID != second || r.Target != models.PostTarget(postID) {
+			t.Errorf("Expected second's reaction on post %d, got %+v", postID, r)
+		}
+	})
+
+	t.Run("no reactions returns the zero Reaction", func(t *testing.T) {
+		unreacted := insertTestComment(t, db, author, postID, channelID)
+		r, err := m.GetLastReaction(ctx, models.CommentTarget(unreacted))
+		if err != nil || r.ID != 0 {
+			t.Errorf("Expected zero Reaction and nil, got %+v, %v", r, err)
+		}
+	})
+
+	t.Run("requires a valid target", func(t *testing.T) {
+		if _, err := m.GetLastReaction(ctx, models.ReactionTarget{}); err == nil {
+			t.Error("Expected an error for the zero target")
+		}
+		if _, err := m.GetLastReaction(ctx, models.PostTarget(0)); err == nil {
+			t.Error("Expected an error for a target without an ID")
+		}
+		if _, err := m.GetLastReaction(ctx, models.ReactionTarget{Type: "channel", ID: postID}); err == nil {
+			t.Error("Expected an error for an unknown target type")
+		}
+	})
+}
+
+func TestCountReactionsForComments(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	channelID := insertTestChannel(t, db, author, "general")
+	postID := insertTestPost(t, db, author, "hello")
+
+	var voters []models.UUIDField
+	for _, name := range []string{"v1", "v2", "v3", "v4"} {
+		voters = append(voters, insertTestUser(t, db, name))
+	}
+	var commentIDs []int64
+	for i := 0; i < 5; i++ {
+		commentIDs = append(commentIDs, insertTestComment(t, db, author, postID, channelID))
+	}
+
+	// comment i gets i reactions, alternating like and dislike; the last comment has none
+	for i, commentID := range commentIDs[:4] {
+		for v := 0; v <= i; v++ {
+			liked := v%2 == 0
+			if err := m.Upsert(ctx, liked, !liked, voters[v], models.CommentTarget(commentID)); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+		}
+	}
+	// a post reaction must not be counted against any comment
+	if err := m.Upsert(ctx, true, false, voters[0], models.PostTarget(postID)); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	batch, err := m.CountReactionsForComments(ctx, commentIDs)
+	if err != nil {
+		t.Fatalf("CountReactionsForComments failed: %v", err)
+	}
+	if len(batch) != len(commentIDs) {
+		t.Fatalf("Expected %d entries, got %d", len(commentIDs), len(batch))
+	}
+	for _, id := range commentIDs {
+		likes, dislikes, err := m.CountReactions(ctx, models.CommentTarget(id))
+		if err != nil {
+			t.Fatalf("CountReactions failed: %v", err)
+		}
+		if got := batch[id]; got.Likes != likes || got.Dislikes != dislikes {
+			t.Errorf("Comment %d: batch %+v, per-comment %d/%d", id, got, likes, dislikes)
+		}
+	}
+
+	empty, err := m.CountReactionsForComments(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected an empty map for no IDs, got %v, %v", empty, err)
+	}
+}
+
+func TestGetLikedPostsByUser(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	fan := insertTestUser(t, db, "fan")
+	var postIDs []int64
+	for _, title := range []string{"one", "two", "three", "four"} {
+		postIDs = append(postIDs, insertTestPost(t, db, author, title))
+	}
+
+	// like every post, then unlike "two" and switch "four" to a dislike
+	for _, id := range postIDs {
+		if err := m.Upsert(ctx, true, false, fan, models.PostTarget(id)); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	if err := m.Upsert(ctx, true, false, fan, models.PostTarget(postIDs[1])); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if err := m.Upsert(ctx, false, true, fan, models.PostTarget(postIDs[3])); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	posts, err := m.GetLikedPostsByUser(ctx, fan, 10, 0)
+	if err != nil {
+		t.Fatalf("GetLikedPostsByUser failed: %v", err)
+	}
+	got := map[string]bool{}
+	for _, p := range posts {
+		got[p.Title] = true
+	}
+	if len(posts) != 2 || !got["one"] || !got["three"] {
+		t.Errorf("Expected only posts one and three, got %v", got)
+	}
+
+	page, err := m.GetLikedPostsByUser(ctx, fan, 1, 1)
+	if err != nil {
+		t.Fatalf("GetLikedPostsByUser failed: %v", err)
+	}
+	if len(page) != 1 {
+		t.Errorf("Expected a single post on the second page, got %d", len(page))
+	}
+
+	none, err := m.GetLikedPostsByUser(ctx, author, 10, 0)
+	if err != nil || len(none) != 0 {
+		t.Errorf("Expected no liked posts for author, got %v, %v", none, err)
+	}
+}
+
+func TestUpsertLandsOnTarget(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	fan := insertTestUser(t, db, "fan")
+	channelID := insertTestChannel(t, db, author, "general")
+	postID := insertTestPost(t, db, author, "hello")
+	commentID := insertTestComment(t, db, author, postID, channelID)
+	// a post and a comment sharing a numeric ID must still be kept apart
+	if postID != commentID {
+		t.Fatalf("Expected the first post and comment to share ID 1, got %d and %d", postID, commentID)
+	}
+
+	if err := m.Upsert(ctx, true, false, fan, models.PostTarget(postID)); err != nil {
+		t.Fatalf("Upsert post failed: %v", err)
+	}
+	if err := m.Upsert(ctx, false, true, fan, models.CommentTarget(commentID)); err != nil {
+		t.Fatalf("Upsert comment failed: %v", err)
+	}
+
+	all, err := m.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 reactions, got %d", len(all))
+	}
+	byTarget := map[models.ReactionTarget]models.Reaction{}
+	for _, r := range all {
+		byTarget[r.Target] = r
+	}
+	if r, ok := byTarget[models.PostTarget(postID)]; !ok || !r.Liked || r.Disliked {
+		t.Errorf("Expected a like on the post, got %+v", r)
+	}
+	if r, ok := byTarget[models.CommentTarget(commentID)]; !ok || r.Liked || !r.Disliked {
+		t.Errorf("Expected a dislike on the comment, got %+v", r)
+	}
+
+	// the raw columns agree: exactly one target column is set per row
+	var postRows, commentRows int
+	if err := db.QueryRow(`SELECT
+		SUM(ReactedPostID IS NOT NULL AND ReactedCommentID IS NULL),
+		SUM(ReactedCommentID IS NOT NULL AND ReactedPostID IS NULL)
+		FROM Reactions`).Scan(&postRows, &commentRows); err != nil {
+		t.Fatalf("Failed to inspect Reactions: %v", err)
+	}
+	if postRows != 1 || commentRows != 1 {
+		t.Errorf("Expected one post row and one comment row, got %d and %d", postRows, commentRows)
+	}
+
+	for _, target := range []models.ReactionTarget{models.PostTarget(postID), models.CommentTarget(commentID)} {
+		likes, dislikes, err := m.CountReactions(ctx, target)
+		if err != nil {
+			t.Fatalf("CountReactions failed: %v", err)
+		}
+		if likes+dislikes != 1 {
+			t.Errorf("%s %d: expected one reaction, got %d likes and %d dislikes", target.Type, target.ID, likes, dislikes)
+		}
+	}
+}
+
+func TestUpsertKeepsCreated(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	fan := insertTestUser(t, db, "fan")
+	target := models.PostTarget(insertTestPost(t, db, author, "hello"))
+
+	if err := m.Upsert(ctx, true, false, fan, target); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	const backdated = "2020-01-01 00:00:00"
+	var id int64
+	if err := db.QueryRow("SELECT ID FROM Reactions WHERE AuthorID = ?", fan).Scan(&id); err != nil {
+		t.Fatalf("Failed to read reaction: %v", err)
+	}
+	if _, err := db.Exec("UPDATE Reactions SET Created = ?, Updated = ? WHERE ID = ?", backdated, backdated, id); err != nil {
+		t.Fatalf("Failed to backdate reaction: %v", err)
+	}
+
+	// switch to a dislike, clear it, then like again
+	for _, liked := range []bool{false, false, true} {
+		if err := m.Upsert(ctx, liked, !liked, fan, target); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		var gotID int64
+		var created, updated string
+		if err := db.QueryRow("SELECT ID, Created, Updated FROM Reactions WHERE AuthorID = ?", fan).
+			Scan(&gotID, &created, &updated); err != nil {
+			t.Fatalf("Failed to read reaction: %v", err)
+		}
+		if gotID != id {
+			t.Errorf("Expected the row to keep ID %d, got %d", id, gotID)
+		}
+		if !strings.HasPrefix(created, "2020-01-01") {
+			t.Errorf("Expected Created to stay %s, got %s", backdated, created)
+		}
+		if strings.HasPrefix(updated, "2020-01-01") {
+			t.Errorf("Expected Updated to move on toggle, got %s", updated)
+		}
+	}
+}
+
+func TestDeleteAllByAuthor(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	leaver := insertTestUser(t, db, "leaver")
+	stayer := insertTestUser(t, db, "stayer")
+	channelID := insertTestChannel(t, db, author, "general")
+	postID := insertTestPost(t, db, author, "hello")
+	commentID := insertTestComment(t, db, author, postID, channelID)
+
+	react := func(user models.UUIDField, liked bool, target models.ReactionTarget) {
+		t.Helper()
+		if err := m.Upsert(ctx, liked, !liked, user, target); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	react(leaver, true, models.PostTarget(postID))
+	react(leaver, false, models.CommentTarget(commentID))
+	react(stayer, true, models.PostTarget(postID))
+
+	counts := func(target models.ReactionTarget) (int, int) {
+		t.Helper()
+		likes, dislikes, err := m.CountReactions(ctx, target)
+		if err != nil {
+			t.Fatalf("CountReactions failed: %v", err)
+		}
+		return likes, dislikes
+	}
+
+	t.Run("removes only the author's reactions", func(t *testing.T) {
+		removed, err := m.DeleteAllByAuthor(ctx, leaver)
+		if err != nil {
+			t.Fatalf("DeleteAllByAuthor failed: %v", err)
+		}
+		if removed != 2 {
+			t.Errorf("Expected 2 reactions removed, got %d", removed)
+		}
+		if likes, dislikes := counts(models.PostTarget(postID)); likes != 1 || dislikes != 0 {
+			t.Errorf("Post: expected 1 like left, got %d/%d", likes, dislikes)
+		}
+		if likes, dislikes := counts(models.CommentTarget(commentID)); likes != 0 || dislikes != 0 {
+			t.Errorf("Comment: expected no reactions left, got %d/%d", likes, dislikes)
+		}
+	})
+
+	t.Run("deleting the user removes their reactions without the cascade", func(t *testing.T) {
+		// the production pool only enables foreign keys on one connection
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			t.Fatalf("Failed to disable foreign keys: %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := db.Exec(`PRAGMA foreign_keys = ON`); err != nil {
+				t.Errorf("Failed to re-enable foreign keys: %v", err)
+			}
+		})
+		users := &UserModel{DB: db}
+		if err := users.Delete(ctx, &models.User{ID: stayer, Username: "stayer"}); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if likes, dislikes := counts(models.PostTarget(postID)); likes != 0 || dislikes != 0 {
+			t.Errorf("Post: expected the deleted user's like to be gone, got %d/%d", likes, dislikes)
+		}
+	})
+}
+
+func TestGetReactionsForPost(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &ReactionModel{DB: db}
+	ctx := context.Background()
+
+	author := insertTestUser(t, db, "author")
+	fan := insertTestUser(t, db, "fan")
+	critic := insertTestUser(t, db, "critic")
+	undecided := insertTestUser(t, db, "undecided")
+	postID := insertTestPost(t, db, author, "post")
+	otherPostID := insertTestPost(t, db, author, "other")
+
+	react := func(liked, disliked bool, userID models.UUIDField, target models.ReactionTarget) {
+		t.Helper()
+		if err := m.Upsert(ctx, liked, disliked, userID, target); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	react(true, false, fan, models.PostTarget(postID))
+	react(false, true, critic, models.PostTarget(postID))
+	// liking twice clears the reaction
+	react(true, false, undecided, models.PostTarget(postID))
+	react(true, false, undecided, models.PostTarget(postID))
+	react(true, false, critic, models.PostTarget(otherPostID))
+
+	details, err := m.GetReactionsForPost(ctx, postID)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	kinds := make(map[models.UUIDField]string)
+	for _, d := range details {
+		kinds[d.UserID] = d.Kind
+		if d.Username == "" || d.Avatar != d.Username+".png" {
+			t.Errorf("Expected the reactor's username and avatar, got %+v", d)
+		}
+	}
+	want := map[models.UUIDField]string{fan: models.ReactionKindLike, critic: models.ReactionKindDislike}
+	if len(details) != len(want) || kinds[fan] != want[fan] || kinds[critic] != want[critic] {
+		t.Errorf("Expected %v, got %v", want, kinds)
+	}
+
+	empty, err := m.GetReactionsForPost(ctx, postID+100)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected no reactions for a missing post, got %v, %v", empty, err)
+	}
+}
@@ -14,11 +14,10 @@ func TestReactionModelUpsert(t *testing.T) {
 		DB *sql.DB
 	}
 	type args struct {
-		liked            bool
-		disliked         bool
-		authorID         models.UUIDField
-		reactedPostID    int64
-		reactedCommentID int64
+		liked    bool
+		disliked bool
+		authorID models.UUIDField
+		target   models.ReactionTarget
 	}
 	tests := []struct {
 		name    string
@@ -33,25 +32,23 @@ func TestReactionModelUpsert(t *testing.T) {
 				DB: setupTestDB(t), // Your test DB or mock setup
 			},
 			args: args{
-				liked:            true,
-				disliked:         false,
-				authorID:         models.NewUUIDField(), // use your UUID generator
-				reactedPostID:    1,
-				reactedCommentID: 0,
+				liked:    true,
+				disliked: false,
+				authorID: models.NewUUIDField(), // use your UUID generator
+				target:   models.PostTarget(1),
 			},
 			wantErr: false,
 		},
 		{
-			name: "Invalid both post and comment",
+			name: "Invalid target without an ID",
 			fields: fields{
 				DB: setupTestDB(t),
 			},
 			args: args{
-				liked:            false,
-				disliked:         true,
-				authorID:         models.NewUUIDField(),
-				reactedPostID:    1,
-				reactedCommentID: 2,
+				liked:    false,
+				disliked: true,
+				authorID: models.NewUUIDField(),
+				target:   models.ReactionTarget{Type: models.ReactionTargetComment},
 			},
 			wantErr: true,
 		},
@@ -61,7 +58,7 @@ func TestReactionModelUpsert(t *testing.T) {
 			m := &ReactionModel{
 				DB: tt.fields.DB,
 			}
-			err := m.Upsert(ctx, tt.args.liked, tt.args.disliked, tt.args.authorID, tt.args.reactedPostID, tt.args.reactedCommentID)
+			err := m.Upsert(ctx, tt.args.liked, tt.args.disliked, tt.args.authorID, tt.args.target)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Upsert() error = %v, wantErr %v", err, tt.wantErr)
 			}
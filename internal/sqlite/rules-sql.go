@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/gary-norman/forum/internal/models"
@@ -120,8 +121,19 @@ func (m *RuleModel) InsertChannelRule(ctx context.Context, channelID, ruleID int
 	return nil
 }
 
-// EditRule edits the rule string in the Rules table
+// EditRule edits the rule string in the Rules table on behalf of a channel owner.
+// Predefined rules are shared by every channel, so they are refused with
+// ErrPredefinedRule; use AdminEditRule to change them.
 func (m *RuleModel) EditRule(ctx context.Context, id int64, rule string) error {
+	return m.editRule(ctx, id, rule, false)
+}
+
+// AdminEditRule edits the rule string in the Rules table, including predefined rules
+func (m *RuleModel) AdminEditRule(ctx context.Context, id int64, rule string) error {
+	return m.editRule(ctx, id, rule, true)
+}
+
+func (m *RuleModel) editRule(ctx context.Context, id int64, rule string, allowPredefined bool) error {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -139,6 +151,20 @@ func (m *RuleModel) EditRule(ctx context.Context, id int64, rule string) error {
 		}
 	}()
 
+	var predefined bool
+	err = tx.QueryRowContext(ctx, "SELECT Predefined FROM Rules WHERE ID = ?", id).Scan(&predefined)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("rule %d: %w", id, ErrNotFound)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up rule %d: %w", id, err)
+	}
+	if predefined && !allowPredefined {
+		err = fmt.Errorf("rule %d: %w", id, ErrPredefinedRule)
+		return err
+	}
+
 	query := "UPDATE Rules SET Rule = ? WHERE ID = ?"
 	_, err = tx.ExecContext(ctx, query, rule, id)
 	if err != nil {
@@ -154,7 +180,9 @@ func (m *RuleModel) EditRule(ctx context.Context, id int64, rule string) error {
 	return nil
 }
 
-// DeleteRule removes a rule/channel reference from the ChannelsRules table
+// DeleteRule removes a rule/channel reference from the ChannelsRules table. Only
+// the link is removed; the Rules row itself is left alone, so detaching a
+// predefined rule never affects the other channels that use it.
 func (m *RuleModel) DeleteRule(ctx context.Context, channelID, ruleID int64) error {
 	// Begin the transaction
 	tx, err := m.DB.BeginTx(ctx, nil)
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEditRulePredefined(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	ctx := context.Background()
+	m := RuleModel{DB: db}
+
+	res, err := db.Exec("INSERT INTO Rules (Rule, Predefined) VALUES ('be kind', 1)")
+	if err != nil {
+		t.Fatalf("insert predefined rule: %v", err)
+	}
+	predefinedID, _ := res.LastInsertId()
+	ownID, err := m.CreateRule(ctx, "no spoilers")
+	if err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	ruleText := func(id int64) string {
+		t.Helper()
+		var rule string
+		if err := db.QueryRow("SELECT Rule FROM Rules WHERE ID = ?", id).Scan(&rule); err != nil {
+			t.Fatalf("read rule %d: %v", id, err)
+		}
+		return rule
+	}
+
+	t.Run("owner cannot edit predefined", func(t *testing.T) {
+		err := m.EditRule(ctx, predefinedID, "be mean")
+		if !errors.Is(err, ErrPredefinedRule) || !errors.Is(err, ErrForbidden) {
+			t.Fatalf("want ErrPredefinedRule, got %v", err)
+		}
+		if got := ruleText(predefinedID); got != "be kind" {
+			t.Errorf("predefined rule changed to %q", got)
+		}
+	})
+
+	t.Run("owner can edit own rule", func(t *testing.T) {
+		if err := m.EditRule(ctx, ownID, "no spoilers, ever"); err != nil {
+			t.Fatalf("EditRule: %v", err)
+		}
+		if got := ruleText(ownID); got != "no spoilers, ever" {
+			t.Errorf("rule = %q", got)
+		}
+	})
+
+	t.Run("admin can edit predefined", func(t *testing.T) {
+		if err := m.AdminEditRule(ctx, predefinedID, "be kind to each other"); err != nil {
+			t.Fatalf("AdminEditRule: %v", err)
+		}
+		if got := ruleText(predefinedID); got != "be kind to each other" {
+			t.Errorf("rule = %q", got)
+		}
+	})
+
+	t.Run("missing rule", func(t *testing.T) {
+		if err := m.EditRule(ctx, 9999, "x"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("want ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("detaching predefined leaves other channels alone", func(t *testing.T) {
+		owner := insertTestUser(t, db, "ruler")
+		first := insertTestChannel(t, db, owner, "first")
+		second := insertTestChannel(t, db, owner, "second")
+		for _, ch := range []int64{first, second} {
+			if err := m.InsertChannelRule(ctx, ch, predefinedID); err != nil {
+				t.Fatalf("InsertChannelRule: %v", err)
+			}
+		}
+		if err := m.DeleteRule(ctx, first, predefinedID); err != nil {
+			t.Fatalf("DeleteRule: %v", err)
+		}
+		var links int
+		if err := db.QueryRow("SELECT COUNT(*) FROM ChannelsRules WHERE ChannelID = ? AND RuleID = ?", second, predefinedID).Scan(&links); err != nil {
+			t.Fatalf("count links: %v", err)
+		}
+		if links != 1 {
+			t.Errorf("second channel lost the predefined rule")
+		}
+	})
+}
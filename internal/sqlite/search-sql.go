@@ -0,0 +1,304 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// SearchModel finds users, posts and channels matching a text query, best match
+// first. When SQLite is built with FTS5 (go build -tags sqlite_fts5), EnsureIndex
+// sets up the SearchIndex table and matches are ranked by bm25. Otherwise, or until
+// EnsureIndex has run, the query is matched as a substring with LIKE.
+type SearchModel struct {
+	DB *sql.DB
+	// Presence fills in MembersOnline on matched channels, as on ChannelModel
+	Presence PresenceProvider
+
+	fts bool
+}
+
+// searchIndexSchema creates the SearchIndex full-text table and the triggers that
+// keep it in step with Users, Posts, Comments and Channels. RefID holds the row's
+// ID as text, and a user's ID in hex, since IDs are stored as UUID bytes.
+const searchIndexSchema = `
+CREATE VIRTUAL TABLE SearchIndex USING fts5(
+	Kind UNINDEXED, RefID UNINDEXED, Title, Body,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER SearchIndexUserInsert AFTER INSERT ON Users BEGIN
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('user', hex(new.ID), new.Username, COALESCE(new.Description, ''));
+END;
+CREATE TRIGGER SearchIndexUserUpdate AFTER UPDATE OF Username, Description ON Users BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'user' AND RefID = hex(old.ID);
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('user', hex(new.ID), new.Username, COALESCE(new.Description, ''));
+END;
+CREATE TRIGGER SearchIndexUserDelete AFTER DELETE ON Users BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'user' AND RefID = hex(old.ID);
+END;
+
+CREATE TRIGGER SearchIndexPostInsert AFTER INSERT ON Posts BEGIN
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('post', CAST(new.ID AS TEXT), new.Title, new.Content);
+END;
+CREATE TRIGGER SearchIndexPostUpdate AFTER UPDATE OF Title, Content ON Posts BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'post' AND RefID = CAST(old.ID AS TEXT);
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('post', CAST(new.ID AS TEXT), new.Title, new.Content);
+END;
+CREATE TRIGGER SearchIndexPostDelete AFTER DELETE ON Posts BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'post' AND RefID = CAST(old.ID AS TEXT);
+END;
+
+CREATE TRIGGER SearchIndexCommentInsert AFTER INSERT ON Comments BEGIN
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('comment', CAST(new.ID AS TEXT), '', new.Content);
+END;
+CREATE TRIGGER SearchIndexCommentUpdate AFTER UPDATE OF Content ON Comments BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'comment' AND RefID = CAST(old.ID AS TEXT);
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('comment', CAST(new.ID AS TEXT), '', new.Content);
+END;
+CREATE TRIGGER SearchIndexCommentDelete AFTER DELETE ON Comments BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'comment' AND RefID = CAST(old.ID AS TEXT);
+END;
+
+CREATE TRIGGER SearchIndexChannelInsert AFTER INSERT ON Channels BEGIN
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('channel', CAST(new.ID AS TEXT), new.Name, COALESCE(new.Description, ''));
+END;
+CREATE TRIGGER SearchIndexChannelUpdate AFTER UPDATE OF Name, Description ON Channels BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'channel' AND RefID = CAST(old.ID AS TEXT);
+	INSERT INTO SearchIndex (Kind, RefID, Title, Body) VALUES ('channel', CAST(new.ID AS TEXT), new.Name, COALESCE(new.Description, ''));
+END;
+CREATE TRIGGER SearchIndexChannelDelete AFTER DELETE ON Channels BEGIN
+	DELETE FROM SearchIndex WHERE Kind = 'channel' AND RefID = CAST(old.ID AS TEXT);
+END;
+
+INSERT INTO SearchIndex (Kind, RefID, Title, Body)
+	SELECT 'user', hex(ID), Username, COALESCE(Description, '') FROM Users;
+INSERT INTO SearchIndex (Kind, RefID, Title, Body)
+	SELECT 'post', CAST(ID AS TEXT), Title, Content FROM Posts;
+INSERT INTO SearchIndex (Kind, RefID, Title, Body)
+	SELECT 'comment', CAST(ID AS TEXT), '', Content FROM Comments;
+INSERT INTO SearchIndex (Kind, RefID, Title, Body)
+	SELECT 'channel', CAST(ID AS TEXT), Name, COALESCE(Description, '') FROM Channels;
+`
+
+// EnsureIndex switches the model to full-text search if this SQLite build has FTS5,
+// creating and filling SearchIndex the first time. It reports whether FTS5 is in use.
+func (m *SearchModel) EnsureIndex(ctx context.Context) (bool, error) {
+	var available bool
+	if err := m.DB.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&available); err != nil {
+		return false, fmt.Errorf("failed to check for FTS5: %w", err)
+	}
+	if !available {
+		return false, nil
+	}
+
+	var exists bool
+	if err := m.DB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'SearchIndex')").Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check for the search index: %w", err)
+	}
+	if !exists {
+		tx, err := m.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to begin transaction for EnsureIndex: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, searchIndexSchema); err != nil {
+			_ = tx.Rollback()
+			return false, fmt.Errorf("failed to create the search index: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit the search index: %w", err)
+		}
+	}
+
+	m.fts = true
+	return true, nil
+}
+
+// matchExpression turns query into an FTS5 expression in which every word must
+// match, each as a prefix. Punctuation is dropped so user input cannot form FTS5
+// syntax. It returns "" when query has no words.
+func matchExpression(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = `"` + word + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+// likePattern returns a LIKE pattern, escaped with \, matching query anywhere
+func likePattern(query string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.TrimSpace(query))
+	return "%" + escaped + "%"
+}
+
+// search runs ftsStmt with the match expression for query, or likeStmt with its
+// LIKE pattern when full-text search is off. Both take the pattern as ?1 and limit
+// as ?2. ok is false when query has nothing to search for. bm25 only works in the
+// query that does the MATCH, so ftsStmt ranks in a MATERIALIZED CTE that SQLite
+// cannot flatten into the outer join.
+func (m *SearchModel) search(ctx context.Context, query string, limit int, ftsStmt, likeStmt string) (rows *sql.Rows, ok bool, err error) {
+	stmt, arg := likeStmt, likePattern(query)
+	if m.fts {
+		stmt, arg = ftsStmt, matchExpression(query)
+	}
+	if arg == "" || arg == "%%" {
+		return nil, false, nil
+	}
+	rows, err = m.DB.QueryContext(ctx, stmt, arg, limit)
+	return rows, err == nil, err
+}
+
+// Users returns up to limit users whose username or description matches query. Only
+// the public profile is read, since results go to anyone who searches.
+func (m *SearchModel) Users(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	const columns = `u.ID, u.Username, u.Avatar, u.Banner, u.Description, u.Usertype,
+		u.Created, u.Updated, u.IsFlagged`
+	rows, ok, err := m.search(ctx, query, limit,
+		`WITH s AS MATERIALIZED (
+			SELECT RefID, bm25(SearchIndex) AS Rank FROM SearchIndex
+			WHERE SearchIndex MATCH ?1 AND Kind = 'user'
+		)
+		SELECT `+columns+` FROM Users u
+		INNER JOIN s ON s.RefID = hex(u.ID)
+		ORDER BY s.Rank LIMIT ?2`,
+		`SELECT `+columns+` FROM Users u
+		WHERE u.Username LIKE ?1 ESCAPE '\' OR u.Description LIKE ?1 ESCAPE '\'
+		ORDER BY u.Username LIKE ?1 ESCAPE '\' DESC, u.Username LIMIT ?2`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	users := make([]*models.User, 0)
+	if !ok {
+		return users, nil
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var user models.User
+		var avatar, banner, description sql.NullString
+		if err := rows.Scan(&user.ID, &user.Username, &avatar, &banner, &description, &user.Usertype,
+			&user.Created, &user.Updated, &user.IsFlagged); err != nil {
+			return nil, fmt.Errorf("failed to scan user search result: %w", err)
+		}
+		setUserProfile(&user, avatar, banner, description)
+		models.UpdateTimeSince(&user)
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user search results: %w", err)
+	}
+	return users, nil
+}
+
+// searchPostColumns are the Posts columns Posts scans, in order
+const searchPostColumns = `p.ID, p.Title, p.Content, p.Images, p.Created, p.Updated, p.IsCommentable,
+		p.Author, p.AuthorID, p.AuthorAvatar, p.IsFlagged`
+
+// Posts returns up to limit posts whose title or content matches query, or that
+// have a matching comment or reply. Deleted posts and comments are never matched.
+func (m *SearchModel) Posts(ctx context.Context, query string, limit int) ([]*models.Post, error) {
+	rows, ok, err := m.search(ctx, query, limit,
+		`WITH matches AS MATERIALIZED (
+			SELECT Kind, RefID, bm25(SearchIndex) AS Rank FROM SearchIndex
+			WHERE SearchIndex MATCH ?1 AND Kind IN ('post', 'comment')
+		),
+		hits AS (
+			SELECT CASE m.Kind
+				WHEN 'post' THEN CAST(m.RefID AS INTEGER)
+				ELSE (SELECT COALESCE(c.CommentedPostID, parent.CommentedPostID)
+					FROM Comments c LEFT JOIN Comments parent ON parent.ID = c.CommentedCommentID
+					WHERE c.ID = CAST(m.RefID AS INTEGER) AND c.DeletedAt IS NULL)
+				END AS PostID,
+				m.Rank
+			FROM matches m
+		)
+		SELECT `+searchPostColumns+` FROM Posts p
+		INNER JOIN (SELECT PostID, MIN(Rank) AS Rank FROM hits GROUP BY PostID) h ON h.PostID = p.ID
+		WHERE p.DeletedAt IS NULL
+		ORDER BY h.Rank, p.ID DESC LIMIT ?2`,
+		`SELECT `+searchPostColumns+` FROM Posts p
+		WHERE p.DeletedAt IS NULL AND (p.Title LIKE ?1 ESCAPE '\' OR p.Content LIKE ?1 ESCAPE '\'
+			OR EXISTS (
+				SELECT 1 FROM Comments c LEFT JOIN Comments parent ON parent.ID = c.CommentedCommentID
+				WHERE COALESCE(c.CommentedPostID, parent.CommentedPostID) = p.ID AND c.DeletedAt IS NULL
+					AND c.Content LIKE ?1 ESCAPE '\'
+			))
+		ORDER BY p.Title LIKE ?1 ESCAPE '\' DESC, p.Created DESC, p.ID DESC LIMIT ?2`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+	posts := make([]*models.Post, 0)
+	if !ok {
+		return posts, nil
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		p := models.Post{}
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.Images, &p.Created, &p.Updated, &p.IsCommentable,
+			&p.Author, &p.AuthorID, &p.AuthorAvatar, &p.IsFlagged); err != nil {
+			return nil, fmt.Errorf("failed to scan post search result: %w", err)
+		}
+		posts = append(posts, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post search results: %w", err)
+	}
+	return posts, nil
+}
+
+// Channels returns up to limit channels whose name or description matches query
+func (m *SearchModel) Channels(ctx context.Context, query string, limit int) ([]*models.Channel, error) {
+	rows, ok, err := m.search(ctx, query, limit,
+		`WITH s AS MATERIALIZED (
+			SELECT RefID, bm25(SearchIndex) AS Rank FROM SearchIndex
+			WHERE SearchIndex MATCH ?1 AND Kind = 'channel'
+		)`+channelSelect+`
+		INNER JOIN s ON s.RefID = CAST(c.ID AS TEXT)
+		GROUP BY c.ID
+		ORDER BY MIN(s.Rank) LIMIT ?2`,
+		channelSelect+`
+		WHERE c.Name LIKE ?1 ESCAPE '\' OR c.Description LIKE ?1 ESCAPE '\'
+		GROUP BY c.ID
+		ORDER BY c.Name LIKE ?1 ESCAPE '\' DESC, c.Name LIMIT ?2`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search channels: %w", err)
+	}
+	channels := make([]*models.Channel, 0)
+	if !ok {
+		return channels, nil
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows: %v", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		channel, err := parseChannelRows(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan channel search result: %w", err)
+		}
+		channels = append(channels, channel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channel search results: %w", err)
+	}
+	channelModel := &ChannelModel{DB: m.DB, Presence: m.Presence}
+	return channels, channelModel.setMembersOnline(ctx, channels...)
+}
@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestSearchModel(t *testing.T) {
+	for _, mode := range []string{"like", "fts5"} {
+		t.Run(mode, func(t *testing.T) {
+			db := setupMigratedTestDB(t)
+			m := &SearchModel{DB: db}
+			ctx := context.Background()
+			if mode == "fts5" {
+				// indexing before the rows exist checks the triggers as well as the backfill
+				fts, err := m.EnsureIndex(ctx)
+				if err != nil {
+					t.Fatalf("EnsureIndex failed: %v", err)
+				}
+				if !fts {
+					t.Skip("SQLite built without FTS5; run with -tags sqlite_fts5")
+				}
+			}
+
+			alice := insertTestUser(t, db, "alice")
+			bob := insertTestUser(t, db, "bob")
+			if _, err := db.Exec("UPDATE Users SET Description = 'sourdough baker' WHERE ID = ?", bob); err != nil {
+				t.Fatalf("Failed to set description: %v", err)
+			}
+			baking := insertTestChannel(t, db, alice, "baking")
+			hiking := insertTestChannel(t, db, alice, "hiking")
+			if _, err := db.Exec("UPDATE Channels SET Description = 'trails and sourdough sandwiches' WHERE ID = ?", hiking); err != nil {
+				t.Fatalf("Failed to set description: %v", err)
+			}
+			byTitle := insertTestPost(t, db, alice, "Sourdough starter")
+			byComment := insertTestPost(t, db, alice, "Weekend plans")
+			byReply := insertTestPost(t, db, bob, "Bread questions")
+			unrelated := insertTestPost(t, db, bob, "Mountain photos")
+			commentWith := func(postID int64, content string) int64 {
+				t.Helper()
+				id := insertTestComment(t, db, alice, postID, baking)
+				if _, err := db.Exec("UPDATE Comments SET Content = ? WHERE ID = ?", content, id); err != nil {
+					t.Fatalf("Failed to set comment content: %v", err)
+				}
+				return id
+			}
+			comment := commentWith(byComment, "bringing sourdough")
+			question := commentWith(byReply, "which flour?")
+			if _, err := db.Exec(`INSERT INTO Comments (Content, CommentedCommentID, IsCommentable, IsFlagged, IsReply,
+				Author, AuthorID, AuthorAvatar, ChannelName, ChannelID)
+				VALUES ('rye for sourdough', ?, 1, 0, 1, 'bob', ?, '', 'baking', ?)`, question, bob, baking); err != nil {
+				t.Fatalf("Failed to insert reply: %v", err)
+			}
+
+			postIDs := func(query string) []int64 {
+				t.Helper()
+				posts, err := m.Posts(ctx, query, 10)
+				if err != nil {
+					t.Fatalf("Posts(%q) failed: %v", query, err)
+				}
+				ids := make([]int64, 0, len(posts))
+				for _, p := range posts {
+					ids = append(ids, p.ID)
+				}
+				slices.Sort(ids)
+				return ids
+			}
+			userIDs := func(query string) []models.UUIDField {
+				t.Helper()
+				users, err := m.Users(ctx, query, 10)
+				if err != nil {
+					t.Fatalf("Users(%q) failed: %v", query, err)
+				}
+				ids := make([]models.UUIDField, 0, len(users))
+				for _, u := range users {
+					ids = append(ids, u.ID)
+				}
+				return ids
+			}
+			channelIDs := func(query string) []int64 {
+				t.Helper()
+				channels, err := m.Channels(ctx, query, 10)
+				if err != nil {
+					t.Fatalf("Channels(%q) failed: %v", query, err)
+				}
+				ids := make([]int64, 0, len(channels))
+				for _, c := range channels {
+					ids = append(ids, c.ID)
+				}
+				slices.Sort(ids)
+				return ids
+			}
+
+			if got, want := postIDs("sourdough"), []int64{byTitle, byComment, byReply}; !slices.Equal(got, want) {
+				t.Errorf("Expected posts %v (title, comment and reply matches), got %v", want, got)
+			}
+			if got := postIDs("mountain"); !slices.Equal(got, []int64{unrelated}) {
+				t.Errorf("Expected post %d, got %v", unrelated, got)
+			}
+			if got := userIDs("ALI"); !slices.Equal(got, []models.UUIDField{alice}) {
+				t.Errorf("Expected alice by username, got %v", got)
+			}
+			if got := userIDs("sourdough"); !slices.Equal(got, []models.UUIDField{bob}) {
+				t.Errorf("Expected bob by description, got %v", got)
+			}
+			if got := channelIDs("sourdough"); !slices.Equal(got, []int64{hiking}) {
+				t.Errorf("Expected hiking by description, got %v", got)
+			}
+			if got := channelIDs("bak"); !slices.Equal(got, []int64{baking}) {
+				t.Errorf("Expected baking by name, got %v", got)
+			}
+
+			// punctuation and wildcards are matched literally, or not at all
+			for _, query := range []string{"", "   ", "%", `"sourdough`, "sourdough OR", "*"} {
+				posts, err := m.Posts(ctx, query, 10)
+				if err != nil {
+					t.Errorf("Posts(%q) failed: %v", query, err)
+				}
+				if query == "%" && len(posts) != 0 {
+					t.Errorf("Expected %q to match nothing, got %d posts", query, len(posts))
+				}
+			}
+
+			// edits and deletes are picked up
+			if _, err := db.Exec("UPDATE Posts SET Title = 'Rye starter' WHERE ID = ?", byTitle); err != nil {
+				t.Fatalf("Failed to edit post: %v", err)
+			}
+			if _, err := db.Exec("DELETE FROM Comments WHERE ID = ?", comment); err != nil {
+				t.Fatalf("Failed to delete comment: %v", err)
+			}
+			if got := postIDs("sourdough"); !slices.Equal(got, []int64{byReply}) {
+				t.Errorf("Expected only post %d after edits, got %v", byReply, got)
+			}
+			if got := postIDs("rye"); !slices.Equal(got, []int64{byTitle, byReply}) {
+				t.Errorf("Expected posts %v for rye, got %v", []int64{byTitle, byReply}, got)
+			}
+
+			if mode == "like" {
+				// indexing existing rows backfills the index with them
+				if fts, err := m.EnsureIndex(ctx); err != nil || !fts {
+					return
+				}
+				if got := postIDs("rye"); !slices.Equal(got, []int64{byTitle, byReply}) {
+					t.Errorf("Expected posts %v for rye after backfill, got %v", []int64{byTitle, byReply}, got)
+				}
+				if got := userIDs("sourdough"); !slices.Equal(got, []models.UUIDField{bob}) {
+					t.Errorf("Expected bob after backfill, got %v", got)
+				}
+			}
+
+			// soft-deleted posts and comments are never matched
+			if _, err := db.Exec("UPDATE Posts SET DeletedAt = CURRENT_TIMESTAMP WHERE ID = ?", unrelated); err != nil {
+				t.Fatalf("Failed to delete post: %v", err)
+			}
+			if _, err := db.Exec("UPDATE Comments SET DeletedAt = CURRENT_TIMESTAMP WHERE CommentedCommentID = ?", question); err != nil {
+				t.Fatalf("Failed to delete reply: %v", err)
+			}
+			if got := postIDs("mountain"); len(got) != 0 {
+				t.Errorf("Expected a deleted post not to match, got %v", got)
+			}
+			if got := postIDs("rye"); !slices.Equal(got, []int64{byTitle}) {
+				t.Errorf("Expected only post %d once the reply is deleted, got %v", byTitle, got)
+			}
+		})
+	}
+}
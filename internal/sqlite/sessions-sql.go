@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// ErrSessionNotFound is returned when no session matches the token or ID
+var ErrSessionNotFound = fmt.Errorf("session %w", ErrNotFound)
+
+// SessionModel stores one row per logged-in device
+type SessionModel struct {
+	DB *sql.DB
+}
+
+const sessionColumns = "ID, Token, CSRFToken, UserID, Expires, IP, UserAgent, Created"
+
+func scanSession(scan func(dest ...any) error) (models.UserSession, error) {
+	var s models.UserSession
+	err := scan(&s.ID, &s.Token, &s.CSRFToken, &s.UserID, &s.Expires, &s.IP, &s.UserAgent, &s.Created)
+	return s, err
+}
+
+// Insert stores session and returns its ID. The user's expired sessions are removed
+// at the same time, so the table only grows with devices that are still logged in.
+func (m *SessionModel) Insert(ctx context.Context, session models.UserSession) (int64, error) {
+	if _, err := m.DB.ExecContext(ctx, "DELETE FROM Sessions WHERE UserID = ? AND Expires <= ?",
+		session.UserID, time.Now()); err != nil {
+		return 0, fmt.Errorf("failed to remove expired sessions for user %s: %w", session.UserID, err)
+	}
+	stmt := "INSERT INTO Sessions (Token, CSRFToken, UserID, Expires, IP, UserAgent) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := m.DB.ExecContext(ctx, stmt, session.Token, session.CSRFToken, session.UserID,
+		session.Expires, session.IP, session.UserAgent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert session for user %s: %w", session.UserID, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID for Sessions: %w", err)
+	}
+	return id, nil
+}
+
+// GetByToken returns the session token was issued for, expired or not
+func (m *SessionModel) GetByToken(ctx context.Context, token string) (models.UserSession, error) {
+	query := "SELECT " + sessionColumns + " FROM Sessions WHERE Token = ?"
+	session, err := scanSession(m.DB.QueryRowContext(ctx, query, token).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, ErrSessionNotFound
+	}
+	if err != nil {
+		return session, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// ForUser returns userID's unexpired sessions, newest first
+func (m *SessionModel) ForUser(ctx context.Context, userID models.UUIDField) ([]models.UserSession, error) {
+	query := "SELECT " + sessionColumns + " FROM Sessions WHERE UserID = ? AND Expires > ? ORDER BY Created DESC, ID DESC"
+	rows, err := m.DB.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions for user %s: %w", userID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			models.LogWarn("Failed to close rows in ForUser: %v", closeErr)
+		}
+	}()
+
+	sessions := []models.UserSession{}
+	for rows.Next() {
+		session, err := scanSession(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// SetFingerprint records the client the session with token was issued to
+func (m *SessionModel) SetFingerprint(ctx context.Context, token string, fp models.SessionFingerprint) error {
+	result, err := m.DB.ExecContext(ctx, "UPDATE Sessions SET IP = ?, UserAgent = ? WHERE Token = ?", fp.IP, fp.UserAgent, token)
+	if err != nil {
+		return fmt.Errorf("failed to set session fingerprint: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteByToken ends the session with token; ending one that does not exist is not an error
+func (m *SessionModel) DeleteByToken(ctx context.Context, token string) error {
+	if _, err := m.DB.ExecContext(ctx, "DELETE FROM Sessions WHERE Token = ?", token); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// Revoke ends session id, which must belong to userID
+func (m *SessionModel) Revoke(ctx context.Context, userID models.UUIDField, id int64) error {
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM Sessions WHERE ID = ? AND UserID = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("session %d of user %s: %w", id, userID, ErrSessionNotFound)
+	}
+	return nil
+}
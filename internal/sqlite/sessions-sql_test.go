@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestSessionModel(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &SessionModel{DB: db}
+	ctx := context.Background()
+	alice := insertTestUser(t, db, "alice")
+	bob := insertTestUser(t, db, "bob")
+
+	insert := func(userID models.UUIDField, token string, expiresIn time.Duration) int64 {
+		t.Helper()
+		id, err := m.Insert(ctx, models.UserSession{Token: token, CSRFToken: "csrf-" + token, UserID: userID, Expires: time.Now().Add(expiresIn)})
+		if err != nil {
+			t.Fatalf("Insert(%s) failed: %v", token, err)
+		}
+		return id
+	}
+	lapsed := insert(alice, "lapsed", -time.Minute)
+	laptop := insert(alice, "laptop", time.Hour)
+	phone := insert(alice, "phone", time.Hour)
+	insert(bob, "bobs", time.Hour)
+
+	if _, err := m.GetByToken(ctx, "lapsed"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a new login to remove the user's expired sessions, got %v", err)
+	}
+	session, err := m.GetByToken(ctx, "laptop")
+	if err != nil {
+		t.Fatalf("GetByToken failed: %v", err)
+	}
+	if session.ID != laptop || session.UserID != alice || session.CSRFToken != "csrf-laptop" {
+		t.Errorf("Unexpected session %+v", session)
+	}
+
+	if err := m.SetFingerprint(ctx, "phone", models.SessionFingerprint{IP: "203.0.113.7", UserAgent: "Mobile"}); err != nil {
+		t.Fatalf("SetFingerprint failed: %v", err)
+	}
+	sessions, err := m.ForUser(ctx, alice)
+	if err != nil {
+		t.Fatalf("ForUser failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != phone || sessions[1].ID != laptop {
+		t.Fatalf("Expected alice's two live sessions newest first, got %+v", sessions)
+	}
+	if fp := sessions[0].Fingerprint(); fp.IP != "203.0.113.7" || fp.UserAgent != "Mobile" {
+		t.Errorf("Expected the phone's fingerprint, got %+v", fp)
+	}
+
+	if err := m.Revoke(ctx, bob, laptop); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected bob to be unable to revoke alice's session, got %v", err)
+	}
+	if err := m.Revoke(ctx, alice, laptop); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := m.Revoke(ctx, alice, lapsed); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected revoking a removed session to fail, got %v", err)
+	}
+	if err := m.DeleteByToken(ctx, "phone"); err != nil {
+		t.Fatalf("DeleteByToken failed: %v", err)
+	}
+	if sessions, err := m.ForUser(ctx, alice); err != nil || len(sessions) != 0 {
+		t.Errorf("Expected no sessions left for alice, got %d (%v)", len(sessions), err)
+	}
+	if _, err := m.GetByToken(ctx, "bobs"); err != nil {
+		t.Errorf("Expected bob's session to be untouched, got %v", err)
+	}
+}
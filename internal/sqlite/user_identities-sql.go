@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+const insertIdentityStmt = "INSERT INTO UserIdentities (Provider, Subject, UserID, Email) VALUES (?, ?, ?, ?)"
+
+// GetUserByIdentity returns the user that subject, an account at provider, logs in as
+func (m *UserModel) GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var username string
+	stmt := `SELECT u.Username FROM UserIdentities i
+		JOIN Users u ON u.ID = i.UserID
+		WHERE i.Provider = ? AND i.Subject = ?`
+	if err := m.DB.QueryRowContext(ctx, stmt, provider, subject).Scan(&username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s identity %s: %w", provider, subject, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to look up %s identity %s: %w", provider, subject, err)
+	}
+	return m.GetUserByUsername(ctx, username, "GetUserByIdentity")
+}
+
+// LinkIdentity lets subject, an account at provider, log in as the user userID.
+// Returns ErrConflict when the identity is already linked, to this user or another.
+func (m *UserModel) LinkIdentity(ctx context.Context, provider, subject string, userID models.UUIDField, email string) error {
+	if _, err := m.DB.ExecContext(ctx, insertIdentityStmt, provider, subject, userID, strings.TrimSpace(email)); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s identity %s is already linked: %w", provider, subject, ErrConflict)
+		}
+		return fmt.Errorf("failed to link %s identity to user %s: %w", provider, userID, err)
+	}
+	models.LogInfo("Linked %s identity to user %s", provider, userID)
+	return nil
+}
+
+// InsertWithIdentity adds user, as Insert does, together with subject, an account at
+// provider, that logs in as them. Neither is stored unless both are.
+func (m *UserModel) InsertWithIdentity(ctx context.Context, user *models.User, provider, subject string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for InsertWithIdentity: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt := `INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, UserType, Created, IsFlagged,
+		SessionToken, CsrfToken, HashedPassword) VALUES (?, ?, ?, ?, ?, ?, ?, DateTime('now'), 0, ?, ?, ?)`
+	if _, err = tx.ExecContext(ctx, stmt, user.ID, user.Username, user.Email, user.Avatar, user.Banner, user.Description,
+		user.Usertype, user.SessionToken, user.CSRFToken, user.HashedPassword); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("user %s already exists: %w", user.Username, ErrConflict)
+		}
+		return fmt.Errorf("failed to insert user %s: %w", user.Username, err)
+	}
+	if _, err = tx.ExecContext(ctx, insertIdentityStmt, provider, subject, user.ID, user.Email); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s identity %s is already linked: %w", provider, subject, ErrConflict)
+		}
+		return fmt.Errorf("failed to link %s identity to user %s: %w", provider, user.Username, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for InsertWithIdentity: %w", err)
+	}
+	models.LogInfo("User created: %s (via %s)", user.Username, provider)
+	return nil
+}
@@ -30,6 +30,9 @@ func (m *UserModel) Insert(ctx context.Context, id models.UUIDField, username, e
 
 	_, err := m.DB.ExecContext(ctx, query, id, username, email, avatar, banner, description, userType, sessionToken, crsfToken, password)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("user %s already exists: %w", username, ErrConflict)
+		}
 		return fmt.Errorf("failed to insert user %s: %w", username, err)
 	}
 
@@ -38,10 +41,13 @@ func (m *UserModel) Insert(ctx context.Context, id models.UUIDField, username, e
 }
 
 func (m *UserModel) Edit(ctx context.Context, user *models.User) error {
-	query := "UPDATE Users SET Username = ?, EmailAddress = ?, HashedPassword = ?, SessionToken = ?, CsrfToken = ?, Avatar = ?, Banner = ?, Description = ? WHERE ID = ?"
+	query := "UPDATE Users SET Username = ?, EmailAddress = ?, HashedPassword = ?, Avatar = ?, Banner = ?, Description = ? WHERE ID = ?"
 
-	result, err := m.DB.ExecContext(ctx, query, user.Username, user.Email, user.HashedPassword, user.SessionToken, user.CSRFToken, user.Avatar, user.Banner, user.Description, user.ID)
+	result, err := m.DB.ExecContext(ctx, query, user.Username, user.Email, user.HashedPassword, user.Avatar, user.Banner, user.Description, user.ID)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("username or email already taken: %w", ErrConflict)
+		}
 		return fmt.Errorf("failed to update user %s: %w", user.Username, err)
 	}
 
@@ -54,14 +60,52 @@ func (m *UserModel) Edit(ctx context.Context, user *models.User) error {
 	return nil
 }
 
+// SetAvatar replaces the avatar file name of the user with the given ID
+func (m *UserModel) SetAvatar(ctx context.Context, id models.UUIDField, avatar string) error {
+	result, err := m.DB.ExecContext(ctx, "UPDATE Users SET Avatar = ? WHERE ID = ?", avatar, id)
+	if err != nil {
+		return fmt.Errorf("failed to set avatar of user %s: %w", id, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no user found for ID %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// Delete removes a user together with their reactions. The reactions are deleted
+// explicitly in the same transaction, since the foreign key cascade only fires on
+// connections that have foreign_keys enabled.
 func (m *UserModel) Delete(ctx context.Context, user *models.User) error {
-	query := "DELETE FROM Users WHERE ID = ?"
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for Delete: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			models.LogWarnWithContext(ctx, "Panic occurred, rolling back transaction: %v", p)
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, deleteReactionsByAuthorStmt, user.ID); err != nil {
+		return fmt.Errorf("failed to delete reactions of user %s: %w", user.Username, err)
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM UserIdentities WHERE UserID = ?", user.ID); err != nil {
+		return fmt.Errorf("failed to delete login identities of user %s: %w", user.Username, err)
+	}
 
-	result, err := m.DB.ExecContext(ctx, query, user.ID)
+	result, err := tx.ExecContext(ctx, "DELETE FROM Users WHERE ID = ?", user.ID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user %s: %w", user.Username, err)
 	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for Delete: %w", err)
+	}
+
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
 		models.LogWarn("User delete affected 0 rows: %s", user.Username)
@@ -103,7 +147,7 @@ func (m *UserModel) GetUserFromLogin(ctx context.Context, login, calledBy string
 			return user, nil
 		}
 	default:
-		return nil, fmt.Errorf("user: %v not found", login)
+		return nil, fmt.Errorf("user %v: %w", login, ErrNotFound)
 	}
 }
 
@@ -114,7 +158,7 @@ func (m *UserModel) QueryUserNameExists(ctx context.Context, username string) (s
 
 	}
 	var count int
-	queryErr := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users WHERE Username = ?", username).Scan(&count)
+	queryErr := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users WHERE Username = ? COLLATE NOCASE", strings.TrimSpace(username)).Scan(&count)
 	if queryErr != nil {
 		return "", false, fmt.Errorf("failed to query user by username: %w", queryErr)
 	}
@@ -142,36 +186,43 @@ func (m *UserModel) QueryUserEmailExists(ctx context.Context, email string) (str
 
 // TODO unify these functions to accept parameters
 
+// GetUserByUsername returns the user whose username matches, ignoring surrounding
+// whitespace and case
 func (m *UserModel) GetUserByUsername(ctx context.Context, username, calledBy string) (*models.User, error) {
 	username = strings.TrimSpace(username)
 	if m == nil || m.DB == nil {
 		return nil, fmt.Errorf("database not initialized in GetUserByUsername for %s", username)
 	}
 
-	query := "SELECT ID, Username, EmailAddress, Avatar, Banner, Description, Usertype, Created, Updated, IsFlagged, SessionToken, CSRFToken, HashedPassword FROM Users WHERE Username = ? LIMIT 1"
+	query := "SELECT ID, Username, EmailAddress, Avatar, Banner, Description, Usertype, Created, Updated, IsFlagged, SessionToken, CSRFToken, CookiesExpire, HashedPassword FROM Users WHERE Username = ? COLLATE NOCASE LIMIT 1"
 	var user models.User
+	var avatar, banner, description sql.NullString
+	var cookiesExpire sql.NullTime
 
 	err := m.DB.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
-		&user.Avatar,
-		&user.Banner,
-		&user.Description,
+		&avatar,
+		&banner,
+		&description,
 		&user.Usertype,
 		&user.Created,
 		&user.Updated,
 		&user.IsFlagged,
 		&user.SessionToken,
 		&user.CSRFToken,
+		&cookiesExpire,
 		&user.HashedPassword)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("user not found: %s: %w", username, err)
+			return nil, fmt.Errorf("user %s: %w", username, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user by username %s: %w", username, err)
 	}
+	setUserProfile(&user, avatar, banner, description)
+	user.CookiesExpire = cookiesExpire.Time
 
 	return &user, nil
 }
@@ -192,7 +243,7 @@ func (m *UserModel) GetUserByEmail(ctx context.Context, email, calledBy string)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("user not found by email: %s: %w", email, err)
+			return nil, fmt.Errorf("user with email %s: %w", email, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user by email %s: %w", email, err)
 	}
@@ -204,13 +255,14 @@ func (m *UserModel) GetUserByID(ctx context.Context, ID models.UUIDField) (model
 	stmt := "SELECT ID, Username, EmailAddress, Avatar, Banner, Description, Usertype, Created, Updated, IsFlagged, SessionToken, CSRFToken, HashedPassword FROM Users WHERE ID = ?"
 	row := m.DB.QueryRowContext(ctx, stmt, ID)
 	u := models.User{}
+	var avatar, banner, description sql.NullString
 	err := row.Scan(
 		&u.ID,
 		&u.Username,
 		&u.Email,
-		&u.Avatar,
-		&u.Banner,
-		&u.Description,
+		&avatar,
+		&banner,
+		&description,
 		&u.Usertype,
 		&u.Created,
 		&u.Updated,
@@ -219,8 +271,12 @@ func (m *UserModel) GetUserByID(ctx context.Context, ID models.UUIDField) (model
 		&u.CSRFToken,
 		&u.HashedPassword)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u, fmt.Errorf("user %s: %w", ID, ErrNotFound)
+		}
 		return u, fmt.Errorf("failed to get user by ID %s: %w", ID, err)
 	}
+	setUserProfile(&u, avatar, banner, description)
 	models.UpdateTimeSince(&u)
 	return u, nil
 }
@@ -264,12 +320,14 @@ func (m *UserModel) GetSingleUserValue(ctx context.Context, ID models.UUIDField,
 		}
 	}()
 	var user models.User
+	var avatar, banner, description sql.NullString
 	if rows.Next() {
 		if scanErr := rows.Scan(
-			&user.ID, &user.Username, &user.Email, &user.Avatar, &user.Banner, &user.Description, &user.Usertype,
+			&user.ID, &user.Username, &user.Email, &avatar, &banner, &description, &user.Usertype,
 			&user.Created, &user.IsFlagged, &user.SessionToken, &user.CSRFToken, &user.HashedPassword); scanErr != nil {
 			return "", scanErr
 		}
+		setUserProfile(&user, avatar, banner, description)
 	} else {
 		return "", fmt.Errorf("no user found")
 	}
@@ -327,14 +385,15 @@ func (m *UserModel) All(ctx context.Context) ([]*models.User, error) {
 
 func parseUserRows(rows *sql.Rows) (*models.User, error) {
 	var user models.User
+	var avatar, banner, description sql.NullString
 
 	if err := rows.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
-		&user.Avatar,
-		&user.Banner,
-		&user.Description,
+		&avatar,
+		&banner,
+		&description,
 		&user.Usertype,
 		&user.Created,
 		&user.Updated,
@@ -345,20 +404,22 @@ func parseUserRows(rows *sql.Rows) (*models.User, error) {
 	); err != nil {
 		return nil, fmt.Errorf("Error parsing UserRows: %w", err)
 	}
+	setUserProfile(&user, avatar, banner, description)
 	models.UpdateTimeSince(&user)
 	return &user, nil
 }
 
 func parseUserRow(row *sql.Row) (*models.User, error) {
 	var user models.User
+	var avatar, banner, description sql.NullString
 
 	if err := row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
-		&user.Avatar,
-		&user.Banner,
-		&user.Description,
+		&avatar,
+		&banner,
+		&description,
 		&user.Usertype,
 		&user.Created,
 		&user.Updated,
@@ -369,6 +430,14 @@ func parseUserRow(row *sql.Row) (*models.User, error) {
 	); err != nil {
 		return nil, fmt.Errorf("Error parsing UserRow: %w", err)
 	}
+	setUserProfile(&user, avatar, banner, description)
 	models.UpdateTimeSince(&user)
 	return &user, nil
 }
+
+// setUserProfile copies the nullable profile columns onto user, leaving NULLs as ""
+func setUserProfile(user *models.User, avatar, banner, description sql.NullString) {
+	user.Avatar = avatar.String
+	user.Banner = banner.String
+	user.Description = description.String
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+func TestUsernamesIgnoreCase(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &UserModel{DB: db}
+	ctx := context.Background()
+	id := insertTestUser(t, db, "Alice")
+
+	for _, username := range []string{"Alice", "alice", " ALICE "} {
+		user, err := m.GetUserByUsername(ctx, username, "test")
+		if err != nil || user.ID != id || user.Username != "Alice" {
+			t.Errorf("GetUserByUsername(%q) = %+v, %v; want Alice", username, user, err)
+		}
+		if _, ok, err := m.QueryUserNameExists(ctx, username); !ok || err != nil {
+			t.Errorf("QueryUserNameExists(%q) = %v, %v; want true", username, ok, err)
+		}
+	}
+
+	err := m.Insert(ctx, models.NewUUIDField(), "aLiCe", "other@example.com", "", "", "", "user", "", "", "hash")
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a username differing only in case, got %v", err)
+	}
+}
+
+func TestUserQueriesAllowNullProfile(t *testing.T) {
+	db := setupMigratedTestDB(t)
+	m := &UserModel{DB: db}
+	ctx := context.Background()
+	id := models.NewUUIDField()
+	if _, err := db.Exec(`INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype,
+		IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, 'bare', 'bare@example.com', NULL, NULL, NULL, 'user', 0, '', '', 'hash')`, id); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	check := func(t *testing.T, user *models.User) {
+		t.Helper()
+		if user.ID != id || user.Avatar != "" || user.Banner != "" || user.Description != "" {
+			t.Errorf("Expected user %s with an empty profile, got %+v", id, user)
+		}
+	}
+
+	t.Run("GetUserByID", func(t *testing.T) {
+		user, err := m.GetUserByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetUserByID failed: %v", err)
+		}
+		check(t, &user)
+	})
+	t.Run("GetUserByUsername", func(t *testing.T) {
+		user, err := m.GetUserByUsername(ctx, "bare", "test")
+		if err != nil {
+			t.Fatalf("GetUserByUsername failed: %v", err)
+		}
+		check(t, user)
+	})
+	t.Run("All", func(t *testing.T) {
+		users, err := m.All(ctx)
+		if err != nil || len(users) != 1 {
+			t.Fatalf("Expected one user, got %v, %v", users, err)
+		}
+		check(t, users[0])
+	})
+	t.Run("GetSingleUserValue", func(t *testing.T) {
+		avatar, err := m.GetSingleUserValue(ctx, id, "ID", "avatar")
+		if err != nil || avatar != "" {
+			t.Errorf("Expected an empty avatar, got %q, %v", avatar, err)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+// Package testutil holds helpers shared by tests across packages.
+package testutil
+
+import (
+	"database/sql"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	forumdb "github.com/gary-norman/forum/internal/db"
+)
+
+// MigrationsDir is the absolute path of the repository's migrations directory,
+// so tests in any package can find it whatever their working directory
+func MigrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
+
+// NewTestDB returns a private in-memory database with foreign keys on and every
+// migration applied. It is closed when the test finishes.
+func NewTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := forumdb.OpenMemory(MigrationsDir())
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
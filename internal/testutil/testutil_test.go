@@ -0,0 +1,31 @@
+package testutil
+
+import "testing"
+
+func TestNewTestDB(t *testing.T) {
+	db := NewTestDB(t)
+
+	var foreignKeys bool
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil || !foreignKeys {
+		t.Errorf("Expected foreign keys on, got %v (%v)", foreignKeys, err)
+	}
+	// tables from the first migration and from later ones are both present
+	for _, table := range []string{"Users", "Chats", "ChannelVisits"} {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n); err != nil || n != 1 {
+			t.Errorf("Expected table %s to exist (%v)", table, err)
+		}
+	}
+}
+
+func TestNewTestDBIsPrivate(t *testing.T) {
+	first, second := NewTestDB(t), NewTestDB(t)
+	if _, err := first.Exec(`INSERT INTO Users (ID, Username, EmailAddress, Usertype, IsFlagged, HashedPassword)
+		VALUES (x'01', 'only-in-first', 'first@example.com', 'user', 0, 'hash')`); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	var count int
+	if err := second.QueryRow("SELECT COUNT(*) FROM Users").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected the second database to be empty, got %d users (%v)", count, err)
+	}
+}
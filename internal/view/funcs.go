@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"html/template"
 	"log"
-	"math/rand"
 	"reflect"
 	"strconv"
 	"strings"
@@ -100,11 +99,6 @@ func ConvertToInt(value any) (int, error) {
 	}
 }
 
-// RandomInt Function to get a random integer between 0 and the max number, for go templates
-func RandomInt(max int) int {
-	return rand.Intn(max)
-}
-
 // dict allows 2 parameters to be passed to the {{template}} in the tmpl
 func dict(values ...any) map[string]any {
 	m := make(map[string]any)
@@ -0,0 +1,60 @@
+package view
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// snippetEllipsis marks where Highlight cut content short
+const snippetEllipsis = "…"
+
+// Highlight returns the first case-insensitive match of query in content with up to
+// contextRunes runes either side, the match wrapped in <mark>. The text is
+// HTML-escaped, so the result is safe to insert as markup. It returns "" when
+// query is blank or does not occur in content.
+func Highlight(content, query string, contextRunes int) string {
+	needle := []rune(strings.TrimSpace(query))
+	if len(needle) == 0 {
+		return ""
+	}
+	text := []rune(content)
+	at := indexFold(text, needle)
+	if at < 0 {
+		return ""
+	}
+
+	start := max(at-contextRunes, 0)
+	end := min(at+len(needle)+contextRunes, len(text))
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(snippetEllipsis)
+	}
+	b.WriteString(html.EscapeString(string(text[start:at])))
+	b.WriteString("<mark>")
+	b.WriteString(html.EscapeString(string(text[at : at+len(needle)])))
+	b.WriteString("</mark>")
+	b.WriteString(html.EscapeString(string(text[at+len(needle) : end])))
+	if end < len(text) {
+		b.WriteString(snippetEllipsis)
+	}
+	return b.String()
+}
+
+// indexFold returns the rune index of the first case-insensitive occurrence of
+// needle in text, or -1
+func indexFold(text, needle []rune) int {
+	for i := 0; i+len(needle) <= len(text); i++ {
+		matched := true
+		for j, r := range needle {
+			if unicode.ToLower(text[i+j]) != unicode.ToLower(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
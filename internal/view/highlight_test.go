@@ -0,0 +1,30 @@
+package view
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		query   string
+		context int
+		want    string
+	}{
+		{"match at start", "Golang tips and tricks", "golang", 5, "<mark>Golang</mark> tips…"},
+		{"match in middle", "Some notes about SQLite indexes for posts", "sqlite", 6, "…about <mark>SQLite</mark> index…"},
+		{"match at end", "Everything you need to know about Go", "go", 4, "…out <mark>Go</mark>"},
+		{"whole content fits", "short text", "text", 20, "short <mark>text</mark>"},
+		{"no match", "Nothing to see here", "missing", 5, ""},
+		{"blank query", "Nothing to see here", "  ", 5, ""},
+		{"multibyte runes", "Café crème brûlée", "crème", 2, "…é <mark>crème</mark> b…"},
+		{"first match only", "go go go", "GO", 1, "<mark>go</mark> …"},
+		{"escapes html", `<b>bold</b> & "quoted" <script>`, "quoted", 4, `… &amp; &#34;<mark>quoted</mark>&#34; &lt;s…`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.content, tt.query, tt.context); got != tt.want {
+				t.Errorf("Highlight(%q, %q, %d) = %q, want %q", tt.content, tt.query, tt.context, got, tt.want)
+			}
+		})
+	}
+}
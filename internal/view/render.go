@@ -5,6 +5,8 @@ import (
 	"context"
 	"html/template"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gary-norman/forum/internal/app"
 	"github.com/gary-norman/forum/internal/models"
@@ -17,10 +19,29 @@ type TempHelper struct {
 
 var Template *template.Template
 
+// PrivateImagePrefix is the route serving images that require a signed URL
+const PrivateImagePrefix = "/private-images/"
+
+// signedImageTTL is how long a signed image link stays valid after rendering
+const signedImageTTL = 15 * time.Minute
+
 // reactionStatusWrapper wraps GetReactionStatus for template use
 // Templates don't have access to request context, so we use background context
 func (t *TempHelper) reactionStatusWrapper(authorID models.UUIDField, reactedPostID, reactedCommentID int64) (sqlite.ReactionStatus, error) {
-	return t.App.Reactions.GetReactionStatus(context.Background(), authorID, reactedPostID, reactedCommentID)
+	// an invalid pair leaves the zero target, which GetReactionStatus rejects
+	target, _ := models.ReactionTargetFromIDs(reactedPostID, reactedCommentID)
+	return t.App.Reactions.GetReactionStatus(context.Background(), authorID, target)
+}
+
+// SignedImageURL turns an image file in a db/userdata/images directory (e.g. "post-images", "abc.jpg")
+// into a time-limited signed URL under PrivateImagePrefix
+func SignedImageURL(a *app.App, dir, name string) string {
+	return a.URLSigner.Sign(PrivateImagePrefix+strings.Trim(dir, "/")+"/"+strings.TrimPrefix(name, "/"), signedImageTTL)
+}
+
+// signedImageWrapper wraps SignedImageURL for template use
+func (t *TempHelper) signedImageWrapper(dir, name string) string {
+	return SignedImageURL(t.App, dir, name)
 }
 
 // Init Function to initialise the custom template functions
@@ -39,9 +60,9 @@ func (t *TempHelper) Init() {
 		"not":            not,
 		"or":             or,
 		"printType":      printType,
-		"random":         RandomInt,
 		"reactionStatus": t.reactionStatusWrapper,
 		"same":           checkSameName,
+		"signedImage":    t.signedImageWrapper,
 		"startsWith":     startsWith,
 	}).ParseFiles(allFiles...))
 }
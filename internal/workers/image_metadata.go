@@ -0,0 +1,169 @@
+package workers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// ErrMetadataUnsupported is returned by StripMetadata for formats it does not re-encode
+var ErrMetadataUnsupported = errors.New("metadata stripping only supports jpeg and png")
+
+// jpegQuality is used when re-encoding uploaded JPEGs
+const jpegQuality = 90
+
+// exifOrientationTag is the IFD0 tag holding the EXIF orientation (1-8)
+const exifOrientationTag = 0x0112
+
+// StripMetadata decodes a JPEG or PNG and re-encodes only its pixels to dst, dropping
+// EXIF (including GPS), XMP and text chunks. JPEG orientation is applied first so the
+// saved image still displays the right way up. Returns the detected format.
+func StripMetadata(data []byte, dst io.Writer) (string, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if format != "jpeg" && format != "png" {
+		return format, ErrMetadataUnsupported
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return format, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	return format, encodeImage(dst, img, format)
+}
+
+// encodeImage writes img to dst as a JPEG or PNG
+func encodeImage(dst io.Writer, img image.Image, format string) error {
+	switch format {
+	case "jpeg":
+		if err := jpeg.Encode(dst, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+	case "png":
+		if err := png.Encode(dst, img); err != nil {
+			return fmt.Errorf("failed to encode png: %w", err)
+		}
+	default:
+		return ErrMetadataUnsupported
+	}
+	return nil
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 1 if it has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		// start of scan: no metadata segments follow
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos = end
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the IFD0 of a TIFF-structured EXIF block
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == exifOrientationTag {
+			o := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if o < 1 || o > 8 {
+				return 1
+			}
+			return o
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates/flips img so that EXIF orientation o becomes 1
+func applyOrientation(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// orientations 5-8 swap width and height
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+
+	src := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	out := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch o {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90 counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			out.SetNRGBA(x, y, src.NRGBAAt(sx, sy))
+		}
+	}
+	return out
+}
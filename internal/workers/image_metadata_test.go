@@ -0,0 +1,141 @@
+package workers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exifSegment builds an APP1 EXIF segment with an orientation tag and a GPS IFD
+// holding a latitude reference, enough for a reader to find location data
+func exifSegment(orientation uint16) []byte {
+	var tiff bytes.Buffer
+	le := binary.LittleEndian
+	tiff.WriteString("II")
+	binary.Write(&tiff, le, uint16(42))
+	binary.Write(&tiff, le, uint32(8)) // IFD0 offset
+
+	// IFD0: orientation + GPS IFD pointer
+	binary.Write(&tiff, le, uint16(2))
+	binary.Write(&tiff, le, uint16(exifOrientationTag))
+	binary.Write(&tiff, le, uint16(3)) // SHORT
+	binary.Write(&tiff, le, uint32(1))
+	binary.Write(&tiff, le, orientation)
+	binary.Write(&tiff, le, uint16(0))
+	gpsOffset := uint32(10 + 2*12 + 4)
+	binary.Write(&tiff, le, uint16(0x8825)) // GPSInfo
+	binary.Write(&tiff, le, uint16(4))      // LONG
+	binary.Write(&tiff, le, uint32(1))
+	binary.Write(&tiff, le, gpsOffset)
+	binary.Write(&tiff, le, uint32(0)) // no next IFD
+
+	// GPS IFD: GPSLatitudeRef = "N"
+	binary.Write(&tiff, le, uint16(1))
+	binary.Write(&tiff, le, uint16(1)) // GPSLatitudeRef
+	binary.Write(&tiff, le, uint16(2)) // ASCII
+	binary.Write(&tiff, le, uint32(2))
+	tiff.WriteString("N\x00\x00\x00")
+	binary.Write(&tiff, le, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// jpegWithEXIF encodes a w x h JPEG whose top-left pixel is red and injects an EXIF segment
+func jpegWithEXIF(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("Failed to encode test JPEG: %v", err)
+	}
+	raw := buf.Bytes()
+	// insert right after SOI
+	out := append([]byte{}, raw[:2]...)
+	out = append(out, exifSegment(orientation)...)
+	return append(out, raw[2:]...)
+}
+
+func TestStripMetadata_RemovesEXIFGPS(t *testing.T) {
+	src := jpegWithEXIF(t, 40, 20, 1)
+	if jpegOrientation(src) != 1 || !bytes.Contains(src, []byte("Exif")) {
+		t.Fatal("Test image should carry EXIF")
+	}
+
+	var out bytes.Buffer
+	format, err := StripMetadata(src, &out)
+	if err != nil {
+		t.Fatalf("StripMetadata failed: %v", err)
+	}
+	if format != "jpeg" {
+		t.Errorf("Expected jpeg, got %s", format)
+	}
+	if bytes.Contains(out.Bytes(), []byte("Exif")) {
+		t.Error("Stripped image still contains an EXIF segment")
+	}
+	if _, _, err := image.Decode(bytes.NewReader(out.Bytes())); err != nil {
+		t.Errorf("Stripped image does not decode: %v", err)
+	}
+}
+
+func TestStripMetadata_AppliesOrientation(t *testing.T) {
+	// orientation 6: the camera was rotated, display needs a 90 degree clockwise turn
+	src := jpegWithEXIF(t, 40, 20, 6)
+	if got := jpegOrientation(src); got != 6 {
+		t.Fatalf("Expected orientation 6, got %d", got)
+	}
+
+	var out bytes.Buffer
+	if _, err := StripMetadata(src, &out); err != nil {
+		t.Fatalf("StripMetadata failed: %v", err)
+	}
+	img, _, err := image.Decode(&out)
+	if err != nil {
+		t.Fatalf("Stripped image does not decode: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Fatalf("Expected 20x40 after rotation, got %dx%d", b.Dx(), b.Dy())
+	}
+	// the red top-left corner ends up top-right after a clockwise turn
+	r, g, _, _ := img.At(17, 2).RGBA()
+	if r < 0xC000 || g > 0x4000 {
+		t.Errorf("Expected red in the top-right corner, got r=%x g=%x", r, g)
+	}
+}
+
+func TestProcessAndSaveImage_StripsEXIF(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "upload.jpg")
+	if err := os.WriteFile(srcPath, jpegWithEXIF(t, 16, 16, 1), 0644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+
+	saved, err := processAndSaveImage(srcPath, dir, "exif-job")
+	if err != nil {
+		t.Fatalf("processAndSaveImage failed: %v", err)
+	}
+	data, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatalf("Failed to read saved image: %v", err)
+	}
+	if bytes.Contains(data, []byte("Exif")) {
+		t.Error("Saved image still contains EXIF metadata")
+	}
+}
@@ -0,0 +1,104 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+// ImageDir is where uploads are saved, relative to the working directory
+const ImageDir = "db/userdata/images"
+
+// imageSubdirs are the upload directories under ImageDir the pruner cleans
+var imageSubdirs = []string{"user-images", "channel-images", "post-images"}
+
+// ImagePruner deletes uploaded images that no database row refers to any more,
+// such as the avatar of a deleted user or the file of a post that failed to save
+type ImagePruner struct {
+	images *sqlite.ImageModel
+	dir    string
+	// files modified more recently than this are kept, so an upload whose row is
+	// still being written is never mistaken for an orphan
+	grace    time.Duration
+	interval time.Duration
+}
+
+// NewImagePruner creates a pruner for the upload directories under dir that runs
+// every interval and keeps anything younger than grace
+func NewImagePruner(images *sqlite.ImageModel, dir string, grace, interval time.Duration) *ImagePruner {
+	return &ImagePruner{images: images, dir: dir, grace: grace, interval: interval}
+}
+
+// PruneOrphanImages removes every unreferenced file older than the grace period and
+// returns the paths it removed. A file that cannot be removed does not stop the
+// rest; the failures are returned together.
+func (p *ImagePruner) PruneOrphanImages(ctx context.Context) ([]string, error) {
+	referenced, err := p.images.ReferencedNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-p.grace)
+	var removed []string
+	var errs []error
+	for _, sub := range imageSubdirs {
+		dir := filepath.Join(p.dir, sub)
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list %s: %w", dir, err))
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || referenced[name] {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if info.ModTime().After(cutoff) {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", path, err))
+				continue
+			}
+			removed = append(removed, path)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// Run prunes once at start and then every interval until ctx is cancelled. It is
+// meant to be registered with a Registry.
+func (p *ImagePruner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		removed, err := p.PruneOrphanImages(ctx)
+		if err != nil {
+			models.LogErrorWithContext(ctx, "Failed to prune orphaned images", err)
+		}
+		if len(removed) > 0 {
+			models.LogInfoWithContext(ctx, "Pruned %d orphaned images", len(removed))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package workers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	forumdb "github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/models"
+	"github.com/gary-norman/forum/internal/sqlite"
+)
+
+func TestPruneOrphanImages(t *testing.T) {
+	db, err := forumdb.OpenMemory("../../migrations")
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	userID := models.NewUUIDField()
+	if _, err := db.Exec(`INSERT INTO Users (ID, Username, EmailAddress, Avatar, Banner, Description, Usertype,
+		IsFlagged, SessionToken, CsrfToken, HashedPassword)
+		VALUES (?, 'owner', 'owner@example.com', 'avatar.png', '', '', 'user', 0, '', '', 'hash')`, userID); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO Posts (Title, Content, Images, IsCommentable, Author, AuthorID, AuthorAvatar, IsFlagged)
+		VALUES ('post', 'content', 'photo.jpg', 1, 'owner', ?, 'avatar.png', 0)`, userID); err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	write := func(sub, name string, modified time.Time) string {
+		t.Helper()
+		path := filepath.Join(dir, sub, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("image"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatalf("Failed to set file time: %v", err)
+		}
+		return path
+	}
+	avatar := write("user-images", "avatar.png", old)
+	photo := write("post-images", "photo.jpg", old)
+	orphan := write("post-images", "orphan.jpg", old)
+	fresh := write("channel-images", "uploading.png", time.Now())
+	hidden := write("user-images", ".gitkeep", old)
+
+	pruner := NewImagePruner(&sqlite.ImageModel{DB: db}, dir, 24*time.Hour, time.Hour)
+	removed, err := pruner.PruneOrphanImages(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if !slices.Equal(removed, []string{orphan}) {
+		t.Errorf("Expected only %s to be removed, got %v", orphan, removed)
+	}
+	for _, path := range []string{avatar, photo, fresh, hidden} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be deleted, got %v", orphan, err)
+	}
+}
@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// ResizeImage decodes a JPEG or PNG, scales it down so neither side is longer than
+// maxSide, and writes it to dst in the same format. Like StripMetadata, the output
+// carries no metadata and JPEG orientation is applied first. Images already small
+// enough are re-encoded at their own size. Returns the detected format.
+func ResizeImage(data []byte, dst io.Writer, maxSide int) (string, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if format != "jpeg" && format != "png" {
+		return format, ErrMetadataUnsupported
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return format, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	return format, encodeImage(dst, fitWithin(img, maxSide), format)
+}
+
+// fitWithin scales img down, keeping its aspect ratio, until neither side exceeds
+// maxSide. Each output pixel averages the block of source pixels it covers.
+func fitWithin(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return img
+	}
+	dw, dh := maxSide, max(1, h*maxSide/w)
+	if h > w {
+		dw, dh = max(1, w*maxSide/h), maxSide
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sx, sy)
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					bl += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+				}
+			}
+			i := out.PixOffset(x, y)
+			out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(bl/n), uint8(a/n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func encodedPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestResizeImage(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h         int
+		wantW, wantH int
+	}{
+		{"landscape", 800, 400, 256, 128},
+		{"portrait", 300, 900, 85, 256},
+		{"already small", 100, 50, 100, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			format, err := ResizeImage(encodedPNG(t, tt.w, tt.h), &out, 256)
+			if err != nil || format != "png" {
+				t.Fatalf("Expected a png and nil, got %q and %v", format, err)
+			}
+			img, err := png.Decode(&out)
+			if err != nil {
+				t.Fatalf("Output is not a png: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.wantW, tt.wantH, b.Dx(), b.Dy())
+			}
+			if r, g, b, _ := img.At(0, 0).RGBA(); r>>8 != 200 || g>>8 != 100 || b>>8 != 50 {
+				t.Errorf("Expected the colour to survive resizing, got %d,%d,%d", r>>8, g>>8, b>>8)
+			}
+		})
+	}
+
+	t.Run("rejects other formats", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black}), nil); err != nil {
+			t.Fatalf("Failed to encode gif: %v", err)
+		}
+		if _, err := ResizeImage(buf.Bytes(), &bytes.Buffer{}, 256); !errors.Is(err, ErrMetadataUnsupported) {
+			t.Errorf("Expected ErrMetadataUnsupported for a gif, got %v", err)
+		}
+		if _, err := ResizeImage([]byte("not an image"), &bytes.Buffer{}, 256); err == nil {
+			t.Error("Expected an error for data that is not an image")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package workers
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
@@ -214,15 +215,14 @@ func determineImageDirectory(job ImageJob) string {
 
 // processAndSaveImage resizes and saves the image
 func processAndSaveImage(sourcePath, destDir, jobID string) (string, error) {
-	// Open source image
-	file, err := os.Open(sourcePath)
+	// Read source image
+	data, err := os.ReadFile(sourcePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open source: %w", err)
 	}
-	defer file.Close()
 
-	// Decode image
-	_, format, err := image.Decode(file)
+	// Detect format
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
 	if err != nil {
 		return "", fmt.Errorf("failed to decode image: %w", err)
 	}
@@ -233,7 +233,6 @@ func processAndSaveImage(sourcePath, destDir, jobID string) (string, error) {
 		ext = ".jpg"
 	}
 
-	// Save original (or slightly optimized version)
 	destPath := filepath.Join(destDir, jobID+ext)
 	destFile, err := os.Create(destPath)
 	if err != nil {
@@ -253,11 +252,17 @@ func processAndSaveImage(sourcePath, destDir, jobID string) (string, error) {
 	//   2. Run: go get gopkg.in/gographics/imagick.v3/imagick
 	//   3. Use imagick.MagickWand for resizing/optimization
 	//
-	// For now, saving the original image unmodified:
-	file.Seek(0, 0) // Reset file pointer
-	_, err = destFile.ReadFrom(file)
-	if err != nil {
-		return "", fmt.Errorf("failed to copy image: %w", err)
+	// JPEGs and PNGs are re-encoded to drop EXIF/GPS metadata; GIFs carry no EXIF
+	// and are copied as-is so animations survive.
+	switch format {
+	case "jpeg", "png":
+		if _, err := StripMetadata(data, destFile); err != nil {
+			return "", fmt.Errorf("failed to strip image metadata: %w", err)
+		}
+	default:
+		if _, err := destFile.Write(data); err != nil {
+			return "", fmt.Errorf("failed to copy image: %w", err)
+		}
 	}
 
 	// TODO(human): Generate thumbnails
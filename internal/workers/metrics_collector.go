@@ -0,0 +1,85 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"time"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// Metric types recorded by MetricsCollector alongside models.MetricTypeMemory
+const (
+	MetricTypeGoroutines = "goroutines"
+	MetricTypeDBPool     = "db_pool"
+)
+
+// MetricRecorder is the part of LoggerPool that MetricsCollector needs
+type MetricRecorder interface {
+	LogMetric(metric models.SystemMetric) error
+}
+
+// MetricsCollector periodically records the process's memory use, goroutine count
+// and database connection pool stats in SystemMetrics
+type MetricsCollector struct {
+	db       *sql.DB
+	recorder MetricRecorder
+	interval time.Duration
+}
+
+// NewMetricsCollector creates a collector that samples db's pool every interval
+// and hands the metrics to recorder
+func NewMetricsCollector(db *sql.DB, recorder MetricRecorder, interval time.Duration) *MetricsCollector {
+	return &MetricsCollector{db: db, recorder: recorder, interval: interval}
+}
+
+// Collect takes one sample of every metric, all stamped with the same time
+func (c *MetricsCollector) Collect() []models.SystemMetric {
+	now := time.Now()
+	metric := func(metricType, name string, value float64, unit string) models.SystemMetric {
+		return models.SystemMetric{
+			Timestamp:   now,
+			MetricType:  metricType,
+			MetricName:  name,
+			MetricValue: value,
+			Unit:        unit,
+		}
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	pool := c.db.Stats()
+	return []models.SystemMetric{
+		metric(models.MetricTypeMemory, "heap_alloc", float64(mem.HeapAlloc), "bytes"),
+		metric(models.MetricTypeMemory, "heap_inuse", float64(mem.HeapInuse), "bytes"),
+		metric(models.MetricTypeMemory, "sys", float64(mem.Sys), "bytes"),
+		metric(models.MetricTypeMemory, "gc_cycles", float64(mem.NumGC), "count"),
+		metric(MetricTypeGoroutines, "goroutines", float64(runtime.NumGoroutine()), "count"),
+		metric(MetricTypeDBPool, "open_connections", float64(pool.OpenConnections), "count"),
+		metric(MetricTypeDBPool, "in_use", float64(pool.InUse), "count"),
+		metric(MetricTypeDBPool, "idle", float64(pool.Idle), "count"),
+		metric(MetricTypeDBPool, "wait_count", float64(pool.WaitCount), "count"),
+		metric(MetricTypeDBPool, "wait_duration", float64(pool.WaitDuration.Milliseconds()), "ms"),
+	}
+}
+
+// Run records a sample at start and then every interval until ctx is cancelled.
+// It is meant to be registered with a Registry.
+func (c *MetricsCollector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		for _, metric := range c.Collect() {
+			if err := c.recorder.LogMetric(metric); err != nil {
+				models.LogWarnWithContext(ctx, "Failed to queue %s metric: %v", metric.MetricName, err)
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
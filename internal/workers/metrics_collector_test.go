@@ -0,0 +1,73 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	forumdb "github.com/gary-norman/forum/internal/db"
+	"github.com/gary-norman/forum/internal/models"
+)
+
+type recordingMetrics struct {
+	mu      sync.Mutex
+	metrics []models.SystemMetric
+}
+
+func (r *recordingMetrics) LogMetric(metric models.SystemMetric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, metric)
+	return nil
+}
+
+func (r *recordingMetrics) recorded() []models.SystemMetric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.SystemMetric(nil), r.metrics...)
+}
+
+func TestMetricsCollector(t *testing.T) {
+	db, err := forumdb.OpenMemory("../../migrations")
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	recorder := &recordingMetrics{}
+	collector := NewMetricsCollector(db, recorder, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- collector.Run(ctx) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(recorder.recorded()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+
+	got := make(map[string]models.SystemMetric)
+	for _, metric := range recorder.recorded() {
+		got[metric.MetricType+"/"+metric.MetricName] = metric
+	}
+	for _, key := range []string{
+		models.MetricTypeMemory + "/heap_alloc",
+		MetricTypeGoroutines + "/goroutines",
+		MetricTypeDBPool + "/open_connections",
+		MetricTypeDBPool + "/wait_duration",
+	} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("Expected a %s metric in the first sample, got %v", key, got)
+		}
+	}
+	if m := got[MetricTypeGoroutines+"/goroutines"]; m.MetricValue < 1 || m.Unit != "count" {
+		t.Errorf("Expected a positive goroutine count, got %+v", m)
+	}
+	if m := got[models.MetricTypeMemory+"/heap_alloc"]; m.MetricValue <= 0 || m.Unit != "bytes" {
+		t.Errorf("Expected heap_alloc in bytes, got %+v", m)
+	}
+}
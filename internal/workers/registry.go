@@ -0,0 +1,124 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/gary-norman/forum/internal/models"
+)
+
+// RunFunc is the body of a background job. It should return once ctx is cancelled.
+type RunFunc func(ctx context.Context) error
+
+// ErrRegistryStarted is returned by Register once the registry is running
+var ErrRegistryStarted = errors.New("worker registry already started")
+
+type registeredJob struct {
+	name string
+	run  RunFunc
+}
+
+// Registry owns the lifecycle of long-running background jobs: they register by
+// name, are started together with Start, and are cancelled and awaited by Shutdown
+type Registry struct {
+	mu      sync.Mutex
+	jobs    []registeredJob
+	names   map[string]bool
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	errs    []error
+}
+
+// NewRegistry creates an empty worker registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Register adds a job to be run by Start. Names must be unique.
+func (r *Registry) Register(name string, run RunFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		return fmt.Errorf("cannot register %s: %w", name, ErrRegistryStarted)
+	}
+	if r.names[name] {
+		return fmt.Errorf("worker %s is already registered", name)
+	}
+	r.names[name] = true
+	r.jobs = append(r.jobs, registeredJob{name: name, run: run})
+	return nil
+}
+
+// Start launches every registered job in its own goroutine. Jobs are cancelled
+// when ctx is cancelled or Shutdown is called, whichever comes first.
+func (r *Registry) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		return
+	}
+	r.started = true
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.runJob(ctx, job)
+	}
+	models.LogInfo("[Registry] Started %d background workers", len(r.jobs))
+}
+
+// runJob runs a single job, turning a panic into an error so one faulty job
+// cannot take the process down with it
+func (r *Registry) runJob(ctx context.Context, job registeredJob) {
+	defer r.wg.Done()
+	defer func() {
+		if p := recover(); p != nil {
+			r.recordError(fmt.Errorf("worker %s panicked: %v\n%s", job.name, p, debug.Stack()))
+		}
+	}()
+
+	if err := job.run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		r.recordError(fmt.Errorf("worker %s failed: %w", job.name, err))
+	}
+}
+
+func (r *Registry) recordError(err error) {
+	models.LogError("Background worker stopped with an error", err)
+	r.mu.Lock()
+	r.errs = append(r.errs, err)
+	r.mu.Unlock()
+}
+
+// Shutdown cancels every job and waits for them to return. It returns ctx.Err()
+// if the jobs are still running when ctx expires, otherwise the joined errors
+// (including recovered panics) of any jobs that failed.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("workers did not stop in time: %w", ctx.Err())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(r.errs...)
+}
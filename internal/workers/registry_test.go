@@ -0,0 +1,125 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRegistryStartsAndStopsAllWorkers checks every job runs and exits on context cancel
+func TestRegistryStartsAndStopsAllWorkers(t *testing.T) {
+	r := NewRegistry()
+	var started, stopped atomic.Int32
+	ready := make(chan struct{}, 3)
+
+	for _, name := range []string{"log-cleanup", "metrics", "outbox"} {
+		err := r.Register(name, func(ctx context.Context) error {
+			started.Add(1)
+			ready <- struct{}{}
+			<-ctx.Done()
+			stopped.Add(1)
+			return ctx.Err()
+		})
+		if err != nil {
+			t.Fatalf("Register %s failed: %v", name, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Start(ctx)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ready:
+		case <-time.After(time.Second):
+			t.Fatalf("Only %d of 3 workers started", started.Load())
+		}
+	}
+
+	cancel()
+	shutdownCtx, release := context.WithTimeout(context.Background(), time.Second)
+	defer release()
+	if err := r.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Expected clean shutdown, got %v", err)
+	}
+	if stopped.Load() != 3 {
+		t.Errorf("Expected 3 stopped workers, got %d", stopped.Load())
+	}
+}
+
+// TestRegistryShutdownCancelsWorkers checks Shutdown alone stops running jobs
+func TestRegistryShutdownCancelsWorkers(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("ticker", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	r.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+// TestRegistrySurfacesPanics checks a panicking job is reported rather than crashing
+func TestRegistrySurfacesPanics(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("faulty", func(ctx context.Context) error {
+		panic("boom")
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	r.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := r.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), "worker faulty panicked: boom") {
+		t.Errorf("Expected the panic to be surfaced, got %v", err)
+	}
+}
+
+// TestRegistryShutdownTimeout checks a job that ignores cancellation does not block forever
+func TestRegistryShutdownTimeout(t *testing.T) {
+	r := NewRegistry()
+	release := make(chan struct{})
+	defer close(release)
+	if err := r.Register("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	r.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestRegistryRegisterValidation checks duplicate names and late registration are rejected
+func TestRegistryRegisterValidation(t *testing.T) {
+	r := NewRegistry()
+	noop := func(ctx context.Context) error { <-ctx.Done(); return nil }
+
+	if err := r.Register("job", noop); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register("job", noop); err == nil {
+		t.Error("Expected an error for a duplicate name")
+	}
+
+	r.Start(context.Background())
+	defer r.Shutdown(context.Background())
+	if err := r.Register("late", noop); !errors.Is(err, ErrRegistryStarted) {
+		t.Errorf("Expected ErrRegistryStarted, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+-- Migration: Per-channel content filter terms
+-- Channel owners can ban words or link fragments; new posts and comments
+-- in the channel are checked against these terms before being stored
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS ChannelFilterTerms (
+    ID INTEGER PRIMARY KEY,
+    ChannelID INTEGER NOT NULL,
+    Term TEXT NOT NULL,                 -- stored lowercase
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ChannelID) REFERENCES Channels(ID) ON DELETE CASCADE,
+    UNIQUE (ChannelID, Term)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channelfilterterms_channel ON ChannelFilterTerms(ChannelID);
+
+COMMIT;
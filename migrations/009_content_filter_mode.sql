@@ -0,0 +1,14 @@
+-- Migration: Per-channel content filter mode
+-- 'reject' refuses content that trips the filter (the default when no row exists),
+-- 'flag' stores it with IsFlagged = 1 and opens an unapproved Flag for moderators
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS ChannelFilterSettings (
+    ChannelID INTEGER PRIMARY KEY,
+    Mode TEXT NOT NULL DEFAULT 'reject' CHECK (Mode IN ('reject', 'flag')),
+    Updated DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ChannelID) REFERENCES Channels(ID) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- Migration: Append-only reaction history
+-- Reactions holds only each user's current reaction per target; every toggle that
+-- goes through ReactionModel.Upsert also appends a row here so it can be replayed.
+-- Kind is the button pressed, Action is whether that press turned it on or off.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS ReactionEvents (
+    ID INTEGER PRIMARY KEY,
+    AuthorID BLOB NOT NULL,
+    TargetType TEXT NOT NULL CHECK (TargetType IN ('post', 'comment')),
+    TargetID INTEGER NOT NULL,
+    Kind TEXT NOT NULL CHECK (Kind IN ('like', 'dislike')),
+    Action TEXT NOT NULL CHECK (Action IN ('add', 'remove')),
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (AuthorID) REFERENCES Users(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactionevents_author ON ReactionEvents(AuthorID, ID);
+CREATE INDEX IF NOT EXISTS idx_reactionevents_target ON ReactionEvents(TargetType, TargetID);
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Migration: Session fingerprint
+-- Login records the client IP and User-Agent next to the session token so the
+-- optional SESSION_BINDING policy can reject a token replayed from another client.
+-- Empty means no fingerprint was captured (sessions created before this migration).
+
+BEGIN TRANSACTION;
+
+ALTER TABLE Users ADD COLUMN SessionIP TEXT NOT NULL DEFAULT '';
+ALTER TABLE Users ADD COLUMN SessionUserAgent TEXT NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- Migration: Per-chat message sequence
+-- Created has one-second resolution, so messages sent together cannot be ordered by it.
+-- Each message now gets the next number in its chat when it is persisted; clients order
+-- and deduplicate live messages by (ChatID, Sequence). Existing messages are numbered
+-- by Created, ties broken by ID.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE Messages ADD COLUMN Sequence INTEGER NOT NULL DEFAULT 0;
+
+UPDATE Messages SET Sequence = (
+    SELECT COUNT(*) FROM Messages earlier
+    WHERE earlier.ChatID = Messages.ChatID
+      AND (earlier.Created < Messages.Created
+           OR (earlier.Created = Messages.Created AND earlier.ID <= Messages.ID))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_chatid_sequence ON Messages(ChatID, Sequence);
+
+COMMIT;
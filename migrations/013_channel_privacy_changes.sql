@@ -0,0 +1,22 @@
+-- Migration: Channel privacy history
+-- ChannelModel.SetPrivacy is the only path that flips Channels.Privacy after creation,
+-- and it appends a row here naming the owner who did it. Changing privacy does not touch
+-- existing memberships, moderators or posts. It changes who can see them (private
+-- channels and their posts are hidden from anonymous visitors) and how later moderation
+-- requests are handled (private channels route them to the owner for approval).
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS ChannelPrivacyChanges (
+    ID INTEGER PRIMARY KEY,
+    ChannelID INTEGER NOT NULL,
+    ChangedBy BLOB NOT NULL,
+    Private INTEGER NOT NULL CHECK (Private IN (0,1)),
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ChannelID) REFERENCES Channels(ID) ON DELETE CASCADE,
+    FOREIGN KEY (ChangedBy) REFERENCES Users(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_channelprivacychanges_channel ON ChannelPrivacyChanges(ChannelID, ID);
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- Migration: UTC RFC3339 log timestamps
+-- Log rows used to be written with whatever offset the server's local zone had, next to
+-- DEFAULT CURRENT_TIMESTAMP rows in SQLite's "YYYY-MM-DD HH:MM:SS" form, so comparing
+-- Timestamp against a `since` value as text gave the wrong answer. LoggingModel now writes
+-- models.TimestampLayout (fixed-width RFC3339, UTC); this rewrites existing rows to match.
+-- strftime applies any stored offset, so each row keeps its instant (to the millisecond).
+
+BEGIN TRANSACTION;
+
+UPDATE RequestLogs SET Timestamp = strftime('%Y-%m-%dT%H:%M:%f', Timestamp) || '000000Z'
+WHERE Timestamp NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T*Z'
+  AND strftime('%Y-%m-%dT%H:%M:%f', Timestamp) IS NOT NULL;
+
+UPDATE ErrorLogs SET Timestamp = strftime('%Y-%m-%dT%H:%M:%f', Timestamp) || '000000Z'
+WHERE Timestamp NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T*Z'
+  AND strftime('%Y-%m-%dT%H:%M:%f', Timestamp) IS NOT NULL;
+
+UPDATE SystemMetrics SET Timestamp = strftime('%Y-%m-%dT%H:%M:%f', Timestamp) || '000000Z'
+WHERE Timestamp NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T*Z'
+  AND strftime('%Y-%m-%dT%H:%M:%f', Timestamp) IS NOT NULL;
+
+COMMIT;
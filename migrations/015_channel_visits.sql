@@ -0,0 +1,17 @@
+-- Migration: Channel visits
+-- One row per user and channel holding when the user last opened the channel page, so
+-- posts created after it can be shown as new. Times are CURRENT_TIMESTAMP (UTC) like
+-- Posts.Created, and rows go away with the user or the channel.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS ChannelVisits (
+    UserID BLOB NOT NULL,
+    ChannelID INTEGER NOT NULL,
+    LastVisited DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (UserID, ChannelID),
+    FOREIGN KEY (UserID) REFERENCES Users(ID) ON DELETE CASCADE,
+    FOREIGN KEY (ChannelID) REFERENCES Channels(ID) ON DELETE CASCADE
+);
+
+COMMIT;
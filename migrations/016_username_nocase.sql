@@ -0,0 +1,11 @@
+-- Migration: Case-insensitive usernames
+-- Usernames keep the case they were registered with, but "Alice" and "alice" are the
+-- same account: lookups compare with COLLATE NOCASE and this index stops a second
+-- registration that differs only in case. If existing users clash, rename one of
+-- them before applying the migration.
+
+BEGIN TRANSACTION;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_nocase ON Users(Username COLLATE NOCASE);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Migration: Membership roles
+-- A channel's owner is also a member of it, with the role 'owner'; everyone who
+-- joins is a 'member'. Existing owners are added to their channels, or have their
+-- membership promoted if they had already joined.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE Memberships ADD COLUMN Role TEXT NOT NULL DEFAULT 'member' CHECK (Role IN ('member', 'owner'));
+
+INSERT INTO Memberships (UserID, ChannelID, Role)
+SELECT OwnerID, ID, 'owner' FROM Channels WHERE true
+ON CONFLICT (UserID, ChannelID) DO UPDATE SET Role = 'owner';
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Migration: Audit log
+-- One row per moderation or admin action: who did it (ActorID), what they did (Action,
+-- e.g. "channel.privacy_changed"), what it was done to (TargetType plus TargetID, which
+-- is text so it can hold user UUIDs as well as integer IDs), the channel it happened in
+-- if any, and a JSON object of action-specific details. There are deliberately no
+-- foreign keys: the record has to outlive the users and channels it mentions.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS AuditLog (
+    ID INTEGER PRIMARY KEY,
+    ActorID BLOB NOT NULL,
+    Action TEXT NOT NULL,
+    TargetType TEXT NOT NULL,
+    TargetID TEXT NOT NULL,
+    ChannelID INTEGER,
+    Metadata TEXT NOT NULL DEFAULT '{}',
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_auditlog_actor ON AuditLog(ActorID, ID);
+CREATE INDEX IF NOT EXISTS idx_auditlog_channel ON AuditLog(ChannelID, ID);
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Migration: Emoji reactions on chat messages
+-- One row per user, message and emoji, so a user can leave several different emoji on
+-- one message but each only once. Reacting again with the same emoji removes the row.
+-- Rows go away with the message or the user.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS MessageReactions (
+    MessageID BLOB NOT NULL,
+    UserID BLOB NOT NULL,
+    Emoji TEXT NOT NULL,
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (MessageID, UserID, Emoji),
+    FOREIGN KEY (MessageID) REFERENCES Messages(ID) ON DELETE CASCADE,
+    FOREIGN KEY (UserID) REFERENCES Users(ID) ON DELETE CASCADE
+);
+
+COMMIT;
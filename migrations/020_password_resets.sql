@@ -0,0 +1,19 @@
+-- Migration: Password reset tokens
+-- One row per reset link sent. Only the SHA-256 hash of the emailed token is stored, so
+-- a leaked database cannot be used to reset passwords. A token works once, before
+-- Expires; Used is set when it is consumed. Rows go away with the user.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS PasswordResets (
+    TokenHash TEXT PRIMARY KEY,
+    UserID BLOB NOT NULL,
+    Expires DATETIME NOT NULL,
+    Used DATETIME,
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (UserID) REFERENCES Users(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_passwordresets_user ON PasswordResets(UserID);
+
+COMMIT;
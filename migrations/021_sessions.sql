@@ -0,0 +1,30 @@
+-- Migration: Session store
+-- One row per logged-in device, so logging in on a second device no longer ends the
+-- first. Each session has its own session and CSRF tokens, its expiry, and the IP and
+-- User-Agent it was issued to. Rows go away with the user.
+-- Users.SessionToken, CsrfToken, CookiesExpire, SessionIP and SessionUserAgent are no
+-- longer used; live sessions are copied across so nobody is logged out by the upgrade.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS Sessions (
+    ID INTEGER PRIMARY KEY,
+    Token TEXT NOT NULL UNIQUE,
+    CSRFToken TEXT NOT NULL DEFAULT '',
+    UserID BLOB NOT NULL,
+    Expires DATETIME NOT NULL,
+    IP TEXT NOT NULL DEFAULT '',
+    UserAgent TEXT NOT NULL DEFAULT '',
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (UserID) REFERENCES Users(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON Sessions(UserID);
+
+INSERT OR IGNORE INTO Sessions (Token, CSRFToken, UserID, Expires, IP, UserAgent)
+SELECT SessionToken, COALESCE(CsrfToken, ''), ID, CookiesExpire,
+    COALESCE(SessionIP, ''), COALESCE(SessionUserAgent, '')
+FROM Users
+WHERE SessionToken IS NOT NULL AND SessionToken != '' AND CookiesExpire IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Migration: Chat read state
+-- Each participant's position in a chat: the Sequence of the last message they have
+-- read, 0 when they have read nothing. It only moves forward, so a read receipt from an
+-- older client or a second device never marks messages unread again.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE ChatUsers ADD COLUMN LastReadSequence INTEGER NOT NULL DEFAULT 0;
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Migration: Comment edit history
+-- Each edit of a comment stores the text it replaced, who made the edit and when, so
+-- moderators can see what a comment said before it was changed. Revisions go away
+-- with the comment.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS CommentRevisions (
+    ID INTEGER PRIMARY KEY,
+    CommentID INTEGER NOT NULL,
+    Content TEXT NOT NULL,
+    EditorID BLOB NOT NULL,
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (CommentID) REFERENCES Comments(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment ON CommentRevisions(CommentID);
+
+COMMIT;
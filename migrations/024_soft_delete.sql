@@ -0,0 +1,11 @@
+-- Migration: Soft delete for posts and comments
+-- Deleting a post or comment now sets DeletedAt instead of removing the row, so the
+-- replies below it stay attached. Deleted rows are left out of listings and searches;
+-- the models' AdminPurge methods remove them for good.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE Posts ADD COLUMN DeletedAt DATETIME;
+ALTER TABLE Comments ADD COLUMN DeletedAt DATETIME;
+
+COMMIT;
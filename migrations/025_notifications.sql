@@ -0,0 +1,28 @@
+-- Migration: Notifications
+-- One row per notification per recipient: who caused it (ActorID), what happened
+-- (Kind) and the post or comment it is about. ReadAt is set once the recipient has
+-- seen it. Rows go away with either user.
+-- The original Notifications and NotificationsUsers tables were never written to;
+-- they are replaced rather than migrated.
+
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS NotificationsUsers;
+DROP TABLE IF EXISTS Notifications;
+
+CREATE TABLE Notifications (
+    ID INTEGER PRIMARY KEY,
+    UserID BLOB NOT NULL,
+    ActorID BLOB NOT NULL,
+    Kind TEXT NOT NULL CHECK (Kind IN ('mention', 'reply', 'follow', 'reaction')),
+    PostID INTEGER,
+    CommentID INTEGER,
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ReadAt DATETIME,
+    FOREIGN KEY (UserID) REFERENCES Users(ID) ON DELETE CASCADE,
+    FOREIGN KEY (ActorID) REFERENCES Users(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON Notifications(UserID, ReadAt);
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- Migration: Request IDs on log rows
+-- Every request is assigned an ID (sent back as X-Request-ID). Storing it on the
+-- request's RequestLogs row and on the ErrorLogs rows written while serving it lets
+-- the two be joined. Rows from before this migration, and errors logged outside a
+-- request, have no RequestID.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE RequestLogs ADD COLUMN RequestID TEXT;
+ALTER TABLE ErrorLogs ADD COLUMN RequestID TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_requestlogs_requestid ON RequestLogs(RequestID);
+CREATE INDEX IF NOT EXISTS idx_errorlogs_requestid ON ErrorLogs(RequestID);
+
+COMMIT;
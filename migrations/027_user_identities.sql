@@ -0,0 +1,20 @@
+-- Migration: External login identities
+-- One row per account at an OAuth provider (Google, GitHub) that may log in as a
+-- local user. Subject is the provider's stable ID for the account, which survives
+-- email and username changes on the provider's side. A user can have several.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS UserIdentities (
+    Provider TEXT NOT NULL,
+    Subject TEXT NOT NULL,
+    UserID BLOB NOT NULL,
+    Email TEXT,
+    Created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (Provider, Subject),
+    FOREIGN KEY (UserID) REFERENCES Users(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_useridentities_user ON UserIdentities(UserID);
+
+COMMIT;